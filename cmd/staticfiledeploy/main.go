@@ -0,0 +1,393 @@
+// Command staticfiledeploy deploys static sites from ZIP artifacts to S3 buckets outside of Terraform, e.g. for
+// emergency deploys while Terraform cannot be applied. It deploys with the same deployer package as the
+// staticfiledeploy_deployment resource, so a deployment made with it is the same as one made by the resource with
+// the same settings, and the next apply finds the target up to date.
+//
+// Usage:
+//
+//	staticfiledeploy deploy -source s3://artifacts/site/build-42.zip -target www.example.com -delete-removed-files
+//	staticfiledeploy diff -source s3://artifacts/site/build-43.zip -target www.example.com
+//	staticfiledeploy verify -source s3://artifacts/site/build-42.zip -target www.example.com
+//	staticfiledeploy rollback -target www.example.com -to 5f1c2e9a0b3d4c7e
+//
+// AWS credentials and the default region are read the same way as by the provider, e.g. from AWS_PROFILE or
+// AWS_REGION.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+const usage = `Usage: staticfiledeploy <command> [flags]
+
+Commands:
+  deploy    Deploy an artifact to a target
+  diff      Show the files deploying an artifact would add, change and delete
+  verify    Check that every file of an artifact is deployed with the expected size and content type
+  rollback  Make an earlier release of a blue/green target the live one
+
+Run "staticfiledeploy <command> -h" for the flags of a command.
+`
+
+// s3EndpointEnv is the environment variable the provider reads the S3 endpoint from.
+const s3EndpointEnv = "STATICFILEDEPLOY_S3_ENDPOINT"
+
+// newDeployerFunc returns the deployer to deploy with, given the S3 endpoint to send requests to, if any.
+type newDeployerFunc func(ctx context.Context, s3Endpoint string) (*deployer.Deployer, error)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, newDeployer)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newDeployer returns a deployer with the default AWS configuration.
+func newDeployer(ctx context.Context, s3Endpoint string) (*deployer.Deployer, error) {
+	retry := deployer.RetryPolicy{}
+	cfg, err := config.LoadDefaultConfig(ctx, retry.ConfigOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load AWS configuration: %w", err)
+	}
+	return &deployer.Deployer{DefaultAWSConfig: cfg, Retry: retry, S3Endpoint: s3Endpoint}, nil
+}
+
+// run runs the command with the given arguments, writing its output to stdout.
+func run(ctx context.Context, args []string, stdout io.Writer, newDeployer newDeployerFunc) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "deploy":
+		return runDeploy(ctx, args[1:], stdout, newDeployer)
+	case "diff":
+		return runDiff(ctx, args[1:], stdout, newDeployer)
+	case "verify":
+		return runVerify(ctx, args[1:], stdout, newDeployer)
+	case "rollback":
+		return runRollback(ctx, args[1:], stdout, newDeployer)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, see \"staticfiledeploy help\"", args[0])
+	}
+}
+
+// targetFlags are the flags selecting the target of a deployment, which every command has.
+type targetFlags struct {
+	target       string
+	targetRegion string
+	targetPrefix string
+	s3Endpoint   string
+
+	blueGreen     bool
+	releasePrefix string
+	pointerKey    string
+}
+
+func (f *targetFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.target, "target", "", "the name or ARN of the S3 bucket to deploy to (required)")
+	fs.StringVar(&f.targetRegion, "target-region", "eu-west-1", "the region of the target bucket")
+	fs.StringVar(&f.targetPrefix, "target-prefix", "", "the prefix to deploy the files under, e.g. site/")
+	fs.StringVar(&f.s3Endpoint, "s3-endpoint", os.Getenv(s3EndpointEnv), "the URL of an S3 compatible service to use instead of AWS, e.g. LocalStack or MinIO")
+	fs.BoolVar(&f.blueGreen, "blue-green", false, "whether the target has blue/green deployments")
+	fs.StringVar(&f.releasePrefix, "release-prefix", deployer.DefaultReleasePrefix, "the prefix releases of blue/green deployments are uploaded under")
+	fs.StringVar(&f.pointerKey, "pointer-key", deployer.DefaultReleasePointerKey, "the key of the release pointer of blue/green deployments")
+}
+
+// newDeployment returns a deployment of the artifact in the given source bucket to the target.
+func (f *targetFlags) newDeployment(ctx context.Context, newDeployer newDeployerFunc, sourceBucket string) (*deployer.Deployment, error) {
+	if f.target == "" {
+		return nil, errors.New("-target is required")
+	}
+	d, err := newDeployer(ctx, f.s3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := f.target
+	if i := strings.LastIndex(bucket, ":"); i >= 0 {
+		bucket = bucket[i+1:]
+	}
+	deployment := d.NewDeployment(sourceBucket, bucket, f.targetRegion)
+	deployment.TargetPrefix = f.targetPrefix
+	if f.blueGreen {
+		deployment.BlueGreen = &deployer.BlueGreen{ReleasePrefix: f.releasePrefix, PointerKey: f.pointerKey}
+	}
+	return deployment, nil
+}
+
+// useLiveRelease makes blue/green deployments compare the artifact with the live release rather than a new one.
+func (f *targetFlags) useLiveRelease(ctx context.Context, deployment *deployer.Deployment) error {
+	if !f.blueGreen {
+		return nil
+	}
+	live, err := deployment.LiveRelease(ctx)
+	if err != nil {
+		return err
+	}
+	if live == "" {
+		return fmt.Errorf("no release of %s is live", deployment.TargetBucket)
+	}
+	deployment.ID = live
+	return nil
+}
+
+// sourceFlags are the flags selecting the artifact to deploy.
+type sourceFlags struct {
+	source        string
+	sourceVersion string
+	sourceRoot    string
+}
+
+func (f *sourceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.source, "source", "", "the artifact to deploy, e.g. s3://artifacts/site.zip, https://example.com/site.zip or codepipeline://<pipeline>/<execution ID>/<artifact> (required)")
+	fs.StringVar(&f.sourceVersion, "source-version", "", "the version of the artifact in a versioned S3 bucket, instead of the latest version")
+	fs.StringVar(&f.sourceRoot, "source-root", "", "the directory in the artifact to deploy, e.g. dist/")
+}
+
+// location returns the source bucket and key to deploy from. Artifacts that are not in S3 are deployed from an empty
+// source bucket with their URI as the key.
+func (f *sourceFlags) location() (string, string, error) {
+	if f.source == "" {
+		return "", "", errors.New("-source is required")
+	}
+	if !strings.HasPrefix(f.source, "s3://") {
+		return "", f.source, nil
+	}
+	bucket, key, found := strings.Cut(strings.TrimPrefix(f.source, "s3://"), "/")
+	if !found || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid source format: %s", f.source)
+	}
+	return bucket, key, nil
+}
+
+// version returns the version of the artifact to deploy, or nil for the latest version.
+func (f *sourceFlags) version() *string {
+	if f.sourceVersion == "" {
+		return nil
+	}
+	return &f.sourceVersion
+}
+
+// artifactDeployment returns a deployment of the artifact selected by the source flags.
+func artifactDeployment(ctx context.Context, newDeployer newDeployerFunc, source *sourceFlags, target *targetFlags) (*deployer.Deployment, string, error) {
+	sourceBucket, key, err := source.location()
+	if err != nil {
+		return nil, "", err
+	}
+	deployment, err := target.newDeployment(ctx, newDeployer, sourceBucket)
+	if err != nil {
+		return nil, "", err
+	}
+	deployment.SourceRoot = source.sourceRoot
+	return deployment, key, nil
+}
+
+// parseFlags parses the arguments of a command, which takes no positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+func runDeploy(ctx context.Context, args []string, stdout io.Writer, newDeployer newDeployerFunc) error {
+	fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	var source sourceFlags
+	var target targetFlags
+	source.register(fs)
+	target.register(fs)
+	sourceChecksum := fs.String("source-checksum", "", "the expected checksum of the artifact, e.g. sha256:...")
+	deleteRemovedFiles := fs.Bool("delete-removed-files", false, "whether to delete files that are not in the artifact from the target")
+	keepFiles := fs.String("keep-files", "", "comma-separated glob patterns of files that are never deleted")
+	hashedAssetPattern := fs.String("hashed-asset-pattern", "", "a regular expression matching files with content hashes in their names, which are cached forever")
+	preflight := fs.Bool("preflight-checks", true, "whether to check that the source can be read and the target accessed before deploying")
+	preflightWriteProbe := fs.Bool("preflight-write-probe", false, "whether the preflight checks also write and delete a probe object in the target")
+	verify := fs.Bool("verify-after-deploy", false, "whether to check every deployed object after the deployment")
+	conditionalWrites := fs.Bool("conditional-writes", false, "whether to fail instead of overwriting files changed by a concurrent deployment")
+	rollbackOnFailure := fs.Bool("rollback-on-failure", false, "whether to undo the changes of a deployment that fails")
+	stagedPromotion := fs.Bool("staged-promotion", false, "whether to stage and verify changed files before copying them to their keys")
+	hashAlgorithm := fs.String("hash-algorithm", string(deployer.HashAlgorithmMD5), "the algorithm of the hashes in the report")
+	reportPath := fs.String("report", "", "the path to write a JSON report of the deployment to")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	deployment, key, err := artifactDeployment(ctx, newDeployer, &source, &target)
+	if err != nil {
+		return err
+	}
+	deployment.SourceChecksum = *sourceChecksum
+	deployment.DeleteRemovedFiles = *deleteRemovedFiles
+	if *keepFiles != "" {
+		deployment.KeepFiles = append(deployment.KeepFiles, strings.Split(*keepFiles, ",")...)
+	}
+	deployment.HashedAssetPattern = *hashedAssetPattern
+	deployment.Preflight = *preflight
+	deployment.PreflightWriteProbe = *preflightWriteProbe
+	deployment.VerifyAfterDeploy = *verify
+	deployment.ConditionalWrites = *conditionalWrites
+	deployment.RollbackOnFailure = *rollbackOnFailure
+	deployment.StagedPromotion = *stagedPromotion
+	deployment.HashAlgorithm, err = deployer.ParseHashAlgorithm(*hashAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	files, deployErr := deployment.Deploy(ctx, key, source.version())
+	summary := deployment.Summary(key, source.sourceVersion, files, deployErr)
+	if *reportPath != "" {
+		err = deployer.WriteDeploymentReport(*reportPath, deployment.Report(summary))
+		if err != nil && deployErr == nil {
+			return err
+		}
+	}
+	if deployErr != nil {
+		return deployErr
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+func runDiff(ctx context.Context, args []string, stdout io.Writer, newDeployer newDeployerFunc) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var source sourceFlags
+	var target targetFlags
+	source.register(fs)
+	target.register(fs)
+	deleteRemovedFiles := fs.Bool("delete-removed-files", false, "whether to show the files that are not in the artifact as deleted")
+	keepFiles := fs.String("keep-files", "", "comma-separated glob patterns of files that are never deleted")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	deployment, key, err := artifactDeployment(ctx, newDeployer, &source, &target)
+	if err != nil {
+		return err
+	}
+	deployment.DeleteRemovedFiles = *deleteRemovedFiles
+	if *keepFiles != "" {
+		deployment.KeepFiles = append(deployment.KeepFiles, strings.Split(*keepFiles, ",")...)
+	}
+	err = target.useLiveRelease(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	changes, err := deployment.PlanChanges(ctx, key, source.version())
+	if err != nil {
+		return err
+	}
+	if changes.Empty() {
+		fmt.Fprintln(stdout, "No changes.")
+		return nil
+	}
+	for _, change := range []struct {
+		symbol string
+		keys   []string
+	}{{"+", changes.Added}, {"~", changes.Changed}, {"-", changes.Deleted}} {
+		for _, key := range change.keys {
+			fmt.Fprintln(stdout, change.symbol, key)
+		}
+	}
+	fmt.Fprintf(stdout, "%d to add, %d to change, %d to delete.\n", len(changes.Added), len(changes.Changed), len(changes.Deleted))
+	return nil
+}
+
+func runVerify(ctx context.Context, args []string, stdout io.Writer, newDeployer newDeployerFunc) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var source sourceFlags
+	var target targetFlags
+	source.register(fs)
+	target.register(fs)
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	deployment, key, err := artifactDeployment(ctx, newDeployer, &source, &target)
+	if err != nil {
+		return err
+	}
+	err = target.useLiveRelease(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	err = deployment.Verify(ctx, key, source.version())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "All files are deployed.")
+	return nil
+}
+
+func runRollback(ctx context.Context, args []string, stdout io.Writer, newDeployer newDeployerFunc) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	var target targetFlags
+	target.register(fs)
+	to := fs.String("to", "", "the deployment ID of the release to make live (required)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage of rollback:")
+		fmt.Fprintln(fs.Output(), "Makes an earlier release of a blue/green target live again. Targets without blue/green deployments are rolled back by deploying an earlier artifact.")
+		fs.PrintDefaults()
+	}
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *to == "" {
+		return errors.New("-to is required")
+	}
+
+	// Only blue/green targets keep earlier releases to switch back to.
+	target.blueGreen = true
+	deployment, err := target.newDeployment(ctx, newDeployer, "")
+	if err != nil {
+		return err
+	}
+	previous, err := deployment.LiveRelease(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = deployment.SwitchRelease(ctx, *to)
+	if err != nil {
+		return err
+	}
+	if previous == "" {
+		fmt.Fprintf(stdout, "Release %s is live.\n", *to)
+	} else {
+		fmt.Fprintf(stdout, "Release %s is live, replacing %s.\n", *to, previous)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// putTestArtifact uploads a ZIP artifact with the given files to the given key in the "artifacts" bucket.
+func putTestArtifact(t *testing.T, client *s3fake.Client, key string, files map[string]string) {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("artifacts"),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(buf.Bytes()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runWithFake runs the command against the given fake, and returns its output.
+func runWithFake(t *testing.T, client *s3fake.Client, args ...string) (string, error) {
+	t.Helper()
+
+	newDeployer := func(ctx context.Context, s3Endpoint string) (*deployer.Deployer, error) {
+		return &deployer.Deployer{S3Client: func(region string) deployer.S3API { return client }}, nil
+	}
+	var stdout bytes.Buffer
+	err := run(context.Background(), args, &stdout, newDeployer)
+	return stdout.String(), err
+}
+
+func TestRun_deployDiffVerify(t *testing.T) {
+	client := s3fake.New()
+	putTestArtifact(t, client, "site-1.zip", map[string]string{"index.html": "one", "old.js": "old"})
+	putTestArtifact(t, client, "site-2.zip", map[string]string{"index.html": "two", "new.js": "new"})
+
+	if _, err := runWithFake(t, client, "deploy", "-source", "s3://artifacts/site-1.zip", "-target", "www"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := client.Keys("www"); !reflect.DeepEqual(keys, []string{"index.html", "old.js"}) {
+		t.Errorf("unexpected objects in the target: %v", keys)
+	}
+	if _, err := runWithFake(t, client, "verify", "-source", "s3://artifacts/site-1.zip", "-target", "www"); err != nil {
+		t.Errorf("expected the deployed artifact to verify, got %v", err)
+	}
+
+	output, err := runWithFake(t, client, "diff", "-source", "s3://artifacts/site-2.zip", "-target", "www", "-delete-removed-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "+ new.js\n~ index.html\n- old.js\n1 to add, 1 to change, 1 to delete.\n"; output != want {
+		t.Errorf("unexpected diff:\n%s", output)
+	}
+	if _, err := runWithFake(t, client, "verify", "-source", "s3://artifacts/site-2.zip", "-target", "www"); err == nil {
+		t.Error("expected an artifact that is not deployed to fail verification")
+	}
+
+	output, err = runWithFake(t, client, "deploy", "-source", "s3://artifacts/site-2.zip", "-target", "www", "-delete-removed-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, `"files_deleted": 1`) {
+		t.Errorf("expected the summary to report the deleted file, got %s", output)
+	}
+	if keys := client.Keys("www"); !reflect.DeepEqual(keys, []string{"index.html", "new.js"}) {
+		t.Errorf("unexpected objects in the target: %v", keys)
+	}
+}
+
+func TestRun_rollback(t *testing.T) {
+	client := s3fake.New()
+	putTestArtifact(t, client, "site.zip", map[string]string{"index.html": "hello"})
+	for _, key := range []string{"releases/first/index.html", "releases/second/index.html"} {
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("www"), Key: aws.String(key), Body: strings.NewReader("hello")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runWithFake(t, client, "rollback", "-target", "www", "-to", "second"); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runWithFake(t, client, "rollback", "-target", "www", "-to", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "Release first is live, replacing second.\n" {
+		t.Errorf("unexpected output: %s", output)
+	}
+	if _, err := runWithFake(t, client, "rollback", "-target", "www", "-to", "missing"); err == nil {
+		t.Error("expected rolling back to a missing release to fail")
+	}
+
+	output, err = runWithFake(t, client, "diff", "-source", "s3://artifacts/site.zip", "-target", "www", "-blue-green")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "No changes.\n" {
+		t.Errorf("expected the live release to match the artifact, got %s", output)
+	}
+}
+
+func TestRun_unknownCommand(t *testing.T) {
+	if _, err := runWithFake(t, s3fake.New(), "promote"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected an unknown command error, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+data "staticfiledeploy_diff" "example_diff" {
+  source = "my-artifact-bucket/path/to/source.zip"
+  target = "my-website-bucket"
+}
+
+output "invalidation_paths" {
+  value = [for key in concat(data.staticfiledeploy_diff.example_diff.changed, data.staticfiledeploy_diff.example_diff.removed) : "/${key}"]
+}
@@ -0,0 +1,15 @@
+# Deployments are imported using the source, the target bucket and optionally the target prefix, in the same format as
+# their id.
+terraform import staticfiledeploy_deployment.example_deployment 123456789012-artifacts/petstore/1.2.3.zip,123456789012-my-cool-bucket,static
+
+# With Terraform 1.12 and later, deployments can also be imported by their identity, the target bucket and optionally
+# the target prefix, in an import block. The source is then taken from the configuration when the deployment is next
+# applied, which deploys only the files that differ from the target.
+#
+# import {
+#   to = staticfiledeploy_deployment.example_deployment
+#   identity = {
+#     target_bucket = "123456789012-my-cool-bucket"
+#     target_prefix = "static"
+#   }
+# }
@@ -7,7 +7,13 @@ data "aws_s3_bucket" "website_bucket" {
 }
 
 resource "staticfiledeploy_deployment" "example_deployment" {
-  source         = "${data.vy_artifact_version.this.store}/${data.vy_artifact_version.this.path}"
-  source_version = data.vy_artifact_version.this.version
-  target         = data.aws_s3_bucket.website_bucket.bucket
+  source {
+    bucket  = data.vy_artifact_version.this.store
+    key     = data.vy_artifact_version.this.path
+    version = data.vy_artifact_version.this.version
+  }
+
+  target {
+    bucket = data.aws_s3_bucket.website_bucket.bucket
+  }
 }
\ No newline at end of file
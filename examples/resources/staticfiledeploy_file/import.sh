@@ -0,0 +1,2 @@
+# Files can be imported using the target bucket and key
+terraform import staticfiledeploy_file.robots 123456789012-my-cool-bucket/robots.txt
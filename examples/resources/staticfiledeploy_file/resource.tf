@@ -0,0 +1,10 @@
+data "aws_s3_bucket" "website_bucket" {
+  bucket = "123456789012-my-cool-bucket"
+}
+
+resource "staticfiledeploy_file" "robots" {
+  target        = data.aws_s3_bucket.website_bucket.bucket
+  key           = "robots.txt"
+  content       = "User-agent: *\nDisallow: /admin/\n"
+  cache_control = "public, max-age=3600"
+}
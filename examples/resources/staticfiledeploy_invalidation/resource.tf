@@ -0,0 +1,7 @@
+resource "staticfiledeploy_invalidation" "example_invalidation" {
+  distribution_id = "E1ABCDEFGHIJKL"
+
+  triggers = {
+    source_etag = staticfiledeploy_deployment.example_deployment.source_etag
+  }
+}
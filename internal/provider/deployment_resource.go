@@ -5,100 +5,2144 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/config"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/objectvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/defaults"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/nsbno/terraform-provider-static-file-deploy/internal/deployer"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
+const (
+	// targetTypeS3 is the target_type of deployments to S3 buckets.
+	targetTypeS3 = "s3"
+	// targetTypeGCS is the target_type of deployments to Google Cloud Storage buckets.
+	targetTypeGCS = "gcs"
+	// targetTypeAzure is the target_type of deployments to Azure Blob Storage containers.
+	targetTypeAzure = "azure"
+)
+
+// sourceVersionLatest is the source version of deployments of the version the source has when they are applied.
+const sourceVersionLatest = "latest"
+
+// sourceVersionTagPrefix starts the source version of deployments of the newest version of the source with a tag,
+// e.g. "tag:environment=staging".
+const sourceVersionTagPrefix = "tag:"
+
+// sourceVersionPath is the path of the configured version of the source.
+var sourceVersionPath = path.Root("source").AtName("version")
+
+// defaultDeploymentTimeout is how long a deployment may take unless configured in the timeouts block.
+const defaultDeploymentTimeout = 30 * time.Minute
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &DeploymentResource{}
 var _ resource.ResourceWithImportState = &DeploymentResource{}
+var _ resource.ResourceWithIdentity = &DeploymentResource{}
+var _ resource.ResourceWithValidateConfig = &DeploymentResource{}
+var _ resource.ResourceWithModifyPlan = &DeploymentResource{}
+var _ resource.ResourceWithUpgradeState = &DeploymentResource{}
+
+func NewDeploymentResource() resource.Resource {
+	return &DeploymentResource{}
+}
+
+// DeploymentResource defines the resource implementation.
+type DeploymentResource struct {
+	deployer *deployer.Deployer
+}
+
+// DeploymentResourceModel describes the resource data model.
+type DeploymentResourceModel struct {
+	ID             types.String `tfsdk:"id"`
+	SourceChecksum types.String `tfsdk:"source_checksum"`
+	SourceETag     types.String `tfsdk:"source_etag"`
+	SourceRoot     types.String `tfsdk:"source_root"`
+	TargetType     types.String `tfsdk:"target_type"`
+	AzureContainer types.String `tfsdk:"azure_container"`
+
+	Source *DeploymentSourceModel `tfsdk:"source"`
+	Target *DeploymentTargetModel `tfsdk:"target"`
+
+	InventoryLocation types.String `tfsdk:"inventory_location"`
+	HashAlgorithm     types.String `tfsdk:"hash_algorithm"`
+
+	ResolvedSourceVersion types.String `tfsdk:"resolved_source_version"`
+
+	PreflightChecks         types.Bool   `tfsdk:"preflight_checks"`
+	PreflightWriteProbe     types.Bool   `tfsdk:"preflight_write_probe"`
+	VerifyAfterDeploy       types.Bool   `tfsdk:"verify_after_deploy"`
+	ConditionalWrites       types.Bool   `tfsdk:"conditional_writes"`
+	DeleteRemovedFiles      types.Bool   `tfsdk:"delete_removed_files"`
+	ResumeFailedDeployments types.Bool   `tfsdk:"resume_failed_deployments"`
+	RollbackOnFailure       types.Bool   `tfsdk:"rollback_on_failure"`
+	StagedPromotion         types.Bool   `tfsdk:"staged_promotion"`
+	TagObjects              types.Bool   `tfsdk:"tag_objects"`
+	KeepDeployments         types.Int64  `tfsdk:"keep_deployments"`
+	PurgeOnDestroy          types.Bool   `tfsdk:"purge_on_destroy"`
+	Paused                  types.Bool   `tfsdk:"paused"`
+	DeletionProtection      types.Bool   `tfsdk:"deletion_protection"`
+	KeepFiles               types.List   `tfsdk:"keep_files"`
+	UnmanagedPaths          types.List   `tfsdk:"unmanaged_paths"`
+	UploadOrder             types.List   `tfsdk:"upload_order"`
+	RequiredFiles           types.List   `tfsdk:"required_files"`
+	HashedAssetPattern      types.String `tfsdk:"hashed_asset_pattern"`
+	PreDeployLambdaArn      types.String `tfsdk:"pre_deploy_lambda_arn"`
+	PostDeployLambdaArn     types.String `tfsdk:"post_deploy_lambda_arn"`
+
+	MultipartPartSizeMB  types.Int64 `tfsdk:"multipart_part_size_mb"`
+	MultipartConcurrency types.Int64 `tfsdk:"multipart_concurrency"`
+	DownloadConcurrency  types.Int64 `tfsdk:"download_concurrency"`
+	MaxRequestsPerSecond types.Int64 `tfsdk:"max_requests_per_second"`
+	MaxArtifactFiles     types.Int64 `tfsdk:"max_artifact_files"`
+	MaxArtifactSizeMB    types.Int64 `tfsdk:"max_artifact_size_mb"`
+	MinFiles             types.Int64 `tfsdk:"min_files"`
+	MinTotalBytes        types.Int64 `tfsdk:"min_total_bytes"`
+	PlanMaxFiles         types.Int64 `tfsdk:"plan_max_files"`
+
+	SourceSSECustomerKey    types.String `tfsdk:"source_sse_customer_key"`
+	SourceSSECustomerKeyMD5 types.String `tfsdk:"source_sse_customer_key_md5"`
+	TargetSSECustomerKey    types.String `tfsdk:"target_sse_customer_key"`
+	TargetSSECustomerKeyMD5 types.String `tfsdk:"target_sse_customer_key_md5"`
+	ACL                     types.String `tfsdk:"acl"`
+	Tags                    types.Map    `tfsdk:"tags"`
+
+	ServerSideEncryption *DeploymentEncryptionModel `tfsdk:"server_side_encryption"`
+
+	VersionParameterName types.String `tfsdk:"version_parameter_name"`
+	WriteVersionFile     types.Bool   `tfsdk:"write_version_file"`
+	VersionFileKey       types.String `tfsdk:"version_file_key"`
+	VersionFileMetadata  types.Map    `tfsdk:"version_file_metadata"`
+	HistoryTableName     types.String `tfsdk:"history_table_name"`
+	MetricsNamespace     types.String `tfsdk:"metrics_namespace"`
+	ReportPath           types.String `tfsdk:"report_path"`
+
+	ObjectLockMode        types.String `tfsdk:"object_lock_mode"`
+	ObjectLockRetainUntil types.String `tfsdk:"object_lock_retain_until"`
+	LegalHold             types.Bool   `tfsdk:"legal_hold"`
+
+	Notification *DeploymentNotificationModel `tfsdk:"notification"`
+	Webhook      *DeploymentWebhookModel      `tfsdk:"webhook"`
+	BlueGreen    *DeploymentBlueGreenModel    `tfsdk:"blue_green"`
+	Gate         *DeploymentGateModel         `tfsdk:"deployment_gate"`
+
+	BatchOperations *DeploymentBatchOperationsModel `tfsdk:"batch_operations"`
+	SPAMode         *DeploymentSPAModeModel         `tfsdk:"spa_mode"`
+
+	FilesAdded         types.Int64                  `tfsdk:"files_added"`
+	FilesChanged       types.Int64                  `tfsdk:"files_changed"`
+	FilesDeleted       types.Int64                  `tfsdk:"files_deleted"`
+	FilesSkipped       types.Int64                  `tfsdk:"files_skipped"`
+	TotalBytesUploaded types.Int64                  `tfsdk:"total_bytes_uploaded"`
+	DeployedFiles      types.Map                    `tfsdk:"deployed_files"`
+	DeployedVersions   types.Map                    `tfsdk:"deployed_versions"`
+	FileHashes         types.Map                    `tfsdk:"file_hashes"`
+	Fingerprint        types.String                 `tfsdk:"fingerprint"`
+	PathRewrites       []DeploymentPathRewriteModel `tfsdk:"path_rewrite"`
+
+	ChangedFiles       types.List   `tfsdk:"changed_files"`
+	ContentFingerprint types.String `tfsdk:"content_fingerprint"`
+
+	ContentDispositionRules []DeploymentMetadataRuleModel `tfsdk:"content_disposition_rule"`
+	ContentLanguageRules    []DeploymentMetadataRuleModel `tfsdk:"content_language_rule"`
+	Templates               []DeploymentTemplateModel     `tfsdk:"template"`
+	MetadataFiles           []DeploymentMetadataFileModel `tfsdk:"metadata_file"`
+
+	ValidationRules []DeploymentValidationRuleModel `tfsdk:"validation_rule"`
+
+	DetectDrift types.Bool `tfsdk:"detect_drift"`
+
+	CloudFrontContinuousDeployment *DeploymentCloudFrontContinuousDeploymentModel `tfsdk:"cloudfront_continuous_deployment"`
+	CodePipelineSource             *DeploymentCodePipelineSourceModel             `tfsdk:"codepipeline_source"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// sourceLocation returns the bucket and key of the source artifact. Artifacts of a CodePipeline execution are looked
+// up when they are deployed, so they have no source bucket, and their "codepipeline://" location as key.
+func (m *DeploymentResourceModel) sourceLocation() (string, string, error) {
+	if m.CodePipelineSource != nil {
+		return "", deployer.CodePipelineSource(m.CodePipelineSource.PipelineName.ValueString(), m.CodePipelineSource.ExecutionID.ValueString(), m.CodePipelineSource.ArtifactName.ValueString()), nil
+	}
+	if m.Source == nil {
+		return "", "", errors.New("either source or codepipeline_source must be set")
+	}
+	return m.Source.Bucket.ValueString(), m.Source.Key.ValueString(), nil
+}
+
+// sourceUnknown returns whether the location of the source is not known yet, e.g. until another resource is created.
+func (m *DeploymentResourceModel) sourceUnknown() bool {
+	if source := m.CodePipelineSource; source != nil && (source.PipelineName.IsUnknown() || source.ExecutionID.IsUnknown() || source.ArtifactName.IsUnknown()) {
+		return true
+	}
+	return m.Source != nil && (m.Source.Bucket.IsUnknown() || m.Source.Key.IsUnknown())
+}
+
+// sourceVersion returns the configured version of the source, which is null for artifacts of a CodePipeline
+// execution.
+func (m *DeploymentResourceModel) sourceVersion() types.String {
+	if m.Source == nil {
+		return types.StringNull()
+	}
+	return m.Source.Version
+}
+
+// sourceVersionTag returns the key and value of the tag of a source version given as "tag:<key>=<value>".
+func (m *DeploymentResourceModel) sourceVersionTag() (string, string, bool) {
+	tag, found := strings.CutPrefix(m.sourceVersion().ValueString(), sourceVersionTagPrefix)
+	if !found {
+		return "", "", false
+	}
+	key, value, _ := strings.Cut(tag, "=")
+	return key, value, true
+}
+
+// resolvesSourceVersion returns whether the version of the source to deploy is resolved from the configured version,
+// rather than being the configured version itself.
+func (m *DeploymentResourceModel) resolvesSourceVersion() bool {
+	_, _, tagged := m.sourceVersionTag()
+	return tagged || m.sourceVersion().ValueString() == sourceVersionLatest
+}
+
+// resolvedVersion returns the version of the source to deploy and compare with, or nil for the latest version. Only
+// a resolved `latest` or tag is deployed by its version, so that other configured versions only trigger
+// deployments.
+func (m *DeploymentResourceModel) resolvedVersion() *string {
+	if !m.resolvesSourceVersion() || !isKnown(m.ResolvedSourceVersion) {
+		return nil
+	}
+	return m.ResolvedSourceVersion.ValueStringPointer()
+}
+
+// planMaxFiles returns how many changed files are listed in the plan. The files are only compared when planning if
+// plan_max_files is configured, as it requires downloading the source and listing the target.
+func (m *DeploymentResourceModel) planMaxFiles() int64 {
+	return m.PlanMaxFiles.ValueInt64()
+}
+
+// deployedSourceVersion returns the source version to publish as deployed, which is the resolved version of `latest`
+// or a tag if it was resolved, and the execution ID for artifacts of a CodePipeline execution.
+func (m *DeploymentResourceModel) deployedSourceVersion() string {
+	if version := m.resolvedVersion(); version != nil {
+		return *version
+	}
+	if m.CodePipelineSource != nil {
+		return m.CodePipelineSource.ExecutionID.ValueString()
+	}
+	return m.sourceVersion().ValueString()
+}
+
+// importID returns the ID the deployment is imported with, "source_bucket/source_key,target_bucket[,target_prefix]".
+func (m *DeploymentResourceModel) importID() (string, error) {
+	sourceBucket, sourceKey, err := m.sourceLocation()
+	if err != nil {
+		return "", err
+	}
+	id := sourceKey + "," + m.Target.Bucket.ValueString()
+	if sourceBucket != "" {
+		id = sourceBucket + "/" + id
+	}
+	if !m.Target.Prefix.IsNull() {
+		id += "," + m.Target.Prefix.ValueString()
+	}
+	return id, nil
+}
+
+// DeploymentIdentityModel identifies a deployment by its target. Unlike the source, the target cannot change without
+// replacing the deployment.
+type DeploymentIdentityModel struct {
+	TargetBucket types.String `tfsdk:"target_bucket"`
+	TargetPrefix types.String `tfsdk:"target_prefix"`
+}
+
+// setDeploymentIdentity sets the identity of a deployment to its target. Terraform versions without resource identity
+// support do not request it, in which case identity is nil.
+func setDeploymentIdentity(ctx context.Context, identity *tfsdk.ResourceIdentity, data *DeploymentResourceModel) diag.Diagnostics {
+	if identity == nil || data.Target == nil {
+		return nil
+	}
+	return identity.Set(ctx, DeploymentIdentityModel{
+		TargetBucket: data.Target.Bucket,
+		TargetPrefix: data.Target.Prefix,
+	})
+}
+
+// DeploymentSourceModel describes the S3 object the source ZIP file is read from.
+type DeploymentSourceModel struct {
+	Bucket  types.String `tfsdk:"bucket"`
+	Key     types.String `tfsdk:"key"`
+	Version types.String `tfsdk:"version"`
+}
+
+// DeploymentTargetModel describes where the files are deployed to.
+type DeploymentTargetModel struct {
+	Bucket  types.String `tfsdk:"bucket"`
+	Prefix  types.String `tfsdk:"prefix"`
+	Region  types.String `tfsdk:"region"`
+	RoleArn types.String `tfsdk:"role_arn"`
+}
+
+// DeploymentNotificationModel describes where to send notifications about a deployment.
+type DeploymentNotificationModel struct {
+	SNSTopicArn  types.String `tfsdk:"sns_topic_arn"`
+	EventBusName types.String `tfsdk:"event_bus_name"`
+}
+
+// DeploymentWebhookModel describes an HTTP endpoint to notify about a deployment.
+type DeploymentWebhookModel struct {
+	URL             types.String `tfsdk:"url"`
+	Headers         types.Map    `tfsdk:"headers"`
+	PayloadTemplate types.String `tfsdk:"payload_template"`
+}
+
+// DeploymentBlueGreenModel describes how to deploy to a new release prefix and switch to it in one step.
+type DeploymentBlueGreenModel struct {
+	ReleasePrefix types.String `tfsdk:"release_prefix"`
+	PointerKey    types.String `tfsdk:"pointer_key"`
+}
+
+// DeploymentGateModel describes the alarms that stop deployments while they are firing.
+type DeploymentGateModel struct {
+	AlarmArns types.List `tfsdk:"alarm_arns"`
+}
+
+// DeploymentBatchOperationsModel describes how to promote staged files with an S3 Batch Operations job.
+type DeploymentBatchOperationsModel struct {
+	RoleArn       types.String `tfsdk:"role_arn"`
+	StagingBucket types.String `tfsdk:"staging_bucket"`
+	AccountID     types.String `tfsdk:"account_id"`
+}
+
+// DeploymentSPAModeModel describes which documents of a single-page application to deploy as error documents.
+type DeploymentSPAModeModel struct {
+	EntryDocument  types.String `tfsdk:"entry_document"`
+	ErrorDocuments types.List   `tfsdk:"error_documents"`
+}
+
+// DeploymentTemplateModel describes the variables to render matching artifact files with.
+type DeploymentTemplateModel struct {
+	Pattern types.String `tfsdk:"pattern"`
+	Vars    types.Map    `tfsdk:"vars"`
+}
+
+// DeploymentValidationRuleModel describes how to validate the content of matching artifact files.
+type DeploymentValidationRuleModel struct {
+	Pattern    types.String `tfsdk:"pattern"`
+	Type       types.String `tfsdk:"type"`
+	Expression types.String `tfsdk:"expression"`
+}
+
+// DeploymentMetadataFileModel describes a file deployed together with the artifact.
+type DeploymentMetadataFileModel struct {
+	Key     types.String `tfsdk:"key"`
+	Content types.String `tfsdk:"content"`
+}
+
+// DeploymentPathRewriteModel describes a rule for rewriting the names of artifact entries.
+type DeploymentPathRewriteModel struct {
+	From types.String `tfsdk:"from"`
+	To   types.String `tfsdk:"to"`
+}
+
+// DeploymentMetadataRuleModel describes a metadata value to set on objects matching a pattern.
+type DeploymentMetadataRuleModel struct {
+	Pattern types.String `tfsdk:"pattern"`
+	Value   types.String `tfsdk:"value"`
+}
+
+// DeploymentEncryptionModel describes the server-side encryption deployed files are written with.
+type DeploymentEncryptionModel struct {
+	Algorithm        types.String `tfsdk:"algorithm"`
+	KMSKeyID         types.String `tfsdk:"kms_key_id"`
+	BucketKeyEnabled types.Bool   `tfsdk:"bucket_key_enabled"`
+}
+
+// encryption returns the server-side encryption settings of the model.
+func (m *DeploymentEncryptionModel) encryption() *deployer.Encryption {
+	return &deployer.Encryption{
+		Algorithm:        s3types.ServerSideEncryption(m.Algorithm.ValueString()),
+		KMSKeyID:         m.KMSKeyID.ValueString(),
+		BucketKeyEnabled: m.BucketKeyEnabled.ValueBool(),
+	}
+}
+
+// DeploymentCodePipelineSourceModel describes the output artifact of a CodePipeline execution to deploy.
+type DeploymentCodePipelineSourceModel struct {
+	PipelineName types.String `tfsdk:"pipeline_name"`
+	ExecutionID  types.String `tfsdk:"execution_id"`
+	ArtifactName types.String `tfsdk:"artifact_name"`
+}
+
+// DeploymentCloudFrontContinuousDeploymentModel describes the CloudFront distributions of a continuous deployment.
+type DeploymentCloudFrontContinuousDeploymentModel struct {
+	PrimaryDistributionID types.String `tfsdk:"primary_distribution_id"`
+	StagingDistributionID types.String `tfsdk:"staging_distribution_id"`
+	Promote               types.Bool   `tfsdk:"promote"`
+}
+
+func (r *DeploymentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_deployment"
+}
+
+func (r *DeploymentResource) IdentitySchema(ctx context.Context, req resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"target_bucket": identityschema.StringAttribute{
+				Description:       "The name of the target bucket.",
+				RequiredForImport: true,
+			},
+			"target_prefix": identityschema.StringAttribute{
+				Description:       "The prefix in the target the files are deployed under, if any.",
+				OptionalForImport: true,
+			},
+		},
+	}
+}
+
+func (r *DeploymentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Deploys a set of files from a source ZIP file in an S3 bucket to a target S3 bucket, Google Cloud Storage bucket or Azure Blob Storage container, see `target_type`.",
+		// Version 2 moved the source and target attributes into the source and target blocks. No release used version 1,
+		// so only the state of version 0 is upgraded.
+		Version: 2,
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Identifies the deployment by the source and target it was created with, in the format accepted by `terraform import`: `source-bucket/path/to/source.zip,target-bucket[,prefix]`. It does not change when the deployment is updated.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"source_sse_customer_key": schema.StringAttribute{
+				MarkdownDescription: "The base64 encoded 256-bit key the source ZIP file is encrypted with, for buckets that require [server-side encryption with customer-provided keys (SSE-C)](https://docs.aws.amazon.com/AmazonS3/latest/userguide/ServerSideEncryptionCustomerKeys.html). It is sent with every request that reads the source.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"source_sse_customer_key_md5": schema.StringAttribute{
+				MarkdownDescription: "The base64 encoded MD5 hash of `source_sse_customer_key`, which S3 checks the key against. Computed from the key if not set.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("source_sse_customer_key")),
+				},
+			},
+			"target_sse_customer_key": schema.StringAttribute{
+				MarkdownDescription: "The base64 encoded 256-bit key to encrypt the deployed files with, for buckets that require [server-side encryption with customer-provided keys (SSE-C)](https://docs.aws.amazon.com/AmazonS3/latest/userguide/ServerSideEncryptionCustomerKeys.html). It is sent with every request that reads or writes deployed files, and only supported when `target_type` is `s3`. S3 does not store the key, so files deployed with it can only be served by something that has it too.",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"target_sse_customer_key_md5": schema.StringAttribute{
+				MarkdownDescription: "The base64 encoded MD5 hash of `target_sse_customer_key`, which S3 checks the key against. Computed from the key if not set.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("target_sse_customer_key")),
+				},
+			},
+			"acl": schema.StringAttribute{
+				MarkdownDescription: "The [canned ACL](https://docs.aws.amazon.com/AmazonS3/latest/userguide/acl-overview.html#canned-acl) to write the deployed files with, e.g. `public-read` or `bucket-owner-full-control`. Files that are not uploaded or copied again keep their ACL. With `preflight_checks`, the deployment fails before any files are deployed if the object ownership of the target bucket is `BucketOwnerEnforced`, which disables ACLs, or if it blocks public ACLs and the ACL is public. Only supported when `target_type` is `s3`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(cannedACLs()...),
+				},
+			},
+			"tags": schema.MapAttribute{
+				MarkdownDescription: "Tags to add to every deployed file, e.g. `{ team = \"web\" }`, for cost allocation or lifecycle rules. Added to the `tags` in the `defaults` of the provider, replacing those with the same keys. Files that are not uploaded again keep their tags, unless `tag_objects` is set. S3 allows at most 10 tags per object, including the 2 added by `tag_objects`. Only supported when `target_type` is `s3`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"source_checksum": schema.StringAttribute{
+				MarkdownDescription: "The expected checksum of the source ZIP file, in the format `<algorithm>:<hex digest>` where the algorithm is `sha256` or `sha512`. If set, the deployment fails before any files are extracted when the downloaded file does not match, protecting against tampered or truncated artifacts.",
+				Optional:            true,
+			},
+			"source_root": schema.StringAttribute{
+				MarkdownDescription: "A directory in the source ZIP file to deploy instead of the whole file, e.g. `dist/`. Only entries under the directory are deployed, with the directory stripped from their names. Applied before `path_rewrite`.",
+				Optional:            true,
+			},
+			"resolved_source_version": schema.StringAttribute{
+				MarkdownDescription: "The version ID of the source ZIP file that is deployed. When the `version` of `source` is `latest`, the current version of the source is resolved when the deployment is first applied, and that version is deployed, refreshed and compared with from then on, so that updates deploy the same files even if the source has been overwritten since. To deploy a newer version, set `version` to its ID, or replace the deployment. When `version` is a tag, it is the newest version with the tag as of the last plan. Null if `version` is `latest` and the source does not keep versions, e.g. a bucket without versioning. Otherwise it is `version`.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					useStateForUnchangedSourceVersion{},
+				},
+			},
+			"source_etag": schema.StringAttribute{
+				MarkdownDescription: "The ETag of the source ZIP file as of the last deployment or refresh, e.g. for use in `replace_triggered_by` or to trigger CDN invalidations.",
+				Computed:            true,
+			},
+			"target_type": schema.StringAttribute{
+				MarkdownDescription: "The kind of storage `target` is, either `s3`, `gcs` (Google Cloud Storage) or `azure` (Azure Blob Storage). Defaults to `s3`. Google Cloud Storage credentials are found the same way as the gcloud CLI does, e.g. from `GOOGLE_APPLICATION_CREDENTIALS`, and Azure credentials the same way as the Azure CLI does, e.g. from `AZURE_CLIENT_ID`, `AZURE_TENANT_ID` and `AZURE_CLIENT_SECRET`.",
+				Optional:            true,
+				Default:             stringdefault.StaticString(targetTypeS3),
+				Computed:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(targetTypeS3, targetTypeGCS, targetTypeAzure),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"azure_container": schema.StringAttribute{
+				MarkdownDescription: "The Azure Blob Storage container to deploy to when `target_type` is `azure`. Defaults to `$web`, the container Azure serves static websites from.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"multipart_part_size_mb": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("The size in MB of each part when files of %d MB or more are uploaded to S3 in parts. Defaults to %d.", deployer.DefaultMultipartThreshold>>20, deployer.DefaultMultipartPartSize>>20),
+				Optional:            true,
+				Validators: []validator.Int64{
+					// S3 rejects parts smaller than 5 MB.
+					int64validator.AtLeast(5),
+				},
+			},
+			"multipart_concurrency": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("How many parts of a file are uploaded to S3 at the same time. Defaults to %d.", deployer.DefaultMultipartConcurrency),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"download_concurrency": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("How many parts of the source ZIP file are downloaded from S3 at the same time. Defaults to %d.", deployer.DefaultDownloadConcurrency),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"max_requests_per_second": schema.Int64Attribute{
+				MarkdownDescription: "The highest number of requests per second sent to the target, spread evenly over each second. Use this for large deployments to buckets shared with production traffic, so that the deployment does not trigger S3 throttling or starve other writers. Files uploaded in parts count as one request. Unlimited by default.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"inventory_location": schema.StringAttribute{
+				MarkdownDescription: "The location S3 Inventory delivers the reports of the target bucket to, as `s3://destination-bucket/prefix/source-bucket/configuration-id/`. If set, the files are compared with the objects in the latest complete report when a deployment is planned, instead of listing the target, which is slow and expensive for buckets with hundreds of thousands of objects. Reports are at most a day or a week old, so the listed changes may be out of date, while deployments always list the target. Only reports in CSV format that include the `ETag` field are supported, and the destination bucket must be in the `region` of `target`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(sourceRegexp, "must be in the format s3://bucket-name/prefix/"),
+				},
+			},
+			"hash_algorithm": schema.StringAttribute{
+				MarkdownDescription: "The algorithm the hashes in `deployed_files` and in the manifest sent to `pre_deploy_lambda_arn` and `post_deploy_lambda_arn` are computed with, one of `md5`, `sha1`, `sha256` or `xxhash64`, e.g. where MD5 is not allowed. Hashes other than MD5 are prefixed with the algorithm, e.g. `sha256:<hex digest>`. Defaults to `md5`. Changing it only updates the hashes on the next apply, without uploading any files again. With `md5`, files are compared with deployed objects by their ETags, which S3 computes with MD5. With any other algorithm, MD5 is not used at all: files are compared with the SHA-256 hash stored in the metadata of the objects, and uploads are checked with SHA-256 checksums instead of `Content-MD5`. Objects deployed without the SHA-256 hash are compared with the hashes in the state of the last deployment instead, and get the hash added to their metadata.",
+				Optional:            true,
+			},
+			"plan_max_files": schema.Int64Attribute{
+				MarkdownDescription: "How many of the files a deployment would add, change, or delete are listed in a warning when it is planned, so that the impact of an apply can be reviewed before approving it. Planning then downloads the source ZIP file and lists the target to compare them, which needs read access to both, and only compares the content of files, not their metadata. Defaults to `0`, which does not compare the files when planning.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"max_artifact_files": schema.Int64Attribute{
+				MarkdownDescription: "The highest number of files the source ZIP file may contain. If it has more, the deployment fails before any files are deployed. Unlimited by default.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"max_artifact_size_mb": schema.Int64Attribute{
+				MarkdownDescription: "The highest total uncompressed size in MB of the files in the source ZIP file. If they are larger, the deployment fails before any files are deployed. Unlimited by default. Zip64 files larger than 4 GB are supported.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"min_files": schema.Int64Attribute{
+				MarkdownDescription: "The lowest number of files to deploy from the source ZIP file, after `source_root` and `path_rewrite` are applied. If it has fewer, the deployment fails before any files are deployed, so that a broken build producing a handful of files cannot overwrite the site, or wipe it together with `delete_removed_files`. Unlimited by default.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"min_total_bytes": schema.Int64Attribute{
+				MarkdownDescription: "The lowest total uncompressed size in bytes of the files to deploy from the source ZIP file, after `source_root` and `path_rewrite` are applied. If they are smaller, the deployment fails before any files are deployed. Unlimited by default.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"preflight_checks": schema.BoolAttribute{
+				MarkdownDescription: "Whether to check that the source can be read, and that the target can be accessed, before the source ZIP file is downloaded. The checks only read: the source with `s3:GetObject`, and an S3 target with `HeadBucket` and `GetBucketEncryption`, which need the `s3:ListBucket` and `s3:GetEncryptionConfiguration` permissions. With `acl`, the ownership controls and public access block of the target are read as well, if `s3:GetBucketOwnershipControls` and `s3:GetBucketPublicAccessBlock` allow it. This way, missing permissions, wrong regions, and bucket policy denials are reported before any files are deployed.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(true),
+				Computed:            true,
+			},
+			"preflight_write_probe": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Whether `preflight_checks` also writes `%s` under the `prefix` of `target` and deletes it again, which checks that objects can be written to the target with the KMS key the bucket encrypts them with. It needs the `s3:PutObject` and `s3:DeleteObject` permissions. On versioned buckets, every probe leaves a noncurrent version and a delete marker behind, and on buckets with Object Lock a locked version. Defaults to `false`.", deployer.PreflightKey),
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"verify_after_deploy": schema.BoolAttribute{
+				MarkdownDescription: "Whether to check every deployed object with a HEAD request after the deployment has completed, confirming that it exists with the expected size and content type.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"detect_drift": schema.BoolAttribute{
+				MarkdownDescription: "Whether refreshing checks every file in `file_hashes` with a HEAD request, comparing it with the SHA-256 hash stored in the metadata of its object, so that files changed or deleted outside of Terraform are deployed again by the next apply even if nothing else changed. Objects deployed by versions of the provider that did not store the hash are not checked, and neither are the releases of `blue_green` deployments. Defaults to `false`.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"conditional_writes": schema.BoolAttribute{
+				MarkdownDescription: "Whether to upload files with S3 conditional requests, so that a deployment fails with a conflict instead of silently overwriting files changed by another deployment running at the same time. Not all S3-compatible stores support conditional writes. Defaults to `false`.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"delete_removed_files": schema.BoolAttribute{
+				MarkdownDescription: "Whether to delete files from the target that are not part of the deployed artifact, e.g. files removed since the previous deployment. Has no effect with `blue_green`, where every deployment is uploaded to a new prefix.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"resume_failed_deployments": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Whether a failed deployment saves which files it deployed to `%s` in the target, so that the next apply resumes it by skipping those files if they are unchanged, instead of checking every file again. The file is removed once a deployment has uploaded all files. The number of resumed files is reported as a warning.", deployer.ResumeProgressKey),
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"rollback_on_failure": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Whether to undo the changes of a deployment that fails, so that a failed apply never leaves the target with a mix of old and new files. Objects are copied to `%s<deployment ID>/` before they are overwritten, and restored from there if the deployment fails, while objects it added are deleted. The copies are deleted once the deployment has succeeded. In buckets with versioning enabled, objects are not copied, and the versions they had are restored instead. Failures while deleting removed files are not rolled back, as all new files are deployed by then. Takes precedence over `resume_failed_deployments`.", deployer.RollbackPrefix),
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"staged_promotion": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Whether to upload new and changed files to `%s<deployment ID>/` in the target first, check that all of them were uploaded with the expected size and content type, and only then copy them server-side to their keys and delete the staging area. This keeps the window in which the live site has a mix of old and new files as short as possible, at the cost of copying every changed file once more. Cannot be combined with `blue_green` or Object Lock.", deployer.StagingPrefix),
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"tag_objects": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Whether to tag every deployed object with `%s` and `%s`, the ID and sequence number of the last deployment that included it. Unchanged objects are tagged without being uploaded again. The sequence number of the last deployment is saved to `%s` under the `prefix` of `target`. Only supported when `target_type` is `s3`, and requires the `s3:PutObjectTagging` permission. Defaults to `false`.", deployer.DeploymentIDTag, deployer.DeploymentSequenceTag, deployer.DeploymentSequenceKey),
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"keep_deployments": schema.Int64Attribute{
+				MarkdownDescription: "Deletes the objects that were removed from the source more than this many deployments ago, e.g. `3` to keep the hashed assets cached pages may still refer to for the next two deployments. Implies `tag_objects`, and only deletes tagged objects, so objects uploaded by other systems are never pruned. Has no effect when `delete_removed_files` is set, which deletes removed objects right away, or with `blue_green`. Requires the `s3:GetObjectTagging` permission.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"purge_on_destroy": schema.BoolAttribute{
+				MarkdownDescription: "Whether to delete the files in `deployed_files` from the target when the deployment is destroyed, including when it is replaced because its target changed. By default, destroying a deployment leaves its files in place.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"paused": schema.BoolAttribute{
+				MarkdownDescription: "Whether to freeze the deployment, e.g. during a change freeze. While paused, applying the deployment uploads and deletes nothing, not even when the source or other settings change, and destroying it does not purge its files, while the state of the last deployment is kept. The changes are deployed once it is no longer paused.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"deletion_protection": schema.BoolAttribute{
+				MarkdownDescription: "Whether to fail when the deployment is destroyed, including when it is replaced, e.g. to protect production targets from an accidental `terraform destroy` with `purge_on_destroy`. To destroy a protected deployment, first apply it with `deletion_protection` set to `false`.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"keep_files": schema.ListAttribute{
+				MarkdownDescription: "Glob patterns of files in the target that are never deleted by `delete_removed_files`, e.g. `uploads/*` or `.well-known/*` for externally managed files. As with `aws s3 sync --exclude`, " + deployer.GlobSyntaxDescription,
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"unmanaged_paths": schema.ListAttribute{
+				MarkdownDescription: "Glob patterns of objects in the target that are written by other systems, e.g. `uploads/*` for user uploads or `logs/*` for access logs. Unlike `keep_files`, the deployment disregards them entirely: they are never compared, overwritten, or deleted, and files in the source ZIP file matching them are not deployed. " + deployer.GlobSyntaxDescription,
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"upload_order": schema.ListAttribute{
+				MarkdownDescription: fmt.Sprintf("Glob patterns of files to upload after all other files, grouped by the first pattern they match in the order of the patterns. Defaults to `[\"%s\"]`, so that users are never served a new HTML page referring to assets that have not been uploaded yet. Set to `[]` to upload files in the order of the source ZIP file. %s", strings.Join(deployer.DefaultUploadOrder, `", "`), deployer.GlobSyntaxDescription),
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"required_files": schema.ListAttribute{
+				MarkdownDescription: "Files the source ZIP file must have, e.g. `[\"index.html\", \"assets/manifest.json\"]`, after `source_root` and `path_rewrite` are applied. If any of them are missing, the deployment fails before any files are deployed, listing the missing files, so that a broken or empty build output is never deployed.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"hashed_asset_pattern": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("A glob pattern matching fingerprinted files whose names change with their content, e.g. `assets/*`. Matching files are deployed with `Cache-Control: %s`, and all other files with `Cache-Control: %s`. %s", deployer.ImmutableCacheControl, deployer.ShortCacheControl, deployer.GlobSyntaxDescription),
+				Optional:            true,
+			},
+			"pre_deploy_lambda_arn": schema.StringAttribute{
+				MarkdownDescription: "The ARN of a Lambda function to invoke synchronously with the deployment manifest before any files are uploaded. The deployment is aborted if the function returns an error.",
+				Optional:            true,
+			},
+			"post_deploy_lambda_arn": schema.StringAttribute{
+				MarkdownDescription: "The ARN of a Lambda function to invoke synchronously with the deployment manifest after all files are uploaded, e.g. to warm caches or run smoke tests. The apply fails if the function returns an error.",
+				Optional:            true,
+			},
+			"version_parameter_name": schema.StringAttribute{
+				MarkdownDescription: "The name of an SSM parameter to write the deployed version of the source to after every successful deployment, so other stacks and services can tell which build is live.",
+				Optional:            true,
+			},
+			"write_version_file": schema.BoolAttribute{
+				MarkdownDescription: "Whether to write a JSON file with the source version, deployment time and `version_file_metadata` to the target, e.g. for showing \"new version available\" banners in single-page applications.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+			"version_file_key": schema.StringAttribute{
+				MarkdownDescription: "The key to write the version file to when `write_version_file` is enabled.",
+				Optional:            true,
+				Default:             stringdefault.StaticString(deployer.DefaultVersionFileKey),
+				Computed:            true,
+			},
+			"version_file_metadata": schema.MapAttribute{
+				MarkdownDescription: "Additional values to include in the version file, such as the git commit and branch the artifact was built from.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"history_table_name": schema.StringAttribute{
+				MarkdownDescription: "The name of a DynamoDB table to append a record of every deployment to, including who deployed which source version, when, and with what result. The table must have `target` (String) as partition key and `deployed_at` (String) as sort key.",
+				Optional:            true,
+			},
+			"metrics_namespace": schema.StringAttribute{
+				MarkdownDescription: "A CloudWatch namespace to emit the `FilesUploaded`, `BytesUploaded`, `Duration` and `Failures` metrics to after every deployment, with the target bucket as the `Target` dimension.",
+				Optional:            true,
+			},
+			"report_path": schema.StringAttribute{
+				MarkdownDescription: "A path on the machine running Terraform to write a JSON report to after every deployment, including failed ones, e.g. for CI pipelines to attach to build summaries and release notes. The report has the fields of the notification payload, the keys of the objects that were added, changed and deleted, and how long each phase of the deployment took in milliseconds. Missing directories are created, and an existing file is replaced.",
+				Optional:            true,
+			},
+			"files_added": schema.Int64Attribute{
+				MarkdownDescription: "The number of files added to the target by the last deployment.",
+				Computed:            true,
+			},
+			"files_changed": schema.Int64Attribute{
+				MarkdownDescription: "The number of files in the target whose content or metadata was changed by the last deployment.",
+				Computed:            true,
+			},
+			"files_deleted": schema.Int64Attribute{
+				MarkdownDescription: "The number of files deleted from the target by the last deployment.",
+				Computed:            true,
+			},
+			"files_skipped": schema.Int64Attribute{
+				MarkdownDescription: "The number of files that were already deployed unchanged and skipped by the last deployment.",
+				Computed:            true,
+			},
+			"changed_files": schema.ListAttribute{
+				MarkdownDescription: fmt.Sprintf("The paths of the objects changed or deleted by the last deployment, sorted and with a leading slash, e.g. `/site/index.html`, to pass to a CloudFront invalidation such as `staticfiledeploy_invalidation` or `aws_cloudfront_invalidation`. Added objects are not included, since nothing can be cached for them. If more than %d objects were changed, it is `[\"/*\"]` instead, and empty if nothing was changed.", deployer.MaxChangedPaths),
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"total_bytes_uploaded": schema.Int64Attribute{
+				MarkdownDescription: "The number of bytes uploaded by the last deployment.",
+				Computed:            true,
+			},
+			"deployed_files": schema.MapAttribute{
+				MarkdownDescription: "The objects deployed from the source ZIP file, keyed by their key in the target, with the hash of their content in `hash_algorithm` as value. For imported deployments, this is the ETag of every object that was in the target at the time.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"deployed_versions": schema.MapAttribute{
+				MarkdownDescription: "The IDs of the object versions the deployed files were last uploaded as, keyed by their key in the target, if the target bucket has versioning enabled. Files copied from the staging area by `staged_promotion` are not included. With versioning, `rollback_on_failure` restores the exact versions objects had before the deployment instead of backing them up.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"file_hashes": schema.MapAttribute{
+				MarkdownDescription: "The files deployed from the source ZIP file, keyed by their name in the artifact after `source_root` and `path_rewrite` are applied, with the base64 encoded SHA-256 hash of their content as value. The hashes are the same as `filebase64sha256` returns for the files, e.g. for `\"sha256-${hash}\"` subresource integrity attributes. A file whose hash is empty was changed outside of Terraform, and is deployed again by the next apply. If nothing else changed since the last deployment, only such files are uploaded, without comparing the other files with the target.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"fingerprint": schema.StringAttribute{
+				MarkdownDescription: "A hash of the files and settings of the last deployment. If the next update would deploy the same, and the target still has all files with the same content, it completes without checking each file, and reports all files as skipped.",
+				Computed:            true,
+			},
+			"content_fingerprint": schema.StringAttribute{
+				MarkdownDescription: "A hash of the names and content of the files deployed by the last deployment. Unlike `fingerprint` and `source_etag`, it only changes when the deployed content changes, e.g. not when the same files are deployed from a rebuilt artifact or with other settings, so it can be used in `replace_triggered_by` and `triggers` to only act on changes to the content. It is kept in plans that deploy the same `resolved_source_version` or CodePipeline execution with the same settings affecting the content of the files, and otherwise only known after apply.",
+				Computed:            true,
+			},
+			"object_lock_mode": schema.StringAttribute{
+				MarkdownDescription: "The Object Lock retention mode to apply to every uploaded object, either `GOVERNANCE` or `COMPLIANCE`. Must be set together with `object_lock_retain_until`, and requires Object Lock to be enabled on the target bucket.",
+				Optional:            true,
+			},
+			"object_lock_retain_until": schema.StringAttribute{
+				MarkdownDescription: "The date and time, in RFC 3339 format, until which uploaded objects are retained under `object_lock_mode`, e.g. `2030-01-01T00:00:00Z`.",
+				Optional:            true,
+			},
+			"legal_hold": schema.BoolAttribute{
+				MarkdownDescription: "Whether to place an Object Lock legal hold on every uploaded object. Requires Object Lock to be enabled on the target bucket.",
+				Optional:            true,
+				Default:             booldefault.StaticBool(false),
+				Computed:            true,
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"source": schema.SingleNestedBlock{
+				MarkdownDescription: "The ZIP file in S3 containing the source files to be deployed. Exactly one of `source` and `codepipeline_source` must be set.",
+				Attributes: map[string]schema.Attribute{
+					"bucket": schema.StringAttribute{
+						MarkdownDescription: "The S3 bucket containing the ZIP file.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(regexp.MustCompile(`^`+bucketNamePattern+`$`), "must be a valid S3 bucket name"),
+						},
+					},
+					"key": schema.StringAttribute{
+						MarkdownDescription: "The key of the ZIP file in `bucket`, e.g. `path/to/source.zip`.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+					},
+					"version": schema.StringAttribute{
+						MarkdownDescription: "The version ID of the ZIP file in the S3 bucket. This is used to handle versioning of files in S3. Use `latest` for unversioned buckets, or to deploy the version the source has when the deployment is applied, which is then tracked in `resolved_source_version`. Use `tag:<key>=<value>`, e.g. `tag:environment=staging`, to deploy the newest version of the source with that [object tag](https://docs.aws.amazon.com/AmazonS3/latest/userguide/object-tagging.html), so that artifacts are promoted between environments by tagging them. The tag is resolved whenever the deployment is planned, and an update is planned when it has moved to another version. Requires the `s3:ListBucketVersions` and `s3:GetObjectVersionTagging` permissions. May refer to a source uploaded in the same apply, e.g. `aws_s3_object.artifact.version_id`, in which case the deployment is planned as an update and runs once the version is known.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+					},
+				},
+				Validators: []validator.Object{
+					objectvalidator.ExactlyOneOf(path.MatchRoot("codepipeline_source")),
+				},
+			},
+			"target": schema.SingleNestedBlock{
+				MarkdownDescription: "Where the unzipped files are deployed to. Changing its `bucket` or `prefix`, `target_type` or `azure_container` replaces the deployment, so that the files at the old location are removed if `purge_on_destroy` is set.",
+				Attributes: map[string]schema.Attribute{
+					"bucket": schema.StringAttribute{
+						MarkdownDescription: "The name of the target bucket. S3 buckets can also be given by ARN. For Azure, this is the name of the storage account.",
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(targetRegexp, "must be a valid S3 bucket name or ARN"),
+						},
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"prefix": schema.StringAttribute{
+						MarkdownDescription: "A prefix in the target to deploy the files under, e.g. `site/`. Only objects under the prefix are compared with the source ZIP file, and deleted by `delete_removed_files`.",
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.RequiresReplace(),
+						},
+					},
+					"region": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("The region of the target S3 bucket. Ignored for other target types. Defaults to the `default_target_region` of the provider, or `%s`.", defaultTargetRegion),
+						Optional:            true,
+						Computed:            true,
+						PlanModifiers: []planmodifier.String{
+							stringplanmodifier.UseStateForUnknown(),
+						},
+					},
+					"role_arn": schema.StringAttribute{
+						MarkdownDescription: "The ARN of an IAM role to read and write the target S3 bucket with, e.g. a role in the account that owns the bucket. It is assumed with the credentials of the provider, which are still used for the source and everything else. Only supported when `target_type` is `s3`.",
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+					},
+				},
+				Validators: []validator.Object{
+					objectvalidator.IsRequired(),
+				},
+			},
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+			"notification": schema.SingleNestedBlock{
+				MarkdownDescription: "Publishes a message describing the deployment after every create and update.",
+				Attributes: map[string]schema.Attribute{
+					"sns_topic_arn": schema.StringAttribute{
+						MarkdownDescription: "The ARN of the SNS topic to publish a JSON message with the deployment ID, source version, target, file count and status to.",
+						Optional:            true,
+					},
+					"event_bus_name": schema.StringAttribute{
+						MarkdownDescription: "The name or ARN of the EventBridge event bus to emit an event with `source = \"staticfiledeploy\"` to, on both successful and failed deployments.",
+						Optional:            true,
+					},
+				},
+			},
+			"webhook": schema.SingleNestedBlock{
+				MarkdownDescription: "Sends an HTTP POST request describing the deployment after every create and update.",
+				Attributes: map[string]schema.Attribute{
+					"url": schema.StringAttribute{
+						MarkdownDescription: "The URL to send the request to.",
+						Required:            true,
+					},
+					"headers": schema.MapAttribute{
+						MarkdownDescription: "Additional HTTP headers to send with the request, e.g. for authorization.",
+						ElementType:         types.StringType,
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"payload_template": schema.StringAttribute{
+						MarkdownDescription: "A [Go template](https://pkg.go.dev/text/template) for the request body, rendered with the fields `DeploymentID`, `Source`, `SourceVersion`, `Target`, `FilesDeployed`, `FilesAdded`, `FilesChanged`, `FilesDeleted`, `FilesSkipped`, `FilesResumed`, `BytesUploaded`, `DurationMs`, `Status`, `Error` and `Timestamp`. Defaults to a JSON document with the same fields.",
+						Optional:            true,
+					},
+				},
+			},
+			"path_rewrite": schema.ListNestedBlock{
+				MarkdownDescription: "Rewrites the names of the entries in the source ZIP file before they are deployed, e.g. to deploy an artifact with an embedded top-level directory flat. Rules are applied in order, and entries rewritten to an empty name are not deployed. Entry names are always normalized first, replacing Windows path separators (`\\`) with `/` and converting them to Unicode normalization form C (NFC).",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"from": schema.StringAttribute{
+							MarkdownDescription: "A [regular expression](https://pkg.go.dev/regexp/syntax) matching the part of the entry names to replace, e.g. `^build/`.",
+							Required:            true,
+						},
+						"to": schema.StringAttribute{
+							MarkdownDescription: "The replacement, which may refer to capture groups of `from` as `${1}`.",
+							Required:            true,
+						},
+					},
+				},
+			},
+			"template": schema.ListNestedBlock{
+				MarkdownDescription: "Renders matching files of the source ZIP file before they are deployed, replacing `${NAME}` placeholders with the value of `NAME` in `vars`, so that one build artifact can be deployed to several environments with their own configuration. Placeholders of variables not in `vars` are left as they are, e.g. template literals of JavaScript files. The rendered files are deployed, compared and tracked in `deployed_files` like the other files. The first matching block applies.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							MarkdownDescription: "A glob pattern matching the names of the files to render, after `source_root` and `path_rewrite` are applied, e.g. `config/*.json`. " + deployer.GlobSyntaxDescription,
+							Required:            true,
+						},
+						"vars": schema.MapAttribute{
+							MarkdownDescription: "The values of the variables, e.g. `{ API_URL = var.api_url }`.",
+							ElementType:         types.StringType,
+							Required:            true,
+						},
+					},
+				},
+			},
+			"validation_rule": schema.ListNestedBlock{
+				MarkdownDescription: "Validates the content of matching files before any file is deployed, so that a malformed configuration file fails the deployment instead of breaking the site. Files are validated after `template` blocks are rendered, against every matching rule, and all invalid files are reported.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							MarkdownDescription: "A glob pattern matching the names of the files to validate, e.g. `config/*.json`. " + deployer.GlobSyntaxDescription,
+							Required:            true,
+						},
+						"type": schema.StringAttribute{
+							MarkdownDescription: fmt.Sprintf("How to validate the files: `%s` and `%s` require them to be well-formed documents, and `%s` requires their content to match `expression`.", deployer.ValidationTypeJSON, deployer.ValidationTypeYAML, deployer.ValidationTypeRegex),
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.OneOf(string(deployer.ValidationTypeJSON), string(deployer.ValidationTypeYAML), string(deployer.ValidationTypeRegex)),
+							},
+						},
+						"expression": schema.StringAttribute{
+							MarkdownDescription: fmt.Sprintf("A [regular expression](https://pkg.go.dev/regexp/syntax) the content of the files must match, e.g. `<title>.+</title>`. Required when `type` is `%s`.", deployer.ValidationTypeRegex),
+							Optional:            true,
+						},
+					},
+				},
+			},
+			"metadata_file": schema.ListNestedBlock{
+				MarkdownDescription: "A file to deploy together with the source ZIP file, e.g. `build-info.json` with the git SHA and time of the build for the frontend to show. It is deployed, compared and tracked in `deployed_files` like the files of the source ZIP file, with the metadata of its key, and replaces a file of the source ZIP file with the same name.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"key": schema.StringAttribute{
+							MarkdownDescription: "The name of the file, relative to the `prefix` of `target` like the files of the source ZIP file, e.g. `build-info.json`.",
+							Required:            true,
+							Validators: []validator.String{
+								stringvalidator.LengthAtLeast(1),
+							},
+						},
+						"content": schema.StringAttribute{
+							MarkdownDescription: "The content of the file, e.g. `jsonencode({ sha = var.git_sha })`.",
+							Required:            true,
+						},
+					},
+				},
+			},
+			"content_disposition_rule": schema.ListNestedBlock{
+				MarkdownDescription: "Sets the `Content-Disposition` header of matching files, e.g. to make browsers download files instead of displaying them. The first matching rule applies.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							MarkdownDescription: "A glob pattern matching the files to apply the rule to, e.g. `downloads/*`. " + deployer.GlobSyntaxDescription,
+							Required:            true,
+						},
+						"value": schema.StringAttribute{
+							MarkdownDescription: "The `Content-Disposition` value, e.g. `attachment`.",
+							Required:            true,
+						},
+					},
+				},
+			},
+			"content_language_rule": schema.ListNestedBlock{
+				MarkdownDescription: "Sets the `Content-Language` header of matching files, e.g. for sites with a directory per locale. The first matching rule applies.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"pattern": schema.StringAttribute{
+							MarkdownDescription: "A glob pattern matching the files to apply the rule to, e.g. `no/*`. " + deployer.GlobSyntaxDescription,
+							Required:            true,
+						},
+						"value": schema.StringAttribute{
+							MarkdownDescription: "The `Content-Language` value, e.g. `nb-NO`.",
+							Required:            true,
+						},
+					},
+				},
+			},
+			"codepipeline_source": schema.SingleNestedBlock{
+				MarkdownDescription: "Deploys an output artifact of a [CodePipeline](https://docs.aws.amazon.com/codepipeline/latest/userguide/welcome.html) execution as the source ZIP file, instead of `source`. The artifact store of a pipeline keeps artifacts under random keys, so the artifact is looked up in the actions of the execution when it is deployed, e.g. from a deploy action that passes `#{codepipeline.PipelineExecutionId}` to Terraform. Requires the `codepipeline:ListActionExecutions` permission, and permission to read the artifact from the artifact store bucket.",
+				Attributes: map[string]schema.Attribute{
+					"pipeline_name": schema.StringAttribute{
+						MarkdownDescription: "The name of the pipeline.",
+						Required:            true,
+					},
+					"execution_id": schema.StringAttribute{
+						MarkdownDescription: "The ID of the pipeline execution whose artifact to deploy.",
+						Required:            true,
+					},
+					"artifact_name": schema.StringAttribute{
+						MarkdownDescription: "The name of the output artifact to deploy, e.g. `BuildOutput`.",
+						Required:            true,
+					},
+				},
+			},
+			"cloudfront_continuous_deployment": schema.SingleNestedBlock{
+				MarkdownDescription: "Rolls out the deployment with [CloudFront continuous deployment](https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/continuous-deployment.html). Set the `bucket` of `target` to the bucket behind the staging distribution, so the new version is first served to the share of traffic selected by the continuous deployment policy, and set `promote` once it is ready to be served to everyone.",
+				Attributes: map[string]schema.Attribute{
+					"primary_distribution_id": schema.StringAttribute{
+						MarkdownDescription: "The ID of the primary CloudFront distribution.",
+						Required:            true,
+					},
+					"staging_distribution_id": schema.StringAttribute{
+						MarkdownDescription: "The ID of the staging CloudFront distribution serving the `target` bucket.",
+						Required:            true,
+					},
+					"promote": schema.BoolAttribute{
+						MarkdownDescription: "Whether to promote the staging distribution after the deployment, copying its configuration to the primary distribution. Defaults to `false`.",
+						Optional:            true,
+					},
+				},
+			},
+			"deployment_gate": schema.SingleNestedBlock{
+				MarkdownDescription: "Checks CloudWatch alarms before anything is uploaded, and aborts the deployment if any of them is in the `ALARM` state, so that static releases are not rolled out during active incidents. Requires the `cloudwatch:DescribeAlarms` permission.",
+				Attributes: map[string]schema.Attribute{
+					"alarm_arns": schema.ListAttribute{
+						MarkdownDescription: "The ARNs of the CloudWatch metric or composite alarms to check. Alarms that do not exist fail the deployment.",
+						ElementType:         types.StringType,
+						Required:            true,
+					},
+				},
+			},
+			"batch_operations": schema.SingleNestedBlock{
+				MarkdownDescription: fmt.Sprintf("Uploads new and changed files to a staging bucket, checks them like `staged_promotion`, and then copies all of them to the target with a single S3 Batch Operations job instead of one request per file, for artifacts with hundreds of thousands of files. The apply waits for the job to complete and fails if any file could not be copied, in which case a report of the failed files is written to `%s<deployment ID>/` in the staging bucket. Only supported when `target_type` is `s3`, and cannot be combined with `blue_green` or Object Lock. Requires the `s3:CreateJob` and `s3:DescribeJob` permissions.", deployer.BatchPrefix),
+				Attributes: map[string]schema.Attribute{
+					"role_arn": schema.StringAttribute{
+						MarkdownDescription: "The ARN of the IAM role S3 Batch Operations assumes to read the staged files and the manifest from the staging bucket, write the copies to the target, and write the report.",
+						Required:            true,
+					},
+					"staging_bucket": schema.StringAttribute{
+						MarkdownDescription: "The name of the S3 bucket files are staged in, in the same region as the target. Staged files are deleted once they have been copied.",
+						Required:            true,
+					},
+					"account_id": schema.StringAttribute{
+						MarkdownDescription: "The ID of the AWS account to create the job in. Defaults to the account of the provider credentials.",
+						Optional:            true,
+					},
+				},
+			},
+			"blue_green": schema.SingleNestedBlock{
+				MarkdownDescription: "Uploads every deployment to its own release prefix, `<release_prefix><deployment ID>/`, and only then updates a small JSON pointer object with the prefix of the new release, so visitors never see a half-deployed site. Serving the release the pointer refers to, e.g. with a CloudFront function, and cleaning up old releases is left to the user.",
+				Attributes: map[string]schema.Attribute{
+					"release_prefix": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("The prefix releases are uploaded under. Defaults to `%s`.", deployer.DefaultReleasePrefix),
+						Optional:            true,
+					},
+					"pointer_key": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("The key of the pointer object referring to the live release. Defaults to `%s`.", deployer.DefaultReleasePointerKey),
+						Optional:            true,
+					},
+				},
+			},
+			"server_side_encryption": schema.SingleNestedBlock{
+				MarkdownDescription: "The server-side encryption to write the deployed files with, instead of the default encryption of the target bucket. Replaces the `server_side_encryption` in the `defaults` of the provider. Files that are not uploaded or copied again keep their encryption. Cannot be combined with `target_sse_customer_key`, which replaces the defaults of the provider instead. Only supported when `target_type` is `s3`.",
+				Attributes: map[string]schema.Attribute{
+					"algorithm": schema.StringAttribute{
+						MarkdownDescription: encryptionAlgorithmDescription,
+						Required:            true,
+						Validators: []validator.String{
+							stringvalidator.OneOf(encryptionAlgorithms()...),
+						},
+					},
+					"kms_key_id": schema.StringAttribute{
+						MarkdownDescription: encryptionKMSKeyIDDescription,
+						Optional:            true,
+					},
+					"bucket_key_enabled": schema.BoolAttribute{
+						MarkdownDescription: encryptionBucketKeyDescription,
+						Optional:            true,
+					},
+				},
+				Validators: []validator.Object{
+					objectvalidator.ConflictsWith(path.MatchRoot("target_sse_customer_key")),
+				},
+			},
+			"spa_mode": schema.SingleNestedBlock{
+				MarkdownDescription: "Deploys a single-page application, whose entry document is also deployed as the error documents of the target, so that S3 website hosting and CloudFront custom error responses serve the application for paths that only exist in its client-side router. The copies are deployed, compared and kept like any other file, with the metadata of their own names. Error documents that are part of the source ZIP file are deployed as they are, and the deployment fails if the entry document is missing.",
+				Attributes: map[string]schema.Attribute{
+					"entry_document": schema.StringAttribute{
+						MarkdownDescription: fmt.Sprintf("The name of the entry document in the deployed files, after `source_root` and `path_rewrite` are applied. Defaults to `%s`.", deployer.DefaultSPAEntryDocument),
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.LengthAtLeast(1),
+						},
+					},
+					"error_documents": schema.ListAttribute{
+						MarkdownDescription: fmt.Sprintf("The names to also deploy the entry document as, relative to the `prefix` of `target`. Defaults to `%s`.", strings.Join(deployer.DefaultSPAErrorDocuments, "`, `")),
+						ElementType:         types.StringType,
+						Optional:            true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// ValidateConfig checks the values that can only be validated together or by parsing them, so that mistakes
+// are reported during plan instead of in the middle of a deployment. Values that are unknown until apply, e.g.
+// because they refer to resources created in the same apply, are skipped and checked when the deployment runs.
+func (r *DeploymentResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var sourceVersion, sourceChecksum, targetType, objectLockMode, objectLockRetainUntil, inventoryLocation, hashAlgorithm types.String
+	var legalHold, stagedPromotion, tagObjects types.Bool
+	var keepDeployments types.Int64
+	var pathRewrites types.List
+	var blueGreen, batchOperations types.Object
+	var targetRoleArn types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, sourceVersionPath, &sourceVersion)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("target").AtName("role_arn"), &targetRoleArn)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("source_checksum"), &sourceChecksum)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("target_type"), &targetType)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("legal_hold"), &legalHold)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("object_lock_mode"), &objectLockMode)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("object_lock_retain_until"), &objectLockRetainUntil)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("path_rewrite"), &pathRewrites)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("staged_promotion"), &stagedPromotion)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("blue_green"), &blueGreen)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("batch_operations"), &batchOperations)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("tag_objects"), &tagObjects)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("inventory_location"), &inventoryLocation)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("keep_deployments"), &keepDeployments)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("hash_algorithm"), &hashAlgorithm)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if tag, tagged := strings.CutPrefix(sourceVersion.ValueString(), sourceVersionTagPrefix); tagged && isKnown(sourceVersion) {
+		if key, _, found := strings.Cut(tag, "="); key == "" || !found {
+			resp.Diagnostics.AddAttributeError(sourceVersionPath, "Invalid source version", fmt.Sprintf("A source version given as a tag must be in the format `%s<key>=<value>`, e.g. `%senvironment=staging`.", sourceVersionTagPrefix, sourceVersionTagPrefix))
+		}
+	}
+
+	if !targetRoleArn.IsNull() && !targetType.IsUnknown() && !targetType.IsNull() && targetType.ValueString() != targetTypeS3 {
+		resp.Diagnostics.AddAttributeError(path.Root("target").AtName("role_arn"), "Invalid target role", "A role for the target is only supported when `target_type` is `s3`.")
+	}
+
+	if isKnown(sourceChecksum) {
+		if err := deployer.ValidateChecksum(sourceChecksum.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("source_checksum"), "Invalid source checksum", err.Error())
+		}
+	}
+
+	if isKnown(hashAlgorithm) {
+		if _, err := deployer.ParseHashAlgorithm(hashAlgorithm.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("hash_algorithm"), "Invalid hash algorithm", err.Error())
+		}
+	}
+
+	if isKnown(targetType) && targetType.ValueString() != targetTypeS3 && (!objectLockMode.IsNull() || legalHold.ValueBool()) {
+		resp.Diagnostics.AddAttributeError(path.Root("object_lock_mode"), "Object Lock not supported", "Object Lock is only supported when `target_type` is `s3`.")
+	}
+
+	if isKnown(targetType) && targetType.ValueString() != targetTypeS3 && !inventoryLocation.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("inventory_location"), "S3 Inventory not supported", "`inventory_location` is only supported when `target_type` is `s3`.")
+	}
+
+	if isKnown(targetType) && targetType.ValueString() != targetTypeS3 && (tagObjects.ValueBool() || !keepDeployments.IsNull()) {
+		resp.Diagnostics.AddAttributeError(path.Root("tag_objects"), "Tagging not supported", "`tag_objects` and `keep_deployments` are only supported when `target_type` is `s3`.")
+	}
+
+	if !objectLockMode.IsUnknown() && !objectLockRetainUntil.IsUnknown() && objectLockMode.IsNull() != objectLockRetainUntil.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("object_lock_mode"), "Incomplete Object Lock retention", "`object_lock_mode` and `object_lock_retain_until` must be set together.")
+	}
+	if isKnown(objectLockMode) {
+		if _, err := parseObjectLockMode(objectLockMode.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("object_lock_mode"), "Invalid Object Lock mode", err.Error())
+		}
+	}
+	if isKnown(objectLockRetainUntil) {
+		if _, err := time.Parse(time.RFC3339, objectLockRetainUntil.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("object_lock_retain_until"), "Invalid Object Lock retention date", err.Error())
+		}
+	}
+
+	if stagedPromotion.ValueBool() && !blueGreen.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("staged_promotion"), "Invalid staged promotion", "`staged_promotion` cannot be combined with `blue_green`, which already makes a deployment go live in a single step.")
+	}
+	if stagedPromotion.ValueBool() && (!objectLockMode.IsNull() || legalHold.ValueBool()) {
+		resp.Diagnostics.AddAttributeError(path.Root("staged_promotion"), "Invalid staged promotion", "`staged_promotion` cannot be combined with Object Lock, as promoted files are copied without their retention settings.")
+	}
+
+	if !batchOperations.IsNull() {
+		if isKnown(targetType) && targetType.ValueString() != targetTypeS3 {
+			resp.Diagnostics.AddAttributeError(path.Root("batch_operations"), "S3 Batch Operations not supported", "`batch_operations` is only supported when `target_type` is `s3`.")
+		}
+		if !blueGreen.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("batch_operations"), "Invalid S3 Batch Operations", "`batch_operations` cannot be combined with `blue_green`, which already makes a deployment go live in a single step.")
+		}
+		if !objectLockMode.IsNull() || legalHold.ValueBool() {
+			resp.Diagnostics.AddAttributeError(path.Root("batch_operations"), "Invalid S3 Batch Operations", "`batch_operations` cannot be combined with Object Lock, as copied files do not keep their retention settings.")
+		}
+	}
+
+	if !pathRewrites.IsUnknown() {
+		var rewrites []DeploymentPathRewriteModel
+		resp.Diagnostics.Append(pathRewrites.ElementsAs(ctx, &rewrites, false)...)
+		for i, rewrite := range rewrites {
+			if !isKnown(rewrite.From) {
+				continue
+			}
+			if _, err := regexp.Compile(rewrite.From.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("path_rewrite").AtListIndex(i).AtName("from"), "Invalid path rewrite", err.Error())
+			}
+		}
+	}
+}
+
+// ModifyPlan lists the files a planned deployment would add, change, or delete in a warning. Failing to compare the
+// files does not fail the plan, as the deployment may still succeed, e.g. if the source is uploaded in the same apply.
+func (r *DeploymentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.deployer == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+	planTargetRegion(ctx, r.deployer, path.Root("target").AtName("region"), req, resp)
+	if resp.Diagnostics.HasError() || r.deployer.PlanOffline {
+		return
+	}
+	planChangedFiles(ctx, req, resp)
+	r.followSourceVersionTag(ctx, req, resp)
+	planContentFingerprint(ctx, req, resp)
+	// Nothing is deployed when the deployment is destroyed or unchanged.
+	if resp.Diagnostics.HasError() || resp.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+
+	var data DeploymentResourceModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Paused.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(path.Root("paused"), "Deployment is paused", "The deployment is paused, so applying it will not upload or delete any files. The changes are deployed once `paused` is unset.")
+		return
+	}
+
+	maxFiles := data.planMaxFiles()
+	if maxFiles == 0 || data.sourceUnknown() || data.sourceVersion().IsUnknown() || data.Target.Bucket.IsUnknown() {
+		return
+	}
+
+	sourceBucket, sourceKey, err := data.sourceLocation()
+	if err != nil {
+		return
+	}
+	// The hashes of the last deployment let objects deployed with MD5 be compared without it after hash_algorithm
+	// changed, as they are when the deployment is applied.
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("file_hashes"), &data.FileHashes)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("deployed_files"), &data.DeployedFiles)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	deployment, diags := r.configureDeployment(ctx, &data, sourceBucket)
+	if diags.HasError() {
+		return
+	}
+	deployment.ReadOnly()
+
+	changes, err := deployment.PlanChanges(ctx, sourceKey, data.resolvedVersion())
+	if err != nil {
+		resp.Diagnostics.AddWarning("Could not compare files", fmt.Sprintf("The files to deploy could not be compared with the target, so the planned changes are not listed: %s", err))
+		return
+	}
+	if !changes.Empty() {
+		resp.Diagnostics.AddWarning("Planned file changes", formatPlannedChanges(changes, int(maxFiles)))
+	}
+}
+
+// formatPlannedChanges describes the planned changes, listing at most maxFiles files.
+func formatPlannedChanges(changes *deployer.PlannedChanges, maxFiles int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Deploying would add %d, change %d and delete %d file(s) in the target", len(changes.Added), len(changes.Changed), len(changes.Deleted))
+	if !changes.ReportedAt.IsZero() {
+		fmt.Fprintf(&sb, ", compared with the S3 Inventory report of %s", changes.ReportedAt.Format(time.RFC3339))
+	}
+	sb.WriteString(":")
+
+	listed := 0
+	for _, group := range []struct {
+		symbol string
+		keys   []string
+	}{{"+", changes.Added}, {"~", changes.Changed}, {"-", changes.Deleted}} {
+		for _, key := range group.keys {
+			if listed == maxFiles {
+				break
+			}
+			fmt.Fprintf(&sb, "\n  %s %s", group.symbol, key)
+			listed++
+		}
+	}
+
+	if total := len(changes.Added) + len(changes.Changed) + len(changes.Deleted); total > listed {
+		fmt.Fprintf(&sb, "\n  ... and %d more", total-listed)
+	}
+
+	return sb.String()
+}
+
+// planChangedFiles plans an update if refreshing found files that were changed outside of Terraform, which
+// detectChangedFiles marks with an empty hash in file_hashes.
+func planChangedFiles(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() {
+		return
+	}
+	var hashes types.Map
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("file_hashes"), &hashes)...)
+	if resp.Diagnostics.HasError() || hashes.IsNull() || hashes.IsUnknown() {
+		return
+	}
+
+	for _, hash := range hashes.Elements() {
+		if hash.Equal(types.StringValue("")) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("file_hashes"), types.MapUnknown(types.StringType))...)
+			return
+		}
+	}
+}
+
+// planContentFingerprint keeps the content_fingerprint of the last deployment in the plan if the update deploys the same
+// files, i.e. the same version of the same source with the same settings affecting their content. Sources without
+// versions can change between the plan and the apply, so their fingerprint is only known after apply.
+func planContentFingerprint(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || resp.Diagnostics.HasError() {
+		return
+	}
+	var plan, state DeploymentResourceModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() || !isKnown(state.ContentFingerprint) {
+		return
+	}
+
+	pinned := plan.CodePipelineSource != nil || isKnown(plan.ResolvedSourceVersion)
+	if pinned && reflect.DeepEqual(plan.contentSettings(), state.contentSettings()) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content_fingerprint"), state.ContentFingerprint)...)
+	}
+}
+
+// contentSettings returns the settings that determine the names and content of the deployed files.
+func (m *DeploymentResourceModel) contentSettings() []interface{} {
+	return []interface{}{
+		m.Source, m.CodePipelineSource, m.ResolvedSourceVersion,
+		m.SourceRoot, m.PathRewrites, m.Templates, m.UnmanagedPaths, m.SPAMode,
+	}
+}
+
+// followSourceVersionTag resolves a source version given as a tag whenever the deployment is planned, so that tagging
+// another version of the source plans an update that deploys it. The planned version is the one that is deployed.
+func (r *DeploymentResource) followSourceVersionTag(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var data DeploymentResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	tagKey, tagValue, tagged := data.sourceVersionTag()
+	if !tagged || data.Paused.ValueBool() || data.sourceUnknown() {
+		return
+	}
+
+	sourceBucket, sourceKey, err := data.sourceLocation()
+	if err != nil {
+		return
+	}
+	deployment, diags := r.newDeployment(ctx, &data, sourceBucket)
+	if diags.HasError() {
+		return
+	}
+	version, err := deployment.ResolveTaggedSourceVersion(ctx, sourceKey, tagKey, tagValue)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(sourceVersionPath, "Could not resolve source version", err.Error())
+		return
+	}
+
+	resolved := types.StringNull()
+	if version != "" {
+		resolved = types.StringValue(version)
+	}
+	if !resolved.Equal(data.ResolvedSourceVersion) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("resolved_source_version"), resolved)...)
+	}
+}
+
+// useStateForUnchangedSourceVersion keeps the resolved_source_version of the state while the version of the source
+// stays the same, so that updates deploy the version resolved by the first deployment instead of resolving it again.
+type useStateForUnchangedSourceVersion struct{}
+
+func (m useStateForUnchangedSourceVersion) Description(ctx context.Context) string {
+	return "Keeps the resolved version of the source while the configured version does not change."
+}
+
+func (m useStateForUnchangedSourceVersion) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m useStateForUnchangedSourceVersion) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	// Unresolved versions are resolved by the next deployment.
+	if req.StateValue.IsNull() || !req.PlanValue.IsUnknown() {
+		return
+	}
+
+	var planned, current types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, sourceVersionPath, &planned)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, sourceVersionPath, &current)...)
+	if planned.Equal(current) {
+		resp.PlanValue = req.StateValue
+	}
+}
+
+// isKnown returns whether a configured value is set and known.
+func isKnown(value types.String) bool {
+	return !value.IsNull() && !value.IsUnknown()
+}
+
+func (r *DeploymentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*deployer.Deployer)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *deployer.Deployer, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.deployer = client
+}
+
+// newDeployment returns a deployment to the target of the given model, with the settings that select which objects
+// in the target belong to it.
+func (r *DeploymentResource) newDeployment(ctx context.Context, data *DeploymentResourceModel, sourceBucket string) (*deployer.Deployment, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	bucket := targetBucket(data.Target.Bucket.ValueString())
+	// The region is only unknown if the provider was not configured yet when the deployment was planned.
+	if data.Target.Region.IsUnknown() || data.Target.Region.IsNull() {
+		data.Target.Region = types.StringValue(r.deployer.Defaults.TargetRegion)
+	}
+	region := data.Target.Region.ValueString()
+
+	var deployment *deployer.Deployment
+	switch data.TargetType.ValueString() {
+	case targetTypeGCS:
+		target, err := r.deployer.NewGCSTarget(ctx, bucket)
+		if err != nil {
+			diags.AddAttributeError(path.Root("target_type"), "Could not create Google Cloud Storage client", err.Error())
+			return nil, diags
+		}
+		deployment = r.deployer.NewDeploymentToTarget(sourceBucket, target)
+	case targetTypeAzure:
+		container := deployer.AzureStaticWebsiteContainer
+		if !data.AzureContainer.IsNull() {
+			container = data.AzureContainer.ValueString()
+		}
+		target, err := r.deployer.NewAzureBlobTarget(bucket, container)
+		if err != nil {
+			diags.AddAttributeError(path.Root("target_type"), "Could not create Azure Blob Storage client", err.Error())
+			return nil, diags
+		}
+		deployment = r.deployer.NewDeploymentToTarget(sourceBucket, target)
+	default:
+		if isKnown(data.Target.RoleArn) {
+			deployment = r.deployer.NewDeploymentToTarget(sourceBucket, r.deployer.NewS3TargetWithRole(bucket, region, data.Target.RoleArn.ValueString()))
+		} else {
+			deployment = r.deployer.NewDeployment(sourceBucket, bucket, region)
+		}
+	}
+
+	deployment.TargetPrefix = data.Target.Prefix.ValueString()
+	diags.Append(data.UnmanagedPaths.ElementsAs(ctx, &deployment.UnmanagedPaths, false)...)
+
+	var sourceKey, targetKey *deployer.CustomerKey
+	var err error
+	if !data.SourceSSECustomerKey.IsNull() {
+		sourceKey, err = deployer.ParseCustomerKey(data.SourceSSECustomerKey.ValueString(), data.SourceSSECustomerKeyMD5.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("source_sse_customer_key"), "Invalid customer-provided encryption key", err.Error())
+		}
+	}
+	if !data.TargetSSECustomerKey.IsNull() {
+		targetKey, err = deployer.ParseCustomerKey(data.TargetSSECustomerKey.ValueString(), data.TargetSSECustomerKeyMD5.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("target_sse_customer_key"), "Invalid customer-provided encryption key", err.Error())
+		}
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+	err = deployment.UseCustomerKeys(sourceKey, targetKey)
+	if err != nil {
+		diags.AddAttributeError(path.Root("target_sse_customer_key"), "Invalid customer-provided encryption key", err.Error())
+		return nil, diags
+	}
+	if data.ServerSideEncryption != nil {
+		err = deployment.UseEncryption(data.ServerSideEncryption.encryption())
+		if err != nil {
+			diags.AddAttributeError(path.Root("server_side_encryption"), "Invalid server-side encryption", err.Error())
+			return nil, diags
+		}
+	}
+	if !data.ACL.IsNull() {
+		err = deployment.UseCannedACL(s3types.ObjectCannedACL(data.ACL.ValueString()))
+		if err != nil {
+			diags.AddAttributeError(path.Root("acl"), "Invalid ACL", err.Error())
+			return nil, diags
+		}
+	}
+
+	return deployment, diags
+}
+
+// configureDeployment returns a deployment with all settings of the given model.
+func (r *DeploymentResource) configureDeployment(ctx context.Context, data *DeploymentResourceModel, sourceBucket string) (*deployer.Deployment, diag.Diagnostics) {
+	deployment, diags := r.newDeployment(ctx, data, sourceBucket)
+	if diags.HasError() {
+		return nil, diags
+	}
+	deployment.Preflight = data.PreflightChecks.ValueBool()
+	deployment.PreflightWriteProbe = data.PreflightWriteProbe.ValueBool()
+	deployment.VerifyAfterDeploy = data.VerifyAfterDeploy.ValueBool()
+	deployment.ConditionalWrites = data.ConditionalWrites.ValueBool()
+	deployment.SourceChecksum = data.SourceChecksum.ValueString()
+	deployment.SourceRoot = data.SourceRoot.ValueString()
+	deployment.DeleteRemovedFiles = data.DeleteRemovedFiles.ValueBool()
+	deployment.ResumeFailedDeployments = data.ResumeFailedDeployments.ValueBool()
+	deployment.RollbackOnFailure = data.RollbackOnFailure.ValueBool()
+	deployment.StagedPromotion = data.StagedPromotion.ValueBool()
+	deployment.TagObjects = data.TagObjects.ValueBool()
+	deployment.KeepDeployments = int(data.KeepDeployments.ValueInt64())
+	deployment.MaxRequestsPerSecond = int(data.MaxRequestsPerSecond.ValueInt64())
+	deployment.Limits = deployer.ArtifactLimits{
+		MaxFiles: int(data.MaxArtifactFiles.ValueInt64()),
+		MaxSize:  data.MaxArtifactSizeMB.ValueInt64() << 20,
+		MinFiles: int(data.MinFiles.ValueInt64()),
+		MinSize:  data.MinTotalBytes.ValueInt64(),
+	}
+	deployment.MultipartUpload.PartSize = data.MultipartPartSizeMB.ValueInt64() << 20
+
+	// Settings of the deployment are added to, or replace, the provider defaults.
+	if !data.DownloadConcurrency.IsNull() {
+		deployment.DownloadConcurrency = int(data.DownloadConcurrency.ValueInt64())
+	}
+	if !data.MultipartConcurrency.IsNull() {
+		deployment.MultipartUpload.Concurrency = int(data.MultipartConcurrency.ValueInt64())
+	}
+	if !data.Tags.IsNull() {
+		var tags map[string]string
+		diags.Append(data.Tags.ElementsAs(ctx, &tags, false)...)
+		if deployment.Tags == nil {
+			deployment.Tags = make(map[string]string, len(tags))
+		}
+		for key, value := range tags {
+			deployment.Tags[key] = value
+		}
+	}
+	var keepFiles []string
+	diags.Append(data.KeepFiles.ElementsAs(ctx, &keepFiles, false)...)
+	deployment.KeepFiles = append(deployment.KeepFiles, keepFiles...)
+	if !data.UploadOrder.IsNull() {
+		deployment.UploadOrder = nil
+		diags.Append(data.UploadOrder.ElementsAs(ctx, &deployment.UploadOrder, false)...)
+	}
+	diags.Append(data.RequiredFiles.ElementsAs(ctx, &deployment.RequiredFiles, false)...)
+	deployment.ContentDispositionRules = append(metadataRulesFromModel(data.ContentDispositionRules), deployment.ContentDispositionRules...)
+	deployment.ContentLanguageRules = append(metadataRulesFromModel(data.ContentLanguageRules), deployment.ContentLanguageRules...)
+	if !data.HashedAssetPattern.IsNull() {
+		deployment.HashedAssetPattern = data.HashedAssetPattern.ValueString()
+	}
+	deployment.PreDeployLambdaArn = data.PreDeployLambdaArn.ValueString()
+	deployment.PostDeployLambdaArn = data.PostDeployLambdaArn.ValueString()
+	deployment.PreviousFingerprint = data.Fingerprint.ValueString()
+	if !data.FileHashes.IsNull() && !data.FileHashes.IsUnknown() {
+		var hashes map[string]string
+		diags.Append(data.FileHashes.ElementsAs(ctx, &hashes, false)...)
+		for name, hash := range hashes {
+			if hash == "" {
+				deployment.RepairFiles = append(deployment.RepairFiles, name)
+			}
+		}
+		sort.Strings(deployment.RepairFiles)
+		deployment.PreviousFileHashes = hashes
+	}
+	if !data.DeployedFiles.IsNull() && !data.DeployedFiles.IsUnknown() {
+		diags.Append(data.DeployedFiles.ElementsAs(ctx, &deployment.PreviousObjectHashes, false)...)
+	}
+	hashAlgorithm, err := deployer.ParseHashAlgorithm(data.HashAlgorithm.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("hash_algorithm"), "Invalid hash algorithm", err.Error())
+		return nil, diags
+	}
+	deployment.UseHashAlgorithm(hashAlgorithm)
+
+	if data.SPAMode != nil {
+		deployment.SPA = &deployer.SPAMode{
+			EntryDocument:  deployer.DefaultSPAEntryDocument,
+			ErrorDocuments: deployer.DefaultSPAErrorDocuments,
+		}
+		if !data.SPAMode.EntryDocument.IsNull() {
+			deployment.SPA.EntryDocument = data.SPAMode.EntryDocument.ValueString()
+		}
+		if !data.SPAMode.ErrorDocuments.IsNull() {
+			deployment.SPA.ErrorDocuments = nil
+			diags.Append(data.SPAMode.ErrorDocuments.ElementsAs(ctx, &deployment.SPA.ErrorDocuments, false)...)
+		}
+	}
+
+	if data.BlueGreen != nil {
+		deployment.BlueGreen = &deployer.BlueGreen{
+			ReleasePrefix: deployer.DefaultReleasePrefix,
+			PointerKey:    deployer.DefaultReleasePointerKey,
+		}
+		if !data.BlueGreen.ReleasePrefix.IsNull() {
+			deployment.BlueGreen.ReleasePrefix = data.BlueGreen.ReleasePrefix.ValueString()
+		}
+		if !data.BlueGreen.PointerKey.IsNull() {
+			deployment.BlueGreen.PointerKey = data.BlueGreen.PointerKey.ValueString()
+		}
+	}
+
+	if !data.InventoryLocation.IsNull() {
+		bucket, prefix, err := parseSource(data.InventoryLocation.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("inventory_location"), "Invalid S3 Inventory location", err.Error())
+			return nil, diags
+		}
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		deployment.Inventory = &deployer.InventoryReports{
+			Store:  r.deployer.NewS3Target(bucket, data.Target.Region.ValueString()),
+			Prefix: prefix,
+		}
+	}
 
-func NewDeploymentResource() resource.Resource {
-	return &DeploymentResource{}
+	if data.BatchOperations != nil {
+		stagingBucket := data.BatchOperations.StagingBucket.ValueString()
+		deployment.BatchOperations = &deployer.BatchOperations{
+			AccountID:     data.BatchOperations.AccountID.ValueString(),
+			Region:        data.Target.Region.ValueString(),
+			RoleArn:       data.BatchOperations.RoleArn.ValueString(),
+			StagingBucket: stagingBucket,
+			Staging:       r.deployer.NewS3Target(stagingBucket, data.Target.Region.ValueString()),
+		}
+	}
+
+	if data.Gate != nil {
+		deployment.Gate = &deployer.DeploymentGate{}
+		diags.Append(data.Gate.AlarmArns.ElementsAs(ctx, &deployment.Gate.AlarmArns, false)...)
+	}
+
+	for i, rewrite := range data.PathRewrites {
+		from, regexpErr := regexp.Compile(rewrite.From.ValueString())
+		if regexpErr != nil {
+			diags.AddAttributeError(path.Root("path_rewrite").AtListIndex(i).AtName("from"), "Invalid path rewrite", regexpErr.Error())
+			continue
+		}
+		deployment.PathRewrites = append(deployment.PathRewrites, deployer.PathRewrite{
+			From: from,
+			To:   rewrite.To.ValueString(),
+		})
+	}
+
+	for _, template := range data.Templates {
+		vars := make(map[string]string)
+		diags.Append(template.Vars.ElementsAs(ctx, &vars, false)...)
+		deployment.Templates = append(deployment.Templates, deployer.Template{
+			Pattern: template.Pattern.ValueString(),
+			Vars:    vars,
+		})
+	}
+
+	for i, rule := range data.ValidationRules {
+		validationRule := deployer.ValidationRule{
+			Pattern: rule.Pattern.ValueString(),
+			Type:    deployer.ValidationType(rule.Type.ValueString()),
+		}
+		if validationRule.Type == deployer.ValidationTypeRegex {
+			if rule.Expression.IsNull() {
+				diags.AddAttributeError(path.Root("validation_rule").AtListIndex(i).AtName("expression"), "Missing validation expression", fmt.Sprintf("`expression` is required when `type` is %q.", deployer.ValidationTypeRegex))
+				continue
+			}
+			expression, regexpErr := regexp.Compile(rule.Expression.ValueString())
+			if regexpErr != nil {
+				diags.AddAttributeError(path.Root("validation_rule").AtListIndex(i).AtName("expression"), "Invalid validation expression", regexpErr.Error())
+				continue
+			}
+			validationRule.Expression = expression
+		}
+		deployment.ValidationRules = append(deployment.ValidationRules, validationRule)
+	}
+
+	keys := make(map[string]bool, len(data.MetadataFiles))
+	for i, file := range data.MetadataFiles {
+		key := file.Key.ValueString()
+		if keys[key] {
+			diags.AddAttributeError(path.Root("metadata_file").AtListIndex(i).AtName("key"), "Duplicate metadata file", fmt.Sprintf("There is more than one metadata file with the key %q.", key))
+			continue
+		}
+		keys[key] = true
+		deployment.MetadataFiles = append(deployment.MetadataFiles, deployer.MetadataFile{
+			Key:     key,
+			Content: []byte(file.Content.ValueString()),
+		})
+	}
+
+	objectLock, objectLockDiags := objectLockFromModel(data)
+	diags.Append(objectLockDiags...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	deployment.ObjectLock = objectLock
+
+	if data.WriteVersionFile.ValueBool() {
+		metadata := make(map[string]string)
+		diags.Append(data.VersionFileMetadata.ElementsAs(ctx, &metadata, false)...)
+
+		deployment.VersionFile = &deployer.VersionFile{
+			Key:           data.VersionFileKey.ValueString(),
+			SourceVersion: data.deployedSourceVersion(),
+			Metadata:      metadata,
+		}
+	}
+
+	return deployment, diags
 }
 
-// DeploymentResource defines the resource implementation.
-type DeploymentResource struct {
-	deployer *deployer.Deployer
+func (r *DeploymentResource) runDeployment(ctx context.Context, data *DeploymentResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	sourceBucket, sourceKey, err := data.sourceLocation()
+	if err != nil {
+		diags.AddAttributeError(path.Root("source"), "Error during deployment", err.Error())
+		return diags
+	}
+
+	diags.Append(r.resolveSourceVersion(ctx, data, sourceBucket, sourceKey)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	deployment, configureDiags := r.configureDeployment(ctx, data, sourceBucket)
+	diags.Append(configureDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	files, err := deployment.Deploy(ctx, sourceKey, data.resolvedVersion())
+	if err != nil {
+		diags.Append(deploymentErrorDiagnostic(deployment, err))
+	}
+	data.SourceETag = types.StringValue(deployment.SourceETag())
+	if err == nil {
+		var filesDiags diag.Diagnostics
+		data.DeployedFiles, filesDiags = types.MapValueFrom(ctx, types.StringType, deployment.ObjectHashes(files))
+		diags.Append(filesDiags...)
+		fileHashes := deployment.FileHashes(files)
+		data.FileHashes, filesDiags = types.MapValueFrom(ctx, types.StringType, fileHashes)
+		diags.Append(filesDiags...)
+		data.ContentFingerprint = types.StringValue(deployer.ContentFingerprint(fileHashes))
+		data.Fingerprint = types.StringValue(deployment.Fingerprint())
+		diags.Append(updateDeployedVersions(ctx, data, deployment, files)...)
+	}
+
+	if err == nil && !data.VersionParameterName.IsNull() {
+		paramErr := r.deployer.PutVersionParameter(ctx, data.VersionParameterName.ValueString(), data.deployedSourceVersion())
+		if paramErr != nil {
+			diags.AddAttributeError(path.Root("version_parameter_name"), "Could not publish deployed version", paramErr.Error())
+		}
+	}
+
+	if err == nil && data.CloudFrontContinuousDeployment != nil && data.CloudFrontContinuousDeployment.Promote.ValueBool() {
+		promoteErr := r.deployer.PromoteStagingDistribution(
+			ctx,
+			data.CloudFrontContinuousDeployment.PrimaryDistributionID.ValueString(),
+			data.CloudFrontContinuousDeployment.StagingDistributionID.ValueString(),
+		)
+		if promoteErr != nil {
+			diags.AddAttributeError(path.Root("cloudfront_continuous_deployment").AtName("promote"), "Could not promote staging distribution", promoteErr.Error())
+		}
+	}
+
+	summary := deployment.Summary(sourceKey, data.deployedSourceVersion(), files, err)
+	data.FilesAdded = types.Int64Value(int64(summary.FilesAdded))
+	data.FilesChanged = types.Int64Value(int64(summary.FilesChanged))
+	data.FilesDeleted = types.Int64Value(int64(summary.FilesDeleted))
+	data.FilesSkipped = types.Int64Value(int64(summary.FilesSkipped))
+	data.TotalBytesUploaded = types.Int64Value(summary.BytesUploaded)
+	var changedDiags diag.Diagnostics
+	data.ChangedFiles, changedDiags = types.ListValueFrom(ctx, types.StringType, deployment.ChangedPaths())
+	diags.Append(changedDiags...)
+
+	if summary.FilesResumed > 0 {
+		diags.AddAttributeWarning(
+			path.Root("resume_failed_deployments"),
+			"Resumed failed deployment",
+			fmt.Sprintf("%d of %d files had already been deployed by a failed deployment, and were not checked again.", summary.FilesResumed, summary.FilesDeployed),
+		)
+	}
+
+	diags.Append(r.sendNotifications(ctx, data, summary)...)
+
+	if !data.HistoryTableName.IsNull() {
+		historyErr := r.deployer.RecordDeploymentHistory(ctx, data.HistoryTableName.ValueString(), summary)
+		if historyErr != nil {
+			diags.AddAttributeWarning(path.Root("history_table_name"), "Could not record deployment history", historyErr.Error())
+		}
+	}
+
+	if !data.MetricsNamespace.IsNull() {
+		metricsErr := r.deployer.PutDeploymentMetrics(ctx, data.MetricsNamespace.ValueString(), summary)
+		if metricsErr != nil {
+			diags.AddAttributeWarning(path.Root("metrics_namespace"), "Could not emit deployment metrics", metricsErr.Error())
+		}
+	}
+
+	if !data.ReportPath.IsNull() {
+		reportErr := deployer.WriteDeploymentReport(data.ReportPath.ValueString(), deployment.Report(summary))
+		if reportErr != nil {
+			diags.AddAttributeWarning(path.Root("report_path"), "Could not write deployment report", reportErr.Error())
+		}
+	}
+
+	return diags
 }
 
-// DeploymentResourceModel describes the resource data model.
-type DeploymentResourceModel struct {
-	Source        types.String `tfsdk:"source"`
-	SourceVersion types.String `tfsdk:"source_version"`
-	Target        types.String `tfsdk:"target"`
-	TargetRegion  types.String `tfsdk:"target_region"`
+// pauseDeployment sets the computed attributes of a paused deployment, which deploys nothing, to those of the last
+// deployment in the given state, or to empty values if it was never deployed.
+func pauseDeployment(data *DeploymentResourceModel, state *DeploymentResourceModel) {
+	if state == nil {
+		data.SourceETag = types.StringNull()
+		data.ResolvedSourceVersion = types.StringNull()
+		data.FilesAdded = types.Int64Value(0)
+		data.FilesChanged = types.Int64Value(0)
+		data.FilesDeleted = types.Int64Value(0)
+		data.FilesSkipped = types.Int64Value(0)
+		data.TotalBytesUploaded = types.Int64Value(0)
+		data.ChangedFiles = types.ListValueMust(types.StringType, []attr.Value{})
+		// Empty rather than null, so that refreshing does not adopt the content of the target.
+		data.DeployedFiles = types.MapValueMust(types.StringType, map[string]attr.Value{})
+		data.DeployedVersions = types.MapValueMust(types.StringType, map[string]attr.Value{})
+		data.FileHashes = types.MapValueMust(types.StringType, map[string]attr.Value{})
+		data.Fingerprint = types.StringNull()
+		data.ContentFingerprint = types.StringNull()
+		return
+	}
+
+	data.SourceETag = state.SourceETag
+	// A version resolved for another source version is resolved again once the deployment is resumed.
+	data.ResolvedSourceVersion = types.StringNull()
+	if data.sourceVersion().Equal(state.sourceVersion()) {
+		data.ResolvedSourceVersion = state.ResolvedSourceVersion
+	}
+	data.FilesAdded = state.FilesAdded
+	data.FilesChanged = state.FilesChanged
+	data.FilesDeleted = state.FilesDeleted
+	data.FilesSkipped = state.FilesSkipped
+	data.TotalBytesUploaded = state.TotalBytesUploaded
+	data.ChangedFiles = state.ChangedFiles
+	data.DeployedFiles = state.DeployedFiles
+	data.DeployedVersions = state.DeployedVersions
+	data.FileHashes = state.FileHashes
+	data.Fingerprint = state.Fingerprint
+	data.ContentFingerprint = state.ContentFingerprint
 }
 
-func (r *DeploymentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_deployment"
+// resolveSourceVersion sets resolved_source_version to the version of the source to deploy. If the version is
+// `latest`, and its version was not resolved by an earlier deployment, the current version of the source is resolved.
+// If it is a tag that was not resolved when the deployment was planned, the newest version with the tag is resolved.
+func (r *DeploymentResource) resolveSourceVersion(ctx context.Context, data *DeploymentResourceModel, sourceBucket string, sourceKey string) diag.Diagnostics {
+	if !data.resolvesSourceVersion() {
+		data.ResolvedSourceVersion = data.sourceVersion()
+		return nil
+	}
+	if !data.ResolvedSourceVersion.IsUnknown() {
+		return nil
+	}
+
+	deployment, diags := r.newDeployment(ctx, data, sourceBucket)
+	if diags.HasError() {
+		return diags
+	}
+	var version string
+	var err error
+	if tagKey, tagValue, tagged := data.sourceVersionTag(); tagged {
+		version, err = deployment.ResolveTaggedSourceVersion(ctx, sourceKey, tagKey, tagValue)
+	} else {
+		version, err = deployment.ResolveSourceVersion(ctx, sourceKey)
+	}
+	if err != nil {
+		diags.AddAttributeError(sourceVersionPath, "Could not resolve source version", err.Error())
+		return diags
+	}
+
+	data.ResolvedSourceVersion = types.StringNull()
+	if version != "" {
+		data.ResolvedSourceVersion = types.StringValue(version)
+	}
+	return diags
 }
 
-func (r *DeploymentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
-	resp.Schema = schema.Schema{
-		MarkdownDescription: "Deploys a set of files from a source ZIP file in an S3 bucket to a target S3 bucket.",
+// updateDeployedVersions sets deployed_versions to the versions of the objects uploaded by the deployment, and the
+// versions from the last deployment of the other deployed objects.
+func updateDeployedVersions(ctx context.Context, data *DeploymentResourceModel, deployment *deployer.Deployment, files deployer.DeployedFiles) diag.Diagnostics {
+	var diags diag.Diagnostics
 
-		Attributes: map[string]schema.Attribute{
-			"source": schema.StringAttribute{
-				MarkdownDescription: "The S3 bucket and path to the ZIP file containing the source files to be deployed. Format: 'bucket-name/path/to/source.zip'.",
-				Required:            true,
-			},
-			"source_version": schema.StringAttribute{
-				MarkdownDescription: "The version ID of the source ZIP file in the S3 bucket. This is used to handle versioning of files in S3.",
-				Required:            true,
-			},
-			"target": schema.StringAttribute{
-				MarkdownDescription: "The target S3 bucket where the unzipped files will be deployed.",
-				Required:            true,
-			},
-			"target_region": schema.StringAttribute{
-				MarkdownDescription: "The target region of the S3 bucket where the unzipped files will be deployed.",
-				Optional:            true,
-				Default:             stringdefault.StaticString("eu-west-1"),
-				Computed:            true,
-			},
-		},
+	previous := make(map[string]string)
+	if !data.DeployedVersions.IsNull() && !data.DeployedVersions.IsUnknown() {
+		diags.Append(data.DeployedVersions.ElementsAs(ctx, &previous, false)...)
 	}
+
+	versions := make(map[string]string)
+	for key := range deployment.ObjectHashes(files) {
+		if versionID, found := previous[key]; found {
+			versions[key] = versionID
+		}
+	}
+	for key, versionID := range deployment.ObjectVersions() {
+		versions[key] = versionID
+	}
+
+	var versionsDiags diag.Diagnostics
+	data.DeployedVersions, versionsDiags = types.MapValueFrom(ctx, types.StringType, versions)
+	diags.Append(versionsDiags...)
+	return diags
 }
 
-func (r *DeploymentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	if req.ProviderData == nil {
-		return
+// metadataRulesFromModel converts metadata rule blocks to deployer rules, keeping their order.
+func metadataRulesFromModel(rules []DeploymentMetadataRuleModel) []deployer.MetadataRule {
+	var result []deployer.MetadataRule
+	for _, rule := range rules {
+		result = append(result, deployer.MetadataRule{
+			Pattern: rule.Pattern.ValueString(),
+			Value:   rule.Value.ValueString(),
+		})
+	}
+	return result
+}
+
+// objectLockFromModel returns the Object Lock settings to apply to uploaded objects, or nil if none are configured.
+func objectLockFromModel(data *DeploymentResourceModel) (*deployer.ObjectLock, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if data.ObjectLockMode.IsNull() && data.ObjectLockRetainUntil.IsNull() && !data.LegalHold.ValueBool() {
+		return nil, diags
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return
+	objectLock := &deployer.ObjectLock{
+		LegalHold: data.LegalHold.ValueBool(),
+	}
+
+	if data.ObjectLockMode.IsNull() != data.ObjectLockRetainUntil.IsNull() {
+		diags.AddAttributeError(path.Root("object_lock_mode"), "Incomplete Object Lock retention", "`object_lock_mode` and `object_lock_retain_until` must be set together.")
+		return nil, diags
+	}
+
+	if !data.ObjectLockMode.IsNull() {
+		mode, err := parseObjectLockMode(data.ObjectLockMode.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("object_lock_mode"), "Invalid Object Lock mode", err.Error())
+		}
+		objectLock.Mode = mode
+
+		retainUntil, err := time.Parse(time.RFC3339, data.ObjectLockRetainUntil.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("object_lock_retain_until"), "Invalid Object Lock retention date", err.Error())
+		}
+		objectLock.RetainUntil = &retainUntil
 	}
 
-	r.deployer = &deployer.Deployer{
-		DefaultAWSConfig: cfg,
+	if diags.HasError() {
+		return nil, diags
 	}
+
+	return objectLock, diags
 }
 
-func (r *DeploymentResource) runDeployment(data *DeploymentResourceModel) error {
-	sourceParts := strings.SplitN(data.Source.ValueString(), "/", 2)
-	if len(sourceParts) != 2 {
-		return fmt.Errorf("invalid source format: %s", sourceParts)
+// parseObjectLockMode returns the Object Lock mode with the given name.
+func parseObjectLockMode(name string) (s3types.ObjectLockMode, error) {
+	mode := s3types.ObjectLockMode(name)
+	if mode != s3types.ObjectLockModeGovernance && mode != s3types.ObjectLockModeCompliance {
+		return "", fmt.Errorf("expected GOVERNANCE or COMPLIANCE, got %q", name)
 	}
-	sourceBucket := sourceParts[0]
-	sourceKey := sourceParts[1]
+	return mode, nil
+}
 
-	deployment := r.deployer.NewDeployment(sourceBucket, data.Target.ValueString(), data.TargetRegion.ValueString())
+// Descriptions of the attributes of the server_side_encryption blocks of deployments and of the provider defaults.
+const (
+	encryptionAlgorithmDescription = "The server-side encryption algorithm, one of `AES256`, `aws:kms` or `aws:kms:dsse`."
+	encryptionKMSKeyIDDescription  = "The ID or ARN of the KMS key to encrypt the files with when `algorithm` is `aws:kms` or `aws:kms:dsse`. Defaults to the AWS managed key of S3."
+	encryptionBucketKeyDescription = "Whether to encrypt the files with an [S3 Bucket Key](https://docs.aws.amazon.com/AmazonS3/latest/userguide/bucket-key.html), which reduces the number of requests to KMS. Defaults to `false`."
+)
 
-	_, err := deployment.Deploy(sourceKey, nil)
-	if err != nil {
-		return fmt.Errorf("Error during deployment: %w", err)
+// encryptionAlgorithms returns the names of the server-side encryption algorithms objects can be written with.
+func encryptionAlgorithms() []string {
+	var names []string
+	for _, algorithm := range s3types.ServerSideEncryption("").Values() {
+		names = append(names, string(algorithm))
+	}
+	return names
+}
+
+// cannedACLs returns the names of the canned ACLs objects can be written with.
+func cannedACLs() []string {
+	var names []string
+	for _, acl := range s3types.ObjectCannedACL("").Values() {
+		names = append(names, string(acl))
+	}
+	return names
+}
+
+// deploymentErrorDiagnostic returns a diagnostic for a failed deployment, scoped to the source or target
+// attribute when the error can be attributed to one of the buckets.
+func deploymentErrorDiagnostic(deployment *deployer.Deployment, err error) diag.Diagnostic {
+	var conflictErr *deployer.ConflictError
+	if errors.As(err, &conflictErr) {
+		return diag.NewAttributeErrorDiagnostic(
+			path.Root("target"),
+			"Conflicting deployment",
+			fmt.Sprintf("%s\n\nAnother deployment to the same target is probably running. Wait for it to finish and apply again.", err),
+		)
+	}
+
+	var preflightErr *deployer.PreflightError
+	if errors.As(err, &preflightErr) {
+		attribute := "target"
+		if preflightErr.Check == deployer.PreflightSource {
+			attribute = "source"
+		}
+		return diag.NewAttributeErrorDiagnostic(path.Root(attribute), "Preflight check failed", err.Error())
+	}
+
+	var gateErr *deployer.DeploymentGateError
+	if errors.As(err, &gateErr) {
+		return diag.NewAttributeErrorDiagnostic(
+			path.Root("deployment_gate").AtName("alarm_arns"),
+			"Deployment gate closed",
+			fmt.Sprintf("%s\n\nApply again once the alarms are back to OK.", err),
+		)
+	}
+
+	var batchErr *deployer.BatchJobError
+	if errors.As(err, &batchErr) {
+		return diag.NewAttributeErrorDiagnostic(path.Root("batch_operations"), "S3 Batch Operations job failed", err.Error())
+	}
+
+	var checksumErr *deployer.ChecksumMismatchError
+	if errors.As(err, &checksumErr) {
+		return diag.NewAttributeErrorDiagnostic(path.Root("source_checksum"), "Artifact checksum mismatch", err.Error())
+	}
+
+	var limitErr *deployer.ArtifactLimitError
+	if errors.As(err, &limitErr) {
+		if limitErr.Min > 0 {
+			attribute := "min_files"
+			if limitErr.Limit == deployer.ArtifactLimitSize {
+				attribute = "min_total_bytes"
+			}
+			return diag.NewAttributeErrorDiagnostic(path.Root(attribute), "Artifact too small", err.Error())
+		}
+		attribute := "max_artifact_files"
+		if limitErr.Limit == deployer.ArtifactLimitSize {
+			attribute = "max_artifact_size_mb"
+		}
+		return diag.NewAttributeErrorDiagnostic(path.Root(attribute), "Artifact too large", err.Error())
+	}
+
+	var objectErr *deployer.ObjectError
+	if errors.As(err, &objectErr) {
+		switch objectErr.Bucket {
+		case deployment.SourceBucket:
+			return diag.NewAttributeErrorDiagnostic(path.Root("source"), "Error during deployment", err.Error())
+		case deployment.TargetBucket:
+			return diag.NewAttributeErrorDiagnostic(path.Root("target"), "Error during deployment", err.Error())
+		}
+	}
+
+	return diag.NewErrorDiagnostic("Error during deployment", err.Error())
+}
+
+// sendNotifications notifies the configured targets about the outcome of a deployment.
+// Failing to notify does not fail the deployment, so problems are reported as warnings.
+func (r *DeploymentResource) sendNotifications(ctx context.Context, data *DeploymentResourceModel, summary deployer.DeploymentSummary) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if data.Notification != nil && !data.Notification.SNSTopicArn.IsNull() {
+		err := r.deployer.PublishSNSNotification(ctx, data.Notification.SNSTopicArn.ValueString(), summary)
+		if err != nil {
+			diags.AddAttributeWarning(path.Root("notification").AtName("sns_topic_arn"), "Could not send deployment notification", err.Error())
+		}
+	}
+
+	if data.Notification != nil && !data.Notification.EventBusName.IsNull() {
+		err := r.deployer.PutEventBridgeEvent(ctx, data.Notification.EventBusName.ValueString(), summary)
+		if err != nil {
+			diags.AddAttributeWarning(path.Root("notification").AtName("event_bus_name"), "Could not send deployment event", err.Error())
+		}
+	}
+
+	if data.Webhook != nil {
+		headers := make(map[string]string)
+		diags.Append(data.Webhook.Headers.ElementsAs(ctx, &headers, false)...)
+
+		err := r.deployer.PostWebhook(ctx, data.Webhook.URL.ValueString(), headers, data.Webhook.PayloadTemplate.ValueString(), summary)
+		if err != nil {
+			diags.AddAttributeWarning(path.Root("webhook").AtName("url"), "Could not send deployment webhook", err.Error())
+		}
 	}
 
-	return nil
+	return diags
 }
 
 func (r *DeploymentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -110,13 +2154,35 @@ func (r *DeploymentResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
-	err := r.runDeployment(&data)
+	timeout, diags := data.Timeouts.Create(ctx, defaultDeploymentTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	id, err := data.importID()
 	if err != nil {
-		resp.Diagnostics.AddError("Error during deployment", err.Error())
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Could not read source format", err.Error())
+		return
+	}
+	data.ID = types.StringValue(id)
+
+	if data.Paused.ValueBool() {
+		pauseDeployment(&data, nil)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.Append(setDeploymentIdentity(ctx, resp.Identity, &data)...)
+		return
+	}
+
+	resp.Diagnostics.Append(r.runDeployment(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	resp.Diagnostics.Append(setDeploymentIdentity(ctx, resp.Identity, &data)...)
 }
 
 func (r *DeploymentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -127,20 +2193,62 @@ func (r *DeploymentResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
-	sourceParts := strings.SplitN(state.Source.ValueString(), "/", 2)
-	if len(sourceParts) != 2 {
-		resp.Diagnostics.AddError("Could not read source format", fmt.Sprintf("invalid source format: %s", sourceParts))
+	// Deployments imported by their identity have no source until the next apply takes it from the configuration, so
+	// only the content of their target is adopted.
+	importedByIdentity := state.Source == nil && state.CodePipelineSource == nil
+	sourceBucket, sourceKey, err := state.sourceLocation()
+	if err != nil && !importedByIdentity {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Could not read source format", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(setDeploymentIdentity(ctx, resp.Identity, &state)...)
+
+	// Deployments created by versions of the provider without an ID get the one they would be imported with.
+	if state.ID.IsNull() && !importedByIdentity {
+		id, _ := state.importID()
+		state.ID = types.StringValue(id)
+	}
+
+	// Offline, the state is kept as it is, so that the plan is made from the deployed files and source ETag in it.
+	if r.deployer.PlanOffline {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+		return
+	}
+
+	deployment, diags := r.newDeployment(ctx, &state, sourceBucket)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
-	sourceBucket := sourceParts[0]
-	sourceKey := sourceParts[1]
+	// Refreshing only reads the source and the target.
+	deployment.ReadOnly()
 
-	deployment := r.deployer.NewDeployment(sourceBucket, state.Target.ValueString(), state.TargetRegion.ValueString())
+	// Imported deployments, and those last deployed by versions of the provider without deployed_files, adopt the
+	// content of the target as it is, so that they are not deployed again only to get the files into the state.
+	if state.DeployedFiles.IsNull() {
+		resp.Diagnostics.Append(adoptDeployedFiles(ctx, &state, deployment)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if state.DetectDrift.ValueBool() {
+		resp.Diagnostics.Append(detectChangedFiles(ctx, &state, deployment)...)
+	}
+	if importedByIdentity {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+		return
+	}
 
-	_, err := deployment.HashesForArtifact(sourceKey, nil)
+	// Only the ETag of the source is refreshed, so that refreshing does not download the source. The files are only
+	// compared with the target when a deployment is planned.
+	err = deployment.RefreshSourceETag(ctx, sourceKey, state.resolvedVersion())
 	if err != nil {
+		// Keep the state as it is, so that a missing source does not fail the refresh.
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 		return
 	}
+	state.SourceETag = types.StringValue(deployment.SourceETag())
 
 	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
@@ -156,13 +2264,55 @@ func (r *DeploymentResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
-	err := r.runDeployment(&data)
-	if err != nil {
-		resp.Diagnostics.AddError("Error during deployment", err.Error())
+	timeout, diags := data.Timeouts.Update(ctx, defaultDeploymentTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Deployments imported by their identity get their ID with the source they are first deployed from.
+	if data.ID.IsUnknown() {
+		id, err := data.importID()
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("source"), "Could not read source format", err.Error())
+			return
+		}
+		data.ID = types.StringValue(id)
+	}
+
+	if data.Paused.ValueBool() {
+		var state DeploymentResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		pauseDeployment(&data, &state)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		resp.Diagnostics.Append(setDeploymentIdentity(ctx, resp.Identity, &data)...)
+		return
+	}
+
+	// The fingerprint of the last deployment lets the deployment skip checking each file if nothing changed, and the
+	// versions of files that are not uploaded again stay the same. Files changed outside of Terraform, which have an
+	// empty hash, are then the only ones uploaded again. The hashes of the last deployment also let objects deployed
+	// with MD5 be compared without it after hash_algorithm changed.
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("fingerprint"), &data.Fingerprint)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("deployed_versions"), &data.DeployedVersions)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("file_hashes"), &data.FileHashes)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("deployed_files"), &data.DeployedFiles)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.runDeployment(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	resp.Diagnostics.Append(setDeploymentIdentity(ctx, resp.Identity, &data)...)
 }
 
 func (r *DeploymentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -173,8 +2323,326 @@ func (r *DeploymentResource) Delete(ctx context.Context, req resource.DeleteRequ
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	if data.DeletionProtection.ValueBool() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("deletion_protection"),
+			"Deployment is protected from deletion",
+			fmt.Sprintf("The deployment to %s has deletion_protection enabled, so it cannot be destroyed or replaced. To destroy it, set deletion_protection to false and apply the change first.", data.Target.Bucket.ValueString()),
+		)
+		return
+	}
+
+	timeout, diags := data.Timeouts.Delete(ctx, defaultDeploymentTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || !data.PurgeOnDestroy.ValueBool() {
+		return
+	}
+	if data.Paused.ValueBool() {
+		resp.Diagnostics.AddAttributeWarning(path.Root("paused"), "Files not purged", "The deployment is paused, so its files were left in the target even though `purge_on_destroy` is set.")
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Purging only deletes files in the target, so the source is not needed. Deployments imported by their identity
+	// and not applied since have none.
+	deployment, diags := r.newDeployment(ctx, &data, "")
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	files := make(map[string]string)
+	resp.Diagnostics.Append(data.DeployedFiles.ElementsAs(ctx, &files, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := deployment.Purge(ctx, files)
+	if err != nil {
+		resp.Diagnostics.Append(deploymentErrorDiagnostic(deployment, err))
+	}
+}
+
+// detectChangedFiles empties the hashes of the files in file_hashes whose objects were changed or deleted outside of
+// Terraform, so that the next plan deploys them again.
+func detectChangedFiles(ctx context.Context, state *DeploymentResourceModel, deployment *deployer.Deployment) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if state.FileHashes.IsNull() || state.FileHashes.IsUnknown() {
+		return diags
+	}
+
+	var hashes map[string]string
+	diags.Append(state.FileHashes.ElementsAs(ctx, &hashes, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	changed, err := deployment.ChangedFiles(ctx, hashes)
+	if err != nil {
+		diags.AddAttributeWarning(path.Root("detect_drift"), "Could not check deployed files", err.Error())
+		return diags
+	}
+	if len(changed) == 0 {
+		return diags
+	}
+
+	for _, name := range changed {
+		hashes[name] = ""
+	}
+	state.FileHashes, diags = types.MapValueFrom(ctx, types.StringType, hashes)
+	return diags
+}
+
+// adoptDeployedFiles reads the objects in the target into deployed_files. If the source version is not known, it is
+// read from the version file, if the target has one.
+func adoptDeployedFiles(ctx context.Context, state *DeploymentResourceModel, deployment *deployer.Deployment) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	files, err := deployment.HashesForDeployedFiles(ctx)
+	if err != nil {
+		diags.AddAttributeError(path.Root("target"), "Could not read deployed files", err.Error())
+		return diags
+	}
+	state.DeployedFiles, diags = types.MapValueFrom(ctx, types.StringType, files)
+
+	if state.Source != nil && state.Source.Version.IsNull() {
+		version, err := deployment.DeployedSourceVersion(ctx, state.VersionFileKey.ValueString())
+		if err != nil {
+			diags.AddAttributeWarning(sourceVersionPath, "Could not read deployed version", err.Error())
+		}
+		if version == "" {
+			version = sourceVersionLatest
+		} else {
+			state.WriteVersionFile = types.BoolValue(true)
+		}
+		state.Source.Version = types.StringValue(version)
+	}
+
+	return diags
 }
 
+// ImportState adopts the content of a target deployed outside of Terraform, or by a deployment that was removed from
+// the state. The ID has the format "source_bucket/source_key,target[,target_prefix]", and the target is expected to
+// be an S3 bucket in the default region. Deployments imported by their identity only have a target, and get their
+// source from the configuration when they are next applied.
 func (r *DeploymentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	var identity DeploymentIdentityModel
+	if req.ID == "" {
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if identity.TargetPrefix.ValueString() == "" {
+			identity.TargetPrefix = types.StringNull()
+		}
+	} else {
+		parts := strings.Split(req.ID, ",")
+		if len(parts) < 2 || len(parts) > 3 || parts[1] == "" {
+			resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("expected the format source-bucket/path/to/source.zip,target-bucket[,prefix], got %q", req.ID))
+			return
+		}
+		sourceBucket, sourceKey, err := parseSource(parts[0])
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid import ID", err.Error())
+			return
+		}
+		identity.TargetBucket = types.StringValue(parts[1])
+		identity.TargetPrefix = types.StringNull()
+		if len(parts) == 3 && parts[2] != "" {
+			identity.TargetPrefix = types.StringValue(parts[2])
+		}
+
+		// The version of the source is read from the version file when the deployment is read.
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("source"), DeploymentSourceModel{
+			Bucket:  types.StringValue(sourceBucket),
+			Key:     types.StringValue(sourceKey),
+			Version: types.StringNull(),
+		})...)
+	}
+	if resp.Identity != nil {
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, identity)...)
+	}
+
+	// The defaults are set as well, so that the first plan after the import has no changes if they are not configured.
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("target"), DeploymentTargetModel{
+		Bucket:  identity.TargetBucket,
+		Prefix:  identity.TargetPrefix,
+		Region:  types.StringValue(r.deployer.Defaults.TargetRegion),
+		RoleArn: types.StringNull(),
+	})...)
+	resp.Diagnostics.Append(setAttributeDefaults(ctx, &resp.State)...)
+}
+
+// deploymentSchemaV0 is the schema of deployments before source and target were moved into blocks.
+var deploymentSchemaV0 = schema.Schema{
+	Attributes: map[string]schema.Attribute{
+		"source": schema.StringAttribute{
+			Required: true,
+		},
+		"source_version": schema.StringAttribute{
+			Required: true,
+		},
+		"target": schema.StringAttribute{
+			Required: true,
+		},
+		"target_region": schema.StringAttribute{
+			Optional: true,
+			Computed: true,
+		},
+	},
+}
+
+// deploymentResourceModelV0 describes the state of deployments before source and target were moved into blocks.
+type deploymentResourceModelV0 struct {
+	Source        types.String `tfsdk:"source"`
+	SourceVersion types.String `tfsdk:"source_version"`
+	Target        types.String `tfsdk:"target"`
+	TargetRegion  types.String `tfsdk:"target_region"`
+}
+
+// UpgradeState upgrades the state of deployments created before source and target were moved into blocks.
+func (r *DeploymentResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {PriorSchema: &deploymentSchemaV0, StateUpgrader: r.upgradeDeploymentStateV0},
+	}
+}
+
+// upgradeDeploymentStateV0 moves the source and source_version attributes of a version 0 state into the source
+// block, and the target and target_region attributes into the target block. The attributes added since then get
+// their defaults, so that the first plan after the upgrade has no changes if they are not configured, and the ID the
+// deployment would be imported with. The content of the target is adopted as the deployed files, unless the provider
+// plans offline, in which case it is adopted when the deployment is next refreshed.
+func (r *DeploymentResource) upgradeDeploymentStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior deploymentResourceModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	sourceBucket, sourceKey, err := parseSource(prior.Source.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Unable to upgrade state", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(setAttributeDefaults(ctx, &resp.State)...)
+	var data DeploymentResourceModel
+	resp.Diagnostics.Append(resp.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Source = &DeploymentSourceModel{
+		Bucket:  types.StringValue(sourceBucket),
+		Key:     types.StringValue(sourceKey),
+		Version: prior.SourceVersion,
+	}
+	data.Target = &DeploymentTargetModel{
+		Bucket:  prior.Target,
+		Prefix:  types.StringNull(),
+		Region:  prior.TargetRegion,
+		RoleArn: types.StringNull(),
+	}
+	id, _ := data.importID()
+	data.ID = types.StringValue(id)
+
+	if r.deployer != nil && !r.deployer.PlanOffline {
+		deployment, diags := r.newDeployment(ctx, &data, sourceBucket)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		deployment.ReadOnly()
+		resp.Diagnostics.Append(adoptDeployedFiles(ctx, &data, deployment)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// setAttributeDefaults sets every attribute of the state that has a default to it, for states written without the
+// attributes, such as those of imported or upgraded deployments. Defaults of attribute types the deployment schema
+// does not use fail, so that adding one without handling it here does not leave it unset.
+func setAttributeDefaults(ctx context.Context, state *tfsdk.State) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for name, attribute := range state.Schema.GetAttributes() {
+		attributePath := path.Root(name)
+		var value attr.Value
+		switch attribute := attribute.(type) {
+		case schema.BoolAttribute:
+			if attribute.Default == nil {
+				continue
+			}
+			defaultResp := &defaults.BoolResponse{}
+			attribute.Default.DefaultBool(ctx, defaults.BoolRequest{Path: attributePath}, defaultResp)
+			diags.Append(defaultResp.Diagnostics...)
+			value = defaultResp.PlanValue
+		case schema.Int64Attribute:
+			if attribute.Default == nil {
+				continue
+			}
+			defaultResp := &defaults.Int64Response{}
+			attribute.Default.DefaultInt64(ctx, defaults.Int64Request{Path: attributePath}, defaultResp)
+			diags.Append(defaultResp.Diagnostics...)
+			value = defaultResp.PlanValue
+		case schema.StringAttribute:
+			if attribute.Default == nil {
+				continue
+			}
+			defaultResp := &defaults.StringResponse{}
+			attribute.Default.DefaultString(ctx, defaults.StringRequest{Path: attributePath}, defaultResp)
+			diags.Append(defaultResp.Diagnostics...)
+			value = defaultResp.PlanValue
+		case schema.ListAttribute:
+			if attribute.Default == nil {
+				continue
+			}
+			defaultResp := &defaults.ListResponse{}
+			attribute.Default.DefaultList(ctx, defaults.ListRequest{Path: attributePath}, defaultResp)
+			diags.Append(defaultResp.Diagnostics...)
+			value = defaultResp.PlanValue
+		case schema.MapAttribute:
+			if attribute.Default == nil {
+				continue
+			}
+			defaultResp := &defaults.MapResponse{}
+			attribute.Default.DefaultMap(ctx, defaults.MapRequest{Path: attributePath}, defaultResp)
+			diags.Append(defaultResp.Diagnostics...)
+			value = defaultResp.PlanValue
+		default:
+			if hasDefault(attribute) {
+				diags.AddAttributeError(attributePath, "Unable to set default", fmt.Sprintf("Defaults of %T attributes are not supported. Please report this issue to the provider developers.", attribute))
+			}
+			continue
+		}
+		diags.Append(state.SetAttribute(ctx, attributePath, value)...)
+	}
+	return diags
+}
+
+// hasDefault reports whether an attribute of a type setAttributeDefaults does not handle has a default.
+func hasDefault(attribute schema.Attribute) bool {
+	switch attribute := attribute.(type) {
+	case interface{ Float32DefaultValue() defaults.Float32 }:
+		return attribute.Float32DefaultValue() != nil
+	case interface{ Float64DefaultValue() defaults.Float64 }:
+		return attribute.Float64DefaultValue() != nil
+	case interface{ Int32DefaultValue() defaults.Int32 }:
+		return attribute.Int32DefaultValue() != nil
+	case interface{ NumberDefaultValue() defaults.Number }:
+		return attribute.NumberDefaultValue() != nil
+	case interface{ ListDefaultValue() defaults.List }:
+		return attribute.ListDefaultValue() != nil
+	case interface{ MapDefaultValue() defaults.Map }:
+		return attribute.MapDefaultValue() != nil
+	case interface{ SetDefaultValue() defaults.Set }:
+		return attribute.SetDefaultValue() != nil
+	case interface{ ObjectDefaultValue() defaults.Object }:
+		return attribute.ObjectDefaultValue() != nil
+	case interface{ DynamicDefaultValue() defaults.Dynamic }:
+		return attribute.DynamicDefaultValue() != nil
+	}
+	return false
 }
@@ -8,17 +8,41 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/float64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	fwtypes "github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 func createS3Bucket(s3Client *s3.Client, bucketName string, targetBucketRegion string) error {
@@ -50,7 +74,7 @@ func deleteS3Bucket(s3Client *s3.Client, bucketName string) error {
 			}
 		}
 
-		if !output.IsTruncated {
+		if !aws.ToBool(output.IsTruncated) {
 			break
 		}
 
@@ -122,9 +146,14 @@ func uploadZIPToS3(s3Client *s3.Client, bucketName, zipPath, zipKey string) erro
 func testAccStaticFileDeployDeploymentConfig(sourceBucketName, zipKey, targetBucketName string) string {
 	return fmt.Sprintf(`
 resource "staticfiledeploy_deployment" "test_deployment" {
-    source        = "%s/%s"
-    source_version = "some-version"
-    target        = "%s"
+    source {
+        bucket  = "%s"
+        key     = "%s"
+        version = "some-version"
+    }
+    target {
+        bucket = "%s"
+    }
 }
 `, sourceBucketName, zipKey, targetBucketName)
 }
@@ -140,9 +169,9 @@ func testAccCheckStaticFileDeployDeploymentExists(s3Client *s3.Client, expectedF
 		}
 
 		// Extract the target bucket name from the state
-		target, ok := rs.Primary.Attributes["target"]
+		target, ok := rs.Primary.Attributes["target.bucket"]
 		if !ok {
-			return fmt.Errorf("target attribute not found in resource state")
+			return fmt.Errorf("target bucket not found in resource state")
 		}
 
 		// List objects in the target bucket
@@ -183,7 +212,7 @@ func TestAccStaticFileDeployDeployment_basic(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	s3Client := s3.NewFromConfig(cfg)
+	s3Client := newTestS3Client(cfg)
 
 	sourceBucketName := fmt.Sprintf("tf-test-bucket-source-%s", acctest.RandString(8))
 	targetBucketName := fmt.Sprintf("tf-test-bucket-target-%s", acctest.RandString(8))
@@ -250,7 +279,7 @@ func TestAccStaticFileDeployDeployment_canChangeArtifact(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	s3Client := s3.NewFromConfig(cfg)
+	s3Client := newTestS3Client(cfg)
 
 	sourceBucketName := fmt.Sprintf("tf-test-bucket-source-%s", acctest.RandString(8))
 	targetBucketName := fmt.Sprintf("tf-test-bucket-target-%s", acctest.RandString(8))
@@ -348,10 +377,15 @@ func TestAccStaticFileDeployDeployment_canChangeArtifact(t *testing.T) {
 func testAccStaticFileDeployDeploymentConfig_withTargetRegion(sourceBucketName, zipKey, targetBucketName string, targetRegion string) string {
 	return fmt.Sprintf(`
 resource "staticfiledeploy_deployment" "test_deployment" {
-    source        = "%s/%s"
-    source_version = "some-version"
-    target        = "%s"
-	target_region = "%s"
+    source {
+        bucket  = "%s"
+        key     = "%s"
+        version = "some-version"
+    }
+    target {
+        bucket = "%s"
+        region = "%s"
+    }
 }
 `, sourceBucketName, zipKey, targetBucketName, targetRegion)
 }
@@ -361,8 +395,8 @@ func TestAccStaticFileDeployDeployment_withTargetRegion(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error: %v", err)
 	}
-	sourceS3Client := s3.NewFromConfig(cfg)
-	targetS3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+	sourceS3Client := newTestS3Client(cfg)
+	targetS3Client := newTestS3Client(cfg, func(o *s3.Options) {
 		o.Region = "eu-central-1"
 	})
 
@@ -426,3 +460,700 @@ func TestAccStaticFileDeployDeployment_withTargetRegion(t *testing.T) {
 		},
 	})
 }
+
+func testAccStaticFileDeployDeploymentConfig_withDeletionProtection(sourceBucketName, zipKey, targetBucketName string, deletionProtection bool) string {
+	return fmt.Sprintf(`
+resource "staticfiledeploy_deployment" "test_deployment" {
+    source {
+        bucket  = "%s"
+        key     = "%s"
+        version = "some-version"
+    }
+    target {
+        bucket = "%s"
+    }
+    deletion_protection = %t
+}
+`, sourceBucketName, zipKey, targetBucketName, deletionProtection)
+}
+
+func TestAccStaticFileDeployDeployment_deletionProtection(t *testing.T) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	s3Client := newTestS3Client(cfg)
+
+	sourceBucketName := fmt.Sprintf("tf-test-bucket-source-%s", acctest.RandString(8))
+	targetBucketName := fmt.Sprintf("tf-test-bucket-target-%s", acctest.RandString(8))
+
+	err = createS3Bucket(s3Client, sourceBucketName, "eu-west-1")
+	if err != nil {
+		t.Fatalf("Failed to create S3 bucket: %s", err)
+	}
+	defer func(s3Client *s3.Client, bucketName string) {
+		_ = deleteS3Bucket(s3Client, bucketName)
+	}(s3Client, sourceBucketName) // Ensure cleanup after the test
+
+	err = createS3Bucket(s3Client, targetBucketName, "eu-west-1")
+	if err != nil {
+		t.Fatalf("Failed to create S3 bucket: %s", err)
+	}
+	defer func(s3Client *s3.Client, bucketName string) {
+		_ = deleteS3Bucket(s3Client, bucketName)
+	}(s3Client, targetBucketName) // Ensure cleanup after the test
+
+	zipPath := "test_protected.zip"
+	zipKey := "test_protected.zip"
+
+	_, err = createTestZIP(zipPath, map[string]string{"index.html": "Protected content"})
+	if err != nil {
+		t.Fatalf("Failed to create ZIP file: %s", err)
+	}
+	defer os.Remove(zipPath)
+
+	err = uploadZIPToS3(s3Client, sourceBucketName, zipPath, zipKey)
+	if err != nil {
+		t.Fatalf("Failed to upload ZIP file to S3: %s", err)
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Steps: []resource.TestStep{
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployDeploymentConfig_withDeletionProtection(sourceBucketName, zipKey, targetBucketName, true),
+			},
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployDeploymentConfig_withDeletionProtection(sourceBucketName, zipKey, targetBucketName, true),
+				Destroy:                  true,
+				ExpectError:              regexp.MustCompile("Deployment is protected from deletion"),
+			},
+			// Disabling the protection lets the test destroy the deployment.
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployDeploymentConfig_withDeletionProtection(sourceBucketName, zipKey, targetBucketName, false),
+			},
+		},
+	})
+}
+
+func TestFormatPlannedChanges(t *testing.T) {
+	changes := &deployer.PlannedChanges{
+		Added:   []string{"new.html"},
+		Changed: []string{"index.html"},
+		Deleted: []string{"old.html", "older.html"},
+	}
+
+	expected := "Deploying would add 1, change 1 and delete 2 file(s) in the target:\n  + new.html\n  ~ index.html\n  ... and 2 more"
+	if got := formatPlannedChanges(changes, 2); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	changes.ReportedAt = time.Date(2024, 1, 31, 1, 0, 0, 0, time.UTC)
+	expected = "Deploying would add 1, change 1 and delete 2 file(s) in the target, compared with the S3 Inventory report of 2024-01-31T01:00:00Z:\n  + new.html\n  ~ index.html\n  ... and 2 more"
+	if got := formatPlannedChanges(changes, 2); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestDeploymentResourceModel_planMaxFiles(t *testing.T) {
+	unset := &DeploymentResourceModel{PlanMaxFiles: basetypes.NewInt64Null()}
+	if got := unset.planMaxFiles(); got != 0 {
+		t.Errorf("expected the files not to be compared by default, got %d", got)
+	}
+
+	configured := &DeploymentResourceModel{PlanMaxFiles: basetypes.NewInt64Value(5)}
+	if got := configured.planMaxFiles(); got != 5 {
+		t.Errorf("expected 5 files to be listed, got %d", got)
+	}
+}
+
+func TestDeploymentResourceModel_resolvedVersion(t *testing.T) {
+	latest := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("latest")}, ResolvedSourceVersion: basetypes.NewStringValue("v1")}
+	if got := latest.resolvedVersion(); got == nil || *got != "v1" {
+		t.Errorf("expected the resolved version to be deployed, got %v", got)
+	}
+	if got := latest.deployedSourceVersion(); got != "v1" {
+		t.Errorf("expected the resolved version to be published, got %q", got)
+	}
+
+	unresolved := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("latest")}, ResolvedSourceVersion: basetypes.NewStringNull()}
+	if got := unresolved.resolvedVersion(); got != nil {
+		t.Errorf("expected the latest version to be deployed, got %q", *got)
+	}
+	if got := unresolved.deployedSourceVersion(); got != "latest" {
+		t.Errorf("expected latest to be published, got %q", got)
+	}
+
+	pinned := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("v2")}, ResolvedSourceVersion: basetypes.NewStringValue("v2")}
+	if got := pinned.resolvedVersion(); got != nil {
+		t.Errorf("expected other source versions to only trigger deployments, got %q", *got)
+	}
+
+	tagged := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("tag:environment=staging")}, ResolvedSourceVersion: basetypes.NewStringValue("v3")}
+	if got := tagged.resolvedVersion(); got == nil || *got != "v3" {
+		t.Errorf("expected the tagged version to be deployed, got %v", got)
+	}
+	if key, value, ok := tagged.sourceVersionTag(); !ok || key != "environment" || value != "staging" {
+		t.Errorf("unexpected tag %q=%q", key, value)
+	}
+}
+
+func TestDeploymentResourceModel_sourceLocation(t *testing.T) {
+	// Unlike a combined location, a separate bucket and key need no parsing, so the key can contain any character.
+	data := &DeploymentResourceModel{
+		Source: &DeploymentSourceModel{Bucket: basetypes.NewStringValue("artifacts"), Key: basetypes.NewStringValue("builds/site,v1.zip")},
+		Target: &DeploymentTargetModel{Bucket: basetypes.NewStringValue("www"), Prefix: basetypes.NewStringValue("site/")},
+	}
+	bucket, key, err := data.sourceLocation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket != "artifacts" || key != "builds/site,v1.zip" {
+		t.Errorf("expected the configured bucket and key, got %q and %q", bucket, key)
+	}
+	if id, _ := data.importID(); id != "artifacts/builds/site,v1.zip,www,site/" {
+		t.Errorf("unexpected ID: %s", id)
+	}
+
+	if _, _, err := (&DeploymentResourceModel{}).sourceLocation(); err == nil {
+		t.Error("expected an error for a deployment without a source")
+	}
+
+	var schemaResp fwresource.SchemaResponse
+	(&DeploymentResource{}).Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	bucketAttribute := schemaResp.Schema.Blocks["source"].(schema.SingleNestedBlock).Attributes["bucket"].(schema.StringAttribute)
+	for bucket, valid := range map[string]bool{"artifacts": true, "artifacts/builds": false, "s3://artifacts": false} {
+		resp := &validator.StringResponse{}
+		for _, v := range bucketAttribute.Validators {
+			v.ValidateString(context.Background(), validator.StringRequest{Path: path.Root("source").AtName("bucket"), ConfigValue: basetypes.NewStringValue(bucket)}, resp)
+		}
+		if resp.Diagnostics.HasError() == valid {
+			t.Errorf("expected the bucket %q to be valid: %t, got %v", bucket, valid, resp.Diagnostics)
+		}
+	}
+}
+
+func TestDeploymentResourceModel_codePipelineSource(t *testing.T) {
+	data := &DeploymentResourceModel{
+		Target: &DeploymentTargetModel{Bucket: basetypes.NewStringValue("www"), Prefix: basetypes.NewStringNull()},
+		CodePipelineSource: &DeploymentCodePipelineSourceModel{
+			PipelineName: basetypes.NewStringValue("site"),
+			ExecutionID:  basetypes.NewStringValue("0b1c2d3e"),
+			ArtifactName: basetypes.NewStringValue("BuildOutput"),
+		},
+	}
+	bucket, key, err := data.sourceLocation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket != "" || key != "codepipeline://site/0b1c2d3e/BuildOutput" {
+		t.Errorf("expected the artifact to be deployed from its CodePipeline location, got %q and %q", bucket, key)
+	}
+	if id, _ := data.importID(); id != "codepipeline://site/0b1c2d3e/BuildOutput,www" {
+		t.Errorf("unexpected ID: %s", id)
+	}
+}
+
+func TestDeploymentResourceModel_contentSettings(t *testing.T) {
+	newModel := func() *DeploymentResourceModel {
+		return &DeploymentResourceModel{
+			Source: &DeploymentSourceModel{
+				Bucket:  basetypes.NewStringValue("artifacts"),
+				Key:     basetypes.NewStringValue("site.zip"),
+				Version: basetypes.NewStringValue("latest"),
+			},
+			ResolvedSourceVersion: basetypes.NewStringValue("v1"),
+			SourceRoot:            basetypes.NewStringValue("dist/"),
+			Templates:             []DeploymentTemplateModel{{Pattern: basetypes.NewStringValue("config.json")}},
+			MetricsNamespace:      basetypes.NewStringNull(),
+		}
+	}
+	previous := newModel()
+
+	otherSettings := newModel()
+	otherSettings.MetricsNamespace = basetypes.NewStringValue("Deployments")
+	if !reflect.DeepEqual(otherSettings.contentSettings(), previous.contentSettings()) {
+		t.Error("expected settings that do not change the files to be disregarded")
+	}
+
+	otherVersion := newModel()
+	otherVersion.ResolvedSourceVersion = basetypes.NewStringValue("v2")
+	otherTemplate := newModel()
+	otherTemplate.Templates[0].Pattern = basetypes.NewStringValue("*.json")
+	for _, data := range []*DeploymentResourceModel{otherVersion, otherTemplate} {
+		if reflect.DeepEqual(data.contentSettings(), previous.contentSettings()) {
+			t.Errorf("expected the content settings to differ from %v", previous.contentSettings())
+		}
+	}
+}
+
+func TestPauseDeployment(t *testing.T) {
+	files := basetypes.NewMapValueMust(basetypes.StringType{}, map[string]attr.Value{"index.html": basetypes.NewStringValue("abc")})
+	state := &DeploymentResourceModel{
+		Source:                &DeploymentSourceModel{Version: basetypes.NewStringValue("latest")},
+		ResolvedSourceVersion: basetypes.NewStringValue("v1"),
+		DeployedFiles:         files,
+		Fingerprint:           basetypes.NewStringValue("fingerprint"),
+		FilesAdded:            basetypes.NewInt64Value(1),
+	}
+
+	data := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("latest")}}
+	pauseDeployment(data, state)
+	if !data.DeployedFiles.Equal(files) || data.Fingerprint.ValueString() != "fingerprint" || data.FilesAdded.ValueInt64() != 1 {
+		t.Errorf("expected the last deployment to be kept, got %v", data)
+	}
+	if data.ResolvedSourceVersion.ValueString() != "v1" {
+		t.Errorf("expected the resolved version to be kept, got %v", data.ResolvedSourceVersion)
+	}
+
+	changed := &DeploymentResourceModel{Source: &DeploymentSourceModel{Version: basetypes.NewStringValue("v2")}}
+	pauseDeployment(changed, state)
+	if !changed.ResolvedSourceVersion.IsNull() {
+		t.Errorf("expected the version resolved for another source version to be dropped, got %v", changed.ResolvedSourceVersion)
+	}
+
+	created := &DeploymentResourceModel{}
+	pauseDeployment(created, nil)
+	if created.DeployedFiles.IsNull() || len(created.DeployedFiles.Elements()) != 0 {
+		t.Errorf("expected no deployed files, got %v", created.DeployedFiles)
+	}
+	if created.ChangedFiles.IsNull() || len(created.ChangedFiles.Elements()) != 0 {
+		t.Errorf("expected no changed files, got %v", created.ChangedFiles)
+	}
+}
+
+// readStateSnapshot returns the schema version and attributes of the deployment in the given state file in testdata.
+func readStateSnapshot(t *testing.T, name string) (int64, []byte) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state struct {
+		Resources []struct {
+			Type      string `json:"type"`
+			Instances []struct {
+				SchemaVersion int64           `json:"schema_version"`
+				Attributes    json.RawMessage `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range state.Resources {
+		if resource.Type == "staticfiledeploy_deployment" && len(resource.Instances) == 1 {
+			return resource.Instances[0].SchemaVersion, resource.Instances[0].Attributes
+		}
+	}
+	t.Fatalf("no deployment in %s", name)
+	return 0, nil
+}
+
+func TestUpgradeDeploymentStateV0(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	files := map[string]string{"index.html": "<h1>Petstore</h1>", "app.js": "console.log('petstore')"}
+	for key, content := range files {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("123456789012-my-cool-bucket"), Key: aws.String(key), Body: strings.NewReader(content)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &DeploymentResource{deployer: &deployer.Deployer{S3Client: func(region string) deployer.S3API { return client }}}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	if schemaResp.Schema.Version != 2 {
+		t.Fatalf("expected schema version 2, got %d", schemaResp.Schema.Version)
+	}
+	schemaType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	// The state of a deployment created by the first release of the provider.
+	version, attributes := readStateSnapshot(t, "deployment_state_v0.tfstate")
+	upgraders := r.UpgradeState(ctx)
+	upgrader, ok := upgraders[version]
+	if !ok || len(upgraders) != 1 {
+		t.Fatalf("expected only an upgrader of version %d, got %v", version, upgraders)
+	}
+	upgrade := func(state []byte) (*tfsdk.State, diag.Diagnostics) {
+		t.Helper()
+		raw, err := (&tfprotov6.RawState{JSON: state}).Unmarshal(upgrader.PriorSchema.Type().TerraformType(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &fwresource.UpgradeStateResponse{State: tfsdk.State{Raw: tftypes.NewValue(schemaType, nil), Schema: schemaResp.Schema}}
+		upgrader.StateUpgrader(ctx, fwresource.UpgradeStateRequest{State: &tfsdk.State{Raw: raw, Schema: *upgrader.PriorSchema}}, resp)
+		return &resp.State, resp.Diagnostics
+	}
+
+	// expectedState returns the upgraded state of the snapshot with the given deployed files. Every attribute not set
+	// by the upgrade is null.
+	expectedState := func(deployedFiles tftypes.Value) tftypes.Value {
+		values := make(map[string]tftypes.Value, len(schemaType.AttributeTypes))
+		for name, attributeType := range schemaType.AttributeTypes {
+			values[name] = tftypes.NewValue(attributeType, nil)
+		}
+		for _, name := range []string{"preflight_write_probe", "verify_after_deploy", "detect_drift", "conditional_writes", "delete_removed_files", "resume_failed_deployments", "rollback_on_failure", "staged_promotion", "tag_objects", "purge_on_destroy", "paused", "deletion_protection", "write_version_file", "legal_hold"} {
+			values[name] = tftypes.NewValue(tftypes.Bool, false)
+		}
+		values["preflight_checks"] = tftypes.NewValue(tftypes.Bool, true)
+		values["target_type"] = tftypes.NewValue(tftypes.String, targetTypeS3)
+		values["version_file_key"] = tftypes.NewValue(tftypes.String, deployer.DefaultVersionFileKey)
+		values["id"] = tftypes.NewValue(tftypes.String, "123456789012-artifacts/petstore/1.2.3.zip,123456789012-my-cool-bucket")
+		values["source"] = tftypes.NewValue(schemaType.AttributeTypes["source"], map[string]tftypes.Value{
+			"bucket":  tftypes.NewValue(tftypes.String, "123456789012-artifacts"),
+			"key":     tftypes.NewValue(tftypes.String, "petstore/1.2.3.zip"),
+			"version": tftypes.NewValue(tftypes.String, "latest"),
+		})
+		values["target"] = tftypes.NewValue(schemaType.AttributeTypes["target"], map[string]tftypes.Value{
+			"bucket":   tftypes.NewValue(tftypes.String, "123456789012-my-cool-bucket"),
+			"prefix":   tftypes.NewValue(tftypes.String, nil),
+			"region":   tftypes.NewValue(tftypes.String, "eu-north-1"),
+			"role_arn": tftypes.NewValue(tftypes.String, nil),
+		})
+		values["deployed_files"] = deployedFiles
+		return tftypes.NewValue(schemaType, values)
+	}
+	checkState := func(state *tfsdk.State, expected tftypes.Value) {
+		t.Helper()
+		if !state.Raw.Equal(expected) {
+			diffs, err := state.Raw.Diff(expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, diff := range diffs {
+				t.Errorf("unexpected %s: got %v, expected %v", diff.Path, diff.Value1, diff.Value2)
+			}
+		}
+	}
+
+	state, diags := upgrade(attributes)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	hashes := make(map[string]tftypes.Value, len(files))
+	for key, content := range files {
+		sum := md5.Sum([]byte(content))
+		hashes[key] = tftypes.NewValue(tftypes.String, hex.EncodeToString(sum[:]))
+	}
+	checkState(state, expectedState(tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, hashes)))
+
+	// Offline, the content of the target is adopted when the deployment is next refreshed.
+	r.deployer.PlanOffline = true
+	state, diags = upgrade(attributes)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	checkState(state, expectedState(tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil)))
+
+	if _, diags := upgrade([]byte(`{"source": "artifacts", "source_version": "latest", "target": "www", "target_region": "eu-west-1"}`)); !diags.HasError() {
+		t.Error("expected an invalid source to fail the upgrade")
+	}
+}
+
+func TestSetAttributeDefaults(t *testing.T) {
+	ctx := context.Background()
+	stateWith := func(attributes map[string]schema.Attribute) *tfsdk.State {
+		s := schema.Schema{Attributes: attributes}
+		return &tfsdk.State{Raw: tftypes.NewValue(s.Type().TerraformType(ctx), nil), Schema: s}
+	}
+
+	state := stateWith(map[string]schema.Attribute{
+		"enabled": schema.BoolAttribute{Optional: true, Computed: true, Default: booldefault.StaticBool(true)},
+		"count":   schema.Int64Attribute{Optional: true, Computed: true, Default: int64default.StaticInt64(3)},
+		"name":    schema.StringAttribute{Optional: true, Computed: true, Default: stringdefault.StaticString("site")},
+		"keep":    schema.ListAttribute{ElementType: fwtypes.StringType, Optional: true, Computed: true, Default: listdefault.StaticValue(fwtypes.ListValueMust(fwtypes.StringType, []attr.Value{fwtypes.StringValue("robots.txt")}))},
+		"tags":    schema.MapAttribute{ElementType: fwtypes.StringType, Optional: true, Computed: true, Default: mapdefault.StaticValue(fwtypes.MapValueMust(fwtypes.StringType, map[string]attr.Value{"team": fwtypes.StringValue("web")}))},
+		"unset":   schema.Int64Attribute{Optional: true},
+	})
+	if diags := setAttributeDefaults(ctx, state); diags.HasError() {
+		t.Fatal(diags)
+	}
+	var data struct {
+		Enabled fwtypes.Bool   `tfsdk:"enabled"`
+		Count   fwtypes.Int64  `tfsdk:"count"`
+		Name    fwtypes.String `tfsdk:"name"`
+		Keep    []string       `tfsdk:"keep"`
+		Tags    fwtypes.Map    `tfsdk:"tags"`
+		Unset   fwtypes.Int64  `tfsdk:"unset"`
+	}
+	if diags := state.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !data.Enabled.ValueBool() || data.Count.ValueInt64() != 3 || data.Name.ValueString() != "site" || !reflect.DeepEqual(data.Keep, []string{"robots.txt"}) || len(data.Tags.Elements()) != 1 || !data.Unset.IsNull() {
+		t.Errorf("unexpected state %+v", data)
+	}
+
+	state = stateWith(map[string]schema.Attribute{
+		"ratio": schema.Float64Attribute{Optional: true, Computed: true, Default: float64default.StaticFloat64(0.5)},
+	})
+	if diags := setAttributeDefaults(ctx, state); !diags.HasError() {
+		t.Error("expected the default of an unsupported attribute type to fail")
+	}
+}
+
+// importDeployment imports a deployment with the given ID, or by the given identity if the ID is empty.
+func importDeployment(t *testing.T, r *DeploymentResource, id string, identity map[string]tftypes.Value) *fwresource.ImportStateResponse {
+	t.Helper()
+	ctx := context.Background()
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	var identitySchemaResp fwresource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, fwresource.IdentitySchemaRequest{}, &identitySchemaResp)
+	identityType := identitySchemaResp.IdentitySchema.Type().TerraformType(ctx)
+
+	req := fwresource.ImportStateRequest{ID: id}
+	resp := &fwresource.ImportStateResponse{
+		State:    tfsdk.State{Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil), Schema: schemaResp.Schema},
+		Identity: &tfsdk.ResourceIdentity{Raw: tftypes.NewValue(identityType, nil), Schema: identitySchemaResp.IdentitySchema},
+	}
+	if identity != nil {
+		req.Identity = &tfsdk.ResourceIdentity{Raw: tftypes.NewValue(identityType, identity), Schema: identitySchemaResp.IdentitySchema}
+	}
+	r.ImportState(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	return resp
+}
+
+func TestDeploymentResource_importState(t *testing.T) {
+	ctx := context.Background()
+	r := &DeploymentResource{deployer: &deployer.Deployer{Defaults: deployer.DeploymentDefaults{TargetRegion: "eu-west-1"}}}
+
+	importState := func(id string, identity map[string]tftypes.Value) (*DeploymentResourceModel, *DeploymentIdentityModel) {
+		t.Helper()
+		resp := importDeployment(t, r, id, identity)
+		var data DeploymentResourceModel
+		var importedIdentity DeploymentIdentityModel
+		if diags := resp.State.Get(ctx, &data); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if diags := resp.Identity.Get(ctx, &importedIdentity); diags.HasError() {
+			t.Fatal(diags)
+		}
+		return &data, &importedIdentity
+	}
+
+	data, identity := importState("artifacts/site.zip,www,site/", nil)
+	if data.ID.ValueString() != "artifacts/site.zip,www,site/" || data.Source.Bucket.ValueString() != "artifacts" || data.Source.Key.ValueString() != "site.zip" {
+		t.Errorf("unexpected ID %v and source %v", data.ID, data.Source)
+	}
+	if identity.TargetBucket.ValueString() != "www" || identity.TargetPrefix.ValueString() != "site/" {
+		t.Errorf("unexpected identity %v", identity)
+	}
+
+	data, identity = importState("", map[string]tftypes.Value{
+		"target_bucket": tftypes.NewValue(tftypes.String, "www"),
+		"target_prefix": tftypes.NewValue(tftypes.String, nil),
+	})
+	if !data.ID.IsNull() || data.Source != nil {
+		t.Errorf("expected no ID and source, got %v and %v", data.ID, data.Source)
+	}
+	if data.Target.Bucket.ValueString() != "www" || !data.Target.Prefix.IsNull() || data.Target.Region.ValueString() != "eu-west-1" {
+		t.Errorf("unexpected target %v", data.Target)
+	}
+	if identity.TargetBucket.ValueString() != "www" || !identity.TargetPrefix.IsNull() {
+		t.Errorf("unexpected identity %v", identity)
+	}
+}
+
+func TestDeploymentResource_deleteImportedByIdentity(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	for _, key := range []string{"site/index.html", "site/app.js", "other/index.html"} {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("www"), Key: aws.String(key), Body: strings.NewReader(key)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &DeploymentResource{deployer: &deployer.Deployer{
+		Defaults: deployer.DeploymentDefaults{TargetRegion: "eu-west-1"},
+		S3Client: func(region string) deployer.S3API { return client },
+	}}
+
+	imported := importDeployment(t, r, "", map[string]tftypes.Value{
+		"target_bucket": tftypes.NewValue(tftypes.String, "www"),
+		"target_prefix": tftypes.NewValue(tftypes.String, "site/"),
+	})
+	if diags := imported.State.SetAttribute(ctx, path.Root("purge_on_destroy"), true); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	readResp := &fwresource.ReadResponse{State: imported.State, Identity: imported.Identity}
+	r.Read(ctx, fwresource.ReadRequest{State: imported.State, Identity: imported.Identity}, readResp)
+	if readResp.Diagnostics.HasError() {
+		t.Fatal(readResp.Diagnostics)
+	}
+
+	deleteResp := &fwresource.DeleteResponse{State: readResp.State}
+	r.Delete(ctx, fwresource.DeleteRequest{State: readResp.State}, deleteResp)
+	if deleteResp.Diagnostics.HasError() {
+		t.Fatal(deleteResp.Diagnostics)
+	}
+	if keys := client.Keys("www"); !reflect.DeepEqual(keys, []string{"other/index.html"}) {
+		t.Errorf("expected only the files outside the target prefix to be kept, got %v", keys)
+	}
+}
+
+func TestDeploymentResource_configureDeploymentDefaults(t *testing.T) {
+	ctx := context.Background()
+	r := &DeploymentResource{deployer: &deployer.Deployer{Defaults: deployer.DeploymentDefaults{
+		DownloadConcurrency:  4,
+		MultipartConcurrency: 8,
+		Tags:                 map[string]string{"team": "web", "env": "prod"},
+		TargetRegion:         "eu-west-1",
+	}}}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	unset := map[string]tftypes.Value{}
+	for name, attributeType := range objectType.AttributeTypes {
+		unset[name] = tftypes.NewValue(attributeType, nil)
+	}
+	// newModel returns a model with every attribute unset, which the given function then configures.
+	newModel := func(set func(data *DeploymentResourceModel)) *DeploymentResourceModel {
+		var data DeploymentResourceModel
+		if diags := (tfsdk.State{Raw: tftypes.NewValue(objectType, unset), Schema: schemaResp.Schema}).Get(ctx, &data); diags.HasError() {
+			t.Fatal(diags)
+		}
+		set(&data)
+		return &data
+	}
+
+	configure := func(data *DeploymentResourceModel) *deployer.Deployment {
+		t.Helper()
+		data.Source = &DeploymentSourceModel{Bucket: basetypes.NewStringValue("artifacts"), Key: basetypes.NewStringValue("site.zip")}
+		data.Target = &DeploymentTargetModel{Bucket: basetypes.NewStringValue("www")}
+		deployment, diags := r.configureDeployment(ctx, data, "artifacts")
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return deployment
+	}
+
+	inherited := configure(newModel(func(data *DeploymentResourceModel) {}))
+	if inherited.DownloadConcurrency != 4 || inherited.MultipartUpload.Concurrency != 8 {
+		t.Errorf("expected the default concurrency, got %d and %d", inherited.DownloadConcurrency, inherited.MultipartUpload.Concurrency)
+	}
+	if !reflect.DeepEqual(inherited.Tags, map[string]string{"team": "web", "env": "prod"}) {
+		t.Errorf("expected the default tags, got %v", inherited.Tags)
+	}
+
+	overridden := configure(newModel(func(data *DeploymentResourceModel) {
+		data.DownloadConcurrency = basetypes.NewInt64Value(2)
+		data.MultipartConcurrency = basetypes.NewInt64Value(1)
+		data.Tags = basetypes.NewMapValueMust(basetypes.StringType{}, map[string]attr.Value{"env": basetypes.NewStringValue("staging")})
+	}))
+	if overridden.DownloadConcurrency != 2 || overridden.MultipartUpload.Concurrency != 1 {
+		t.Errorf("expected the concurrency of the deployment, got %d and %d", overridden.DownloadConcurrency, overridden.MultipartUpload.Concurrency)
+	}
+	if !reflect.DeepEqual(overridden.Tags, map[string]string{"team": "web", "env": "staging"}) {
+		t.Errorf("expected the tags of the deployment to replace the defaults with the same keys, got %v", overridden.Tags)
+	}
+	if r.deployer.Defaults.Tags["env"] != "prod" {
+		t.Error("expected the default tags to be left unchanged")
+	}
+}
+
+// validateDeploymentConfig validates a deployment configured with the given attributes, leaving the others unset.
+func validateDeploymentConfig(t *testing.T, attributes map[string]tftypes.Value) diag.Diagnostics {
+	t.Helper()
+	ctx := context.Background()
+	r := &DeploymentResource{}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := map[string]tftypes.Value{}
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, nil)
+	}
+	for name, value := range attributes {
+		values[name] = value
+	}
+	resp := &fwresource.ValidateConfigResponse{}
+	r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Raw: tftypes.NewValue(objectType, values), Schema: schemaResp.Schema}}, resp)
+	return resp.Diagnostics
+}
+
+func TestDeploymentResource_validateConfig(t *testing.T) {
+	var schemaResp fwresource.SchemaResponse
+	(&DeploymentResource{}).Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	rewritesType := schemaResp.Schema.Type().TerraformType(context.Background()).(tftypes.Object).AttributeTypes["path_rewrite"].(tftypes.List)
+	rewriteType := rewritesType.ElementType.(tftypes.Object)
+	pathRewrites := func(from interface{}) tftypes.Value {
+		rewrite := map[string]tftypes.Value{}
+		for name, attributeType := range rewriteType.AttributeTypes {
+			rewrite[name] = tftypes.NewValue(attributeType, nil)
+		}
+		rewrite["from"] = tftypes.NewValue(tftypes.String, from)
+		return tftypes.NewValue(rewritesType, []tftypes.Value{tftypes.NewValue(rewriteType, rewrite)})
+	}
+	str := func(value interface{}) tftypes.Value {
+		return tftypes.NewValue(tftypes.String, value)
+	}
+
+	for name, test := range map[string]struct {
+		attributes map[string]tftypes.Value
+		errorPath  path.Path
+		summary    string
+	}{
+		"valid": {attributes: map[string]tftypes.Value{
+			"source_checksum":          str("SHA256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"),
+			"object_lock_mode":         str("GOVERNANCE"),
+			"object_lock_retain_until": str("2030-01-01T00:00:00Z"),
+			"path_rewrite":             pathRewrites(`^build/(.*)$`),
+		}},
+		// Values that refer to resources created in the same apply are checked when the deployment runs.
+		"unknown": {attributes: map[string]tftypes.Value{
+			"source_checksum":  str(tftypes.UnknownValue),
+			"object_lock_mode": str(tftypes.UnknownValue),
+			"path_rewrite":     pathRewrites(tftypes.UnknownValue),
+		}},
+		"invalid checksum": {
+			attributes: map[string]tftypes.Value{"source_checksum": str("crc32:abcd")},
+			errorPath:  path.Root("source_checksum"),
+			summary:    "Invalid source checksum",
+		},
+		"invalid object lock mode": {
+			attributes: map[string]tftypes.Value{"object_lock_mode": str("governance"), "object_lock_retain_until": str("2030-01-01T00:00:00Z")},
+			errorPath:  path.Root("object_lock_mode"),
+			summary:    "Invalid Object Lock mode",
+		},
+		"object lock mode without retention": {
+			attributes: map[string]tftypes.Value{"object_lock_mode": str("GOVERNANCE")},
+			errorPath:  path.Root("object_lock_mode"),
+			summary:    "Incomplete Object Lock retention",
+		},
+		"invalid retention date": {
+			attributes: map[string]tftypes.Value{"object_lock_mode": str("GOVERNANCE"), "object_lock_retain_until": str("2030-01-01")},
+			errorPath:  path.Root("object_lock_retain_until"),
+			summary:    "Invalid Object Lock retention date",
+		},
+		"invalid path rewrite": {
+			attributes: map[string]tftypes.Value{"path_rewrite": pathRewrites(`^build/(.*$`)},
+			errorPath:  path.Root("path_rewrite").AtListIndex(0).AtName("from"),
+			summary:    "Invalid path rewrite",
+		},
+	} {
+		diags := validateDeploymentConfig(t, test.attributes)
+		if test.summary == "" {
+			if diags.HasError() {
+				t.Errorf("%s: unexpected errors %v", name, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Summary() != test.summary {
+			t.Errorf("%s: expected the error %q, got %v", name, test.summary, diags)
+		} else if withPath, ok := diags[0].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(test.errorPath) {
+			t.Errorf("%s: expected the error at %s, got %v", name, test.errorPath, diags[0])
+		}
+	}
+}
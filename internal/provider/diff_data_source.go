@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &DiffDataSource{}
+
+func NewDiffDataSource() datasource.DataSource {
+	return &DiffDataSource{}
+}
+
+// DiffDataSource defines the data source implementation.
+type DiffDataSource struct {
+	deployer *deployer.Deployer
+}
+
+// DiffDataSourceModel describes the data source data model.
+type DiffDataSourceModel struct {
+	ID            types.String `tfsdk:"id"`
+	Source        types.String `tfsdk:"source"`
+	SourceVersion types.String `tfsdk:"source_version"`
+	SourceRoot    types.String `tfsdk:"source_root"`
+	Target        types.String `tfsdk:"target"`
+	TargetPrefix  types.String `tfsdk:"target_prefix"`
+	TargetRegion  types.String `tfsdk:"target_region"`
+	KeepFiles     types.List   `tfsdk:"keep_files"`
+	Added         types.List   `tfsdk:"added"`
+	Changed       types.List   `tfsdk:"changed"`
+	Removed       types.List   `tfsdk:"removed"`
+}
+
+func (d *DiffDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_diff"
+}
+
+func (d *DiffDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Compares the files of a source ZIP file with the objects in a target bucket, without changing anything, e.g. to review the changes of a new artifact or to compute CloudFront invalidation paths outside of Terraform. Files are compared by content the same way as by `staticfiledeploy_deployment`.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The target bucket and prefix that are compared.",
+				Computed:            true,
+			},
+			"source": schema.StringAttribute{
+				MarkdownDescription: "The S3 bucket and path to the ZIP file to compare. Format: 'bucket-name/path/to/source.zip' or 's3://bucket-name/path/to/source.zip'.",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(sourceRegexp, "must be in the format bucket-name/path/to/source.zip or s3://bucket-name/path/to/source.zip"),
+				},
+			},
+			"source_version": schema.StringAttribute{
+				MarkdownDescription: "The version ID of the source ZIP file in a versioned bucket. Defaults to the latest version.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"source_root": schema.StringAttribute{
+				MarkdownDescription: "A directory in the source ZIP file to compare instead of the whole file, e.g. `dist/`, with the directory stripped from the names of its entries.",
+				Optional:            true,
+			},
+			"target": schema.StringAttribute{
+				MarkdownDescription: "The name or ARN of the S3 bucket to compare with.",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(targetRegexp, "must be a valid S3 bucket name or ARN"),
+				},
+			},
+			"target_prefix": schema.StringAttribute{
+				MarkdownDescription: "A prefix in the target the files are deployed under, e.g. `site/`. Only objects under the prefix are compared.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"target_region": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The region of the target S3 bucket. Defaults to the `default_target_region` of the provider, or `%s`.", defaultTargetRegion),
+				Optional:            true,
+				Computed:            true,
+			},
+			"keep_files": schema.ListAttribute{
+				MarkdownDescription: "Glob patterns of objects in the target that are never listed in `removed`, as with `keep_files` of `staticfiledeploy_deployment`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"added": schema.ListAttribute{
+				MarkdownDescription: "The keys of the files in the source ZIP file that are not in the target, sorted.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"changed": schema.ListAttribute{
+				MarkdownDescription: "The keys of the files whose content in the target differs from the source ZIP file, sorted.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+			"removed": schema.ListAttribute{
+				MarkdownDescription: "The keys of the objects in the target that are not in the source ZIP file, sorted. These are the objects a deployment with `delete_removed_files` would delete.",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *DiffDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*deployer.Deployer)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *deployer.Deployer, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.deployer = client
+}
+
+func (d *DiffDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data DiffDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	sourceBucket, sourceKey, err := parseSource(data.Source.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Invalid source", err.Error())
+		return
+	}
+	if data.TargetRegion.IsNull() {
+		data.TargetRegion = types.StringValue(d.deployer.Defaults.TargetRegion)
+	}
+
+	bucket := targetBucket(data.Target.ValueString())
+	deployment := d.deployer.NewDeployment(sourceBucket, bucket, data.TargetRegion.ValueString())
+	deployment.ReadOnly()
+	deployment.SourceRoot = data.SourceRoot.ValueString()
+	deployment.TargetPrefix = data.TargetPrefix.ValueString()
+	// Removed files are always listed, as if the deployment deleted them.
+	deployment.DeleteRemovedFiles = true
+	var keepFiles []string
+	resp.Diagnostics.Append(data.KeepFiles.ElementsAs(ctx, &keepFiles, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	deployment.KeepFiles = append(deployment.KeepFiles, keepFiles...)
+
+	var version *string
+	if !data.SourceVersion.IsNull() {
+		version = data.SourceVersion.ValueStringPointer()
+	}
+	changes, err := deployment.PlanChanges(ctx, sourceKey, version)
+	if err != nil {
+		resp.Diagnostics.AddError("Error comparing the source with the target", err.Error())
+		return
+	}
+
+	data.ID = types.StringValue(bucket + "/" + deployment.TargetPrefix)
+	resp.Diagnostics.Append(setDiffLists(ctx, &data, changes)...)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// setDiffLists sets the added, changed and removed files of the data source to the planned changes.
+func setDiffLists(ctx context.Context, data *DiffDataSourceModel, changes *deployer.PlannedChanges) diag.Diagnostics {
+	var diags, listDiags diag.Diagnostics
+	for _, list := range []struct {
+		value *types.List
+		keys  []string
+	}{{&data.Added, changes.Added}, {&data.Changed, changes.Changed}, {&data.Removed, changes.Deleted}} {
+		keys := list.keys
+		if keys == nil {
+			keys = []string{}
+		}
+		*list.value, listDiags = types.ListValueFrom(ctx, types.StringType, keys)
+		diags.Append(listDiags...)
+	}
+	return diags
+}
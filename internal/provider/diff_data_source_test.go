@@ -0,0 +1,101 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"os"
+	"strings"
+	"testing"
+)
+
+func testAccStaticFileDeployDiffConfig(sourceBucketName, zipKey, targetBucketName string) string {
+	return fmt.Sprintf(`
+data "staticfiledeploy_diff" "test" {
+    source = "%s/%s"
+    target = "%s"
+}
+`, sourceBucketName, zipKey, targetBucketName)
+}
+
+func TestAccStaticFileDeployDiffDataSource_basic(t *testing.T) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	s3Client := newTestS3Client(cfg)
+
+	sourceBucketName := fmt.Sprintf("tf-test-bucket-source-%s", acctest.RandString(8))
+	targetBucketName := fmt.Sprintf("tf-test-bucket-target-%s", acctest.RandString(8))
+
+	err = createS3Bucket(s3Client, sourceBucketName, "eu-west-1")
+	if err != nil {
+		t.Fatalf("Failed to create S3 bucket: %s", err)
+	}
+	defer func(s3Client *s3.Client, bucketName string) {
+		_ = deleteS3Bucket(s3Client, bucketName)
+	}(s3Client, sourceBucketName) // Ensure cleanup after the test
+
+	err = createS3Bucket(s3Client, targetBucketName, "eu-west-1")
+	if err != nil {
+		t.Fatalf("Failed to create S3 bucket: %s", err)
+	}
+	defer func(s3Client *s3.Client, bucketName string) {
+		_ = deleteS3Bucket(s3Client, bucketName)
+	}(s3Client, targetBucketName) // Ensure cleanup after the test
+
+	zipPath := "test_diff.zip"
+	zipKey := "test_diff.zip"
+
+	_, err = createTestZIP(zipPath, map[string]string{
+		"index.html": "New content",
+		"new.js":     "New file",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create ZIP file: %s", err)
+	}
+	defer os.Remove(zipPath)
+
+	err = uploadZIPToS3(s3Client, sourceBucketName, zipPath, zipKey)
+	if err != nil {
+		t.Fatalf("Failed to upload ZIP file to S3: %s", err)
+	}
+
+	for key, content := range map[string]string{"index.html": "Old content", "old.js": "Old file"} {
+		_, err = s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket: aws.String(targetBucketName),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(content),
+		})
+		if err != nil {
+			t.Fatalf("Failed to upload %s to S3: %s", key, err)
+		}
+	}
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Steps: []resource.TestStep{
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployDiffConfig(sourceBucketName, zipKey, targetBucketName),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "added.#", "1"),
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "added.0", "new.js"),
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "changed.#", "1"),
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "changed.0", "index.html"),
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "removed.#", "1"),
+					resource.TestCheckResourceAttr("data.staticfiledeploy_diff.test", "removed.0", "old.js"),
+				),
+			},
+		},
+	})
+}
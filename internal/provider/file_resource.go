@@ -0,0 +1,348 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"strings"
+	"time"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &FileResource{}
+var _ resource.ResourceWithImportState = &FileResource{}
+var _ resource.ResourceWithModifyPlan = &FileResource{}
+
+func NewFileResource() resource.Resource {
+	return &FileResource{}
+}
+
+// defaultFileTimeout is how long writing or deleting a file may take unless configured in the timeouts block.
+const defaultFileTimeout = 5 * time.Minute
+
+// FileResource defines the resource implementation.
+type FileResource struct {
+	deployer *deployer.Deployer
+}
+
+// FileResourceModel describes the resource data model.
+type FileResourceModel struct {
+	ID           types.String `tfsdk:"id"`
+	Target       types.String `tfsdk:"target"`
+	TargetRegion types.String `tfsdk:"target_region"`
+	Key          types.String `tfsdk:"key"`
+	Content      types.String `tfsdk:"content"`
+	Source       types.String `tfsdk:"source"`
+	ContentType  types.String `tfsdk:"content_type"`
+	CacheControl types.String `tfsdk:"cache_control"`
+	ETag         types.String `tfsdk:"etag"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+func (r *FileResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_file"
+}
+
+func (r *FileResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Deploys a single file to a target S3 bucket, e.g. `robots.txt` or a maintenance page, without packaging it in a ZIP file. Changes made to the file outside of Terraform are detected and reverted.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "The target bucket and key of the file. Format: 'bucket-name/key'.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"target": schema.StringAttribute{
+				MarkdownDescription: "The name or ARN of the target S3 bucket to deploy the file to.",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(targetRegexp, "must be a valid S3 bucket name or ARN"),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"target_region": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The region of the target S3 bucket. Defaults to the `default_target_region` of the provider, or `%s`.", defaultTargetRegion),
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"key": schema.StringAttribute{
+				MarkdownDescription: "The key to deploy the file to.",
+				Required:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"content": schema.StringAttribute{
+				MarkdownDescription: "The content of the file. Exactly one of `content` or `source` must be set.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(path.MatchRoot("source")),
+				},
+			},
+			"source": schema.StringAttribute{
+				MarkdownDescription: "An S3 object to copy the file from. Format: 'bucket-name/path/to/file' or 's3://bucket-name/path/to/file'.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(sourceRegexp, "must be in the format bucket-name/path/to/file or s3://bucket-name/path/to/file"),
+				},
+			},
+			"content_type": schema.StringAttribute{
+				MarkdownDescription: "The content type of the file. Defaults to the content type for the extension of `key`, including the provider's `mime_types`.",
+				Optional:            true,
+				Computed:            true,
+			},
+			"cache_control": schema.StringAttribute{
+				MarkdownDescription: "The `Cache-Control` header of the file.",
+				Optional:            true,
+			},
+			"etag": schema.StringAttribute{
+				MarkdownDescription: "The ETag of the deployed file.",
+				Computed:            true,
+			},
+		},
+
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+func (r *FileResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*deployer.Deployer)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *deployer.Deployer, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.deployer = client
+}
+
+// ModifyPlan fills in the default content type, so that a content type changed outside of Terraform is reverted
+// even when it is not configured.
+func (r *FileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// The deployer is not configured yet when the provider configuration is unknown.
+	if req.Plan.Raw.IsNull() || r.deployer == nil {
+		return
+	}
+
+	if planTargetRegion(ctx, r.deployer, path.Root("target_region"), req, resp) {
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("target_region"))
+	}
+
+	var contentType, key types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("content_type"), &contentType)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("key"), &key)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if contentType.IsNull() && isKnown(key) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content_type"), r.deployer.ContentTypeForKey(key.ValueString()))...)
+	}
+}
+
+// putFile deploys the file described by the model, and updates its computed attributes.
+func (r *FileResource) putFile(ctx context.Context, data *FileResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// The region is only unknown if the provider was not configured yet when the file was planned.
+	if data.TargetRegion.IsUnknown() {
+		data.TargetRegion = types.StringValue(r.deployer.Defaults.TargetRegion)
+	}
+	file := deployer.File{
+		Bucket:       targetBucket(data.Target.ValueString()),
+		Region:       data.TargetRegion.ValueString(),
+		Key:          data.Key.ValueString(),
+		ContentType:  data.ContentType.ValueString(),
+		CacheControl: data.CacheControl.ValueString(),
+	}
+	if data.ContentType.IsUnknown() {
+		file.ContentType = r.deployer.ContentTypeForKey(file.Key)
+	}
+	if !data.Content.IsNull() {
+		file.Content = data.Content.ValueStringPointer()
+	} else {
+		sourceBucket, sourceKey, err := parseSource(data.Source.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("source"), "Error deploying file", err.Error())
+			return diags
+		}
+		file.SourceBucket = sourceBucket
+		file.SourceKey = sourceKey
+	}
+
+	etag, err := r.deployer.PutFile(ctx, file)
+	if err != nil {
+		diags.AddError("Error deploying file", err.Error())
+		return diags
+	}
+
+	data.ID = types.StringValue(file.Bucket + "/" + file.Key)
+	data.ContentType = types.StringValue(file.ContentType)
+	data.ETag = types.StringValue(etag)
+
+	return diags
+}
+
+func (r *FileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data FileResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := data.Timeouts.Create(ctx, defaultFileTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp.Diagnostics.Append(r.putFile(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *FileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state FileResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Offline, the state is kept as it is.
+	if r.deployer.PlanOffline {
+		return
+	}
+
+	deployed, err := r.deployer.GetDeployedFile(ctx, state.TargetRegion.ValueString(), targetBucket(state.Target.ValueString()), state.Key.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading file", err.Error())
+		return
+	}
+	if deployed == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	// The content was changed outside of Terraform. Clearing the content or source in the state makes the next plan
+	// deploy the file again.
+	if deployed.ETag != state.ETag.ValueString() {
+		state.Content = types.StringNull()
+		state.Source = types.StringNull()
+	}
+
+	state.ETag = types.StringValue(deployed.ETag)
+	state.ContentType = types.StringValue(deployed.ContentType)
+	state.CacheControl = types.StringNull()
+	if deployed.CacheControl != "" {
+		state.CacheControl = types.StringValue(deployed.CacheControl)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *FileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data FileResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := data.Timeouts.Update(ctx, defaultFileTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp.Diagnostics.Append(r.putFile(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *FileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data FileResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, diags := data.Timeouts.Delete(ctx, defaultFileTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := r.deployer.DeleteFile(ctx, data.TargetRegion.ValueString(), targetBucket(data.Target.ValueString()), data.Key.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Error deleting file", err.Error())
+	}
+}
+
+func (r *FileResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	bucket, key, found := strings.Cut(req.ID, "/")
+	if !found || bucket == "" || key == "" {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("expected the format bucket-name/key, got %q", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("target"), bucket)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("key"), key)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("target_region"), r.deployer.Defaults.TargetRegion)...)
+}
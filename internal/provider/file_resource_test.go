@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"io"
+	"testing"
+)
+
+func testAccStaticFileDeployFileConfig(targetBucketName, content string) string {
+	return fmt.Sprintf(`
+resource "staticfiledeploy_file" "test_file" {
+    target        = "%s"
+    key           = "robots.txt"
+    content       = "%s"
+    cache_control = "public, max-age=3600"
+}
+`, targetBucketName, content)
+}
+
+const FileResourceName = "staticfiledeploy_file.test_file"
+
+func testAccCheckStaticFileDeployFileContent(s3Client *s3.Client, expectedContent string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[FileResourceName]
+		if !ok {
+			return fmt.Errorf("resource not found in Terraform state: %s", FileResourceName)
+		}
+
+		output, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+			Bucket: aws.String(rs.Primary.Attributes["target"]),
+			Key:    aws.String(rs.Primary.Attributes["key"]),
+		})
+		if err != nil {
+			return fmt.Errorf("error getting deployed file: %w", err)
+		}
+		defer output.Body.Close()
+
+		content, err := io.ReadAll(output.Body)
+		if err != nil {
+			return fmt.Errorf("error reading deployed file: %w", err)
+		}
+		if string(content) != expectedContent {
+			return fmt.Errorf("content mismatch: expected %q, got %q", expectedContent, content)
+		}
+
+		return nil
+	}
+}
+
+func TestAccStaticFileDeployFile_basic(t *testing.T) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	s3Client := newTestS3Client(cfg)
+
+	targetBucketName := fmt.Sprintf("tf-test-bucket-target-%s", acctest.RandString(8))
+
+	err = createS3Bucket(s3Client, targetBucketName, "eu-west-1")
+	if err != nil {
+		t.Fatalf("Failed to create S3 bucket: %s", err)
+	}
+	defer func(s3Client *s3.Client, bucketName string) {
+		_ = deleteS3Bucket(s3Client, bucketName)
+	}(s3Client, targetBucketName) // Ensure cleanup after the test
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+		},
+		Steps: []resource.TestStep{
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployFileConfig(targetBucketName, "User-agent: *"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStaticFileDeployFileContent(s3Client, "User-agent: *"),
+					resource.TestCheckResourceAttr(FileResourceName, "content_type", "text/plain; charset=utf-8"),
+					resource.TestCheckResourceAttr(FileResourceName, "target_region", defaultTargetRegion),
+				),
+			},
+			{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Config:                   testAccStaticFileDeployFileConfig(targetBucketName, "User-agent: *\\nDisallow: /"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckStaticFileDeployFileContent(s3Client, "User-agent: *\nDisallow: /"),
+				),
+			},
+		},
+	})
+}
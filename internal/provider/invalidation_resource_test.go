@@ -0,0 +1,217 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	fwtypes "github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// invalidationBatch is the body of a CloudFront CreateInvalidation request.
+type invalidationBatch struct {
+	CallerReference string   `xml:"CallerReference"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Paths           []string `xml:"Paths>Items>Path"`
+}
+
+// newCloudFrontServer returns a CloudFront API endpoint that records the invalidations created through it in batches.
+// If distributionID is not the ID of the distribution, it fails like CloudFront does for a missing distribution.
+func newCloudFrontServer(t *testing.T, distributionID string, batches *[]invalidationBatch) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/2020-05-31/distribution/"+distributionID+"/invalidation" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>NoSuchDistribution</Code><Message>The specified distribution does not exist.</Message></Error></ErrorResponse>`)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var batch invalidationBatch
+		if err := xml.Unmarshal(body, &batch); err != nil {
+			t.Errorf("unexpected invalidation batch %s: %v", body, err)
+		}
+		*batches = append(*batches, batch)
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `<Invalidation><Id>I%d</Id><Status>InProgress</Status></Invalidation>`, len(*batches))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newInvalidationResource returns an invalidation resource creating invalidations through the CloudFront API at the
+// given URL.
+func newInvalidationResource(url string) *InvalidationResource {
+	return &InvalidationResource{deployer: &deployer.Deployer{DefaultAWSConfig: aws.Config{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(url),
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		RetryMaxAttempts: 1,
+	}}}
+}
+
+// invalidationPlan returns the plan of an invalidation of the given paths in the distribution, with the given
+// triggers.
+func invalidationPlan(t *testing.T, distributionID string, paths []string, triggers map[string]string) tfsdk.Plan {
+	t.Helper()
+	ctx := context.Background()
+
+	schemaResp := &fwresource.SchemaResponse{}
+	NewInvalidationResource().Schema(ctx, fwresource.SchemaRequest{}, schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	var pathValues []tftypes.Value
+	for _, p := range paths {
+		pathValues = append(pathValues, tftypes.NewValue(tftypes.String, p))
+	}
+	var triggerValues map[string]tftypes.Value
+	if triggers != nil {
+		triggerValues = make(map[string]tftypes.Value, len(triggers))
+		for key, value := range triggers {
+			triggerValues[key] = tftypes.NewValue(tftypes.String, value)
+		}
+	}
+	raw := tftypes.NewValue(objectType, map[string]tftypes.Value{
+		"id":                  tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"distribution_id":     tftypes.NewValue(tftypes.String, distributionID),
+		"paths":               tftypes.NewValue(objectType.AttributeTypes["paths"], pathValues),
+		"triggers":            tftypes.NewValue(objectType.AttributeTypes["triggers"], triggerValues),
+		"wait_for_completion": tftypes.NewValue(tftypes.Bool, false),
+		"timeouts":            tftypes.NewValue(objectType.AttributeTypes["timeouts"], nil),
+	})
+	return tfsdk.Plan{Raw: raw, Schema: schemaResp.Schema}
+}
+
+func createInvalidation(t *testing.T, r *InvalidationResource, plan tfsdk.Plan) *fwresource.CreateResponse {
+	t.Helper()
+
+	resp := &fwresource.CreateResponse{State: tfsdk.State{Raw: tftypes.NewValue(plan.Raw.Type(), nil), Schema: plan.Schema}}
+	r.Create(context.Background(), fwresource.CreateRequest{Plan: plan}, resp)
+	return resp
+}
+
+func TestInvalidationResource_create(t *testing.T) {
+	var batches []invalidationBatch
+	r := newInvalidationResource(newCloudFrontServer(t, "E2QWRUHAPOMQZL", &batches).URL)
+
+	for _, paths := range [][]string{{"/index.html", "/assets/*"}, {"/index.html", "/assets/*"}} {
+		resp := createInvalidation(t, r, invalidationPlan(t, "E2QWRUHAPOMQZL", paths, nil))
+		if resp.Diagnostics.HasError() {
+			t.Fatal(resp.Diagnostics)
+		}
+		var id fwtypes.String
+		resp.State.GetAttribute(context.Background(), path.Root("id"), &id)
+		if id.ValueString() != fmt.Sprintf("I%d", len(batches)) {
+			t.Errorf("expected the ID of the invalidation in the state, got %s", id)
+		}
+	}
+
+	for _, batch := range batches {
+		if !reflect.DeepEqual(batch.Paths, []string{"/index.html", "/assets/*"}) || batch.Quantity != 2 {
+			t.Errorf("expected the configured paths to be invalidated, got %d paths %v", batch.Quantity, batch.Paths)
+		}
+		if batch.CallerReference == "" {
+			t.Error("expected a caller reference")
+		}
+	}
+	// CloudFront returns the earlier invalidation instead of creating a new one for a caller reference it has seen.
+	if batches[0].CallerReference == batches[1].CallerReference {
+		t.Errorf("expected every invalidation to have its own caller reference, got %s twice", batches[0].CallerReference)
+	}
+}
+
+func TestInvalidationResource_createFails(t *testing.T) {
+	var batches []invalidationBatch
+	r := newInvalidationResource(newCloudFrontServer(t, "E2QWRUHAPOMQZL", &batches).URL)
+
+	resp := createInvalidation(t, r, invalidationPlan(t, "EMISSING", []string{"/*"}, nil))
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected creating an invalidation of a missing distribution to fail")
+	}
+	if summary, detail := resp.Diagnostics[0].Summary(), resp.Diagnostics[0].Detail(); summary != "Error creating invalidation" || !strings.Contains(detail, "NoSuchDistribution") {
+		t.Errorf("unexpected error %s: %s", summary, detail)
+	}
+	if !resp.State.Raw.IsNull() {
+		t.Errorf("expected no state for the failed invalidation, got %s", resp.State.Raw)
+	}
+}
+
+func TestInvalidationResource_triggersReplace(t *testing.T) {
+	ctx := context.Background()
+	state := invalidationPlan(t, "E2QWRUHAPOMQZL", []string{"/*"}, map[string]string{"source_etag": "a"})
+	attribute := state.Schema.GetAttributes()["triggers"].(schema.MapAttribute)
+
+	for etag, replace := range map[string]bool{"a": false, "b": true} {
+		plan := invalidationPlan(t, "E2QWRUHAPOMQZL", []string{"/*"}, map[string]string{"source_etag": etag})
+		var stateValue, planValue fwtypes.Map
+		state.GetAttribute(ctx, path.Root("triggers"), &stateValue)
+		plan.GetAttribute(ctx, path.Root("triggers"), &planValue)
+
+		resp := &planmodifier.MapResponse{PlanValue: planValue}
+		for _, modifier := range attribute.PlanModifiers {
+			modifier.PlanModifyMap(ctx, planmodifier.MapRequest{
+				Path:        path.Root("triggers"),
+				State:       tfsdk.State{Raw: state.Raw, Schema: state.Schema},
+				StateValue:  stateValue,
+				Plan:        plan,
+				PlanValue:   planValue,
+				ConfigValue: planValue,
+			}, resp)
+		}
+		if resp.RequiresReplace != replace {
+			t.Errorf("expected the triggers changing to %s to require replacement: %t, got %t", etag, replace, resp.RequiresReplace)
+		}
+	}
+
+	// Only wait_for_completion is updated in place.
+	if modifiers := state.Schema.GetAttributes()["wait_for_completion"].(schema.BoolAttribute).PlanModifiers; len(modifiers) != 0 {
+		t.Errorf("expected wait_for_completion to be updated in place, got %v", modifiers)
+	}
+}
+
+func TestInvalidationResource_createTimesOut(t *testing.T) {
+	// CloudFront does not respond until the test ends, so that only the timeout ends the request.
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(done) })
+
+	plan := invalidationPlan(t, "E2QWRUHAPOMQZL", []string{"/*"}, nil)
+	diags := plan.SetAttribute(context.Background(), path.Root("timeouts").AtName("create"), fwtypes.StringValue("100ms"))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	start := time.Now()
+	resp := createInvalidation(t, newInvalidationResource(server.URL), plan)
+	if !resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics[0].Detail(), "deadline exceeded") {
+		t.Errorf("expected creating the invalidation to time out, got %v", resp.Diagnostics)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the configured timeout rather than the default, took %s", elapsed)
+	}
+}
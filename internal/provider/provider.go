@@ -6,8 +6,21 @@ package provider
 import (
 	"context"
 	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
-	"net/http"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
@@ -28,6 +41,78 @@ type StaticFileDeployProvider struct {
 
 // ScaffoldingProviderModel describes the provider data model.
 type ScaffoldingProviderModel struct {
+	MimeTypes  types.Map              `tfsdk:"mime_types"`
+	MaxRetries types.Int64            `tfsdk:"max_retries"`
+	RetryMode  types.String           `tfsdk:"retry_mode"`
+	Defaults   *ProviderDefaultsModel `tfsdk:"defaults"`
+
+	PlanOnlyOffline types.Bool `tfsdk:"plan_only_offline"`
+
+	DefaultTargetRegion types.String `tfsdk:"default_target_region"`
+
+	AssumeRoleWithWebIdentity *ProviderWebIdentityModel `tfsdk:"assume_role_with_web_identity"`
+	AssumeRole                *ProviderAssumeRoleModel  `tfsdk:"assume_role"`
+
+	MaxGlobalConcurrentUploads types.Int64 `tfsdk:"max_global_concurrent_uploads"`
+
+	AppID     types.String `tfsdk:"app_id"`
+	UserAgent types.List   `tfsdk:"user_agent"`
+
+	S3Endpoint                types.String `tfsdk:"s3_endpoint"`
+	SkipCredentialsValidation types.Bool   `tfsdk:"skip_credentials_validation"`
+	SkipRegionValidation      types.Bool   `tfsdk:"skip_region_validation"`
+	SkipMetadataAPICheck      types.Bool   `tfsdk:"skip_metadata_api_check"`
+	EC2MetadataEndpoint       types.String `tfsdk:"ec2_metadata_service_endpoint"`
+
+	Profile types.String `tfsdk:"profile"`
+}
+
+// Environment variables that configure the provider when the corresponding attribute is not set.
+const (
+	s3EndpointEnv                = "STATICFILEDEPLOY_S3_ENDPOINT"
+	skipCredentialsValidationEnv = "STATICFILEDEPLOY_SKIP_CREDENTIALS_VALIDATION"
+	skipRegionValidationEnv      = "STATICFILEDEPLOY_SKIP_REGION_VALIDATION"
+	skipMetadataAPICheckEnv      = "STATICFILEDEPLOY_SKIP_METADATA_API_CHECK"
+)
+
+// defaultTargetRegion is the region of target buckets unless configured on the resource or the provider.
+const defaultTargetRegion = "eu-west-1"
+
+// userAgentProduct is the product the provider always adds to the User-Agent header of AWS requests.
+const userAgentProduct = "terraform-provider-staticfiledeploy"
+
+// awsRegionPattern matches the names of AWS regions, e.g. `eu-west-1` or `us-gov-west-1`.
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// ProviderDefaultsModel describes the settings inherited by every deployment.
+type ProviderDefaultsModel struct {
+	HashedAssetPattern      types.String                  `tfsdk:"hashed_asset_pattern"`
+	KeepFiles               types.List                    `tfsdk:"keep_files"`
+	ContentDispositionRules []DeploymentMetadataRuleModel `tfsdk:"content_disposition_rule"`
+	ContentLanguageRules    []DeploymentMetadataRuleModel `tfsdk:"content_language_rule"`
+	DownloadConcurrency     types.Int64                   `tfsdk:"download_concurrency"`
+	MultipartConcurrency    types.Int64                   `tfsdk:"multipart_concurrency"`
+	Tags                    types.Map                     `tfsdk:"tags"`
+	ServerSideEncryption    *DeploymentEncryptionModel    `tfsdk:"server_side_encryption"`
+}
+
+// ProviderWebIdentityModel describes the IAM role the provider assumes with a web identity token.
+type ProviderWebIdentityModel struct {
+	RoleARN              types.String `tfsdk:"role_arn"`
+	SessionName          types.String `tfsdk:"session_name"`
+	WebIdentityToken     types.String `tfsdk:"web_identity_token"`
+	WebIdentityTokenFile types.String `tfsdk:"web_identity_token_file"`
+	DurationSeconds      types.Int64  `tfsdk:"duration_seconds"`
+}
+
+// ProviderAssumeRoleModel describes the IAM role the provider assumes with the credentials of the environment.
+type ProviderAssumeRoleModel struct {
+	RoleARN           types.String `tfsdk:"role_arn"`
+	SessionName       types.String `tfsdk:"session_name"`
+	ExternalID        types.String `tfsdk:"external_id"`
+	DurationSeconds   types.Int64  `tfsdk:"duration_seconds"`
+	Tags              types.Map    `tfsdk:"tags"`
+	TransitiveTagKeys types.List   `tfsdk:"transitive_tag_keys"`
 }
 
 func (p *StaticFileDeployProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -37,7 +122,212 @@ func (p *StaticFileDeployProvider) Metadata(ctx context.Context, req provider.Me
 
 func (p *StaticFileDeployProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Attributes: map[string]schema.Attribute{},
+		Attributes: map[string]schema.Attribute{
+			"mime_types": schema.MapAttribute{
+				MarkdownDescription: "Content types to upload files with, keyed by file extension, e.g. `{ \".glb\" = \"model/gltf-binary\" }`. Extends and overrides the built-in table, which in addition to the system MIME types covers `.wasm`, `.mjs`, `.webmanifest`, `.avif`, `.woff2` and `.map`.",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
+			"max_retries": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("How many times a failed AWS request is retried, and how many times a file is uploaded again when S3 keeps throttling it with `SlowDown` errors, waiting longer between each attempt. Defaults to %d.", deployer.DefaultMaxRetries),
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"retry_mode": schema.StringAttribute{
+				MarkdownDescription: "The retry mode of the AWS SDK, either `standard` or `adaptive`. In `adaptive` mode requests are also sent at a lower rate while they are being throttled, which helps large deployments to busy buckets. Defaults to `standard`.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(string(aws.RetryModeStandard), string(aws.RetryModeAdaptive)),
+				},
+			},
+			"default_target_region": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The region of target S3 buckets whose region is not configured: the `region` of the `target` block of `staticfiledeploy_deployment`, and the `target_region` of `staticfiledeploy_file` and the `staticfiledeploy_diff` data source. Defaults to `%s`.", defaultTargetRegion),
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"max_global_concurrent_uploads": schema.Int64Attribute{
+				MarkdownDescription: "How many files all deployments of the provider upload at the same time in total, so that a workspace applying many deployments in parallel does not overwhelm S3 or the local network. Deployments wait for a free slot before each upload. Unlimited by default.",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"app_id": schema.StringAttribute{
+				MarkdownDescription: "An application ID added to the User-Agent header of every AWS request as `app/<id>`, so that S3 request costs and CloudTrail activity can be attributed to the team or pipeline deploying. Defaults to the `AWS_SDK_UA_APP_ID` environment variable or the `sdk_ua_app_id` setting of the shared config file.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthBetween(1, 50),
+				},
+			},
+			"user_agent": schema.ListAttribute{
+				MarkdownDescription: fmt.Sprintf("Products to append to the User-Agent header of every AWS request, each either a name or a `name/version` pair, e.g. `[\"my-pipeline/1.0\"]`. `%s/<version>` is always added.", userAgentProduct),
+				ElementType:         types.StringType,
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+				},
+			},
+			"plan_only_offline": schema.BoolAttribute{
+				MarkdownDescription: "Skip every request to AWS when refreshing and planning, using the hashes and source ETags in the state instead, so that speculative plans can run in CI with read-only or no AWS credentials. Changes made to the source or the target outside of Terraform are then only noticed once an online plan runs, and deployments are not compared with the target before they are applied. Refreshing and planning never modify the source or the target, whether or not this is set. Defaults to `false`.",
+				Optional:            true,
+			},
+			"s3_endpoint": schema.StringAttribute{
+				MarkdownDescription: fmt.Sprintf("The URL of an S3 compatible service to send S3 requests to instead of AWS, e.g. `http://localhost:4566` for LocalStack or `http://localhost:9000` for MinIO. Buckets are then addressed by path rather than by host name. Requests to other services, such as CloudFront, use the endpoints of the AWS configuration, which the `AWS_ENDPOINT_URL` environment variable overrides. Can also be set with the `%s` or `AWS_ENDPOINT_URL_S3` environment variables.", s3EndpointEnv),
+				Optional:            true,
+			},
+			"skip_credentials_validation": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Skip checking that AWS credentials can be retrieved when the provider is configured, e.g. when the credentials only exist at apply time, or when `s3_endpoint` is a local server that accepts any credentials. The credentials are then retrieved when they are first needed. The check is always skipped with `plan_only_offline`. Can also be set with the `%s` environment variable. Defaults to `false`.", skipCredentialsValidationEnv),
+				Optional:            true,
+			},
+			"skip_region_validation": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Skip checking that the configured AWS region is the name of an AWS region, so that the region of an S3 compatible service, e.g. `minio`, can be used. Can also be set with the `%s` environment variable. Defaults to `false`.", skipRegionValidationEnv),
+				Optional:            true,
+			},
+			"profile": schema.StringAttribute{
+				MarkdownDescription: "The name of the profile in the shared AWS config and credentials files to use, including profiles that sign in with AWS IAM Identity Center (SSO) after `aws sso login`. Can also be set with the `AWS_PROFILE` environment variable.",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.LengthAtLeast(1),
+				},
+			},
+			"skip_metadata_api_check": schema.BoolAttribute{
+				MarkdownDescription: fmt.Sprintf("Skip looking up credentials and the region in the [EC2 instance metadata service](https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/configuring-instance-metadata-service.html), so that CI runners outside of EC2 whose network drops requests to it do not wait several seconds for it to time out. Can also be set with the `%s` or `AWS_EC2_METADATA_DISABLED` environment variables. Defaults to `false`.", skipMetadataAPICheckEnv),
+				Optional:            true,
+			},
+			"ec2_metadata_service_endpoint": schema.StringAttribute{
+				MarkdownDescription: "The URL of the EC2 instance metadata service, e.g. `http://[fd00:ec2::254]` on IPv6 only instances. Can also be set with the `AWS_EC2_METADATA_SERVICE_ENDPOINT` environment variable.",
+				Optional:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"assume_role": schema.SingleNestedBlock{
+				MarkdownDescription: "Assumes an IAM role with the credentials of the environment, or of `assume_role_with_web_identity` if it is set, e.g. to deploy to buckets in another account.",
+				Attributes: map[string]schema.Attribute{
+					"role_arn": schema.StringAttribute{
+						MarkdownDescription: "The ARN of the IAM role to assume.",
+						Required:            true,
+					},
+					"session_name": schema.StringAttribute{
+						MarkdownDescription: "The name of the role session, which shows up in CloudTrail. Defaults to a name generated by the AWS SDK.",
+						Optional:            true,
+					},
+					"external_id": schema.StringAttribute{
+						MarkdownDescription: "The external ID the trust policy of the role requires, if any.",
+						Optional:            true,
+					},
+					"duration_seconds": schema.Int64Attribute{
+						MarkdownDescription: "How long the credentials of the role are valid, in seconds. Defaults to 900.",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.Between(900, 43200),
+						},
+					},
+					"tags": schema.MapAttribute{
+						MarkdownDescription: "[Session tags](https://docs.aws.amazon.com/IAM/latest/UserGuide/id_session-tags.html) of the role session, so that bucket and key policies using attribute-based access control can authorize deployments with `aws:PrincipalTag` conditions. The trust policy of the role must allow `sts:TagSession`.",
+						ElementType:         types.StringType,
+						Optional:            true,
+						Validators: []validator.Map{
+							mapvalidator.SizeAtMost(50),
+						},
+					},
+					"transitive_tag_keys": schema.ListAttribute{
+						MarkdownDescription: "Keys of `tags` that are kept when the role session assumes another role.",
+						ElementType:         types.StringType,
+						Optional:            true,
+					},
+				},
+			},
+			"assume_role_with_web_identity": schema.SingleNestedBlock{
+				MarkdownDescription: "Assumes an IAM role with a web identity token, e.g. the OIDC token of a GitHub Actions or GitLab CI job, instead of using the credentials of the environment. Exactly one of `web_identity_token` and `web_identity_token_file` must be set.",
+				Attributes: map[string]schema.Attribute{
+					"role_arn": schema.StringAttribute{
+						MarkdownDescription: "The ARN of the IAM role to assume.",
+						Required:            true,
+					},
+					"session_name": schema.StringAttribute{
+						MarkdownDescription: "The name of the role session, which shows up in CloudTrail. Defaults to a name generated by the AWS SDK.",
+						Optional:            true,
+					},
+					"web_identity_token": schema.StringAttribute{
+						MarkdownDescription: "The web identity token, e.g. the value of a GitLab CI `id_tokens` variable.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"web_identity_token_file": schema.StringAttribute{
+						MarkdownDescription: "The path of a file containing the web identity token. The file is read again whenever the credentials are refreshed, so long applies keep working if the runner replaces the token.",
+						Optional:            true,
+					},
+					"duration_seconds": schema.Int64Attribute{
+						MarkdownDescription: "How long the credentials of the role are valid, in seconds. Defaults to the maximum session duration of the role.",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.Between(900, 43200),
+						},
+					},
+				},
+			},
+			"defaults": schema.SingleNestedBlock{
+				MarkdownDescription: "Settings inherited by every `staticfiledeploy_deployment`. Rules, `keep_files` and `tags` configured on a deployment are added to the defaults, with the rules and tags of the deployment taking precedence, while the other settings replace the defaults.",
+				Attributes: map[string]schema.Attribute{
+					"hashed_asset_pattern": schema.StringAttribute{
+						MarkdownDescription: "The default `hashed_asset_pattern` of deployments.",
+						Optional:            true,
+					},
+					"keep_files": schema.ListAttribute{
+						MarkdownDescription: "Glob patterns of files that are never deleted by `delete_removed_files`.",
+						ElementType:         types.StringType,
+						Optional:            true,
+					},
+					"download_concurrency": schema.Int64Attribute{
+						MarkdownDescription: "The default `download_concurrency` of deployments.",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.AtLeast(1),
+						},
+					},
+					"multipart_concurrency": schema.Int64Attribute{
+						MarkdownDescription: "The default `multipart_concurrency` of deployments.",
+						Optional:            true,
+						Validators: []validator.Int64{
+							int64validator.AtLeast(1),
+						},
+					},
+					"tags": schema.MapAttribute{
+						MarkdownDescription: "Tags to add to every file deployed to S3.",
+						ElementType:         types.StringType,
+						Optional:            true,
+					},
+				},
+				Blocks: map[string]schema.Block{
+					"content_disposition_rule": metadataRuleProviderBlock("Content-Disposition", "downloads/*", "attachment"),
+					"content_language_rule":    metadataRuleProviderBlock("Content-Language", "no/*", "nb-NO"),
+					"server_side_encryption": schema.SingleNestedBlock{
+						MarkdownDescription: "The server-side encryption to write files deployed to S3 with. Deployments with a `target_sse_customer_key` are encrypted with the key instead.",
+						Attributes: map[string]schema.Attribute{
+							"algorithm": schema.StringAttribute{
+								MarkdownDescription: encryptionAlgorithmDescription,
+								Required:            true,
+								Validators: []validator.String{
+									stringvalidator.OneOf(encryptionAlgorithms()...),
+								},
+							},
+							"kms_key_id": schema.StringAttribute{
+								MarkdownDescription: encryptionKMSKeyIDDescription,
+								Optional:            true,
+							},
+							"bucket_key_enabled": schema.BoolAttribute{
+								MarkdownDescription: encryptionBucketKeyDescription,
+								Optional:            true,
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -50,23 +340,230 @@ func (p *StaticFileDeployProvider) Configure(ctx context.Context, req provider.C
 		return
 	}
 
-	// Configuration values are now available.
-	// if data.Endpoint.IsNull() { /* ... */ }
+	mimeTypes := make(map[string]string)
+	resp.Diagnostics.Append(data.MimeTypes.ElementsAs(ctx, &mimeTypes, false)...)
+
+	defaults := deployer.DeploymentDefaults{TargetRegion: defaultTargetRegion}
+	if !data.DefaultTargetRegion.IsNull() {
+		defaults.TargetRegion = data.DefaultTargetRegion.ValueString()
+	}
+	if data.Defaults != nil {
+		defaults.HashedAssetPattern = data.Defaults.HashedAssetPattern.ValueString()
+		defaults.ContentDispositionRules = metadataRulesFromModel(data.Defaults.ContentDispositionRules)
+		defaults.ContentLanguageRules = metadataRulesFromModel(data.Defaults.ContentLanguageRules)
+		resp.Diagnostics.Append(data.Defaults.KeepFiles.ElementsAs(ctx, &defaults.KeepFiles, false)...)
+		resp.Diagnostics.Append(data.Defaults.Tags.ElementsAs(ctx, &defaults.Tags, false)...)
+		defaults.DownloadConcurrency = int(data.Defaults.DownloadConcurrency.ValueInt64())
+		defaults.MultipartConcurrency = int(data.Defaults.MultipartConcurrency.ValueInt64())
+		if data.Defaults.ServerSideEncryption != nil {
+			defaults.Encryption = data.Defaults.ServerSideEncryption.encryption()
+		}
+	}
+
+	retry := deployer.RetryPolicy{
+		MaxRetries: int(data.MaxRetries.ValueInt64()),
+		Mode:       aws.RetryMode(data.RetryMode.ValueString()),
+	}
+
+	userAgent := deployer.UserAgent{
+		AppID:    data.AppID.ValueString(),
+		Products: []string{userAgentProduct + "/" + p.version},
+	}
+	var products []string
+	resp.Diagnostics.Append(data.UserAgent.ElementsAs(ctx, &products, false)...)
+	userAgent.Products = append(userAgent.Products, products...)
+
+	s3Endpoint := stringFromEnv(data.S3Endpoint, s3EndpointEnv, "AWS_ENDPOINT_URL_S3")
+	skipCredentialsValidation, err := boolFromEnv(data.SkipCredentialsValidation, skipCredentialsValidationEnv)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("skip_credentials_validation"), "Invalid environment variable", err.Error())
+	}
+	skipRegionValidation, err := boolFromEnv(data.SkipRegionValidation, skipRegionValidationEnv)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("skip_region_validation"), "Invalid environment variable", err.Error())
+	}
+	skipMetadataAPICheck, err := boolFromEnv(data.SkipMetadataAPICheck, skipMetadataAPICheckEnv)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("skip_metadata_api_check"), "Invalid environment variable", err.Error())
+	}
+	metadataService := deployer.MetadataService{
+		Disabled: skipMetadataAPICheck,
+		Endpoint: data.EC2MetadataEndpoint.ValueString(),
+	}
+	var webIdentityRole *deployer.WebIdentityRole
+	if role := data.AssumeRoleWithWebIdentity; role != nil {
+		if role.WebIdentityToken.IsNull() == role.WebIdentityTokenFile.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("assume_role_with_web_identity"),
+				"Invalid web identity configuration",
+				"Exactly one of web_identity_token and web_identity_token_file must be set.",
+			)
+		}
+		webIdentityRole = &deployer.WebIdentityRole{
+			RoleARN:     role.RoleARN.ValueString(),
+			SessionName: role.SessionName.ValueString(),
+			Token:       role.WebIdentityToken.ValueString(),
+			TokenFile:   role.WebIdentityTokenFile.ValueString(),
+			Duration:    time.Duration(role.DurationSeconds.ValueInt64()) * time.Second,
+		}
+	}
+	var assumeRole *deployer.AssumeRole
+	if role := data.AssumeRole; role != nil {
+		assumeRole = &deployer.AssumeRole{
+			RoleARN:     role.RoleARN.ValueString(),
+			SessionName: role.SessionName.ValueString(),
+			ExternalID:  role.ExternalID.ValueString(),
+			Duration:    time.Duration(role.DurationSeconds.ValueInt64()) * time.Second,
+		}
+		resp.Diagnostics.Append(role.Tags.ElementsAs(ctx, &assumeRole.Tags, false)...)
+		resp.Diagnostics.Append(role.TransitiveTagKeys.ElementsAs(ctx, &assumeRole.TransitiveTagKeys, false)...)
+		for _, key := range assumeRole.TransitiveTagKeys {
+			if _, found := assumeRole.Tags[key]; !found {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("assume_role").AtName("transitive_tag_keys"),
+					"Invalid transitive tag key",
+					fmt.Sprintf("%q is not a key of tags.", key),
+				)
+			}
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	configOptions := append(retry.ConfigOptions(), userAgent.ConfigOptions()...)
+	configOptions = append(configOptions, metadataService.ConfigOptions()...)
+	if !data.Profile.IsNull() {
+		configOptions = append(configOptions, config.WithSharedConfigProfile(data.Profile.ValueString()))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, configOptions...)
+	if err != nil {
+		resp.Diagnostics.AddError("Could not load AWS configuration", err.Error())
+		return
+	}
+	if webIdentityRole != nil {
+		cfg.Credentials = webIdentityRole.Credentials(cfg)
+	}
+	if assumeRole != nil {
+		cfg.Credentials = assumeRole.Credentials(cfg)
+	}
 
-	// Example client configuration for data sources and resources
-	client := http.DefaultClient
+	if !skipRegionValidation && cfg.Region != "" && !awsRegionPattern.MatchString(cfg.Region) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("skip_region_validation"),
+			"Invalid AWS region",
+			fmt.Sprintf("%q is not the name of an AWS region. Set skip_region_validation to use the region of an S3 compatible service.", cfg.Region),
+		)
+		return
+	}
+	if !skipCredentialsValidation && !data.PlanOnlyOffline.ValueBool() {
+		if cfg.Credentials == nil {
+			resp.Diagnostics.AddError("No AWS credentials", "No AWS credentials are configured. Set skip_credentials_validation if the S3 endpoint does not need any.")
+			return
+		}
+		if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+			detail := fmt.Sprintf("%s\n\nSet skip_credentials_validation if the credentials are only available at apply time or the S3 endpoint does not need any.", err)
+			if !data.Profile.IsNull() {
+				detail += fmt.Sprintf(" If the profile signs in with AWS IAM Identity Center, run `aws sso login --profile %s` to refresh its session.", data.Profile.ValueString())
+			}
+			resp.Diagnostics.AddError("Could not retrieve AWS credentials", detail)
+			return
+		}
+	}
+
+	client := &deployer.Deployer{
+		DefaultAWSConfig: cfg,
+		MimeTypes:        mimeTypes,
+		Defaults:         defaults,
+		Retry:            retry,
+		PlanOffline:      data.PlanOnlyOffline.ValueBool(),
+		S3Endpoint:       s3Endpoint,
+	}
+	if !data.MaxGlobalConcurrentUploads.IsNull() {
+		client.UploadLimiter = deployer.NewUploadLimiter(int(data.MaxGlobalConcurrentUploads.ValueInt64()))
+	}
 	resp.DataSourceData = client
 	resp.ResourceData = client
 }
 
+// stringFromEnv returns the configured value, or else the first of the given environment variables that is set.
+func stringFromEnv(value types.String, envs ...string) string {
+	if !value.IsNull() {
+		return value.ValueString()
+	}
+	for _, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// boolFromEnv returns the configured value, or else the value of the given environment variable, which defaults to
+// false.
+func boolFromEnv(value types.Bool, env string) (bool, error) {
+	if !value.IsNull() {
+		return value.ValueBool(), nil
+	}
+	v := os.Getenv(env)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, not %q", env, v)
+	}
+	return b, nil
+}
+
+// planTargetRegion plans the default target region of the provider for a resource that does not configure the
+// target region at the given path, and returns whether it differs from the region in the state.
+func planTargetRegion(ctx context.Context, d *deployer.Deployer, regionPath path.Path, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) bool {
+	var configured, state types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, regionPath, &configured)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, regionPath, &state)...)
+	}
+	if resp.Diagnostics.HasError() || !configured.IsNull() {
+		return false
+	}
+
+	region := d.Defaults.TargetRegion
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, regionPath, region)...)
+	return isKnown(state) && state.ValueString() != region
+}
+
+// metadataRuleProviderBlock returns the schema of a provider-level rule block setting the given header.
+func metadataRuleProviderBlock(header string, examplePattern string, exampleValue string) schema.ListNestedBlock {
+	return schema.ListNestedBlock{
+		MarkdownDescription: fmt.Sprintf("Sets the `%s` header of matching files in every deployment. The first matching rule applies.", header),
+		NestedObject: schema.NestedBlockObject{
+			Attributes: map[string]schema.Attribute{
+				"pattern": schema.StringAttribute{
+					MarkdownDescription: fmt.Sprintf("A glob pattern matching the files to apply the rule to, e.g. `%s`. %s", examplePattern, deployer.GlobSyntaxDescription),
+					Required:            true,
+				},
+				"value": schema.StringAttribute{
+					MarkdownDescription: fmt.Sprintf("The `%s` value, e.g. `%s`.", header, exampleValue),
+					Required:            true,
+				},
+			},
+		},
+	}
+}
+
 func (p *StaticFileDeployProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewDeploymentResource,
+		NewFileResource,
+		NewInvalidationResource,
 	}
 }
 
 func (p *StaticFileDeployProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return nil
+	return []func() datasource.DataSource{
+		NewDiffDataSource,
+	}
 }
 
 func New(version string) func() provider.Provider {
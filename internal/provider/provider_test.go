@@ -4,10 +4,21 @@
 package provider
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer"
 )
 
 var testAccProvider, _ = convertProviderType(New("test")())
@@ -25,3 +36,148 @@ func testAccPreCheck(t *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// newTestS3Client returns an S3 client for setting up acceptance tests, which like the provider sends its requests to
+// the S3 endpoint in the environment if one is set, so that the tests can run against LocalStack or MinIO.
+func newTestS3Client(cfg aws.Config, optFns ...func(*s3.Options)) *s3.Client {
+	if endpoint := stringFromEnv(types.StringNull(), s3EndpointEnv, "AWS_ENDPOINT_URL_S3"); endpoint != "" {
+		optFns = append(optFns, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		})
+	}
+	return s3.NewFromConfig(cfg, optFns...)
+}
+
+func TestStringFromEnv(t *testing.T) {
+	t.Setenv(s3EndpointEnv, "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "http://localhost:9000")
+
+	if got := stringFromEnv(types.StringValue("http://localhost:4566"), s3EndpointEnv, "AWS_ENDPOINT_URL_S3"); got != "http://localhost:4566" {
+		t.Errorf("configured value: got %q", got)
+	}
+	if got := stringFromEnv(types.StringNull(), s3EndpointEnv, "AWS_ENDPOINT_URL_S3"); got != "http://localhost:9000" {
+		t.Errorf("fallback environment variable: got %q", got)
+	}
+	t.Setenv(s3EndpointEnv, "http://localhost:4566")
+	if got := stringFromEnv(types.StringNull(), s3EndpointEnv, "AWS_ENDPOINT_URL_S3"); got != "http://localhost:4566" {
+		t.Errorf("first environment variable: got %q", got)
+	}
+}
+
+func TestBoolFromEnv(t *testing.T) {
+	t.Setenv(skipRegionValidationEnv, "true")
+	if got, err := boolFromEnv(types.BoolNull(), skipRegionValidationEnv); err != nil || !got {
+		t.Errorf("environment variable: got %v, %v", got, err)
+	}
+	if got, err := boolFromEnv(types.BoolValue(false), skipRegionValidationEnv); err != nil || got {
+		t.Errorf("configured value: got %v, %v", got, err)
+	}
+	t.Setenv(skipRegionValidationEnv, "yes")
+	if _, err := boolFromEnv(types.BoolNull(), skipRegionValidationEnv); err == nil {
+		t.Error("expected an error for an invalid value")
+	}
+}
+
+func TestAWSRegionPattern(t *testing.T) {
+	for region, want := range map[string]bool{
+		"eu-west-1":      true,
+		"us-gov-west-1":  true,
+		"ap-southeast-2": true,
+		"minio":          false,
+		"eu-west":        false,
+		"EU-WEST-1":      false,
+	} {
+		if got := awsRegionPattern.MatchString(region); got != want {
+			t.Errorf("%s: got %v, want %v", region, got, want)
+		}
+	}
+}
+
+// configureProvider configures the provider with the given attributes, leaving all others unset.
+func configureProvider(t *testing.T, attributes map[string]tftypes.Value) *provider.ConfigureResponse {
+	ctx := context.Background()
+	p := New("test")()
+	schemaResp := &provider.SchemaResponse{}
+	p.Schema(ctx, provider.SchemaRequest{}, schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := map[string]tftypes.Value{}
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, nil)
+	}
+	for name, value := range attributes {
+		values[name] = value
+	}
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+	}, resp)
+	return resp
+}
+
+func TestProviderConfigure_skipCredentialsValidation(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/credentials")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv(skipCredentialsValidationEnv, "")
+
+	if resp := configureProvider(t, nil); !resp.Diagnostics.HasError() {
+		t.Error("expected missing credentials to fail the configuration")
+	}
+	if resp := configureProvider(t, map[string]tftypes.Value{"skip_credentials_validation": tftypes.NewValue(tftypes.Bool, true)}); resp.Diagnostics.HasError() {
+		t.Errorf("expected missing credentials to be ignored with skip_credentials_validation, got %v", resp.Diagnostics)
+	}
+
+	t.Setenv(skipCredentialsValidationEnv, "true")
+	if resp := configureProvider(t, nil); resp.Diagnostics.HasError() {
+		t.Errorf("expected missing credentials to be ignored with %s, got %v", skipCredentialsValidationEnv, resp.Diagnostics)
+	}
+}
+
+func TestProviderConfigure_profile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv(skipCredentialsValidationEnv, "")
+
+	config := "[profile deploy]\nregion = eu-north-1\n\n[profile sso]\nregion = eu-north-1\nsso_start_url = https://example.awsapps.com/start\nsso_region = eu-west-1\nsso_account_id = 123456789012\nsso_role_name = Deploy\n"
+	if err := os.WriteFile(filepath.Join(home, "config"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	credentials := "[deploy]\naws_access_key_id = AKIDDEPLOY\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(filepath.Join(home, "credentials"), []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := configureProvider(t, map[string]tftypes.Value{"profile": tftypes.NewValue(tftypes.String, "deploy")})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected the profile to configure the provider, got %v", resp.Diagnostics)
+	}
+	cfg := resp.ResourceData.(*deployer.Deployer).DefaultAWSConfig
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "AKIDDEPLOY" || cfg.Region != "eu-north-1" {
+		t.Errorf("expected the credentials and region of the profile, got %s in %s (%v)", creds.AccessKeyID, cfg.Region, err)
+	}
+
+	// Without a cached session, the credentials of an SSO profile cannot be retrieved until signing in again.
+	resp = configureProvider(t, map[string]tftypes.Value{"profile": tftypes.NewValue(tftypes.String, "sso")})
+	if !resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics[0].Detail(), "aws sso login --profile sso") {
+		t.Errorf("expected an error telling to sign in to the SSO profile, got %v", resp.Diagnostics)
+	}
+
+	if resp := configureProvider(t, map[string]tftypes.Value{"profile": tftypes.NewValue(tftypes.String, "missing")}); !resp.Diagnostics.HasError() {
+		t.Error("expected a missing profile to fail the configuration")
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// bucketNamePattern matches valid S3 bucket names.
+const bucketNamePattern = `[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]`
+
+var (
+	// sourceRegexp matches an S3 object as "bucket/key" or "s3://bucket/key".
+	sourceRegexp = regexp.MustCompile(`^(s3://)?` + bucketNamePattern + `/.+$`)
+	// targetRegexp matches an S3 bucket name or ARN.
+	targetRegexp = regexp.MustCompile(`^(arn:aws[a-z-]*:s3:::)?` + bucketNamePattern + `$`)
+)
+
+// parseSource returns the bucket and key of a source in the format "bucket/key" or "s3://bucket/key".
+func parseSource(source string) (string, string, error) {
+	sourceParts := strings.SplitN(strings.TrimPrefix(source, "s3://"), "/", 2)
+	if len(sourceParts) != 2 || sourceParts[0] == "" || sourceParts[1] == "" {
+		return "", "", fmt.Errorf("invalid source format: %s", source)
+	}
+	return sourceParts[0], sourceParts[1], nil
+}
+
+// targetBucket returns the bucket name of a target given as a bucket name or ARN.
+func targetBucket(target string) string {
+	if i := strings.LastIndex(target, ":"); i >= 0 {
+		return target[i+1:]
+	}
+	return target
+}
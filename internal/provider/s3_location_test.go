@@ -0,0 +1,47 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import "testing"
+
+func TestParseSource(t *testing.T) {
+	for _, source := range []string{"my-bucket/path/to/source.zip", "s3://my-bucket/path/to/source.zip"} {
+		bucket, key, err := parseSource(source)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", source, err)
+		}
+		if bucket != "my-bucket" || key != "path/to/source.zip" {
+			t.Errorf("unexpected bucket and key for %q: %s, %s", source, bucket, key)
+		}
+		if !sourceRegexp.MatchString(source) {
+			t.Errorf("expected %q to be a valid source", source)
+		}
+	}
+
+	for _, source := range []string{"my-bucket", "my-bucket/", "s3://my-bucket"} {
+		if _, _, err := parseSource(source); err == nil {
+			t.Errorf("expected an error for %q", source)
+		}
+		if sourceRegexp.MatchString(source) {
+			t.Errorf("expected %q to be an invalid source", source)
+		}
+	}
+}
+
+func TestTargetBucket(t *testing.T) {
+	for _, target := range []string{"my-bucket", "arn:aws:s3:::my-bucket"} {
+		if !targetRegexp.MatchString(target) {
+			t.Errorf("expected %q to be a valid target", target)
+		}
+		if got := targetBucket(target); got != "my-bucket" {
+			t.Errorf("unexpected bucket for %q: %s", target, got)
+		}
+	}
+
+	for _, target := range []string{"My_Bucket", "my-bucket/prefix", "arn:aws:s3:::my-bucket/object"} {
+		if targetRegexp.MatchString(target) {
+			t.Errorf("expected %q to be an invalid target", target)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// publicCannedACLs are the canned ACLs that grant access to everyone, which S3 rejects for buckets that block public
+// ACLs.
+var publicCannedACLs = map[types.ObjectCannedACL]bool{
+	types.ObjectCannedACLPublicRead:        true,
+	types.ObjectCannedACLPublicReadWrite:   true,
+	types.ObjectCannedACLAuthenticatedRead: true,
+}
+
+// UseCannedACL makes the deployment write every object to the target with the given canned ACL, e.g. "public-read".
+// Only S3 targets support ACLs, so it returns an error for targets in other stores.
+func (d *Deployment) UseCannedACL(acl types.ObjectCannedACL) error {
+	store, ok := d.target.(*s3Store)
+	if !ok {
+		return fmt.Errorf("ACLs are only supported for S3 targets, not %s", d.target.Name())
+	}
+	withACL := *store
+	withACL.acl = acl
+	d.target = &withACL
+	return nil
+}
+
+// checkACL returns an error if the settings of the bucket make S3 reject objects written with the canned ACL of the
+// store, so that the deployment fails with the reason before any files are deployed rather than with AccessDenied
+// after some of them. Settings that cannot be read are not checked, as the uploads still fail if the ACL is rejected.
+func (s *s3Store) checkACL(ctx context.Context) error {
+	client, ok := s.client.(BucketOwnershipAPI)
+	if s.acl == "" || !ok {
+		return nil
+	}
+
+	ownership, err := client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{Bucket: aws.String(s.bucket)})
+	if err == nil && ownership.OwnershipControls != nil {
+		for _, rule := range ownership.OwnershipControls.Rules {
+			if rule.ObjectOwnership == types.ObjectOwnershipBucketOwnerEnforced && s.acl != types.ObjectCannedACLBucketOwnerFullControl {
+				return fmt.Errorf("bucket %s enforces %s object ownership, which disables ACLs; remove acl", s.bucket, types.ObjectOwnershipBucketOwnerEnforced)
+			}
+		}
+	}
+
+	if !publicCannedACLs[s.acl] {
+		return nil
+	}
+	publicAccess, err := client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: aws.String(s.bucket)})
+	if err == nil && publicAccess.PublicAccessBlockConfiguration != nil && aws.ToBool(publicAccess.PublicAccessBlockConfiguration.BlockPublicAcls) {
+		return fmt.Errorf("bucket %s blocks public ACLs with BlockPublicAcls, so objects cannot be written with the %s ACL; remove acl or allow public ACLs on the bucket", s.bucket, s.acl)
+	}
+
+	return nil
+}
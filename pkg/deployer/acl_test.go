@@ -0,0 +1,78 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+// ownershipS3API is a recordingS3API whose bucket has the given object ownership and public access block settings.
+type ownershipS3API struct {
+	*recordingS3API
+	ownership       types.ObjectOwnership
+	blockPublicACLs bool
+}
+
+func (c *ownershipS3API) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	return &s3.GetBucketOwnershipControlsOutput{OwnershipControls: &types.OwnershipControls{
+		Rules: []types.OwnershipControlsRule{{ObjectOwnership: c.ownership}},
+	}}, nil
+}
+
+func (c *ownershipS3API) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+		BlockPublicAcls: aws.Bool(c.blockPublicACLs),
+	}}, nil
+}
+
+func TestPreflight_checksACL(t *testing.T) {
+	cases := []struct {
+		acl             types.ObjectCannedACL
+		ownership       types.ObjectOwnership
+		blockPublicACLs bool
+		contains        string
+	}{
+		{types.ObjectCannedACLPublicRead, types.ObjectOwnershipBucketOwnerEnforced, false, "enforces BucketOwnerEnforced"},
+		{types.ObjectCannedACLPublicRead, types.ObjectOwnershipBucketOwnerPreferred, true, "BlockPublicAcls"},
+		{types.ObjectCannedACLBucketOwnerFullControl, types.ObjectOwnershipBucketOwnerEnforced, true, ""},
+		{types.ObjectCannedACLPrivate, types.ObjectOwnershipObjectWriter, true, ""},
+		{types.ObjectCannedACLPublicRead, types.ObjectOwnershipObjectWriter, false, ""},
+	}
+	for _, c := range cases {
+		client := &ownershipS3API{recordingS3API: &recordingS3API{Client: s3fake.New()}, ownership: c.ownership, blockPublicACLs: c.blockPublicACLs}
+		d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+		d.PreflightWriteProbe = true
+		if err := d.UseCannedACL(c.acl); err != nil {
+			t.Fatal(err)
+		}
+
+		err := d.preflight(context.Background(), source, nil)
+		if c.contains == "" {
+			if err != nil {
+				t.Errorf("%s with %s: %v", c.acl, c.ownership, err)
+			} else if len(client.puts) != 1 || client.puts[0].ACL != c.acl {
+				t.Errorf("%s with %s: expected the probe to be written with the ACL", c.acl, c.ownership)
+			}
+			continue
+		}
+		var preflightErr *PreflightError
+		if !errors.As(err, &preflightErr) || preflightErr.Check != PreflightTarget || !strings.Contains(err.Error(), c.contains) {
+			t.Errorf("%s with %s: expected an error about %s, got %v", c.acl, c.ownership, c.contains, err)
+		}
+		if len(client.puts) != 0 {
+			t.Errorf("%s with %s: expected nothing to be written", c.acl, c.ownership)
+		}
+	}
+}
+
+func TestUseCannedACL_onlyS3(t *testing.T) {
+	d := &Deployment{target: &gcsStore{name: "site"}}
+	if err := d.UseCannedACL(types.ObjectCannedACLPublicRead); err == nil {
+		t.Error("expected ACLs to be rejected for a target that is not in S3")
+	}
+}
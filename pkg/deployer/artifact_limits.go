@@ -0,0 +1,88 @@
+package deployer
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// ArtifactLimits are safety thresholds for the artifacts a deployment accepts, protecting the provider and the
+// target against unexpectedly large artifacts and ZIP bombs, and the site against unexpectedly small ones, e.g. a
+// broken build replacing it with a handful of files. Zero values mean no limit.
+// Zip64 archives, with more than 65,535 entries or entries of 4 GB or more, are supported up to these limits.
+type ArtifactLimits struct {
+	// MaxFiles is the highest number of entries in the artifact.
+	MaxFiles int
+	// MaxSize is the highest total uncompressed size in bytes of the entries in the artifact.
+	MaxSize int64
+	// MinFiles is the lowest number of files deployed from the artifact.
+	MinFiles int
+	// MinSize is the lowest total uncompressed size in bytes of the files deployed from the artifact.
+	MinSize int64
+}
+
+// ArtifactLimit names one of the ArtifactLimits.
+type ArtifactLimit string
+
+const (
+	ArtifactLimitFiles ArtifactLimit = "number of files"
+	ArtifactLimitSize  ArtifactLimit = "uncompressed size"
+)
+
+// ArtifactLimitError is returned when an artifact exceeds one of the configured ArtifactLimits, or falls short of
+// one of the minimums, in which case Min is set instead of Max.
+type ArtifactLimitError struct {
+	Limit ArtifactLimit
+	Value int64
+	Max   int64
+	Min   int64
+}
+
+func (e *ArtifactLimitError) Error() string {
+	if e.Min > 0 {
+		return fmt.Sprintf("artifact is below the minimum of its %s: it has %d, at least %d is required", e.Limit, e.Value, e.Min)
+	}
+	return fmt.Sprintf("artifact exceeds the limit on its %s: it has %d, at most %d is allowed", e.Limit, e.Value, e.Max)
+}
+
+// check returns an ArtifactLimitError if the artifact exceeds the limits. The uncompressed sizes are those declared
+// by the archive, which the ZIP reader checks the content of the entries against when they are read.
+func (l ArtifactLimits) check(artifactZip *zip.Reader) error {
+	if l.MaxFiles > 0 && len(artifactZip.File) > l.MaxFiles {
+		return &ArtifactLimitError{Limit: ArtifactLimitFiles, Value: int64(len(artifactZip.File)), Max: int64(l.MaxFiles)}
+	}
+
+	if l.MaxSize > 0 {
+		var size uint64
+		for _, file := range artifactZip.File {
+			size += file.UncompressedSize64
+		}
+		if size > uint64(l.MaxSize) {
+			return &ArtifactLimitError{Limit: ArtifactLimitSize, Value: int64(size), Max: l.MaxSize}
+		}
+	}
+
+	return nil
+}
+
+// checkMinimums returns an ArtifactLimitError if the files to deploy from the artifact fall short of the minimums.
+// Unlike the maximums, which protect against the archive as a whole, it is checked after SourceRoot and PathRewrites
+// are applied, and does not count directory entries.
+func (l ArtifactLimits) checkMinimums(artifactZip *zip.Reader) error {
+	var files int
+	var size uint64
+	for _, file := range artifactZip.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		files++
+		size += file.UncompressedSize64
+	}
+
+	if files < l.MinFiles {
+		return &ArtifactLimitError{Limit: ArtifactLimitFiles, Value: int64(files), Min: int64(l.MinFiles)}
+	}
+	if l.MinSize > 0 && size < uint64(l.MinSize) {
+		return &ArtifactLimitError{Limit: ArtifactLimitSize, Value: int64(size), Min: l.MinSize}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip64Artifact writes an artifact with more entries than fit in a ZIP file without the Zip64 extensions.
+func writeZip64Artifact(t *testing.T, entries int) string {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for i := 0; i < entries; i++ {
+		file, err := writer.Create(fmt.Sprintf("files/%d.txt", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	if err := os.WriteFile(artifactPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return artifactPath
+}
+
+func TestGetDeploymentArtifact_readsZip64Archives(t *testing.T) {
+	const entries = 0xFFFF + 10
+	artifactPath := writeZip64Artifact(t, entries)
+
+	d := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}}
+	artifact, err := d.getDeploymentArtifact(context.Background(), "file://"+artifactPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer artifact.Close()
+
+	if len(artifact.File) != entries {
+		t.Errorf("expected %d files, got %d", entries, len(artifact.File))
+	}
+}
+
+func TestGetDeploymentArtifact_enforcesLimits(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	content := newTestArtifact(t, map[string]string{"index.html": "hello", "about.html": "about"})
+	if err := os.WriteFile(artifactPath, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limits ArtifactLimits
+		limit  ArtifactLimit
+	}{
+		{limits: ArtifactLimits{MaxFiles: 1}, limit: ArtifactLimitFiles},
+		{limits: ArtifactLimits{MaxSize: 9}, limit: ArtifactLimitSize},
+		{limits: ArtifactLimits{MaxFiles: 2, MaxSize: 10}},
+	}
+	for _, test := range tests {
+		d := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}, Limits: test.limits}
+		artifact, err := d.getDeploymentArtifact(context.Background(), "file://"+artifactPath, nil)
+
+		var limitErr *ArtifactLimitError
+		if test.limit == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", test.limits, err)
+				continue
+			}
+			artifact.Close()
+		} else if !errors.As(err, &limitErr) || limitErr.Limit != test.limit {
+			t.Errorf("%+v: expected the %s limit to be exceeded, got %v", test.limits, test.limit, err)
+		}
+	}
+}
+
+func TestGetDeploymentArtifact_enforcesMinimums(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	content := newTestArtifact(t, map[string]string{"build/": "", "build/index.html": "hello", "build/about.html": "about", "README.md": "readme"})
+	if err := os.WriteFile(artifactPath, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limits ArtifactLimits
+		limit  ArtifactLimit
+	}{
+		{limits: ArtifactLimits{MinFiles: 3}, limit: ArtifactLimitFiles},
+		{limits: ArtifactLimits{MinSize: 11}, limit: ArtifactLimitSize},
+		{limits: ArtifactLimits{MinFiles: 2, MinSize: 10}},
+	}
+	for _, test := range tests {
+		d := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}, SourceRoot: "build", Limits: test.limits}
+		artifact, err := d.getDeploymentArtifact(context.Background(), "file://"+artifactPath, nil)
+
+		var limitErr *ArtifactLimitError
+		if test.limit == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", test.limits, err)
+				continue
+			}
+			artifact.Close()
+		} else if !errors.As(err, &limitErr) || limitErr.Limit != test.limit || limitErr.Min == 0 {
+			t.Errorf("%+v: expected the %s minimum to be missed, got %v", test.limits, test.limit, err)
+		}
+	}
+}
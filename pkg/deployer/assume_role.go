@@ -0,0 +1,58 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"sort"
+	"time"
+)
+
+// AssumeRole is an IAM role assumed with the credentials of an AWS configuration, optionally with session tags, so
+// that bucket and key policies using attribute-based access control can authorize the deployment.
+type AssumeRole struct {
+	RoleARN string
+	// SessionName is the name of the role session. Empty means a name generated by the AWS SDK.
+	SessionName string
+	// ExternalID, if set, is the external ID the trust policy of the role requires.
+	ExternalID string
+	// Duration is how long the credentials of the role are valid. Zero means stscreds.DefaultDuration.
+	Duration time.Duration
+	// Tags are the session tags of the role session.
+	Tags map[string]string
+	// TransitiveTagKeys are the keys of the Tags that are kept when the session assumes another role.
+	TransitiveTagKeys []string
+}
+
+// Credentials returns cached credentials of the role, which is assumed with an STS client created from cfg.
+func (r AssumeRole) Credentials(cfg aws.Config) aws.CredentialsProvider {
+	return r.credentials(sts.NewFromConfig(cfg))
+}
+
+func (r AssumeRole) credentials(client stscreds.AssumeRoleAPIClient) aws.CredentialsProvider {
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(client, r.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = r.SessionName
+		o.Duration = r.Duration
+		if r.ExternalID != "" {
+			o.ExternalID = aws.String(r.ExternalID)
+		}
+		o.Tags = r.sessionTags()
+		o.TransitiveTagKeys = r.TransitiveTagKeys
+	}))
+}
+
+// sessionTags returns the Tags sorted by key.
+func (r AssumeRole) sessionTags() []types.Tag {
+	keys := make([]string, 0, len(r.Tags))
+	for key := range r.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tags []types.Tag
+	for _, key := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(r.Tags[key])})
+	}
+	return tags
+}
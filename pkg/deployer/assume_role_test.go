@@ -0,0 +1,51 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeAssumeRoleSTS is an STS client that records the request roles are assumed with.
+type fakeAssumeRoleSTS struct {
+	input *sts.AssumeRoleInput
+}
+
+func (c *fakeAssumeRoleSTS) AssumeRole(ctx context.Context, input *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	c.input = input
+	return &sts.AssumeRoleOutput{Credentials: &types.Credentials{
+		AccessKeyId:     aws.String("AKID"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("session"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestAssumeRole_sendsSessionTags(t *testing.T) {
+	role := AssumeRole{
+		RoleARN:           "arn:aws:iam::123456789012:role/deploy",
+		ExternalID:        "external",
+		Tags:              map[string]string{"team": "web", "environment": "prod"},
+		TransitiveTagKeys: []string{"team"},
+	}
+	client := &fakeAssumeRoleSTS{}
+
+	if _, err := role.credentials(client).Retrieve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+	for _, tag := range client.input.Tags {
+		tags = append(tags, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+	}
+	if !reflect.DeepEqual(tags, []string{"environment=prod", "team=web"}) {
+		t.Errorf("unexpected session tags: %v", tags)
+	}
+	if !reflect.DeepEqual(client.input.TransitiveTagKeys, []string{"team"}) || aws.ToString(client.input.ExternalId) != "external" {
+		t.Errorf("unexpected request: %+v", client.input)
+	}
+}
@@ -0,0 +1,271 @@
+package deployer
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"io"
+	"strings"
+	"time"
+)
+
+// AzureStaticWebsiteContainer is the container Azure Storage serves static websites from.
+const AzureStaticWebsiteContainer = "$web"
+
+// azureContentHashMetadataKey is the metadata name the SHA-256 hash of the content of deployed files is stored under.
+// Metadata names have to be C# identifiers, so ContentHashMetadataKey cannot be used.
+const azureContentHashMetadataKey = "sfd_hash"
+
+// azureStore is a TargetStore for an Azure Blob Storage container.
+type azureStore struct {
+	client *container.Client
+	name   string
+}
+
+// NewAzureBlobTarget returns a TargetStore for a container in the Azure storage account with the given name.
+// Credentials are found the same way as the Azure CLI does, e.g. from the AZURE_CLIENT_ID, AZURE_TENANT_ID and
+// AZURE_CLIENT_SECRET environment variables or a managed identity.
+func (d *Deployer) NewAzureBlobTarget(account string, containerName string) (TargetStore, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The name is the URL of the container without the scheme, so that errors refer to objects by their URL.
+	name := account + ".blob.core.windows.net/" + containerName
+	client, err := container.NewClient("https://"+name, credential, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &azureStore{client: client, name: name}, nil
+}
+
+func (s *azureStore) Name() string {
+	return s.name
+}
+
+// newObjectError wraps an error returned by the Azure client with the context of the failed operation.
+func (s *azureStore) newObjectError(operation string, key string, err error) *ObjectError {
+	return &ObjectError{Operation: operation, Scheme: "https", Bucket: s.name, Key: key, Err: err}
+}
+
+func (s *azureStore) List(ctx context.Context, prefix string) (DeployedFiles, error) {
+	foundFiles := make(DeployedFiles)
+
+	pager := s.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: to.Ptr(prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, s.newObjectError("ListBlobs", "", err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			// Blobs uploaded without an MD5 hash, e.g. by other tools, are always uploaded again.
+			foundFiles[*item.Name] = hex.EncodeToString(item.Properties.ContentMD5)
+		}
+	}
+
+	return foundFiles, nil
+}
+
+func (s *azureStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	props, err := s.client.NewBlobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, s.newObjectError("GetBlobProperties", key, err)
+	}
+
+	return &ObjectInfo{
+		Size: aws.ToInt64(props.ContentLength),
+		Metadata: ObjectMetadata{
+			ContentType:        aws.ToString(props.ContentType),
+			ContentDisposition: aws.ToString(props.ContentDisposition),
+			ContentLanguage:    aws.ToString(props.ContentLanguage),
+			CacheControl:       aws.ToString(props.CacheControl),
+			ContentSHA256:      blobContentHash(props.Metadata),
+		},
+	}, nil
+}
+
+func (s *azureStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, s.newObjectError("GetBlob", key, err)
+	}
+
+	return result.Body, nil
+}
+
+func (s *azureStore) Put(ctx context.Context, input PutInput) error {
+	if input.ObjectLock != nil {
+		return s.newObjectError("PutBlob", input.Key, errObjectLockNotSupported)
+	}
+
+	conditions := &blob.ModifiedAccessConditions{}
+	if input.IfNoneMatch {
+		conditions.IfNoneMatch = to.Ptr(azcore.ETagAny)
+	}
+	if input.IfMatch != "" {
+		// Azure preconditions refer to ETags rather than hashes, so the hash is checked first, and the upload
+		// is made conditional on the ETag of the blob that had it.
+		props, err := s.client.NewBlobClient(input.Key).GetProperties(ctx, nil)
+		if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return s.newObjectError("GetBlobProperties", input.Key, err)
+		}
+		if err != nil || hex.EncodeToString(props.ContentMD5) != input.IfMatch {
+			return &ConflictError{Scheme: "https", Bucket: s.name, Key: input.Key, Err: errors.New("the blob does not have the expected hash")}
+		}
+		conditions.IfMatch = props.ETag
+	}
+
+	headers := blobHTTPHeaders(input.Metadata)
+	if input.MD5 != "" {
+		headers.BlobContentMD5, _ = hex.DecodeString(input.MD5)
+	}
+
+	_, err := s.client.NewBlockBlobClient(input.Key).UploadStream(ctx, input.Body, &blockblob.UploadStreamOptions{
+		HTTPHeaders:      headers,
+		Metadata:         blobMetadata(input.Metadata),
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+	})
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return &ConflictError{Scheme: "https", Bucket: s.name, Key: input.Key, Err: err}
+		}
+		return s.newObjectError("PutBlob", input.Key, err)
+	}
+
+	return nil
+}
+
+// azureCopyPollInterval is how often the status of a blob copy is checked until it has completed.
+const azureCopyPollInterval = time.Second
+
+// Copy starts a server-side copy of the blob, and waits for it to complete. Copies within a storage account usually
+// complete right away.
+func (s *azureStore) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	client := s.client.NewBlobClient(destinationKey)
+	result, err := client.StartCopyFromURL(ctx, s.client.NewBlobClient(sourceKey).URL(), nil)
+	if err != nil {
+		return s.newObjectError("CopyBlob", destinationKey, err)
+	}
+
+	status := result.CopyStatus
+	for status != nil && *status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return s.newObjectError("CopyBlob", destinationKey, ctx.Err())
+		case <-time.After(azureCopyPollInterval):
+		}
+
+		props, err := client.GetProperties(ctx, nil)
+		if err != nil {
+			return s.newObjectError("GetBlobProperties", destinationKey, err)
+		}
+		status = props.CopyStatus
+	}
+	if status != nil && *status != blob.CopyStatusTypeSuccess {
+		return s.newObjectError("CopyBlob", destinationKey, fmt.Errorf("copy %s", *status))
+	}
+
+	return nil
+}
+
+// UpdateMetadata replaces the properties of the blob. Azure replaces all of them at once, so the MD5 hash
+// of the blob is read first and kept.
+func (s *azureStore) UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error {
+	if lock != nil {
+		return s.newObjectError("SetBlobProperties", key, errObjectLockNotSupported)
+	}
+
+	client := s.client.NewBlobClient(key)
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return s.newObjectError("GetBlobProperties", key, err)
+	}
+
+	headers := blobHTTPHeaders(metadata)
+	headers.BlobContentMD5 = props.ContentMD5
+	updated, err := client.SetHTTPHeaders(ctx, *headers, &blob.SetHTTPHeadersOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: props.ETag}},
+	})
+	if err != nil {
+		return s.newObjectError("SetBlobProperties", key, err)
+	}
+
+	// Blobs deployed before the content hash was stored get it added, keeping their other metadata.
+	if metadata.ContentSHA256 == "" || blobContentHash(props.Metadata) == metadata.ContentSHA256 {
+		return nil
+	}
+	blobMetadata := make(map[string]*string, len(props.Metadata)+1)
+	for name, value := range props.Metadata {
+		if !strings.EqualFold(name, azureContentHashMetadataKey) {
+			blobMetadata[name] = value
+		}
+	}
+	blobMetadata[azureContentHashMetadataKey] = to.Ptr(metadata.ContentSHA256)
+	_, err = client.SetMetadata(ctx, blobMetadata, &blob.SetMetadataOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: updated.ETag}},
+	})
+	if err != nil {
+		return s.newObjectError("SetBlobMetadata", key, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the blobs one at a time.
+func (s *azureStore) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		_, err := s.client.NewBlobClient(key).Delete(ctx, nil)
+		if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return s.newObjectError("DeleteBlob", key, err)
+		}
+	}
+
+	return nil
+}
+
+// blobHTTPHeaders returns the blob properties for the given metadata.
+func blobHTTPHeaders(metadata ObjectMetadata) *blob.HTTPHeaders {
+	return &blob.HTTPHeaders{
+		BlobContentType:        optionalString(metadata.ContentType),
+		BlobContentDisposition: optionalString(metadata.ContentDisposition),
+		BlobContentLanguage:    optionalString(metadata.ContentLanguage),
+		BlobCacheControl:       optionalString(metadata.CacheControl),
+	}
+}
+
+// blobMetadata returns the blob metadata for the given metadata, or nil if there is none.
+func blobMetadata(metadata ObjectMetadata) map[string]*string {
+	if metadata.ContentSHA256 == "" {
+		return nil
+	}
+	return map[string]*string{azureContentHashMetadataKey: to.Ptr(metadata.ContentSHA256)}
+}
+
+// blobContentHash returns the content hash stored in the given blob metadata, or an empty string if there is none.
+// The names of blob metadata are case-insensitive.
+func blobContentHash(metadata map[string]*string) string {
+	for name, value := range metadata {
+		if strings.EqualFold(name, azureContentHashMetadataKey) {
+			return aws.ToString(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,356 @@
+package deployer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// azurePageSize is the number of blobs per page of the listings of fakeAzureContainer.
+const azurePageSize = 2
+
+// azureBlob is a blob of fakeAzureContainer.
+type azureBlob struct {
+	body       string
+	etag       string
+	contentMD5 string
+	headers    http.Header
+	metadata   map[string]string
+}
+
+// fakeAzureContainer implements the parts of the Azure Blob Storage REST API used by azureStore for a single
+// container, keeping blobs in memory. Listings return azurePageSize blobs per page.
+type fakeAzureContainer struct {
+	name string
+
+	mu     sync.Mutex
+	blobs  map[string]*azureBlob
+	blocks map[string][]byte
+	etags  int
+	pages  int
+}
+
+// newAzureTestStore returns an azureStore for a new fakeAzureContainer with the given name.
+func newAzureTestStore(t *testing.T, containerName string) (*azureStore, *fakeAzureContainer) {
+	t.Helper()
+
+	fake := &fakeAzureContainer{name: containerName, blobs: map[string]*azureBlob{}, blocks: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := container.NewClientWithNoCredential(server.URL+"/"+containerName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &azureStore{client: client, name: strings.TrimPrefix(server.URL, "http://") + "/" + containerName}, fake
+}
+
+// blobHeaders are the blob properties set by the x-ms-blob-* headers of uploads, and returned as the headers without
+// the x-ms-blob- prefix.
+var blobHeaders = []string{"Content-Type", "Content-Disposition", "Content-Language", "Cache-Control"}
+
+func (f *fakeAzureContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch key := strings.TrimPrefix(r.URL.Path, "/"+f.name+"/"); {
+	case r.Method == http.MethodGet && r.URL.Path == "/"+f.name && query.Get("comp") == "list":
+		f.list(w, query)
+	case key == r.URL.Path:
+		writeAzureError(w, http.StatusNotFound, "ContainerNotFound")
+	case r.Method == http.MethodHead:
+		blob, found := f.blobs[key]
+		if !found {
+			writeAzureError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		for name, values := range blob.headers {
+			w.Header()[name] = values
+		}
+		for name, value := range blob.metadata {
+			w.Header().Set("x-ms-meta-"+name, value)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob.body)))
+		w.Header().Set("Content-MD5", blob.contentMD5)
+		w.Header().Set("ETag", blob.etag)
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeAzureError(w, http.StatusBadRequest, "InvalidInput")
+			return
+		}
+		f.blocks[key+"/"+query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		f.commit(w, r, key)
+	case r.Method == http.MethodPut && query.Get("comp") == "" && r.Header.Get("x-ms-blob-type") == "BlockBlob":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeAzureError(w, http.StatusBadRequest, "InvalidInput")
+			return
+		}
+		// Unlike blobs committed from blocks, blobs uploaded at once get an MD5 hash if none is given.
+		if r.Header.Get("x-ms-blob-content-md5") == "" {
+			sum := md5.Sum(body)
+			r.Header.Set("x-ms-blob-content-md5", base64.StdEncoding.EncodeToString(sum[:]))
+		}
+		f.put(w, r, key, string(body))
+	case r.Method == http.MethodDelete:
+		if _, found := f.blobs[key]; !found {
+			writeAzureError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(f.blobs, key)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		writeAzureError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// azureBlobList is the body of a List Blobs response.
+type azureBlobList struct {
+	XMLName    xml.Name        `xml:"EnumerationResults"`
+	Blobs      []azureBlobItem `xml:"Blobs>Blob"`
+	NextMarker string          `xml:"NextMarker"`
+}
+
+type azureBlobItem struct {
+	Name          string `xml:"Name"`
+	ContentLength int    `xml:"Properties>Content-Length"`
+	ContentMD5    string `xml:"Properties>Content-MD5,omitempty"`
+	ETag          string `xml:"Properties>Etag"`
+	BlobType      string `xml:"Properties>BlobType"`
+}
+
+func (f *fakeAzureContainer) list(w http.ResponseWriter, query map[string][]string) {
+	f.pages++
+	var names []string
+	prefix := firstValue(query["prefix"])
+	for name := range f.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// The marker is the index of the first blob of the page.
+	start, _ := strconv.Atoi(firstValue(query["marker"]))
+	page := azureBlobList{}
+	for i := start; i < len(names) && i < start+azurePageSize; i++ {
+		blob := f.blobs[names[i]]
+		page.Blobs = append(page.Blobs, azureBlobItem{Name: names[i], ContentLength: len(blob.body), ContentMD5: blob.contentMD5, ETag: blob.etag, BlobType: "BlockBlob"})
+	}
+	if start+azurePageSize < len(names) {
+		page.NextMarker = strconv.Itoa(start + azurePageSize)
+	}
+	body, _ := xml.Marshal(page)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append([]byte(xml.Header), body...))
+}
+
+// commit creates the blob from the staged blocks in the block list of the request.
+func (f *fakeAzureContainer) commit(w http.ResponseWriter, r *http.Request, key string) {
+	var blockList struct {
+		Latest []string `xml:"Latest"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&blockList); err != nil {
+		writeAzureError(w, http.StatusBadRequest, "InvalidXmlDocument")
+		return
+	}
+
+	var body strings.Builder
+	for _, id := range blockList.Latest {
+		block, found := f.blocks[key+"/"+id]
+		if !found {
+			writeAzureError(w, http.StatusBadRequest, "InvalidBlockList")
+			return
+		}
+		body.Write(block)
+	}
+	f.put(w, r, key, body.String())
+}
+
+// put stores the blob with the properties and metadata in the headers of the request, if its conditions hold. Like
+// Azure, it does not check the MD5 hash given for the blob, but stores it with the blob.
+func (f *fakeAzureContainer) put(w http.ResponseWriter, r *http.Request, key string, body string) {
+	existing, exists := f.blobs[key]
+	if r.Header.Get("If-None-Match") == "*" && exists {
+		writeAzureError(w, http.StatusConflict, "BlobAlreadyExists")
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (!exists || ifMatch != existing.etag) {
+		writeAzureError(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return
+	}
+
+	f.etags++
+	blob := &azureBlob{
+		body:       body,
+		etag:       fmt.Sprintf(`"0x%d"`, f.etags),
+		contentMD5: r.Header.Get("x-ms-blob-content-md5"),
+		headers:    http.Header{},
+		metadata:   map[string]string{},
+	}
+	for _, name := range blobHeaders {
+		if value := r.Header.Get("x-ms-blob-" + name); value != "" {
+			blob.headers.Set(name, value)
+		}
+	}
+	for name, values := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-meta-") {
+			blob.metadata[strings.ToLower(strings.TrimPrefix(strings.ToLower(name), "x-ms-meta-"))] = values[0]
+		}
+	}
+	f.blobs[key] = blob
+	w.Header().Set("ETag", blob.etag)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func writeAzureError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, code)
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// helloMD5 is the hex encoded MD5 hash of "hello".
+const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+
+func TestAzureStore_list(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newAzureTestStore(t, "$web")
+	for _, key := range []string{"site/index.html", "site/app.js", "site/style.css", "other/index.html"} {
+		if err := store.Put(ctx, PutInput{Key: key, Body: strings.NewReader("hello"), MD5: helloMD5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := store.List(ctx, "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DeployedFiles{"site/app.js": helloMD5, "site/index.html": helloMD5, "site/style.css": helloMD5}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+	if fake.pages != 2 {
+		t.Errorf("expected the blobs to be listed in 2 pages, got %d", fake.pages)
+	}
+}
+
+func TestAzureStore_putAndHead(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newAzureTestStore(t, "$web")
+
+	metadata := ObjectMetadata{ContentType: "text/html; charset=utf-8", ContentLanguage: "nb", CacheControl: "no-cache", ContentSHA256: "2cf24dba"}
+	if err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("hello"), Metadata: metadata, MD5: helloMD5}); err != nil {
+		t.Fatal(err)
+	}
+	blob := fake.blobs["index.html"]
+	if blob == nil || blob.body != "hello" || blob.contentMD5 != "XUFAKrxLKna5cZ2REBfFkg==" {
+		t.Errorf("expected the content and its MD5 hash to be uploaded, got %+v", blob)
+	}
+	if blob != nil && blob.metadata[azureContentHashMetadataKey] != "2cf24dba" {
+		t.Errorf("expected the content hash to be stored as %s, got %v", azureContentHashMetadataKey, blob.metadata)
+	}
+
+	info, err := store.Head(ctx, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Size != 5 || info.Metadata != metadata {
+		t.Errorf("expected the size and metadata of the upload, got %+v", info)
+	}
+	if info, err := store.Head(ctx, "missing.html"); info != nil || err != nil {
+		t.Errorf("expected a missing blob to be reported as nil, got %+v, %v", info, err)
+	}
+
+	// Files larger than a block are uploaded in blocks, and committed with the metadata.
+	large := strings.Repeat("a", 3<<20/2)
+	if err := store.Put(ctx, PutInput{Key: "app.js", Body: strings.NewReader(large), Metadata: metadata}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := store.Head(ctx, "app.js"); err != nil || info == nil || info.Size != int64(len(large)) || info.Metadata != metadata {
+		t.Errorf("expected the blocks to be committed with the metadata, got %+v, %v", info, err)
+	}
+}
+
+func TestAzureStore_putConditions(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newAzureTestStore(t, "$web")
+	if err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("hello"), MD5: helloMD5}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, input := range map[string]PutInput{
+		"if none match": {Key: "index.html", Body: strings.NewReader("new"), IfNoneMatch: true},
+		"if match":      {Key: "index.html", Body: strings.NewReader("new"), IfMatch: "0123456789abcdef0123456789abcdef"},
+		"missing":       {Key: "missing.html", Body: strings.NewReader("new"), IfMatch: helloMD5},
+	} {
+		var conflictErr *ConflictError
+		if err := store.Put(ctx, input); !errors.As(err, &conflictErr) {
+			t.Errorf("%s: expected a ConflictError, got %v", name, err)
+		}
+	}
+	if fake.blobs["index.html"].body != "hello" {
+		t.Errorf("expected the blob to be unchanged, got %q", fake.blobs["index.html"].body)
+	}
+
+	err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("new"), IfMatch: helloMD5})
+	if err != nil || fake.blobs["index.html"].body != "new" {
+		t.Errorf("expected an upload matching the hash of the blob to replace it, got %q, %v", fake.blobs["index.html"].body, err)
+	}
+}
+
+func TestAzureStore_delete(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newAzureTestStore(t, "$web")
+	for _, key := range []string{"index.html", "app.js", "upload.png"} {
+		if err := store.Put(ctx, PutInput{Key: key, Body: strings.NewReader(key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Blobs that were already deleted are skipped.
+	if err := store.Delete(ctx, []string{"index.html", "missing.html", "app.js"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, kept := fake.blobs["upload.png"]; len(fake.blobs) != 1 || !kept {
+		t.Errorf("expected only upload.png to be kept, got %v", fake.blobs)
+	}
+
+	// Only missing blobs are skipped, not a missing container.
+	missing, err := container.NewClientWithNoCredential(strings.TrimSuffix(store.client.URL(), "$web")+"missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.client = missing
+	var objectErr *ObjectError
+	if err := store.Delete(ctx, []string{"index.html"}); !errors.As(err, &objectErr) || objectErr.Operation != "DeleteBlob" || objectErr.Key != "index.html" {
+		t.Errorf("expected an ObjectError for a missing container, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/s3control/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BatchPrefix is the prefix the manifest and the completion report of the S3 Batch Operations job of a deployment
+// are written under in the staging bucket, followed by the deployment ID.
+const BatchPrefix = ".staticfiledeploy-batch/"
+
+// batchJobPollInterval is how long to wait between checking the status of a job. It is replaced in tests.
+var batchJobPollInterval = 10 * time.Second
+
+// BatchOperations configures deployments that promote their staged files with an S3 Batch Operations job, rather
+// than by copying every file from the provider. The files are uploaded to the staging bucket under the keys they
+// are deployed to, and one job copies all of them to the target.
+type BatchOperations struct {
+	// AccountID is the ID of the AWS account the job is created in. It defaults to the account of the caller.
+	AccountID string
+	// Region is the region the job is created in, that of the target bucket. It defaults to the configured region.
+	Region string
+	// RoleArn is the ARN of the IAM role S3 assumes to read from the staging bucket and write to the target.
+	RoleArn string
+	// StagingBucket is the name of the S3 bucket the files are staged in, and Staging the store for it.
+	StagingBucket string
+	Staging       TargetStore
+}
+
+// batchJobClient is the part of the S3 Control API used to run jobs. It is replaced in tests.
+type batchJobClient interface {
+	CreateJob(ctx context.Context, params *s3control.CreateJobInput, optFns ...func(*s3control.Options)) (*s3control.CreateJobOutput, error)
+	DescribeJob(ctx context.Context, params *s3control.DescribeJobInput, optFns ...func(*s3control.Options)) (*s3control.DescribeJobOutput, error)
+}
+
+// BatchJobError is returned when the S3 Batch Operations job of a deployment did not copy all files.
+type BatchJobError struct {
+	JobID  string
+	Status string
+	// Failed is the number of files that could not be copied.
+	Failed int64
+	// Reasons are the reasons S3 gave for the job to fail, if any.
+	Reasons []string
+	// ReportPrefix is where the report of the failed copies is written in the staging bucket.
+	ReportPrefix string
+}
+
+func (e *BatchJobError) Error() string {
+	message := fmt.Sprintf("S3 Batch Operations job %s ended with status %s and %d failed file(s)", e.JobID, e.Status, e.Failed)
+	if len(e.Reasons) > 0 {
+		message += ": " + strings.Join(e.Reasons, "; ")
+	}
+	if e.Failed > 0 {
+		message += fmt.Sprintf(". The failed files are listed in the report under %s", e.ReportPrefix)
+	}
+	return message
+}
+
+// stagingStore returns the store files are staged in.
+func (d *Deployment) stagingStore() TargetStore {
+	if d.BatchOperations != nil {
+		return d.BatchOperations.Staging
+	}
+	return d.target
+}
+
+// batchPrefix returns the prefix the manifest and report of the job of the deployment are written under.
+func (d *Deployment) batchPrefix() string {
+	return BatchPrefix + d.ID + "/"
+}
+
+func (d *Deployment) newBatchJobClient() batchJobClient {
+	if d.batchClient != nil {
+		return d.batchClient
+	}
+	return s3control.NewFromConfig(d.awsConfig, func(o *s3control.Options) {
+		if d.BatchOperations.Region != "" {
+			o.Region = d.BatchOperations.Region
+		}
+	})
+}
+
+// batchAccountID returns the ID of the account the job is created in.
+func (d *Deployment) batchAccountID(ctx context.Context) (string, error) {
+	if d.BatchOperations.AccountID != "" {
+		return d.BatchOperations.AccountID, nil
+	}
+	identity, err := sts.NewFromConfig(d.awsConfig).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the account to create the S3 Batch Operations job in: %w", err)
+	}
+	d.BatchOperations.AccountID = aws.ToString(identity.Account)
+	return d.BatchOperations.AccountID, nil
+}
+
+// uploadBatchManifest writes the manifest of the files to copy to the staging bucket, and returns its ETag.
+func (d *Deployment) uploadBatchManifest(ctx context.Context) (string, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	for _, staged := range d.staged {
+		// Keys are URL-encoded in manifests.
+		err := writer.Write([]string{d.BatchOperations.StagingBucket, strings.ReplaceAll(url.QueryEscape(staged.key), "+", "%20")})
+		if err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+
+	manifestKey := d.batchPrefix() + "manifest.csv"
+	err := d.BatchOperations.Staging.Put(ctx, PutInput{
+		Key:      manifestKey,
+		Body:     bytes.NewReader(buf.Bytes()),
+		Size:     int64(buf.Len()),
+		Metadata: ObjectMetadata{ContentType: "text/csv"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	listed, err := d.BatchOperations.Staging.List(ctx, manifestKey)
+	if err != nil {
+		return "", err
+	}
+	etag, found := listed[manifestKey]
+	if !found {
+		return "", fmt.Errorf("the S3 Batch Operations manifest %s was not found after it was written", manifestKey)
+	}
+	return etag, nil
+}
+
+// promoteWithBatchJob copies the staged files from the staging bucket to the target with an S3 Batch Operations
+// job, waits for it to complete, and then deletes the staged files.
+func (d *Deployment) promoteWithBatchJob(ctx context.Context) error {
+	if len(d.staged) == 0 {
+		return nil
+	}
+
+	for _, staged := range d.staged {
+		err := d.recordWrite(ctx, staged.key)
+		if err != nil {
+			return err
+		}
+	}
+
+	accountID, err := d.batchAccountID(ctx)
+	if err != nil {
+		return err
+	}
+	manifestETag, err := d.uploadBatchManifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	batch := d.BatchOperations
+	client := d.newBatchJobClient()
+	created, err := client.CreateJob(ctx, &s3control.CreateJobInput{
+		AccountId:            aws.String(accountID),
+		ClientRequestToken:   aws.String(d.ID),
+		ConfirmationRequired: aws.Bool(false),
+		Description:          aws.String(fmt.Sprintf("Deployment %s to %s", d.ID, d.TargetBucket)),
+		Priority:             aws.Int32(10),
+		RoleArn:              aws.String(batch.RoleArn),
+		Operation: &types.JobOperation{
+			S3PutObjectCopy: &types.S3CopyObjectOperation{
+				TargetResource:    aws.String("arn:aws:s3:::" + d.TargetBucket),
+				MetadataDirective: types.S3MetadataDirectiveCopy,
+			},
+		},
+		Manifest: &types.JobManifest{
+			Spec: &types.JobManifestSpec{
+				Format: types.JobManifestFormatS3BatchOperationsCsv20180820,
+				Fields: []types.JobManifestFieldName{types.JobManifestFieldNameBucket, types.JobManifestFieldNameKey},
+			},
+			Location: &types.JobManifestLocation{
+				ObjectArn: aws.String("arn:aws:s3:::" + batch.StagingBucket + "/" + d.batchPrefix() + "manifest.csv"),
+				ETag:      aws.String(manifestETag),
+			},
+		},
+		Report: &types.JobReport{
+			Enabled:     true,
+			Bucket:      aws.String("arn:aws:s3:::" + batch.StagingBucket),
+			Prefix:      aws.String(strings.TrimSuffix(d.batchPrefix(), "/")),
+			Format:      types.JobReportFormatReportCsv20180820,
+			ReportScope: types.JobReportScopeFailedTasksOnly,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create S3 Batch Operations job: %w", err)
+	}
+	jobID := aws.ToString(created.JobId)
+
+	tflog.Info(ctx, "Created S3 Batch Operations job", map[string]interface{}{
+		"job_id": jobID,
+		"files":  len(d.staged),
+	})
+
+	err = d.waitForBatchJob(ctx, client, jobID)
+	if err != nil {
+		return err
+	}
+
+	// The report is only written if files failed, and is kept with it.
+	err = deleteKeysFrom(context.WithoutCancel(ctx), batch.Staging, []string{d.batchPrefix() + "manifest.csv"})
+	if err != nil {
+		return err
+	}
+	return d.deleteStagedFiles(ctx)
+}
+
+// waitForBatchJob polls the job until it has ended, and returns a BatchJobError unless it copied every file.
+func (d *Deployment) waitForBatchJob(ctx context.Context, client batchJobClient, jobID string) error {
+	for {
+		described, err := client.DescribeJob(ctx, &s3control.DescribeJobInput{
+			AccountId: aws.String(d.BatchOperations.AccountID),
+			JobId:     aws.String(jobID),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe S3 Batch Operations job %s: %w", jobID, err)
+		}
+
+		job := described.Job
+		var succeeded, failed int64
+		if job.ProgressSummary != nil {
+			succeeded = aws.ToInt64(job.ProgressSummary.NumberOfTasksSucceeded)
+			failed = aws.ToInt64(job.ProgressSummary.NumberOfTasksFailed)
+		}
+
+		switch job.Status {
+		case types.JobStatusComplete, types.JobStatusFailed, types.JobStatusCancelled, types.JobStatusSuspended:
+			tflog.Info(ctx, "S3 Batch Operations job ended", map[string]interface{}{
+				"job_id":          jobID,
+				"status":          string(job.Status),
+				"files_succeeded": succeeded,
+				"files_failed":    failed,
+			})
+			if job.Status == types.JobStatusComplete && failed == 0 {
+				return nil
+			}
+
+			jobErr := &BatchJobError{JobID: jobID, Status: string(job.Status), Failed: failed, ReportPrefix: d.batchPrefix()}
+			for _, failure := range job.FailureReasons {
+				jobErr.Reasons = append(jobErr.Reasons, aws.ToString(failure.FailureReason))
+			}
+			return jobErr
+		}
+
+		tflog.Debug(ctx, "Waiting for S3 Batch Operations job", map[string]interface{}{
+			"job_id":          jobID,
+			"status":          string(job.Status),
+			"files_succeeded": succeeded,
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(batchJobPollInterval):
+		}
+	}
+}
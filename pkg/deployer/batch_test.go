@@ -0,0 +1,116 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/s3control/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBatchJobClient runs jobs by copying the objects in their manifest from the staging bucket to the target
+// bucket of client. Jobs report failed as the number of failed tasks.
+type fakeBatchJobClient struct {
+	client   *s3fake.Client
+	failed   int64
+	manifest string
+}
+
+func (c *fakeBatchJobClient) CreateJob(ctx context.Context, params *s3control.CreateJobInput, optFns ...func(*s3control.Options)) (*s3control.CreateJobOutput, error) {
+	manifestKey := strings.TrimPrefix(aws.ToString(params.Manifest.Location.ObjectArn), "arn:aws:s3:::staging/")
+	c.manifest = string(c.client.Object("staging", manifestKey).Body)
+	if c.failed == 0 {
+		for _, line := range strings.Split(strings.TrimSpace(c.manifest), "\n") {
+			key := strings.SplitN(line, ",", 2)[1]
+			_, err := c.client.CopyObject(ctx, &s3.CopyObjectInput{
+				Bucket:     aws.String("target"),
+				Key:        aws.String(key),
+				CopySource: aws.String("staging/" + key),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &s3control.CreateJobOutput{JobId: aws.String("job")}, nil
+}
+
+func (c *fakeBatchJobClient) DescribeJob(ctx context.Context, params *s3control.DescribeJobInput, optFns ...func(*s3control.Options)) (*s3control.DescribeJobOutput, error) {
+	return &s3control.DescribeJobOutput{Job: &types.JobDescriptor{
+		Status:          types.JobStatusComplete,
+		ProgressSummary: &types.JobProgressSummary{NumberOfTasksFailed: aws.Int64(c.failed)},
+	}}, nil
+}
+
+func TestDeploy_batchOperations(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	jobs := &fakeBatchJobClient{client: client}
+	d.BatchOperations = &BatchOperations{AccountID: "123456789012", StagingBucket: "staging", Staging: NewS3Store(client, "staging")}
+	d.batchClient = jobs
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if jobs.manifest != "staging,index.html\nstaging,about.html\n" && jobs.manifest != "staging,about.html\nstaging,index.html\n" {
+		t.Errorf("unexpected manifest %q", jobs.manifest)
+	}
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "new", "about.html": "about"}) {
+		t.Errorf("expected the files to be copied by the job, got %q", objects)
+	}
+	if keys := client.Keys("staging"); len(keys) != 0 {
+		t.Errorf("expected the staged files and the manifest to be deleted, got %v", keys)
+	}
+}
+
+func TestDeploy_batchOperationsWithoutConditionalWrites(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.ConditionalWrites = true
+	staging := &recordingS3API{Client: client}
+	d.BatchOperations = &BatchOperations{AccountID: "123456789012", StagingBucket: "staging", Staging: NewS3Store(staging, "staging")}
+	d.batchClient = &fakeBatchJobClient{client: client}
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, put := range staging.puts {
+		if put.IfMatch != nil || put.IfNoneMatch != nil {
+			t.Errorf("expected no conditions to be sent to the staging bucket, got a conditional upload of %s", aws.ToString(put.Key))
+		}
+	}
+}
+
+func TestDeploy_batchOperationsFailedTasks(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.BatchOperations = &BatchOperations{AccountID: "123456789012", StagingBucket: "staging", Staging: NewS3Store(client, "staging")}
+	d.batchClient = &fakeBatchJobClient{client: client, failed: 1}
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	var jobErr *BatchJobError
+	if !errors.As(err, &jobErr) || jobErr.Failed != 1 {
+		t.Fatalf("expected a BatchJobError with one failed file, got %v", err)
+	}
+
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "old"}) {
+		t.Errorf("expected the target to be unchanged, got %q", objects)
+	}
+	for _, key := range client.Keys("staging") {
+		if !strings.HasPrefix(key, BatchPrefix) {
+			t.Errorf("expected the staged files of the failed deployment to be deleted, found %s", key)
+		}
+	}
+}
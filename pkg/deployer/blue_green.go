@@ -0,0 +1,128 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"time"
+)
+
+const (
+	// DefaultReleasePrefix is the prefix releases are uploaded under unless another one is configured.
+	DefaultReleasePrefix = "releases/"
+	// DefaultReleasePointerKey is the key the release pointer is written to unless another one is configured.
+	DefaultReleasePointerKey = "current-release.json"
+)
+
+// BlueGreen configures blue/green deployments, where every deployment is uploaded to its own release prefix
+// and goes live in a single step when the pointer object is updated to refer to it.
+type BlueGreen struct {
+	// ReleasePrefix is the prefix each release is uploaded under, followed by the deployment ID.
+	ReleasePrefix string
+	// PointerKey is the key of the JSON object that refers to the live release.
+	PointerKey string
+}
+
+type releasePointerContent struct {
+	Prefix       string    `json:"prefix"`
+	DeploymentID string    `json:"deployment_id"`
+	ReleasedAt   time.Time `json:"released_at"`
+}
+
+// keyPrefix returns the prefix all objects of this deployment are uploaded under.
+func (d *Deployment) keyPrefix() string {
+	if d.BlueGreen == nil {
+		return d.TargetPrefix
+	}
+	return d.TargetPrefix + d.BlueGreen.ReleasePrefix + d.ID + "/"
+}
+
+// pointerKey returns the key of the release pointer, which is not part of any release.
+func (d *Deployment) pointerKey() string {
+	return d.TargetPrefix + d.BlueGreen.PointerKey
+}
+
+// objectKey returns the key in the target bucket for the artifact file with the given name.
+func (d *Deployment) objectKey(name string) string {
+	return d.keyPrefix() + name
+}
+
+// uploadReleasePointer makes the release of this deployment the live one.
+func (d *Deployment) uploadReleasePointer(ctx context.Context) error {
+	content, err := json.MarshalIndent(releasePointerContent{
+		Prefix:       d.keyPrefix(),
+		DeploymentID: d.ID,
+		ReleasedAt:   time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode release pointer: %w", err)
+	}
+
+	err = d.recordWrite(ctx, d.pointerKey())
+	if err != nil {
+		return err
+	}
+	err = d.target.Put(ctx, PutInput{
+		Key:  d.pointerKey(),
+		Body: bytes.NewReader(content),
+		Size: int64(len(content)),
+		Metadata: ObjectMetadata{
+			ContentType: "application/json",
+			// The pointer decides which release is served, so it must never be cached.
+			CacheControl: "no-cache",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Switched to new release", map[string]interface{}{
+		"prefix": d.keyPrefix(),
+	})
+
+	return nil
+}
+
+// LiveRelease returns the ID of the deployment whose release the pointer refers to, or an empty string if no
+// release has been made live yet.
+func (d *Deployment) LiveRelease(ctx context.Context) (string, error) {
+	if d.BlueGreen == nil {
+		return "", errors.New("the deployment is not a blue/green deployment")
+	}
+	body, err := d.target.Get(ctx, d.pointerKey())
+	if err != nil || body == nil {
+		return "", err
+	}
+	defer body.Close()
+
+	var content releasePointerContent
+	err = json.NewDecoder(body).Decode(&content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode release pointer %s: %w", d.pointerKey(), err)
+	}
+
+	return content.DeploymentID, nil
+}
+
+// SwitchRelease makes the release of the deployment with the given ID the live one, e.g. to roll back to the
+// release of an earlier deployment. Nothing is uploaded but the release pointer, and it fails if the release does
+// not exist in the target.
+func (d *Deployment) SwitchRelease(ctx context.Context, deploymentID string) error {
+	if d.BlueGreen == nil {
+		return errors.New("the deployment is not a blue/green deployment")
+	}
+	d.ID = deploymentID
+
+	files, err := d.target.List(ctx, d.keyPrefix())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("there is no release %s in %s", d.keyPrefix(), d.TargetBucket)
+	}
+
+	return d.uploadReleasePointer(ctx)
+}
@@ -0,0 +1,60 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestObjectKey_withoutBlueGreen(t *testing.T) {
+	d := &Deployment{ID: "abc123"}
+
+	if got := d.objectKey("index.html"); got != "index.html" {
+		t.Errorf("unexpected key: %s", got)
+	}
+}
+
+func TestObjectKey_withBlueGreen(t *testing.T) {
+	d := &Deployment{ID: "abc123", BlueGreen: &BlueGreen{ReleasePrefix: "releases/", PointerKey: "current-release.json"}}
+
+	if got := d.objectKey("assets/app.js"); got != "releases/abc123/assets/app.js" {
+		t.Errorf("unexpected key: %s", got)
+	}
+}
+
+func TestObjectKey_withTargetPrefix(t *testing.T) {
+	d := &Deployment{ID: "abc123", TargetPrefix: "site/", BlueGreen: &BlueGreen{ReleasePrefix: "releases/", PointerKey: "current-release.json"}}
+
+	if got := d.objectKey("index.html"); got != "site/releases/abc123/index.html" {
+		t.Errorf("unexpected key: %s", got)
+	}
+	if got := d.pointerKey(); got != "site/current-release.json" {
+		t.Errorf("unexpected pointer key: %s", got)
+	}
+}
+
+func TestSwitchRelease(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"releases/previous/index.html": "previous"})
+	d, _ := newTestDeployment(t, client, nil)
+	d.ID = "current"
+	d.BlueGreen = &BlueGreen{ReleasePrefix: "releases/", PointerKey: "current-release.json"}
+
+	if live, err := d.LiveRelease(ctx); err != nil || live != "" {
+		t.Fatalf("expected no live release, got %q, %v", live, err)
+	}
+	if err := d.SwitchRelease(ctx, "previous"); err != nil {
+		t.Fatal(err)
+	}
+	if live, err := d.LiveRelease(ctx); err != nil || live != "previous" {
+		t.Errorf("expected the previous release to be live, got %q, %v", live, err)
+	}
+
+	if err := d.SwitchRelease(ctx, "missing"); err == nil {
+		t.Error("expected switching to a missing release to fail")
+	}
+	if live, _ := d.LiveRelease(ctx); live != "previous" {
+		t.Errorf("expected the previous release to stay live, got %q", live)
+	}
+}
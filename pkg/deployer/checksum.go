@@ -0,0 +1,73 @@
+package deployer
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ChecksumMismatchError is returned when the downloaded artifact does not match the expected checksum.
+type ChecksumMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("artifact checksum mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// checksumHashes are the supported checksum algorithms, keyed by the prefix used in checksum strings.
+var checksumHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// ValidateChecksum checks that a checksum is in the format "<algorithm>:<hex digest>" with a supported algorithm.
+func ValidateChecksum(checksum string) error {
+	_, _, _, err := parseChecksum(checksum)
+	return err
+}
+
+// parseChecksum returns the lower case algorithm and the hash function of a checksum, along with its digest.
+func parseChecksum(checksum string) (string, func() hash.Hash, string, error) {
+	algorithm, digest, found := strings.Cut(checksum, ":")
+	if !found {
+		return "", nil, "", fmt.Errorf("invalid checksum %q: expected format <algorithm>:<hex digest>", checksum)
+	}
+
+	algorithm = strings.ToLower(algorithm)
+	newHash, ok := checksumHashes[algorithm]
+	if !ok {
+		return "", nil, "", fmt.Errorf("invalid checksum %q: unsupported algorithm %q", checksum, algorithm)
+	}
+
+	return algorithm, newHash, digest, nil
+}
+
+// verifyChecksum checks content against an expected checksum in the format "<algorithm>:<hex digest>".
+func verifyChecksum(content io.Reader, expected string) error {
+	algorithm, newHash, digest, err := parseChecksum(expected)
+	if err != nil {
+		return err
+	}
+
+	hasher := newHash()
+	_, err = io.Copy(hasher, content)
+	if err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(hasher.Sum(nil))
+
+	if !strings.EqualFold(actual, digest) {
+		return &ChecksumMismatchError{
+			Expected: expected,
+			Actual:   algorithm + ":" + actual,
+		}
+	}
+
+	return nil
+}
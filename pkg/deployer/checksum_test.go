@@ -0,0 +1,41 @@
+package deployer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sha256 of "hello"
+const helloSHA256 = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestVerifyChecksum_matches(t *testing.T) {
+	if err := verifyChecksum(strings.NewReader("hello"), helloSHA256); err != nil {
+		t.Errorf("expected checksum to match, got %s", err)
+	}
+}
+
+func TestVerifyChecksum_mismatch(t *testing.T) {
+	err := verifyChecksum(strings.NewReader("hello!"), helloSHA256)
+
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a ChecksumMismatchError, got %v", err)
+	}
+	if mismatch.Expected != helloSHA256 {
+		t.Errorf("unexpected expected checksum: %s", mismatch.Expected)
+	}
+}
+
+func TestVerifyChecksum_invalidFormat(t *testing.T) {
+	for _, checksum := range []string{"2cf24dba", "md5:5d41402abc4b2a76b9719d911017c592"} {
+		err := verifyChecksum(strings.NewReader("hello"), checksum)
+		if err == nil {
+			t.Errorf("expected an error for %q", checksum)
+		}
+		var mismatch *ChecksumMismatchError
+		if errors.As(err, &mismatch) {
+			t.Errorf("expected a format error for %q, got a mismatch", checksum)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"time"
+)
+
+// maxInvalidationWait is how long to wait for an invalidation to complete.
+const maxInvalidationWait = 30 * time.Minute
+
+// PromoteStagingDistribution copies the configuration of a CloudFront staging distribution to its primary distribution,
+// completing a CloudFront continuous deployment.
+func (d *Deployer) PromoteStagingDistribution(ctx context.Context, primaryDistributionID string, stagingDistributionID string) error {
+	client := cloudfront.NewFromConfig(d.DefaultAWSConfig)
+
+	primary, err := client.GetDistributionConfig(ctx, &cloudfront.GetDistributionConfigInput{
+		Id: aws.String(primaryDistributionID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get CloudFront distribution (%s): %w", primaryDistributionID, err)
+	}
+
+	staging, err := client.GetDistributionConfig(ctx, &cloudfront.GetDistributionConfigInput{
+		Id: aws.String(stagingDistributionID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get CloudFront staging distribution (%s): %w", stagingDistributionID, err)
+	}
+
+	_, err = client.UpdateDistributionWithStagingConfig(ctx, &cloudfront.UpdateDistributionWithStagingConfigInput{
+		Id:                    aws.String(primaryDistributionID),
+		StagingDistributionId: aws.String(stagingDistributionID),
+		IfMatch:               aws.String(aws.ToString(primary.ETag) + "," + aws.ToString(staging.ETag)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to promote CloudFront staging distribution (%s) to %s: %w", stagingDistributionID, primaryDistributionID, err)
+	}
+
+	tflog.Info(ctx, "Promoted CloudFront staging distribution", map[string]interface{}{
+		"primary_distribution_id": primaryDistributionID,
+		"staging_distribution_id": stagingDistributionID,
+	})
+
+	return nil
+}
+
+// CreateInvalidation invalidates the given paths in a CloudFront distribution and returns the ID of the invalidation.
+// If wait is set, it returns once the invalidation has completed.
+func (d *Deployer) CreateInvalidation(ctx context.Context, distributionID string, paths []string, wait bool) (string, error) {
+	client := cloudfront.NewFromConfig(d.DefaultAWSConfig)
+
+	result, err := client.CreateInvalidation(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(distributionID),
+		InvalidationBatch: &types.InvalidationBatch{
+			// Every call creates a new invalidation, so the caller reference only has to be unique.
+			CallerReference: aws.String(newDeploymentID()),
+			Paths: &types.Paths{
+				Items:    paths,
+				Quantity: aws.Int32(int32(len(paths))),
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create invalidation for CloudFront distribution (%s): %w", distributionID, err)
+	}
+	invalidationID := aws.ToString(result.Invalidation.Id)
+
+	tflog.Info(ctx, "Created CloudFront invalidation", map[string]interface{}{
+		"distribution_id": distributionID,
+		"invalidation_id": invalidationID,
+		"paths":           paths,
+	})
+
+	if wait {
+		err = cloudfront.NewInvalidationCompletedWaiter(client).Wait(ctx, &cloudfront.GetInvalidationInput{
+			DistributionId: aws.String(distributionID),
+			Id:             aws.String(invalidationID),
+		}, maxInvalidationWait)
+		if err != nil {
+			return invalidationID, fmt.Errorf("failed waiting for invalidation (%s) of CloudFront distribution (%s): %w", invalidationID, distributionID, err)
+		}
+	}
+
+	return invalidationID, nil
+}
@@ -0,0 +1,80 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// promotion is a request to promote a CloudFront staging distribution.
+type promotion struct {
+	DistributionID        string
+	StagingDistributionID string
+	IfMatch               string
+}
+
+// newCloudFrontTestDeployer returns a Deployer for a CloudFront API with distributions that have the given ETags,
+// keyed by their ID. It records the promotions of staging distributions in promotions, and fails them like CloudFront
+// does if the ETags of the distributions do not match.
+func newCloudFrontTestDeployer(t *testing.T, etags map[string]string, promotions *[]promotion) *Deployer {
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/2020-05-31/distribution/"), "/")
+		etag, found := etags[parts[0]]
+		if !found || len(parts) != 2 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>NoSuchDistribution</Code><Message>The specified distribution does not exist.</Message></Error></ErrorResponse>`)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && parts[1] == "config":
+			w.Header().Set("ETag", etag)
+			fmt.Fprint(w, `<DistributionConfig><CallerReference>site</CallerReference><Comment></Comment><Enabled>true</Enabled></DistributionConfig>`)
+		case r.Method == http.MethodPut && parts[1] == "promote-staging-config":
+			stagingID := r.URL.Query().Get("StagingDistributionId")
+			*promotions = append(*promotions, promotion{DistributionID: parts[0], StagingDistributionID: stagingID, IfMatch: r.Header.Get("If-Match")})
+			if r.Header.Get("If-Match") != etag+","+etags[stagingID] {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>PreconditionFailed</Code><Message>The If-Match version is missing or not valid.</Message></Error></ErrorResponse>`)
+				return
+			}
+			w.Header().Set("ETag", etag+"2")
+			fmt.Fprintf(w, `<Distribution><Id>%s</Id><Status>InProgress</Status></Distribution>`, parts[0])
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	return &Deployer{DefaultAWSConfig: cfg}
+}
+
+func TestPromoteStagingDistribution(t *testing.T) {
+	var promotions []promotion
+	d := newCloudFrontTestDeployer(t, map[string]string{"EPRIMARY": "E1", "ESTAGING": "E2"}, &promotions)
+
+	err := d.PromoteStagingDistribution(context.Background(), "EPRIMARY", "ESTAGING")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// CloudFront requires the ETags of both distributions, so that neither changed since they were read.
+	expected := promotion{DistributionID: "EPRIMARY", StagingDistributionID: "ESTAGING", IfMatch: "E1,E2"}
+	if len(promotions) != 1 || promotions[0] != expected {
+		t.Errorf("expected the promotion %+v, got %+v", expected, promotions)
+	}
+}
+
+func TestPromoteStagingDistribution_missingDistribution(t *testing.T) {
+	var promotions []promotion
+	d := newCloudFrontTestDeployer(t, map[string]string{"EPRIMARY": "E1"}, &promotions)
+
+	err := d.PromoteStagingDistribution(context.Background(), "EPRIMARY", "ESTAGING")
+	if err == nil || !strings.Contains(err.Error(), "staging distribution (ESTAGING)") || !strings.Contains(err.Error(), "NoSuchDistribution") {
+		t.Errorf("expected an error for the missing staging distribution, got %v", err)
+	}
+	if len(promotions) != 0 {
+		t.Errorf("expected nothing to be promoted, got %+v", promotions)
+	}
+}
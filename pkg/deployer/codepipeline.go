@@ -0,0 +1,121 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// CodePipelineSource returns the location of the output artifact with the given name of an execution of a
+// CodePipeline pipeline, "codepipeline://<pipeline>/<execution ID>/<artifact>", which is fetched from wherever the
+// artifact store of the pipeline keeps it.
+func CodePipelineSource(pipelineName string, executionID string, artifactName string) string {
+	location := url.URL{Scheme: "codepipeline", Host: pipelineName, Path: "/" + executionID + "/" + artifactName}
+	return location.String()
+}
+
+// codePipelineSourceFetcher fetches the output artifacts of pipeline executions from "codepipeline://" locations.
+// The artifact store keeps them under random keys, so they are looked up in the action executions of the pipeline
+// execution, and then fetched from S3.
+type codePipelineSourceFetcher struct {
+	client codepipeline.ListActionExecutionsAPIClient
+	s3     *s3SourceFetcher
+	// resolved are the S3 locations of the artifacts that were looked up, keyed by their "codepipeline://" location.
+	// The artifacts of an execution never change, so they are only looked up once.
+	resolved sync.Map
+}
+
+// NewCodePipelineSourceFetcher returns a SourceFetcher for "codepipeline://" locations, looking up artifacts with
+// the given CodePipeline client and downloading them with the given S3 client.
+func NewCodePipelineSourceFetcher(client codepipeline.ListActionExecutionsAPIClient, s3Client S3SourceAPI) SourceFetcher {
+	return &codePipelineSourceFetcher{client: client, s3: &s3SourceFetcher{client: s3Client}}
+}
+
+// resolve returns the S3 location of the artifact at the given "codepipeline://" location.
+func (f *codePipelineSourceFetcher) resolve(ctx context.Context, location *url.URL) (*url.URL, error) {
+	if resolved, ok := f.resolved.Load(location.String()); ok {
+		return resolved.(*url.URL), nil
+	}
+
+	pipelineName := location.Host
+	executionID, artifactName, found := strings.Cut(strings.TrimPrefix(location.Path, "/"), "/")
+	if pipelineName == "" || executionID == "" || artifactName == "" || !found {
+		return nil, fmt.Errorf("invalid CodePipeline source %s, expected codepipeline://<pipeline>/<execution ID>/<artifact>", location)
+	}
+
+	paginator := codepipeline.NewListActionExecutionsPaginator(f.client, &codepipeline.ListActionExecutionsInput{
+		PipelineName: aws.String(pipelineName),
+		Filter:       &types.ActionExecutionFilter{PipelineExecutionId: aws.String(executionID)},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the actions of execution %s of pipeline %s: %w", executionID, pipelineName, err)
+		}
+		for _, action := range page.ActionExecutionDetails {
+			if action.Output == nil {
+				continue
+			}
+			for _, artifact := range action.Output.OutputArtifacts {
+				if aws.ToString(artifact.Name) != artifactName || artifact.S3location == nil {
+					continue
+				}
+				resolved := &url.URL{
+					Scheme: "s3",
+					Host:   aws.ToString(artifact.S3location.Bucket),
+					Path:   "/" + aws.ToString(artifact.S3location.Key),
+				}
+				f.resolved.Store(location.String(), resolved)
+				return resolved, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("execution %s of pipeline %s has no output artifact %s", executionID, pipelineName, artifactName)
+}
+
+func (f *codePipelineSourceFetcher) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	resolved, err := f.resolve(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return f.s3.Fetch(ctx, resolved, version)
+}
+
+// Check checks that the artifact can be looked up and read.
+func (f *codePipelineSourceFetcher) Check(ctx context.Context, location *url.URL, version *string) (string, error) {
+	resolved, err := f.resolve(ctx, location)
+	if err != nil {
+		return "", err
+	}
+	return f.s3.Check(ctx, resolved, version)
+}
+
+func (f *codePipelineSourceFetcher) ResolveVersion(ctx context.Context, location *url.URL) (string, error) {
+	resolved, err := f.resolve(ctx, location)
+	if err != nil {
+		return "", err
+	}
+	return f.s3.ResolveVersion(ctx, resolved)
+}
+
+func (f *codePipelineSourceFetcher) ResolveTaggedVersion(ctx context.Context, location *url.URL, tagKey string, tagValue string) (string, error) {
+	resolved, err := f.resolve(ctx, location)
+	if err != nil {
+		return "", err
+	}
+	return f.s3.ResolveTaggedVersion(ctx, resolved, tagKey, tagValue)
+}
+
+func (f *codePipelineSourceFetcher) Download(ctx context.Context, location *url.URL, version *string, w io.WriterAt, concurrency int) (string, int64, error) {
+	resolved, err := f.resolve(ctx, location)
+	if err != nil {
+		return "", 0, err
+	}
+	return f.s3.Download(ctx, resolved, version, w, concurrency)
+}
@@ -0,0 +1,90 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+// fakeActionExecutions returns the given action executions of the "site" pipeline, one per page.
+type fakeActionExecutions struct {
+	executionID string
+	actions     []types.ActionExecutionDetail
+	calls       int
+}
+
+func (f *fakeActionExecutions) ListActionExecutions(ctx context.Context, input *codepipeline.ListActionExecutionsInput, optFns ...func(*codepipeline.Options)) (*codepipeline.ListActionExecutionsOutput, error) {
+	f.calls++
+	if aws.ToString(input.PipelineName) != "site" || aws.ToString(input.Filter.PipelineExecutionId) != f.executionID {
+		return &codepipeline.ListActionExecutionsOutput{}, nil
+	}
+	page := 0
+	if input.NextToken != nil {
+		page = len(aws.ToString(input.NextToken))
+	}
+	output := &codepipeline.ListActionExecutionsOutput{ActionExecutionDetails: f.actions[page : page+1]}
+	if page+1 < len(f.actions) {
+		output.NextToken = aws.String(strings.Repeat("x", page+1))
+	}
+	return output, nil
+}
+
+func TestCodePipelineSourceFetcher(t *testing.T) {
+	client := s3fake.New()
+	artifact := newTestArtifact(t, map[string]string{"index.html": "built"})
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("codepipeline-artifacts"),
+		Key:    aws.String("site/BuildOutpu/Ab3xYz9"),
+		Body:   bytes.NewReader(artifact),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := &fakeActionExecutions{
+		executionID: "0b1c2d3e",
+		actions: []types.ActionExecutionDetail{
+			{ActionName: aws.String("Source"), Output: &types.ActionExecutionOutput{OutputArtifacts: []types.ArtifactDetail{
+				{Name: aws.String("SourceOutput"), S3location: &types.S3Location{Bucket: aws.String("codepipeline-artifacts"), Key: aws.String("site/SourceOutp/Qr5tUv1")}},
+			}}},
+			{ActionName: aws.String("Deploy")},
+			{ActionName: aws.String("Build"), Output: &types.ActionExecutionOutput{OutputArtifacts: []types.ArtifactDetail{
+				{Name: aws.String("BuildOutput"), S3location: &types.S3Location{Bucket: aws.String("codepipeline-artifacts"), Key: aws.String("site/BuildOutpu/Ab3xYz9")}},
+			}}},
+		},
+	}
+	deployment, _ := newTestDeployment(t, client, nil)
+	deployment.Sources = SourceFetchers{"codepipeline": NewCodePipelineSourceFetcher(actions, client)}
+
+	source := CodePipelineSource("site", "0b1c2d3e", "BuildOutput")
+	if source != "codepipeline://site/0b1c2d3e/BuildOutput" {
+		t.Errorf("unexpected source: %s", source)
+	}
+	if _, err := deployment.Deploy(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(client.Object("target", "index.html").Body); got != "built" {
+		t.Errorf("expected the build output to be deployed, got %q", got)
+	}
+	calls := actions.calls
+	if _, err := deployment.PlanChanges(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+	if actions.calls != calls {
+		t.Errorf("expected the artifact to be looked up once, got %d more requests", actions.calls-calls)
+	}
+
+	_, err = deployment.Deploy(context.Background(), CodePipelineSource("site", "0b1c2d3e", "TestOutput"), nil)
+	if err == nil || !strings.Contains(err.Error(), "has no output artifact TestOutput") {
+		t.Errorf("expected a missing artifact error, got %v", err)
+	}
+	_, err = deployment.Deploy(context.Background(), "codepipeline://site/0b1c2d3e", nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid CodePipeline source") {
+		t.Errorf("expected an invalid source error, got %v", err)
+	}
+}
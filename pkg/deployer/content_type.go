@@ -0,0 +1,55 @@
+package deployer
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// builtinContentTypes are content types for extensions that are missing from the MIME table of many systems,
+// such as minimal CI images, or that the system table gets wrong.
+var builtinContentTypes = map[string]string{
+	".avif":        "image/avif",
+	".map":         "application/json",
+	".mjs":         "text/javascript; charset=utf-8",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".woff2":       "font/woff2",
+}
+
+// normalizeExtension returns the extension in lower case with a leading dot, so "WASM" and ".wasm" are equivalent.
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
+
+// contentTypeForKey returns the content type to use for the given object key.
+// The given MIME types take precedence over the built-in ones, which take precedence over the system MIME table.
+func contentTypeForKey(mimeTypes map[string]string, key string) string {
+	extension := normalizeExtension(filepath.Ext(key))
+
+	for configuredExtension, contentType := range mimeTypes {
+		if normalizeExtension(configuredExtension) == extension {
+			return contentType
+		}
+	}
+
+	if contentType, found := builtinContentTypes[extension]; found {
+		return contentType
+	}
+
+	return mime.TypeByExtension(extension)
+}
+
+// contentTypeForKey returns the content type to use for the given object key in this deployment.
+func (d *Deployment) contentTypeForKey(key string) string {
+	return contentTypeForKey(d.MimeTypes, key)
+}
+
+// ContentTypeForKey returns the content type to use for the given object key, taking the configured MIME types into account.
+func (d *Deployer) ContentTypeForKey(key string) string {
+	return contentTypeForKey(d.MimeTypes, key)
+}
@@ -0,0 +1,28 @@
+package deployer
+
+import "testing"
+
+func TestContentTypeForKey(t *testing.T) {
+	d := &Deployment{MimeTypes: map[string]string{
+		"map":  "application/octet-stream",
+		".TXT": "text/plain; charset=iso-8859-1",
+	}}
+
+	cases := map[string]string{
+		"app.wasm":               "application/wasm",
+		"module.MJS":             "text/javascript; charset=utf-8",
+		"site.webmanifest":       "application/manifest+json",
+		"image.avif":             "image/avif",
+		"fonts/inter.woff2":      "font/woff2",
+		"app.js.map":             "application/octet-stream",
+		"notes.txt":              "text/plain; charset=iso-8859-1",
+		"index.html":             "text/html; charset=utf-8",
+		"file-without-extension": "",
+	}
+
+	for key, expected := range cases {
+		if got := d.contentTypeForKey(key); got != expected {
+			t.Errorf("contentTypeForKey(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+}
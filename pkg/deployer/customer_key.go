@@ -0,0 +1,124 @@
+package deployer
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// customerKeyAlgorithm is the only algorithm S3 supports for customer-provided encryption keys.
+const customerKeyAlgorithm = "AES256"
+
+// CustomerKey is a customer-provided encryption key (SSE-C) that S3 objects are encrypted with. S3 does not store the
+// key, so it must be sent with every request that reads or writes the content of the objects.
+type CustomerKey struct {
+	// Key is the base64 encoded 256-bit AES key.
+	Key string
+	// KeyMD5 is the base64 encoded MD5 hash of the key, which S3 checks the key against.
+	KeyMD5 string
+}
+
+// ParseCustomerKey returns the customer-provided encryption key with the given base64 encoded key, and an error if it
+// is not a 256-bit key or does not match keyMD5. If keyMD5 is empty, it is computed from the key.
+func ParseCustomerKey(key string, keyMD5 string) (*CustomerKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("the key is not base64 encoded: %w", err)
+	}
+	if len(decoded) != 32 {
+		return nil, fmt.Errorf("the key must be 256 bits, got %d", len(decoded)*8)
+	}
+
+	sum := md5.Sum(decoded)
+	computed := base64.StdEncoding.EncodeToString(sum[:])
+	if keyMD5 != "" && keyMD5 != computed {
+		return nil, errors.New("the key MD5 does not match the key")
+	}
+	return &CustomerKey{Key: key, KeyMD5: computed}, nil
+}
+
+// applyToGetObject adds the key to a GetObject request.
+func (k *CustomerKey) applyToGetObject(input *s3.GetObjectInput) {
+	if k == nil {
+		return
+	}
+
+	input.SSECustomerAlgorithm = aws.String(customerKeyAlgorithm)
+	input.SSECustomerKey = aws.String(k.Key)
+	input.SSECustomerKeyMD5 = aws.String(k.KeyMD5)
+}
+
+// applyToHeadObject adds the key to a HeadObject request, without which S3 does not return the metadata of objects
+// encrypted with it.
+func (k *CustomerKey) applyToHeadObject(input *s3.HeadObjectInput) {
+	if k == nil {
+		return
+	}
+
+	input.SSECustomerAlgorithm = aws.String(customerKeyAlgorithm)
+	input.SSECustomerKey = aws.String(k.Key)
+	input.SSECustomerKeyMD5 = aws.String(k.KeyMD5)
+}
+
+// applyToPutObject adds the key to a PutObject request. Uploads in parts pass it on to every part.
+func (k *CustomerKey) applyToPutObject(input *s3.PutObjectInput) {
+	if k == nil {
+		return
+	}
+
+	input.SSECustomerAlgorithm = aws.String(customerKeyAlgorithm)
+	input.SSECustomerKey = aws.String(k.Key)
+	input.SSECustomerKeyMD5 = aws.String(k.KeyMD5)
+}
+
+// applyToCopyObject adds the key to a CopyObject request within the bucket, both to decrypt the source and to encrypt
+// the copy.
+func (k *CustomerKey) applyToCopyObject(input *s3.CopyObjectInput) {
+	if k == nil {
+		return
+	}
+
+	input.CopySourceSSECustomerAlgorithm = aws.String(customerKeyAlgorithm)
+	input.CopySourceSSECustomerKey = aws.String(k.Key)
+	input.CopySourceSSECustomerKeyMD5 = aws.String(k.KeyMD5)
+	input.SSECustomerAlgorithm = aws.String(customerKeyAlgorithm)
+	input.SSECustomerKey = aws.String(k.Key)
+	input.SSECustomerKeyMD5 = aws.String(k.KeyMD5)
+}
+
+// UseCustomerKeys makes the deployment read the source, and read and write the target, with the given
+// customer-provided encryption keys, either of which may be nil. Only S3 sources and targets support them, so it
+// returns an error for a key of a target in another store.
+func (d *Deployment) UseCustomerKeys(source *CustomerKey, target *CustomerKey) error {
+	if source != nil {
+		sources := make(SourceFetchers, len(d.Sources))
+		for scheme, fetcher := range d.Sources {
+			switch fetcher := fetcher.(type) {
+			case *s3SourceFetcher:
+				sources[scheme] = &s3SourceFetcher{client: fetcher.client, customerKey: source}
+			case *codePipelineSourceFetcher:
+				sources[scheme] = &codePipelineSourceFetcher{client: fetcher.client, s3: &s3SourceFetcher{client: fetcher.s3.client, customerKey: source}}
+			default:
+				sources[scheme] = fetcher
+			}
+		}
+		d.Sources = sources
+	}
+
+	if target != nil {
+		store, ok := d.target.(*s3Store)
+		if !ok {
+			return fmt.Errorf("customer-provided encryption keys are only supported for S3 targets, not %s", d.target.Name())
+		}
+		withKey := *store
+		withKey.customerKey = target
+		// S3 rejects requests with both a customer-provided key and other server-side encryption settings, so the
+		// key replaces those inherited from the defaults of the Deployer.
+		withKey.encryption = nil
+		d.target = &withKey
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+// customerKeyClient fails every request that reads or writes the content of an object without the customer key,
+// like S3 does for objects encrypted with SSE-C.
+type customerKeyClient struct {
+	*s3fake.Client
+	key string
+}
+
+func (c *customerKeyClient) check(key *string, keyMD5 *string) error {
+	if aws.ToString(key) != c.key || aws.ToString(keyMD5) == "" {
+		return errors.New("missing customer key")
+	}
+	return nil
+}
+
+func (c *customerKeyClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := c.check(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	return c.Client.GetObject(ctx, params, optFns...)
+}
+
+func (c *customerKeyClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if err := c.check(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	return c.Client.HeadObject(ctx, params, optFns...)
+}
+
+func (c *customerKeyClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := c.check(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func (c *customerKeyClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if err := c.check(params.CopySourceSSECustomerKey, params.CopySourceSSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	if err := c.check(params.SSECustomerKey, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+	return c.Client.CopyObject(ctx, params, optFns...)
+}
+
+func TestParseCustomerKey(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	sum := md5.Sum(bytes.Repeat([]byte{7}, 32))
+	keyMD5 := base64.StdEncoding.EncodeToString(sum[:])
+
+	parsed, err := ParseCustomerKey(key, "")
+	if err != nil || parsed.KeyMD5 != keyMD5 {
+		t.Errorf("expected the key MD5 to be computed, got %+v, %v", parsed, err)
+	}
+	if _, err := ParseCustomerKey(key, keyMD5); err != nil {
+		t.Errorf("expected a matching key MD5 to be accepted, got %v", err)
+	}
+	if _, err := ParseCustomerKey(key, base64.StdEncoding.EncodeToString(make([]byte, 16))); err == nil {
+		t.Error("expected a key MD5 that does not match to be rejected")
+	}
+	if _, err := ParseCustomerKey(base64.StdEncoding.EncodeToString(make([]byte, 16)), ""); err == nil {
+		t.Error("expected a 128-bit key to be rejected")
+	}
+	if _, err := ParseCustomerKey("not base64!", ""); err == nil {
+		t.Error("expected a key that is not base64 encoded to be rejected")
+	}
+}
+
+func TestUseCustomerKeys(t *testing.T) {
+	ctx := context.Background()
+	sourceKey, err := ParseCustomerKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetKey, err := ParseCustomerKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &customerKeyClient{Client: s3fake.New(), key: sourceKey.Key}
+	target := &customerKeyClient{Client: s3fake.New(), key: targetKey.Key}
+	_, err = source.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("source"),
+		Key:    aws.String("site.zip"),
+		Body:   bytes.NewReader(newTestArtifact(t, map[string]string{"index.html": "hello", "app.js": "app"})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Deployer{S3Client: func(region string) S3API {
+		if region == "" {
+			return source
+		}
+		return target
+	}}
+	newDeployment := func() *Deployment {
+		deployment := d.NewDeployment("source", "target", "eu-north-1")
+		if err := deployment.UseCustomerKeys(sourceKey, targetKey); err != nil {
+			t.Fatal(err)
+		}
+		return deployment
+	}
+
+	if _, err := newDeployment().Deploy(ctx, "site.zip", nil); err != nil {
+		t.Fatal(err)
+	}
+	// Comparing with the deployed objects reads their metadata with the key.
+	again := newDeployment()
+	if _, err := again.Deploy(ctx, "site.zip", nil); err != nil {
+		t.Fatal(err)
+	}
+	if again.filesSkipped != 2 {
+		t.Errorf("expected the deployed files to be unchanged, got %d skipped", again.filesSkipped)
+	}
+
+	withoutKeys := d.NewDeployment("source", "target", "eu-north-1")
+	if _, err := withoutKeys.Deploy(ctx, "site.zip", nil); err == nil || !strings.Contains(err.Error(), "missing customer key") {
+		t.Errorf("expected reading the source without its key to fail, got %v", err)
+	}
+
+	other := &Deployment{target: &gcsStore{name: "site"}}
+	if err := other.UseCustomerKeys(nil, targetKey); err == nil {
+		t.Error("expected a customer key for a target that is not in S3 to be rejected")
+	}
+}
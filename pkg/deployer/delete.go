@@ -0,0 +1,93 @@
+package deployer
+
+import (
+	"archive/zip"
+	"context"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"regexp"
+	"sort"
+)
+
+// maxDeleteObjects is the maximum number of keys S3 accepts in a single DeleteObjects request, and the largest
+// batch any TargetStore is asked to delete at a time.
+const maxDeleteObjects = 1000
+
+// removedKeys returns the keys that exist in the target but are not part of the deployment, except for those
+// matching one of the keep patterns.
+func removedKeys(existingFiles DeployedFiles, deployedKeys map[string]bool, keepPatterns []string) []string {
+	keep := make([]*regexp.Regexp, len(keepPatterns))
+	for i, pattern := range keepPatterns {
+		keep[i] = globRegexp(pattern)
+	}
+
+	var keys []string
+	for key := range existingFiles {
+		if deployedKeys[key] || matchesAny(keep, key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// deployedKeys returns the keys of all objects written by this deployment.
+func (d *Deployment) deployedKeys(artifactZip *zip.Reader) map[string]bool {
+	keys := make(map[string]bool, len(artifactZip.File)+2)
+	for _, file := range artifactZip.File {
+		keys[d.objectKey(file.Name)] = true
+	}
+	if d.VersionFile != nil {
+		keys[d.objectKey(d.VersionFile.Key)] = true
+	}
+	if d.ResumeFailedDeployments {
+		// The progress has already been removed, and may have been saved again by a concurrent deployment.
+		keys[d.resumeProgressKey()] = true
+	}
+	return keys
+}
+
+// deleteRemovedFiles deletes the files in the target that are not part of the deployment.
+func (d *Deployment) deleteRemovedFiles(ctx context.Context, existingFiles DeployedFiles, deployedKeys map[string]bool) error {
+	keys := removedKeys(existingFiles, deployedKeys, d.KeepFiles)
+
+	for start := 0; start < len(keys); start += maxDeleteObjects {
+		batch := keys[start:min(start+maxDeleteObjects, len(keys))]
+
+		err := d.target.Delete(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		d.filesDeleted += len(batch)
+		d.deletedKeys = append(d.deletedKeys, batch...)
+	}
+
+	tflog.Info(ctx, "Deleted removed files", map[string]interface{}{
+		"files": len(keys),
+	})
+
+	return nil
+}
+
+// Purge deletes the given deployed objects from the target, e.g. when the deployment is destroyed. Objects that no
+// longer exist are ignored.
+func (d *Deployment) Purge(ctx context.Context, files DeployedFiles) error {
+	keys := make([]string, 0, len(files))
+	for key := range files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	err := d.deleteKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Purged deployed files", map[string]interface{}{
+		"files": len(keys),
+	})
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+func TestRemovedKeys(t *testing.T) {
+	existing := DeployedFiles{
+		"index.html":           "a",
+		"old.html":             "b",
+		"uploads/avatar.png":   "c",
+		".well-known/security": "d",
+		"assets/old.js":        "e",
+	}
+	deployed := map[string]bool{"index.html": true}
+
+	got := removedKeys(existing, deployed, []string{"uploads/*", ".well-known/*"})
+
+	expected := []string{"assets/old.js", "old.html"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"site/index.html": "hello", "site/uploads/a.png": "upload"})
+	d, _ := newTestDeployment(t, client, nil)
+
+	err := d.Purge(context.Background(), DeployedFiles{"site/index.html": "hash", "site/missing.html": "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := client.Keys("target"); !reflect.DeepEqual(keys, []string{"site/uploads/a.png"}) {
+		t.Errorf("expected only the files that were not deployed to be kept, got %v", keys)
+	}
+}
@@ -0,0 +1,1222 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Deployer is a client for deploying artifacts.
+type Deployer struct {
+	DefaultAWSConfig aws.Config
+	// MimeTypes maps file extensions to content types, overriding the built-in ones for every deployment.
+	MimeTypes map[string]string
+	// Defaults are the settings every new deployment starts out with.
+	Defaults DeploymentDefaults
+	// Retry is the retry policy DefaultAWSConfig was loaded with, which deployments also use to upload throttled
+	// objects again.
+	Retry RetryPolicy
+	// S3Client, if set, returns the client for S3 buckets in the given region, or in the default region if it is
+	// empty, instead of one created from DefaultAWSConfig, e.g. an s3fake.Client in tests.
+	S3Client func(region string) S3API
+	// S3Endpoint, if set, is the URL that S3 requests are sent to instead of AWS, e.g. a LocalStack or MinIO server.
+	// Buckets are then addressed by path rather than by host name.
+	S3Endpoint string
+	// PlanOffline makes refreshing and planning skip every request to AWS, so that speculative plans only need the
+	// state. Deployments are then only compared with their source and target when they are applied.
+	PlanOffline bool
+	// UploadLimiter, if set, is shared by all deployments of the Deployer, limiting how many files they upload at the
+	// same time in total.
+	UploadLimiter *UploadLimiter
+}
+
+// DeploymentDefaults are settings shared by all deployments of a Deployer.
+type DeploymentDefaults struct {
+	HashedAssetPattern      string
+	KeepFiles               []string
+	ContentDispositionRules []MetadataRule
+	ContentLanguageRules    []MetadataRule
+	// DownloadConcurrency and MultipartConcurrency are the default DownloadConcurrency and MultipartUpload
+	// concurrency of deployments.
+	DownloadConcurrency  int
+	MultipartConcurrency int
+	// Tags and Encryption are the default tags and server-side encryption of objects deployed to S3 targets. They are
+	// not applied to targets in other stores.
+	Tags       map[string]string
+	Encryption *Encryption
+
+	// TargetRegion is the region of the target buckets of callers that do not choose one themselves.
+	TargetRegion string
+}
+
+// NewDeployment returns a deployment to the S3 bucket with the given name in the given region.
+func (d *Deployer) NewDeployment(sourceBucket string, targetBucket string, targetRegion string) *Deployment {
+	return d.NewDeploymentToTarget(sourceBucket, d.NewS3Target(targetBucket, targetRegion))
+}
+
+// NewDeploymentToTarget returns a deployment to the given target store.
+func (d *Deployer) NewDeploymentToTarget(sourceBucket string, target TargetStore) *Deployment {
+	var tags map[string]string
+	if store, ok := target.(*s3Store); ok {
+		if len(d.Defaults.Tags) > 0 {
+			tags = make(map[string]string, len(d.Defaults.Tags))
+			for key, value := range d.Defaults.Tags {
+				tags[key] = value
+			}
+		}
+		if d.Defaults.Encryption != nil {
+			withEncryption := *store
+			withEncryption.encryption = d.Defaults.Encryption
+			target = &withEncryption
+		}
+	}
+
+	// The default slices and tags are copied, so that deployments can add their own values without affecting each
+	// other.
+	return &Deployment{
+		ID:                      newDeploymentID(),
+		SourceBucket:            sourceBucket,
+		TargetBucket:            target.Name(),
+		MimeTypes:               d.MimeTypes,
+		HashedAssetPattern:      d.Defaults.HashedAssetPattern,
+		KeepFiles:               append([]string(nil), d.Defaults.KeepFiles...),
+		ContentDispositionRules: append([]MetadataRule(nil), d.Defaults.ContentDispositionRules...),
+		ContentLanguageRules:    append([]MetadataRule(nil), d.Defaults.ContentLanguageRules...),
+		UploadOrder:             append([]string(nil), DefaultUploadOrder...),
+		Tags:                    tags,
+		DownloadConcurrency:     d.Defaults.DownloadConcurrency,
+		MultipartUpload:         MultipartUpload{Concurrency: d.Defaults.MultipartConcurrency},
+		Retry:                   d.Retry,
+		awsConfig:               d.DefaultAWSConfig,
+		Sources:                 d.defaultSourceFetchers(),
+		target:                  target,
+		uploadLimiter:           d.UploadLimiter,
+	}
+}
+
+// Deployment is responsible for deploying artifacts from a source bucket to a target store.
+type Deployment struct {
+	// ID uniquely identifies this deployment run.
+	ID           string
+	SourceBucket string
+	TargetBucket string
+	// TargetPrefix, if set, is the prefix in the target the artifact is deployed under, e.g. "site/". Only objects
+	// under it are part of the deployment.
+	TargetPrefix string
+	// VerifyAfterDeploy checks every uploaded object once the upload has completed.
+	VerifyAfterDeploy bool
+	// PreDeployLambdaArn is a Lambda function invoked with the deployment manifest before any files are uploaded.
+	PreDeployLambdaArn string
+	// PostDeployLambdaArn is a Lambda function invoked with the deployment manifest after all files are uploaded.
+	PostDeployLambdaArn string
+	// ConditionalWrites makes uploads fail with a ConflictError if an object was changed by someone else
+	// after the deployment read the state of the target bucket.
+	ConditionalWrites bool
+	// MimeTypes maps file extensions to content types, overriding the built-in ones.
+	MimeTypes map[string]string
+	// ContentDispositionRules set the Content-Disposition of matching objects. The first matching rule applies.
+	ContentDispositionRules []MetadataRule
+	// ContentLanguageRules set the Content-Language of matching objects. The first matching rule applies.
+	ContentLanguageRules []MetadataRule
+	// HashedAssetPattern, if set, matches fingerprinted files, which are deployed with ImmutableCacheControl.
+	// All other files are deployed with ShortCacheControl.
+	HashedAssetPattern string
+	// SourceChecksum, if set, is compared with the downloaded artifact before it is extracted.
+	// The format is "<algorithm>:<hex digest>", e.g. "sha256:...".
+	SourceChecksum string
+	// SourceRoot, if set, is the directory of the artifact to deploy. Entries outside of it are ignored,
+	// and it is stripped from the names of the others.
+	SourceRoot string
+	// PathRewrites are applied to the names of the artifact entries before they are deployed.
+	PathRewrites []PathRewrite
+	// Templates render the placeholders of matching artifact files before they are deployed.
+	Templates []Template
+	// RequiredFiles are the names of files the artifact must have, after SourceRoot and PathRewrites are applied.
+	RequiredFiles []string
+	// ValidationRules are checked against the files to deploy before any of them is uploaded.
+	ValidationRules []ValidationRule
+	// MetadataFiles are deployed together with the artifact, replacing artifact files with the same names.
+	MetadataFiles []MetadataFile
+	// SPA, if set, also deploys the entry document of the artifact as the error documents of the target.
+	SPA *SPAMode
+	// UploadOrder are glob patterns of files to upload after all other files, in the order of the patterns.
+	UploadOrder []string
+	// Tags, if set, are added to the tags of every uploaded object. They are only supported by S3 targets.
+	Tags map[string]string
+	// ObjectLock, if set, is applied to every uploaded object.
+	ObjectLock *ObjectLock
+	// DeleteRemovedFiles deletes files from the target that are not part of the deployed artifact,
+	// except for those matching one of the KeepFiles glob patterns.
+	DeleteRemovedFiles bool
+	KeepFiles          []string
+	// UnmanagedPaths are glob patterns of objects in the target written by other systems, such as user uploads or
+	// logs. They are left out of the state of the target, so they are never compared, overwritten, or deleted, and
+	// files of the artifact matching them are not deployed.
+	UnmanagedPaths []string
+	// BlueGreen, if set, uploads the artifact to a new release prefix and switches to it by updating a pointer object.
+	BlueGreen *BlueGreen
+	// VersionFile, if set, is written to the target after the artifact has been uploaded.
+	VersionFile *VersionFile
+	// Limits are the safety thresholds the artifact is checked against before any files are deployed.
+	Limits ArtifactLimits
+	// DownloadConcurrency is how many parts of the artifact are downloaded at the same time from sources that
+	// support it. Zero means DefaultDownloadConcurrency.
+	DownloadConcurrency int
+	// MultipartUpload configures how large files are uploaded to S3 in parts.
+	MultipartUpload MultipartUpload
+	// Retry configures how many times throttled objects are uploaded again.
+	Retry RetryPolicy
+	// RollbackOnFailure backs up objects before they are overwritten, so that a failed deployment can restore them
+	// and delete the objects it added, instead of leaving the target with a mix of old and new files. Deployments
+	// are rolled back if they fail before removed files are deleted.
+	RollbackOnFailure bool
+	// ResumeFailedDeployments saves the files deployed by a failed deployment to ResumeProgressKey in the target,
+	// so that the next deployment skips those that are unchanged instead of checking every file again. Deployments
+	// that are rolled back have nothing to resume.
+	ResumeFailedDeployments bool
+	// MaxRequestsPerSecond, if set, limits the rate of requests to the target, so that large deployments to a bucket
+	// shared with production traffic do not get throttled or starve other writers.
+	MaxRequestsPerSecond int
+	// Preflight checks that the source can be read and that the target bucket can be accessed before the artifact is
+	// downloaded, so that missing permissions are reported before any files are deployed. The checks only read from
+	// the source and the target.
+	Preflight bool
+	// PreflightWriteProbe makes Preflight also write PreflightKey to the target and delete it again, which checks that
+	// objects can be written to the target with the KMS key it encrypts them with. On versioned buckets, this leaves a
+	// delete marker and a noncurrent version behind, and on buckets with Object Lock a locked version.
+	PreflightWriteProbe bool
+	// TagObjects tags every deployed object with DeploymentIDTag and DeploymentSequenceTag, the ID and sequence
+	// number of the last deployment that included it. Objects that are not uploaded again are tagged without being
+	// copied. It is only supported by S3 targets.
+	TagObjects bool
+	// KeepDeployments, if set, implies TagObjects, and deletes the objects that were removed from the artifact more
+	// than this many deployments ago, so that removed files are pruned safely even when DeleteRemovedFiles is not
+	// set, e.g. to keep hashed assets referred to by cached pages for a few deployments. Objects that were never
+	// tagged are kept. It has no effect on BlueGreen deployments, which upload every deployment to its own prefix.
+	KeepDeployments int
+	// StagedPromotion uploads the new and changed files to StagingPrefix in the target first, verifies them, and only
+	// then copies them server-side to their keys, so that the live files are inconsistent for as short a time as
+	// possible. It cannot be combined with BlueGreen or ObjectLock.
+	StagedPromotion bool
+	// BatchOperations, if set, implies StagedPromotion, but stages the files in a separate bucket and promotes them
+	// with a single S3 Batch Operations job instead of copying every file, e.g. for artifacts with hundreds of
+	// thousands of files. It is only supported by S3 targets.
+	BatchOperations *BatchOperations
+	// Gate, if set, aborts the deployment with a DeploymentGateError before the artifact is downloaded if one of its
+	// alarms is in the ALARM state.
+	Gate *DeploymentGate
+	// Inventory, if set, are the S3 Inventory reports of the target, which PlanChanges compares the artifact with
+	// instead of listing the target.
+	Inventory *InventoryReports
+	// PreviousFingerprint, if set, is the Fingerprint of the last deployment to the target. If the artifact and
+	// settings have the same fingerprint, and the target still has all files with the same content, nothing is
+	// deployed.
+	PreviousFingerprint string
+	// PreviousObjectHashes and PreviousFileHashes, if set, are the ObjectHashes and FileHashes of the last deployment
+	// to the target. If the HashAlgorithm is not MD5, they are used to compare objects that have no SHA-256 hash in
+	// their metadata with the files, so that switching from MD5 does not deploy every file again.
+	PreviousObjectHashes DeployedFiles
+	PreviousFileHashes   map[string]string
+	// RepairFiles are the names of artifact files whose objects were changed outside of deployments, as returned by
+	// ChangedFiles. If the artifact and settings still have the PreviousFingerprint, only these files are uploaded,
+	// without comparing the other files with the target.
+	RepairFiles []string
+	// HashAlgorithm is the algorithm of the hashes returned by ObjectHashes and sent to hooks. It defaults to MD5.
+	HashAlgorithm HashAlgorithm
+	// Sources are the fetchers used to download the artifact. The artifact is fetched from "s3://SourceBucket/key",
+	// or, if SourceBucket is empty, the key is the URI of the artifact, e.g. "https://example.com/site.zip".
+	Sources SourceFetchers
+
+	awsConfig     aws.Config
+	target        TargetStore
+	sourceETag    string
+	startedAt     time.Time
+	bytesUploaded int64
+	filesAdded    int
+	filesChanged  int
+	filesCopied   int
+	filesSkipped  int
+	filesDeleted  int
+	filesResumed  int
+
+	// resumedProgress are the files deployed by the last failed deployment, and deployedProgress those deployed
+	// by this one, keyed by object key with the fingerprint of their content and metadata as value.
+	resumedProgress  map[string]string
+	deployedProgress map[string]string
+	// contentHashes are the hex encoded SHA-256 hashes of the files of the artifact, keyed by file name, once they
+	// were computed before or during the upload.
+	contentHashes map[string]string
+	// fileStateHashes are the hashes of the files of the artifact in the HashAlgorithm, keyed by file name.
+	fileStateHashes map[string]string
+	// deployedFingerprint is the fingerprint of what was deployed, and unchanged whether the target was already up
+	// to date.
+	deployedFingerprint string
+	unchanged           bool
+	// rollback records the changes made by the deployment while it can still be rolled back.
+	rollback *rollbackJournal
+	// objectVersions are the IDs of the versions created by the uploads of the deployment, keyed by object key.
+	objectVersions map[string]string
+	// uploadLimiter, if set, limits the uploads of this and other deployments of the same Deployer.
+	uploadLimiter *UploadLimiter
+	// sequence is the sequence number of a deployment that tags objects.
+	sequence int
+	// staged are the files uploaded to the staging area of a deployment with StagedPromotion, in upload order.
+	staged []stagedFile
+	// alarmClient, if set, returns the client the alarms of the Gate are described with.
+	alarmClient func(region string) cloudwatch.DescribeAlarmsAPIClient
+	// batchClient, if set, is the client the job of BatchOperations is run with.
+	batchClient batchJobClient
+	// addedKeys, changedKeys, and deletedKeys are the keys of the objects the deployment added, changed, and deleted,
+	// and phaseDurations how long each of its phases took in milliseconds, for its Report.
+	addedKeys      []string
+	changedKeys    []string
+	deletedKeys    []string
+	phaseDurations map[string]int64
+}
+
+// SourceETag returns the ETag of the source artifact, once it has been downloaded.
+func (d *Deployment) SourceETag() string {
+	return d.sourceETag
+}
+
+// DeployedFiles is a map of file keys to file hashes.
+type DeployedFiles map[string]string
+
+// sourceLocation returns the location of the artifact with the given key.
+func (d *Deployment) sourceLocation(key string) (*url.URL, error) {
+	if d.SourceBucket == "" {
+		return url.Parse(key)
+	}
+	return &url.URL{Scheme: "s3", Host: d.SourceBucket, Path: "/" + key}, nil
+}
+
+// sourceName returns the name of the source artifact with the given key, "source-bucket/key" for artifacts in the
+// source bucket, and the location of other artifacts.
+func (d *Deployment) sourceName(key string) string {
+	if d.SourceBucket == "" {
+		return key
+	}
+	return d.SourceBucket + "/" + key
+}
+
+// artifact is a downloaded deployment artifact. It is kept in a temporary file rather than in memory, so that
+// the size of artifacts is not limited by the memory available to the provider.
+type artifact struct {
+	*zip.Reader
+	file *os.File
+}
+
+// Close removes the temporary file of the artifact.
+func (a *artifact) Close() error {
+	a.file.Close()
+	return os.Remove(a.file.Name())
+}
+
+// getDeploymentArtifact returns the deployment artifact for the given key and version, which must be closed by
+// the caller. If version is nil, the latest version is returned.
+func (d *Deployment) getDeploymentArtifact(ctx context.Context, key string, version *string) (*artifact, error) {
+	location, err := d.sourceLocation(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source %q: %w", key, err)
+	}
+
+	tflog.Info(ctx, "Downloading deployment artifact", map[string]interface{}{
+		"source": location.Redacted(),
+	})
+	start := time.Now()
+
+	fetcher, err := d.Sources.fetcher(location)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "staticfiledeploy-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for artifact %s: %w", location.Redacted(), err)
+	}
+	result := &artifact{file: file}
+
+	var size int64
+	if downloader, ok := fetcher.(SourceDownloader); ok {
+		concurrency := d.DownloadConcurrency
+		if concurrency == 0 {
+			concurrency = DefaultDownloadConcurrency
+		}
+		d.sourceETag, size, err = downloader.Download(ctx, location, version, file, concurrency)
+		if err == nil && d.SourceChecksum != "" {
+			err = verifyChecksum(io.NewSectionReader(file, 0, size), d.SourceChecksum)
+			if err != nil {
+				err = fmt.Errorf("failed to verify artifact %s: %w", location.Redacted(), err)
+			}
+		}
+	} else {
+		size, err = d.streamArtifact(ctx, fetcher, location, version, file)
+	}
+	if err != nil {
+		result.Close()
+		return nil, err
+	}
+
+	result.Reader, err = zip.NewReader(file, size)
+	if err != nil {
+		result.Close()
+		return nil, fmt.Errorf("failed to unzip artifact %s: %w", location.Redacted(), err)
+	}
+	err = d.Limits.check(result.Reader)
+	if err != nil {
+		result.Close()
+		return nil, fmt.Errorf("artifact %s is too large: %w", location.Redacted(), err)
+	}
+	d.rewriteEntryNames(result.Reader)
+	err = d.Limits.checkMinimums(result.Reader)
+	if err != nil {
+		result.Close()
+		return nil, fmt.Errorf("artifact %s is too small: %w", location.Redacted(), err)
+	}
+	if len(d.Templates) > 0 {
+		err = d.renderTemplates(result.Reader)
+		if err != nil {
+			result.Close()
+			return nil, err
+		}
+	}
+	if len(d.MetadataFiles) > 0 {
+		err = d.addMetadataFiles(result.Reader)
+		if err != nil {
+			result.Close()
+			return nil, err
+		}
+	}
+	if d.SPA != nil {
+		err = d.addSPAErrorDocuments(result.Reader)
+		if err != nil {
+			result.Close()
+			return nil, fmt.Errorf("invalid artifact %s: %w", location.Redacted(), err)
+		}
+	}
+	if len(d.RequiredFiles) > 0 {
+		err = d.checkRequiredFiles(result.Reader)
+		if err != nil {
+			result.Close()
+			return nil, fmt.Errorf("invalid artifact %s: %w", location.Redacted(), err)
+		}
+	}
+	if len(d.ValidationRules) > 0 {
+		err = d.validateFiles(result.Reader)
+		if err != nil {
+			result.Close()
+			return nil, fmt.Errorf("invalid artifact %s: %w", location.Redacted(), err)
+		}
+	}
+
+	tflog.Info(ctx, "Downloaded deployment artifact", map[string]interface{}{
+		"size_bytes":  size,
+		"files":       len(result.File),
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+
+	return result, nil
+}
+
+// streamArtifact writes the artifact at the given location to file, and returns its size. The checksum is computed
+// while the artifact is written, so that it is only read once.
+func (d *Deployment) streamArtifact(ctx context.Context, fetcher SourceFetcher, location *url.URL, version *string, file *os.File) (int64, error) {
+	source, err := fetcher.Fetch(ctx, location, version)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Body.Close()
+	d.sourceETag = source.ETag
+
+	body := &countingWriter{w: file}
+	if d.SourceChecksum != "" {
+		err = verifyChecksum(io.TeeReader(source.Body, body), d.SourceChecksum)
+	} else {
+		_, err = io.Copy(body, source.Body)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to download artifact %s: %w", location.Redacted(), err)
+	}
+
+	return body.n, nil
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// getDeploymentArtifactFileHashes returns the content hashes of the files of the deployment artifact. Their SHA-256
+// hashes are kept in contentHashes, so that the files are not hashed again when they are uploaded.
+func (d *Deployment) getDeploymentArtifactFileHashes(ctx context.Context, artifactZip *zip.Reader) (map[string]string, error) {
+	hashes := make(map[string]string)
+	d.contentHashes = make(map[string]string)
+	d.fileStateHashes = make(map[string]string)
+
+	for _, file := range artifactZip.File {
+		fileHashes, err := hashArtifactFile(file, d.HashAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashes[file.Name] = fileHashes.content()
+		d.contentHashes[file.Name] = fileHashes.sha256
+		d.fileStateHashes[file.Name] = fileHashes.state
+	}
+
+	return hashes, nil
+}
+
+// artifactFileHashes are the hex encoded hashes of a file in the artifact. The MD5 hash is compared with the ETags
+// of deployed objects, and the SHA-256 hash with the one stored in their metadata. The MD5 hash is empty if the
+// HashAlgorithm of the deployment is not MD5. state is the hash in the HashAlgorithm of the deployment, formatted as
+// it is returned by ObjectHashes.
+type artifactFileHashes struct {
+	md5    string
+	sha256 string
+	state  string
+}
+
+// content returns the hash the file is kept with in the DeployedFiles of the deployment: the MD5 hash if the file was
+// hashed with MD5, and the SHA-256 hash otherwise.
+func (h artifactFileHashes) content() string {
+	if h.md5 != "" {
+		return h.md5
+	}
+	return h.sha256
+}
+
+// complete returns whether the file does not need to be hashed again for a deployment with the given algorithm.
+func (h artifactFileHashes) complete(algorithm HashAlgorithm) bool {
+	return (h.md5 != "" || !algorithm.usesMD5()) && h.sha256 != "" && h.state != ""
+}
+
+// knownFileHashes returns the hashes of the file with the given name that are already known, given the
+// DeployedFiles of the deployment.
+func (d *Deployment) knownFileHashes(name string, hashes DeployedFiles) artifactFileHashes {
+	known := artifactFileHashes{sha256: d.contentHashes[name], state: d.fileStateHashes[name]}
+	if d.HashAlgorithm.usesMD5() {
+		known.md5 = hashes[name]
+	}
+	return known
+}
+
+// artifactFileHasher computes the hashes of a file in the artifact while it is written to it.
+type artifactFileHasher struct {
+	io.Writer
+	// md5 is nil unless the algorithm is MD5.
+	md5       hash.Hash
+	sha256    hash.Hash
+	algorithm HashAlgorithm
+	// state is the hash in the algorithm, if it is neither MD5 nor SHA-256.
+	state hash.Hash
+}
+
+func newArtifactFileHasher(algorithm HashAlgorithm) *artifactFileHasher {
+	h := &artifactFileHasher{sha256: sha256.New(), algorithm: algorithm, state: algorithm.newHash()}
+	writers := []io.Writer{h.sha256}
+	if algorithm.usesMD5() {
+		h.md5 = newMD5()
+		writers = append(writers, h.md5)
+	}
+	if h.state != nil {
+		writers = append(writers, h.state)
+	}
+	h.Writer = io.MultiWriter(writers...)
+	return h
+}
+
+func (h *artifactFileHasher) hashes() artifactFileHashes {
+	hashes := artifactFileHashes{
+		sha256: hex.EncodeToString(h.sha256.Sum(nil)),
+	}
+	if h.md5 != nil {
+		hashes.md5 = hex.EncodeToString(h.md5.Sum(nil))
+	}
+	switch {
+	case h.state != nil:
+		hashes.state = h.algorithm.format(hex.EncodeToString(h.state.Sum(nil)))
+	case h.algorithm == HashAlgorithmSHA256:
+		hashes.state = h.algorithm.format(hashes.sha256)
+	default:
+		hashes.state = hashes.md5
+	}
+	return hashes
+}
+
+// hashArtifactFile returns the hashes of a file in the artifact. The file is streamed through the hashes, so that
+// large files are not read into memory.
+func hashArtifactFile(file *zip.File, algorithm HashAlgorithm) (artifactFileHashes, error) {
+	zippedFile, err := file.Open()
+	if err != nil {
+		return artifactFileHashes{}, fmt.Errorf("failed to open zipped file %s: %w", file.Name, err)
+	}
+	defer zippedFile.Close()
+
+	hasher := newArtifactFileHasher(algorithm)
+	_, err = io.Copy(hasher, zippedFile)
+	if err != nil {
+		return artifactFileHashes{}, fmt.Errorf("failed to read zipped file content of %s: %w", file.Name, err)
+	}
+
+	return hasher.hashes(), nil
+}
+
+// maxBufferedFileSize is the size up to which files are read into memory before they are uploaded, so that they
+// are only read once and failed uploads can be retried. Larger files are hashed first, and then streamed from the
+// artifact when uploaded.
+const maxBufferedFileSize = 16 << 20
+
+// readArtifactFile returns the content and hashes of a file in the artifact, hashing it while it is read.
+// The content of files larger than maxBufferedFileSize is nil. known is used instead of hashing them, if set.
+func readArtifactFile(file *zip.File, known artifactFileHashes, algorithm HashAlgorithm) ([]byte, artifactFileHashes, error) {
+	if file.UncompressedSize64 > maxBufferedFileSize {
+		if known.complete(algorithm) {
+			return nil, known, nil
+		}
+		fileHashes, err := hashArtifactFile(file, algorithm)
+		return nil, fileHashes, err
+	}
+
+	zippedFile, err := file.Open()
+	if err != nil {
+		return nil, artifactFileHashes{}, fmt.Errorf("failed to open zipped file %s: %w", file.Name, err)
+	}
+	defer zippedFile.Close()
+
+	hasher := newArtifactFileHasher(algorithm)
+	content, err := io.ReadAll(io.TeeReader(zippedFile, hasher))
+	if err != nil {
+		return nil, artifactFileHashes{}, fmt.Errorf("failed to read zipped file content of %s: %w", file.Name, err)
+	}
+
+	return content, hasher.hashes(), nil
+}
+
+// uploadDeploymentArtifactFiles uploads the given files to the target.
+// Files that are already deployed with the same content are not uploaded again, but have their metadata updated in place if needed.
+// The hashes of the files are added to hashes while they are uploaded, unless they are already in it.
+// existingFiles is the state of the target before the upload started.
+func (d *Deployment) uploadDeploymentArtifactFiles(ctx context.Context, artifactZip *zip.Reader, hashes DeployedFiles, existingFiles DeployedFiles) error {
+	progress := newUploadProgress(artifactZip.File)
+	stopProgress := progress.logEvery(ctx, uploadProgressInterval)
+	defer stopProgress()
+
+	for _, file := range d.orderedFiles(artifactZip) {
+		// Reading files from the artifact does not check the context, so a cancelled deployment is stopped here.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		content, fileHashes, err := readArtifactFile(file, d.knownFileHashes(file.Name, hashes), d.HashAlgorithm)
+		if err != nil {
+			return err
+		}
+		hash := fileHashes.content()
+		hashes[file.Name] = hash
+		if d.fileStateHashes == nil {
+			d.fileStateHashes = make(map[string]string)
+		}
+		d.fileStateHashes[file.Name] = fileHashes.state
+		if d.contentHashes == nil {
+			d.contentHashes = make(map[string]string)
+		}
+		d.contentHashes[file.Name] = fileHashes.sha256
+
+		key := d.objectKey(file.Name)
+		metadata := d.metadataForKey(key)
+		metadata.ContentSHA256 = fileHashes.sha256
+
+		unchanged := false
+		if existingHash, found := existingFiles[key]; found {
+			unchanged, err = d.matchesExistingHash(ctx, file, key, fileHashes, existingHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		fingerprint := resumeFingerprint(hash, metadata)
+		if unchanged && d.resumedProgress[key] == fingerprint {
+			// The failed deployment already made sure the object has the expected metadata.
+			d.filesSkipped++
+			d.filesResumed++
+		} else if unchanged {
+			var copied bool
+			err := d.retryThrottled(ctx, key, func() error {
+				var refreshErr error
+				copied, refreshErr = d.refreshObjectMetadata(ctx, key, metadata)
+				return refreshErr
+			})
+			if err != nil {
+				return err
+			}
+			if copied {
+				d.filesCopied++
+				d.changedKeys = append(d.changedKeys, key)
+			} else {
+				d.filesSkipped++
+			}
+		} else {
+			err := d.retryThrottled(ctx, key, func() error {
+				return d.uploadFile(ctx, file, content, key, fileHashes, metadata, existingFiles)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if unchanged && d.tagsObjects() {
+			err := d.retryThrottled(ctx, key, func() error {
+				return d.tagObject(ctx, key)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if d.deployedProgress != nil {
+			d.deployedProgress[key] = fingerprint
+		}
+
+		progress.fileDone(file, !unchanged)
+	}
+
+	progress.log(ctx)
+	return nil
+}
+
+// matchesExistingHash returns whether a file in the artifact has the same content as the deployed object with the
+// given key and ETag. The ETags of objects encrypted with KMS or uploaded in parts are not MD5 hashes, so unless the
+// ETag matches, the SHA-256 hash stored in the metadata of the object is compared instead. Objects deployed before
+// the hash was stored only have their ETag, so for objects uploaded in parts the ETag of the file is computed the
+// same way. The parts may have been uploaded with another part size than the configured one, e.g. by the AWS CLI,
+// so the part size of the object is used if the target can tell it. Without MD5, neither is computed, and objects
+// without the SHA-256 hash are compared with the last deployment instead.
+func (d *Deployment) matchesExistingHash(ctx context.Context, file *zip.File, key string, fileHashes artifactFileHashes, existingHash string) (bool, error) {
+	if fileHashes.md5 != "" && existingHash == fileHashes.md5 {
+		return true, nil
+	}
+
+	head, err := d.target.Head(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if head != nil && head.Metadata.ContentSHA256 != "" {
+		return head.Metadata.ContentSHA256 == fileHashes.sha256, nil
+	}
+
+	if !d.HashAlgorithm.usesMD5() {
+		return d.matchesPreviousDeployment(file.Name, key, fileHashes, existingHash), nil
+	}
+
+	if !strings.Contains(existingHash, "-") {
+		return false, nil
+	}
+
+	partSize := d.MultipartUpload.withDefaults().PartSize
+	if sizer, ok := d.target.(multipartPartSizer); ok {
+		objectPartSize, err := sizer.PartSize(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if objectPartSize > 0 {
+			partSize = objectPartSize
+		}
+	}
+
+	zippedFile, err := file.Open()
+	if err != nil {
+		return false, fmt.Errorf("failed to open zipped file %s: %w", file.Name, err)
+	}
+	defer zippedFile.Close()
+
+	etag, err := multipartETag(zippedFile, partSize)
+	if err != nil {
+		return false, fmt.Errorf("failed to read zipped file content of %s: %w", file.Name, err)
+	}
+	return etag == existingHash, nil
+}
+
+// matchesPreviousDeployment returns whether an object that has no SHA-256 hash in its metadata, e.g. because it was
+// deployed before the hash was stored, still has the content of the file: its ETag is still the MD5 hash the last
+// deployment gave it in PreviousObjectHashes, and the file still has the SHA-256 hash it had in PreviousFileHashes.
+// This lets deployments that switch from MD5 to another HashAlgorithm compare files without hashing them with MD5.
+func (d *Deployment) matchesPreviousDeployment(name string, key string, fileHashes artifactFileHashes, existingHash string) bool {
+	previousHash := d.PreviousObjectHashes[key]
+	if previousHash == "" || previousHash != existingHash {
+		return false
+	}
+	previousContent, err := base64.StdEncoding.DecodeString(d.PreviousFileHashes[name])
+	return err == nil && len(previousContent) > 0 && hex.EncodeToString(previousContent) == fileHashes.sha256
+}
+
+// checkWriteConflict returns nil if err is a ConflictError for an object that already has the content and metadata
+// of the upload, e.g. because the SDK retried a conditional write that had already succeeded, and err otherwise.
+func (d *Deployment) checkWriteConflict(ctx context.Context, input PutInput, err error) error {
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || input.SHA256 == "" {
+		return err
+	}
+	head, headErr := d.target.Head(ctx, input.Key)
+	if headErr != nil || head == nil || head.Metadata.ContentSHA256 != input.SHA256 || !input.Metadata.matches(head.Metadata) {
+		return err
+	}
+	if head.VersionID != "" {
+		if d.objectVersions == nil {
+			d.objectVersions = make(map[string]string)
+		}
+		d.objectVersions[input.Key] = head.VersionID
+	}
+	return nil
+}
+
+// uploadFile uploads a single file from the artifact to the given key in the target. content is the content of the
+// file, or nil if it should be streamed from the artifact.
+// existingFiles is the state of the target before the upload started, used for conditional writes.
+func (d *Deployment) uploadFile(ctx context.Context, file *zip.File, content []byte, key string, fileHashes artifactFileHashes, metadata ObjectMetadata, existingFiles DeployedFiles) error {
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	} else {
+		zippedFile, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zipped file %s: %w", file.Name, err)
+		}
+		defer zippedFile.Close()
+		body = zippedFile
+	}
+
+	uploadKey := key
+	if d.stagesFiles() {
+		uploadKey = d.stagingKey(key)
+	}
+
+	multipart := d.MultipartUpload.withDefaults()
+	input := PutInput{
+		Key:        uploadKey,
+		Body:       body,
+		Size:       int64(file.UncompressedSize64),
+		MD5:        fileHashes.md5,
+		SHA256:     fileHashes.sha256,
+		Metadata:   metadata,
+		ObjectLock: d.ObjectLock,
+		Multipart:  &multipart,
+		Tags:       d.deploymentTags(),
+	}
+	// Staged files, including those promoted by S3 Batch Operations, are only written to their keys when they are
+	// promoted, so the conditions of their keys are not sent with their uploads to the staging area.
+	if d.ConditionalWrites && !d.stagesFiles() {
+		if existingHash, found := existingFiles[key]; found {
+			input.IfMatch = existingHash
+		} else {
+			input.IfNoneMatch = true
+		}
+	}
+	if !d.stagesFiles() {
+		err := d.recordWrite(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	err := d.uploadLimiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if d.BatchOperations != nil {
+		err = d.BatchOperations.Staging.Put(ctx, input)
+	} else {
+		err = d.putObject(ctx, input)
+		if err != nil {
+			err = d.checkWriteConflict(ctx, input, err)
+		}
+	}
+	d.uploadLimiter.release()
+	if err != nil {
+		return err
+	}
+	if d.stagesFiles() {
+		d.staged = append(d.staged, stagedFile{key: key, file: file})
+	}
+	if _, found := existingFiles[key]; found {
+		d.filesChanged++
+		d.changedKeys = append(d.changedKeys, key)
+	} else {
+		d.filesAdded++
+		d.addedKeys = append(d.addedKeys, key)
+	}
+	d.bytesUploaded += input.Size
+
+	tflog.Debug(ctx, "Uploaded file", map[string]interface{}{
+		"key":          uploadKey,
+		"size_bytes":   input.Size,
+		"content_type": metadata.ContentType,
+	})
+
+	return nil
+}
+
+// Deploy deploys the artifact with the given key from the source bucket to the target.
+func (d *Deployment) Deploy(ctx context.Context, key string, version *string) (_ DeployedFiles, err error) {
+	d.startedAt = time.Now()
+	ctx = tflog.SetField(ctx, "deployment_id", d.ID)
+	ctx = tflog.SetField(ctx, "target", d.TargetBucket)
+
+	if d.stagesFiles() {
+		err = d.checkStagedPromotion()
+		if err != nil {
+			return nil, err
+		}
+	}
+	// The target is checked before it is wrapped, as the stores wrapping it always forward tags.
+	if _, ok := d.target.(objectTagger); (d.tagsObjects() || len(d.Tags) > 0) && !ok {
+		return nil, errTaggingNotSupported
+	}
+
+	if d.MaxRequestsPerSecond > 0 {
+		d.target = newRateLimitedStore(d.target, d.MaxRequestsPerSecond)
+	}
+
+	// Timings of each phase of the deployment, logged once it completes.
+	timings := make(map[string]interface{})
+	d.phaseDurations = make(map[string]int64)
+	phaseStart := time.Now()
+	endPhase := func(name string) {
+		d.phaseDurations[name] = time.Since(phaseStart).Milliseconds()
+		timings[name+"_ms"] = d.phaseDurations[name]
+		phaseStart = time.Now()
+	}
+
+	if d.Gate != nil && len(d.Gate.AlarmArns) > 0 {
+		err = d.checkGate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		endPhase("gate")
+	}
+
+	if d.Preflight {
+		err = d.preflight(ctx, key, version)
+		if err != nil {
+			return nil, err
+		}
+		endPhase("preflight")
+	}
+
+	downloaded, err := d.getDeploymentArtifact(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+	defer downloaded.Close()
+	artifactZip := downloaded.Reader
+	endPhase("download")
+
+	// Files are hashed while they are uploaded, unless the pre-deploy hook or the check for changes needs all hashes
+	// up front.
+	hashes := make(DeployedFiles)
+	if d.PreDeployLambdaArn != "" || d.PreviousFingerprint != "" {
+		hashes, err = d.getDeploymentArtifactFileHashes(ctx, artifactZip)
+		if err != nil {
+			return nil, err
+		}
+		endPhase("hash")
+	}
+
+	if d.PreDeployLambdaArn != "" {
+		err = d.invokeLambdaHook(ctx, d.PreDeployLambdaArn, d.manifest(HookPhasePreDeploy, key, d.stateHashes(hashes)))
+		if err != nil {
+			return nil, err
+		}
+		endPhase("pre_deploy_hook")
+	}
+
+	if d.repairsOnly(hashes) {
+		err = d.repairFiles(ctx, artifactZip, hashes)
+		if err != nil {
+			return nil, err
+		}
+		return hashes, nil
+	}
+
+	existingFiles, err := d.HashesForDeployedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	upToDate, err := d.isUpToDate(ctx, hashes, existingFiles, d.deployedKeys(artifactZip))
+	if err != nil {
+		return nil, err
+	}
+	if upToDate {
+		d.unchanged = true
+		d.filesSkipped = len(hashes)
+		d.deployedFingerprint = d.PreviousFingerprint
+		tflog.Info(ctx, "Target is up to date, nothing to deploy", map[string]interface{}{
+			"files":    len(hashes),
+			"total_ms": time.Since(d.startedAt).Milliseconds(),
+		})
+		return hashes, nil
+	}
+
+	if d.RollbackOnFailure {
+		d.rollback = &rollbackJournal{existing: existingFiles, recorded: make(map[string]bool)}
+		defer func() {
+			if err != nil && d.rollback != nil {
+				err = d.rollBack(ctx, err)
+			}
+		}()
+	}
+
+	if d.stagesFiles() {
+		// Files staged by a failed deployment are never promoted.
+		defer func() {
+			if err != nil && len(d.staged) > 0 {
+				cleanupErr := d.deleteStagedFiles(ctx)
+				if cleanupErr != nil {
+					tflog.Warn(ctx, "Could not delete the staged files of the failed deployment", map[string]interface{}{
+						"error": cleanupErr.Error(),
+					})
+				}
+			}
+		}()
+	}
+
+	if d.tagsObjects() {
+		err = d.startTagging(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d.ResumeFailedDeployments {
+		d.resumedProgress, err = d.readResumeProgress(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.deployedProgress = make(map[string]string)
+	}
+
+	err = d.uploadDeploymentArtifactFiles(ctx, artifactZip, hashes, existingFiles)
+	if err != nil {
+		if d.ResumeFailedDeployments && !d.RollbackOnFailure {
+			d.saveResumeProgress(ctx)
+		}
+		return nil, err
+	}
+	if d.ResumeFailedDeployments {
+		err = d.target.Delete(ctx, []string{d.resumeProgressKey()})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The version file is written once the files are live, which for staged deployments is after their promotion.
+	uploadPhase := "upload"
+	if d.stagesFiles() {
+		endPhase(uploadPhase)
+		err = d.verifyStagedFiles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if d.BatchOperations != nil {
+			err = d.promoteWithBatchJob(ctx)
+		} else {
+			err = d.promoteStagedFiles(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		uploadPhase = "promote"
+	}
+
+	if d.VersionFile != nil {
+		err = d.uploadVersionFile(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	endPhase(uploadPhase)
+
+	if d.tagsObjects() {
+		err = d.saveDeploymentSequence(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d.VerifyAfterDeploy {
+		err = d.verifyDeployedFiles(ctx, artifactZip)
+		if err != nil {
+			return nil, err
+		}
+		endPhase("verify")
+	}
+
+	if d.BlueGreen != nil {
+		// Only switch to the new release once all of it has been uploaded and verified.
+		err = d.uploadReleasePointer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		endPhase("release")
+	}
+
+	if d.rollback != nil {
+		err = d.commitRollback(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Blue/green deployments upload to a new prefix, so there is nothing to delete.
+	if d.DeleteRemovedFiles && d.BlueGreen == nil {
+		err = d.deleteRemovedFiles(ctx, existingFiles, d.deployedKeys(artifactZip))
+		if err != nil {
+			return nil, err
+		}
+		endPhase("delete")
+	} else if d.KeepDeployments > 0 && d.BlueGreen == nil {
+		err = d.pruneOldDeployments(ctx, existingFiles, d.deployedKeys(artifactZip))
+		if err != nil {
+			return nil, err
+		}
+		endPhase("prune")
+	}
+
+	if d.PostDeployLambdaArn != "" {
+		err = d.invokeLambdaHook(ctx, d.PostDeployLambdaArn, d.manifest(HookPhasePostDeploy, key, d.stateHashes(hashes)))
+		if err != nil {
+			return nil, err
+		}
+		endPhase("post_deploy_hook")
+	}
+
+	d.deployedFingerprint = d.fingerprint(hashes)
+
+	timings["total_ms"] = time.Since(d.startedAt).Milliseconds()
+	timings["files"] = len(hashes)
+	timings["files_added"] = d.filesAdded
+	timings["files_changed"] = d.filesChanged
+	timings["files_copied"] = d.filesCopied
+	timings["files_skipped"] = d.filesSkipped
+	timings["files_resumed"] = d.filesResumed
+	timings["files_deleted"] = d.filesDeleted
+	timings["bytes_uploaded"] = d.bytesUploaded
+	tflog.Info(ctx, "Deployment completed", timings)
+
+	return hashes, nil
+}
+
+// HashesForArtifact returns all files that are in the given zip.
+func (d *Deployment) HashesForArtifact(ctx context.Context, key string, version *string) (DeployedFiles, error) {
+	downloaded, err := d.getDeploymentArtifact(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+	defer downloaded.Close()
+
+	var hashes DeployedFiles
+	hashes, err = d.getDeploymentArtifactFileHashes(ctx, downloaded.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// RefreshSourceETag reads the ETag of the artifact with the given key, which is then returned by SourceETag. The
+// artifact is only downloaded if the source cannot tell its ETag otherwise.
+func (d *Deployment) RefreshSourceETag(ctx context.Context, key string, version *string) error {
+	location, err := d.sourceLocation(key)
+	if err != nil {
+		return fmt.Errorf("invalid source %q: %w", key, err)
+	}
+	fetcher, err := d.Sources.fetcher(location)
+	if err != nil {
+		return err
+	}
+
+	if checker, ok := fetcher.(SourceChecker); ok {
+		d.sourceETag, err = checker.Check(ctx, location, version)
+		return err
+	}
+
+	_, err = d.HashesForArtifact(ctx, key, version)
+	return err
+}
+
+// ResolveSourceVersion returns the ID of the current version of the artifact with the given key, so that the same
+// version can be deployed again even if the artifact is overwritten. It returns an empty string if the source does
+// not keep versions of the artifact.
+func (d *Deployment) ResolveSourceVersion(ctx context.Context, key string) (string, error) {
+	location, err := d.sourceLocation(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid source %q: %w", key, err)
+	}
+	fetcher, err := d.Sources.fetcher(location)
+	if err != nil {
+		return "", err
+	}
+
+	resolver, ok := fetcher.(SourceVersionResolver)
+	if !ok {
+		return "", nil
+	}
+	return resolver.ResolveVersion(ctx, location)
+}
+
+// ResolveTaggedSourceVersion returns the ID of the newest version of the source artifact with the given key that has
+// the given tag, e.g. "environment=staging", so that artifacts can be promoted by tagging them. It returns an error if
+// the source does not keep versions with tags, or if no version has the tag.
+func (d *Deployment) ResolveTaggedSourceVersion(ctx context.Context, key string, tagKey string, tagValue string) (string, error) {
+	location, err := d.sourceLocation(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid source %q: %w", key, err)
+	}
+	fetcher, err := d.Sources.fetcher(location)
+	if err != nil {
+		return "", err
+	}
+
+	resolver, ok := fetcher.(SourceTagResolver)
+	if !ok {
+		return "", fmt.Errorf("source %s does not support resolving versions by tag", location.Redacted())
+	}
+	return resolver.ResolveTaggedVersion(ctx, location, tagKey, tagValue)
+}
+
+// ObjectHashes returns the hashes of the given artifact files, such as those returned by Deploy, keyed by the keys of
+// the objects the files are deployed to. The hashes are in the HashAlgorithm of the deployment.
+func (d *Deployment) ObjectHashes(files DeployedFiles) DeployedFiles {
+	objects := make(DeployedFiles, len(files))
+	for name, hash := range d.stateHashes(files) {
+		objects[d.objectKey(name)] = hash
+	}
+	return objects
+}
+
+// HashesForDeployedFiles returns all files that have been deployed to the target, except for unmanaged ones.
+func (d *Deployment) HashesForDeployedFiles(ctx context.Context) (DeployedFiles, error) {
+	files, err := d.target.List(ctx, d.keyPrefix())
+	if err != nil {
+		return nil, err
+	}
+	return d.withoutUnmanaged(files), nil
+}
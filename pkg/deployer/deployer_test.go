@@ -0,0 +1,270 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDeployment returns a deployment to the "target" bucket of client, made by a Deployer the way the provider
+// makes them, and the URI of an artifact with the given files.
+func newTestDeployment(t *testing.T, client S3API, files map[string]string) (*Deployment, string) {
+	t.Helper()
+
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	if err := os.WriteFile(artifactPath, newTestArtifact(t, files), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	deployer := &Deployer{S3Client: func(region string) S3API { return client }}
+	return deployer.NewDeployment("", "target", "eu-north-1"), "file://" + artifactPath
+}
+
+// newAWSTestConfig returns an AWS config that sends the requests of every AWS client to handler, without retrying
+// failed requests.
+func newAWSTestConfig(t *testing.T, handler http.HandlerFunc) aws.Config {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return aws.Config{
+		Region:           "eu-west-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		RetryMaxAttempts: 1,
+	}
+}
+
+// putTestObjects writes objects with the given content, keyed by object key, to the bucket.
+func putTestObjects(t *testing.T, client S3API, bucket string, objects map[string]string) {
+	t.Helper()
+
+	for key, content := range objects {
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   strings.NewReader(content),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testObjects returns the content of the objects in the bucket, keyed by object key.
+func testObjects(client *s3fake.Client, bucket string) map[string]string {
+	objects := make(map[string]string)
+	for _, key := range client.Keys(bucket) {
+		objects[key] = string(client.Object(bucket, key).Body)
+	}
+	return objects
+}
+
+// recordingS3API is an s3fake.Client that records the uploads, deletes and HEAD requests made to it, and fails
+// uploads to failKey.
+type recordingS3API struct {
+	*s3fake.Client
+	failKey string
+
+	mu      sync.Mutex
+	puts    []*s3.PutObjectInput
+	deletes []*s3.DeleteObjectsInput
+	heads   []time.Time
+}
+
+func (c *recordingS3API) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if aws.ToString(params.Key) == c.failKey {
+		return nil, errors.New("upload failed")
+	}
+	c.mu.Lock()
+	c.puts = append(c.puts, params)
+	c.mu.Unlock()
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func (c *recordingS3API) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.mu.Lock()
+	c.deletes = append(c.deletes, params)
+	c.mu.Unlock()
+	return c.Client.DeleteObjects(ctx, params, optFns...)
+}
+
+func (c *recordingS3API) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.mu.Lock()
+	c.heads = append(c.heads, time.Now())
+	c.mu.Unlock()
+	return c.Client.HeadObject(ctx, params, optFns...)
+}
+
+// putKeys returns the keys of the recorded uploads.
+func (c *recordingS3API) putKeys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.puts))
+	for _, put := range c.puts {
+		keys = append(keys, aws.ToString(put.Key))
+	}
+	return keys
+}
+
+func TestReadArtifactFile_hashesWhileReading(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileContent, hashes, err := readArtifactFile(reader.File[0], artifactFileHashes{}, HashAlgorithmMD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(fileContent) != "hello" || hashes.md5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("unexpected content %q with hash %s", fileContent, hashes.md5)
+	}
+	if hashes.sha256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected SHA-256 hash %s", hashes.sha256)
+	}
+}
+
+func TestMatchesExistingHash_comparesStoredContentHash(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := hashArtifactFile(reader.File[0], HashAlgorithmMD5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects encrypted with KMS have an ETag that is not the MD5 hash of their content.
+	const kmsETag = "0123456789abcdef0123456789abcdef"
+	client := s3fake.New()
+	for key, sha256 := range map[string]string{"index.html": hashes.sha256, "about.html": "other"} {
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:   aws.String("target"),
+			Key:      aws.String(key),
+			Body:     strings.NewReader("hello"),
+			Metadata: map[string]string{ContentHashMetadataKey: sha256},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, _ := newTestDeployment(t, client, nil)
+
+	for key, expected := range map[string]bool{"index.html": true, "about.html": false} {
+		matches, err := d.matchesExistingHash(context.Background(), reader.File[0], key, hashes, kmsETag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matches != expected {
+			t.Errorf("matchesExistingHash(%s) = %t, expected %t", key, matches, expected)
+		}
+	}
+}
+
+func TestUploadDeploymentArtifactFiles_stopsWhenCancelled(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The deployment has no target, so it would fail differently if it tried to upload the file.
+	err = (&Deployment{}).uploadDeploymentArtifactFiles(ctx, reader, DeployedFiles{}, DeployedFiles{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the upload to be cancelled, got %v", err)
+	}
+}
+
+func TestUploadDeploymentArtifactFiles_conditionalWriteConflicts(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is uploaded to a target that had no objects when the deployment listed it. If the object was written
+	// since, it was written either with the content of the file, like by a conditional write the SDK retried after it
+	// had succeeded, or with other content.
+	for _, written := range []bool{true, false} {
+		client := s3fake.New()
+		d, _ := newTestDeployment(t, client, nil)
+		d.ConditionalWrites = true
+		if written {
+			err = d.uploadDeploymentArtifactFiles(context.Background(), reader, DeployedFiles{}, DeployedFiles{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			putTestObjects(t, client, "target", map[string]string{"index.html": "other"})
+		}
+
+		err = d.uploadDeploymentArtifactFiles(context.Background(), reader, DeployedFiles{}, DeployedFiles{})
+		var conflictErr *ConflictError
+		if written && err != nil {
+			t.Errorf("expected a conflict for an object with the content of the file to be ignored, got %v", err)
+		}
+		if !written && !errors.As(err, &conflictErr) {
+			t.Errorf("expected a ConflictError, got %v", err)
+		}
+	}
+}
+
+func TestDeploy_logsUploadsAndTimings(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	d, key := newTestDeployment(t, s3fake.New(), map[string]string{"index.html": "hello", "app.js": "app"})
+	if _, err := d.Deploy(ctx, key, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(map[string][]map[string]interface{})
+	for _, entry := range entries {
+		// Every message of a deployment identifies it, as deployments of several resources are logged together.
+		if entry["deployment_id"] != d.ID || entry["target"] != "target" {
+			t.Errorf("expected %q to be logged with the deployment ID and target, got %v", entry["@message"], entry)
+		}
+		message := entry["@message"].(string)
+		messages[message] = append(messages[message], entry)
+	}
+
+	if len(messages["Downloading deployment artifact"]) != 1 || len(messages["Uploaded file"]) != 2 {
+		t.Errorf("expected the download and every upload to be logged, got %v", entries)
+	}
+	completed := messages["Deployment completed"]
+	if len(completed) != 1 {
+		t.Fatalf("expected the completed deployment to be logged once, got %v", entries)
+	}
+	for _, field := range []string{"download_ms", "upload_ms", "total_ms"} {
+		if _, found := completed[0][field]; !found {
+			t.Errorf("expected the timing %s to be logged, got %v", field, completed[0])
+		}
+	}
+	if completed[0]["files"] != 2.0 || completed[0]["bytes_uploaded"] != 8.0 {
+		t.Errorf("expected the number of files and bytes uploaded to be logged, got %v", completed[0])
+	}
+}
@@ -0,0 +1,26 @@
+// Package deployer deploys static sites from ZIP artifacts to object storage. It is what the static-file-deploy
+// Terraform provider deploys with, and can be used by other tools to deploy with exactly the same semantics.
+//
+// A Deployer holds the AWS configuration and the defaults shared by deployments. Each Deployment deploys one
+// artifact to one TargetStore: an S3 bucket, a Google Cloud Storage bucket, an Azure Blob Storage container, or any
+// other implementation of the interface. S3 targets send their requests through S3API, so that they can be backed
+// by any client implementing it:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	d := &deployer.Deployer{DefaultAWSConfig: cfg}
+//
+//	deployment := d.NewDeploymentToTarget("artifacts", deployer.NewS3Store(s3.NewFromConfig(cfg), "www.example.com"))
+//	deployment.DeleteRemovedFiles = true
+//	files, err := deployment.Deploy(ctx, "site/build-42.zip", nil)
+//
+// Setting Deployer.S3Client makes every S3 request go through the given clients instead, e.g. an in-memory
+// s3fake.Client to test deployments without AWS.
+//
+// Deploy returns the hashes of the deployed files, keyed by file name. ObjectHashes returns them keyed by the keys
+// of the objects in the target, and Summary and Report describe the outcome of the deployment.
+//
+// Progress is logged with terraform-plugin-log, which discards the logs unless the context has a logger.
+package deployer
@@ -0,0 +1,113 @@
+package deployer
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"sort"
+	"time"
+)
+
+// FileHashes returns the base64 encoded SHA-256 hashes of the given artifact files, such as those returned by Deploy,
+// keyed by file name. They are the hashes Terraform's filebase64sha256 function returns for the same files, and can
+// be used as subresource integrity hashes.
+func (d *Deployment) FileHashes(files DeployedFiles) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for name := range files {
+		digest, err := hex.DecodeString(d.contentHashes[name])
+		if err != nil || len(digest) == 0 {
+			continue
+		}
+		hashes[name] = base64.StdEncoding.EncodeToString(digest)
+	}
+	return hashes
+}
+
+// ChangedFiles returns the names of the deployed files whose objects were changed or deleted since they were deployed,
+// given the hashes FileHashes returned for them. Every object is read with a Head request, and its content compared
+// with the SHA-256 hash stored in its metadata, so objects deployed before the hash was stored are never reported.
+// Blue/green releases are not checked, as their keys change with every deployment.
+func (d *Deployment) ChangedFiles(ctx context.Context, hashes map[string]string) ([]string, error) {
+	if d.BlueGreen != nil {
+		return nil, nil
+	}
+
+	var changed []string
+	for name, hash := range hashes {
+		head, err := d.target.Head(ctx, d.objectKey(name))
+		if err != nil {
+			return nil, err
+		}
+		if head == nil {
+			changed = append(changed, name)
+			continue
+		}
+		digest, err := base64.StdEncoding.DecodeString(hash)
+		if err != nil || (head.Metadata.ContentSHA256 != "" && head.Metadata.ContentSHA256 != hex.EncodeToString(digest)) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// repairsOnly returns whether deploying the files with the given hashes only needs to upload the RepairFiles again:
+// the deployment has the fingerprint of the previous one, so every other file is still deployed as it was.
+func (d *Deployment) repairsOnly(hashes DeployedFiles) bool {
+	if len(d.RepairFiles) == 0 || d.PreviousFingerprint == "" || d.BlueGreen != nil || d.stagesFiles() {
+		return false
+	}
+	return d.fingerprint(hashes) == d.PreviousFingerprint
+}
+
+// repairFiles uploads the RepairFiles from the artifact again. All other files count as skipped.
+func (d *Deployment) repairFiles(ctx context.Context, artifactZip *zip.Reader, hashes DeployedFiles) error {
+	repair := make(map[string]bool, len(d.RepairFiles))
+	for _, name := range d.RepairFiles {
+		repair[name] = true
+	}
+	tflog.Info(ctx, fmt.Sprintf("Repairing %d files changed outside of deployments", len(repair)))
+	// The repaired objects were changed by someone else, so there is no ETag to write them conditionally against.
+	d.ConditionalWrites = false
+
+	for _, file := range d.orderedFiles(artifactZip) {
+		if !repair[file.Name] {
+			d.filesSkipped++
+			continue
+		}
+
+		content, fileHashes, err := readArtifactFile(file, d.knownFileHashes(file.Name, hashes), d.HashAlgorithm)
+		if err != nil {
+			return err
+		}
+		key := d.objectKey(file.Name)
+		metadata := d.metadataForKey(key)
+		metadata.ContentSHA256 = fileHashes.sha256
+
+		// Objects that still exist count as changed, and deleted ones as added.
+		existing := make(DeployedFiles)
+		head, err := d.target.Head(ctx, key)
+		if err != nil {
+			return err
+		}
+		if head != nil {
+			existing[key] = ""
+		}
+		err = d.retryThrottled(ctx, key, func() error {
+			return d.uploadFile(ctx, file, content, key, fileHashes, metadata, existing)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	d.deployedFingerprint = d.PreviousFingerprint
+	tflog.Info(ctx, "Repaired files changed outside of deployments", map[string]interface{}{
+		"files":    d.filesAdded + d.filesChanged,
+		"total_ms": time.Since(d.startedAt).Milliseconds(),
+	})
+	return nil
+}
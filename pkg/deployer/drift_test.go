@@ -0,0 +1,89 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChangedFiles_detectsChangesOutsideOfDeployments(t *testing.T) {
+	client := s3fake.New()
+	deployment, source := newTestDeployment(t, client, map[string]string{"index.html": "hello", "app.js": "app", "style.css": "style"})
+	deployment.TargetPrefix = "site/"
+
+	files, err := deployment.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := deployment.FileHashes(files)
+	// The base64 encoded SHA-256 hash of "hello", as returned by filebase64sha256.
+	if hashes["index.html"] != "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" || len(hashes) != 3 {
+		t.Fatalf("unexpected file hashes: %v", hashes)
+	}
+
+	changed, err := deployment.ChangedFiles(context.Background(), hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Errorf("expected no changes right after the deployment, got %v", changed)
+	}
+
+	_, err = client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:   aws.String("target"),
+		Key:      aws.String("site/app.js"),
+		Body:     strings.NewReader("app"),
+		Metadata: map[string]string{ContentHashMetadataKey: "0000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.DeleteObject(context.Background(), &s3.DeleteObjectInput{Bucket: aws.String("target"), Key: aws.String("site/style.css")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err = deployment.ChangedFiles(context.Background(), hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"app.js", "style.css"}) {
+		t.Errorf("expected the changed and deleted files to be reported, got %v", changed)
+	}
+}
+
+func TestDeploy_repairsOnlyChangedFiles(t *testing.T) {
+	client := s3fake.New()
+	files := map[string]string{"index.html": "hello", "app.js": "app", "style.css": "style"}
+
+	first, source := newTestDeployment(t, client, files)
+	if _, err := first.Deploy(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+	putTestObjects(t, client, "target", map[string]string{"app.js": "tampered"})
+	_, err := client.DeleteObject(context.Background(), &s3.DeleteObjectInput{Bucket: aws.String("target"), Key: aws.String("style.css")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repair, source := newTestDeployment(t, client, files)
+	repair.PreviousFingerprint = first.Fingerprint()
+	repair.RepairFiles = []string{"app.js", "style.css"}
+	deployed, err := repair.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, files) {
+		t.Errorf("expected the changed files to be repaired, got %q", objects)
+	}
+	if repair.filesAdded != 1 || repair.filesChanged != 1 || repair.filesSkipped != 1 {
+		t.Errorf("expected 1 added, 1 changed and 1 skipped file, got %d, %d and %d", repair.filesAdded, repair.filesChanged, repair.filesSkipped)
+	}
+	if len(deployed) != 3 || repair.Fingerprint() != first.Fingerprint() {
+		t.Errorf("expected the repaired deployment to keep all files and the fingerprint, got %v", deployed)
+	}
+}
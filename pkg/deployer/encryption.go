@@ -0,0 +1,65 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Encryption is the server-side encryption S3 objects are written with, instead of the default encryption of the
+// bucket.
+type Encryption struct {
+	// Algorithm is AES256, aws:kms or aws:kms:dsse.
+	Algorithm types.ServerSideEncryption
+	// KMSKeyID, if set, is the ID or ARN of the KMS key objects are encrypted with by aws:kms and aws:kms:dsse.
+	// S3 uses the AWS managed key if it is not set.
+	KMSKeyID string
+	// BucketKeyEnabled makes S3 encrypt objects with an S3 Bucket Key, which reduces the number of requests to KMS.
+	BucketKeyEnabled bool
+}
+
+// applyToPutObject adds the encryption settings to a PutObject request. Uploads in parts pass them on to the upload.
+func (e *Encryption) applyToPutObject(input *s3.PutObjectInput) {
+	if e == nil {
+		return
+	}
+
+	input.ServerSideEncryption = e.Algorithm
+	input.SSEKMSKeyId = optionalString(e.KMSKeyID)
+	if e.BucketKeyEnabled {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+}
+
+// applyToCopyObject adds the encryption settings to a CopyObject request. A copy is encrypted with the settings of
+// the request, or the default encryption of the bucket, rather than those of the object it was copied from.
+func (e *Encryption) applyToCopyObject(input *s3.CopyObjectInput) {
+	if e == nil {
+		return
+	}
+
+	input.ServerSideEncryption = e.Algorithm
+	input.SSEKMSKeyId = optionalString(e.KMSKeyID)
+	if e.BucketKeyEnabled {
+		input.BucketKeyEnabled = aws.Bool(true)
+	}
+}
+
+// UseEncryption makes the deployment write every object to the target with the given server-side encryption. Only
+// S3 targets support it, and it cannot be combined with a customer-provided key, so it returns an error for targets
+// in other stores and targets read and written with a customer-provided key.
+func (d *Deployment) UseEncryption(encryption *Encryption) error {
+	store, ok := d.target.(*s3Store)
+	if !ok {
+		return fmt.Errorf("server-side encryption settings are only supported for S3 targets, not %s", d.target.Name())
+	}
+	if store.customerKey != nil {
+		return errors.New("server-side encryption settings cannot be combined with a customer-provided encryption key")
+	}
+	withEncryption := *store
+	withEncryption.encryption = encryption
+	d.target = &withEncryption
+	return nil
+}
@@ -0,0 +1,68 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestEncryption_applyToPutObject(t *testing.T) {
+	encryption := &Encryption{Algorithm: types.ServerSideEncryptionAwsKms, KMSKeyID: "alias/site", BucketKeyEnabled: true}
+	input := &s3.PutObjectInput{}
+	encryption.applyToPutObject(input)
+	if input.ServerSideEncryption != types.ServerSideEncryptionAwsKms || aws.ToString(input.SSEKMSKeyId) != "alias/site" || !aws.ToBool(input.BucketKeyEnabled) {
+		t.Errorf("unexpected encryption settings %v, %v, %v", input.ServerSideEncryption, input.SSEKMSKeyId, input.BucketKeyEnabled)
+	}
+
+	input = &s3.PutObjectInput{}
+	(*Encryption)(nil).applyToPutObject(input)
+	if input.ServerSideEncryption != "" || input.SSEKMSKeyId != nil {
+		t.Error("expected no encryption settings without encryption")
+	}
+}
+
+func TestDeployer_defaultEncryptionAndTags(t *testing.T) {
+	defaultEncryption := &Encryption{Algorithm: types.ServerSideEncryptionAwsKms}
+	deployer := &Deployer{Defaults: DeploymentDefaults{
+		Tags:       map[string]string{"team": "web", "env": "prod"},
+		Encryption: defaultEncryption,
+	}}
+
+	d := deployer.NewDeploymentToTarget("", NewS3Store(s3fake.New(), "www"))
+	if d.target.(*s3Store).encryption != defaultEncryption {
+		t.Error("expected the default encryption to be inherited")
+	}
+	d.Tags["env"] = "staging"
+	if deployer.Defaults.Tags["env"] != "prod" {
+		t.Error("expected the default tags to be copied")
+	}
+
+	override := &Encryption{Algorithm: types.ServerSideEncryptionAes256}
+	if err := d.UseEncryption(override); err != nil {
+		t.Fatal(err)
+	}
+	if d.target.(*s3Store).encryption != override {
+		t.Error("expected the encryption of the deployment to replace the default")
+	}
+
+	d = deployer.NewDeploymentToTarget("", NewS3Store(s3fake.New(), "www"))
+	if err := d.UseCustomerKeys(nil, &CustomerKey{Key: "key", KeyMD5: "md5"}); err != nil {
+		t.Fatal(err)
+	}
+	if d.target.(*s3Store).encryption != nil {
+		t.Error("expected a customer-provided key to replace the default encryption")
+	}
+	if err := d.UseEncryption(override); err == nil {
+		t.Error("expected encryption settings to be rejected together with a customer-provided key")
+	}
+
+	d = deployer.NewDeploymentToTarget("", &gcsStore{name: "site"})
+	if d.Tags != nil {
+		t.Errorf("expected no default tags for a target that is not in S3, got %v", d.Tags)
+	}
+	if err := d.UseEncryption(override); err == nil {
+		t.Error("expected encryption settings to be rejected for a target that is not in S3")
+	}
+}
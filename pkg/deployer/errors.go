@@ -0,0 +1,95 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/smithy-go"
+)
+
+// ObjectError is returned when an operation on a bucket fails, and describes which bucket and key it failed for.
+type ObjectError struct {
+	Operation string
+	// Scheme is the URL scheme of the bucket in error messages. It defaults to "s3".
+	Scheme string
+	Bucket string
+	// Key is empty for operations on the bucket itself, such as listing objects.
+	Key string
+	// RequestID is the AWS request ID of the failed request, if a request was made.
+	RequestID string
+	Err       error
+}
+
+func (e *ObjectError) Error() string {
+	location := bucketURL(e.Scheme, e.Bucket)
+	if e.Key != "" {
+		location += "/" + e.Key
+	}
+
+	message := fmt.Sprintf("%s on %s failed", e.Operation, location)
+	if e.RequestID != "" {
+		message += fmt.Sprintf(" (request ID: %s)", e.RequestID)
+	}
+
+	return fmt.Sprintf("%s: %s", message, e.Err)
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// newObjectError wraps an error returned by the S3 client with the context of the failed operation.
+func newObjectError(operation string, bucket string, key string, err error) *ObjectError {
+	objectErr := &ObjectError{
+		Operation: operation,
+		Bucket:    bucket,
+		Key:       key,
+		Err:       err,
+	}
+
+	var responseErr interface{ ServiceRequestID() string }
+	if errors.As(err, &responseErr) {
+		objectErr.RequestID = responseErr.ServiceRequestID()
+	}
+
+	return objectErr
+}
+
+// ConflictError is returned when a conditional write fails because the object was changed
+// by someone else during the deployment, e.g. by another deployment to the same target.
+type ConflictError struct {
+	// Scheme is the URL scheme of the bucket in error messages. It defaults to "s3".
+	Scheme string
+	Bucket string
+	Key    string
+	Err    error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s/%s was modified by someone else during the deployment: %s", bucketURL(e.Scheme, e.Bucket), e.Key, e.Err)
+}
+
+// bucketURL returns the URL of a bucket, e.g. "s3://my-bucket".
+func bucketURL(scheme string, bucket string) string {
+	if scheme == "" {
+		scheme = "s3"
+	}
+	return scheme + "://" + bucket
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// isConditionalWriteConflict returns whether the error is S3 rejecting a conditional write.
+func isConditionalWriteConflict(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		return true
+	}
+	return false
+}
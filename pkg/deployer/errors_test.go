@@ -0,0 +1,62 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestNewObjectError_includesRequestID(t *testing.T) {
+	responseErr := &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+			Err:      errors.New("access denied"),
+		},
+		RequestID: "REQ123",
+	}
+
+	err := newObjectError("PutObject", "my-bucket", "assets/app.js", responseErr)
+
+	if err.RequestID != "REQ123" {
+		t.Errorf("expected request ID to be extracted, got %q", err.RequestID)
+	}
+	expected := "PutObject on s3://my-bucket/assets/app.js failed (request ID: REQ123): " + responseErr.Error()
+	if err.Error() != expected {
+		t.Errorf("unexpected message:\n got: %s\nwant: %s", err.Error(), expected)
+	}
+	if !errors.Is(err, responseErr) {
+		t.Error("expected the original error to be unwrappable")
+	}
+}
+
+func TestNewObjectError_withoutKeyOrRequestID(t *testing.T) {
+	err := newObjectError("ListObjectsV2", "my-bucket", "", errors.New("boom"))
+
+	if err.Error() != "ListObjectsV2 on s3://my-bucket failed: boom" {
+		t.Errorf("unexpected message: %s", err.Error())
+	}
+}
+
+func TestObjectError_usesScheme(t *testing.T) {
+	err := &ObjectError{Operation: "objects.insert", Scheme: "gs", Bucket: "my-bucket", Key: "index.html", Err: errors.New("boom")}
+
+	if err.Error() != "objects.insert on gs://my-bucket/index.html failed: boom" {
+		t.Errorf("unexpected message: %s", err.Error())
+	}
+}
+
+func TestIsConditionalWriteConflict(t *testing.T) {
+	conflict := &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	if !isConditionalWriteConflict(fmt.Errorf("wrapped: %w", conflict)) {
+		t.Error("expected PreconditionFailed to be a conflict")
+	}
+
+	if isConditionalWriteConflict(&smithy.GenericAPIError{Code: "AccessDenied"}) {
+		t.Error("expected AccessDenied not to be a conflict")
+	}
+}
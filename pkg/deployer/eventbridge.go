@@ -0,0 +1,53 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// EventSource is the source of all EventBridge events emitted by the deployer.
+const EventSource = "staticfiledeploy"
+
+// eventDetailType returns the EventBridge detail type for a deployment with the given status.
+func eventDetailType(status string) string {
+	if status == StatusFailed {
+		return "Static File Deployment Failed"
+	}
+	return "Static File Deployment Succeeded"
+}
+
+// PutEventBridgeEvent emits the deployment summary as a custom event on the given event bus.
+func (d *Deployer) PutEventBridgeEvent(ctx context.Context, eventBusName string, summary DeploymentSummary) error {
+	detail, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode deployment summary: %w", err)
+	}
+
+	client := eventbridge.NewFromConfig(d.DefaultAWSConfig)
+
+	result, err := client.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{
+			{
+				EventBusName: aws.String(eventBusName),
+				Source:       aws.String(EventSource),
+				DetailType:   aws.String(eventDetailType(summary.Status)),
+				Detail:       aws.String(string(detail)),
+				Time:         aws.Time(summary.Timestamp),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put event on event bus (%s): %w", eventBusName, err)
+	}
+
+	if result.FailedEntryCount > 0 {
+		entry := result.Entries[0]
+		return fmt.Errorf("event bus (%s) rejected the event: %s: %s", eventBusName, aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage))
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventEntry is an entry of an EventBridge PutEvents request.
+type eventEntry struct {
+	EventBusName string
+	Source       string
+	DetailType   string
+	Detail       string
+}
+
+// newEventBridgeTestDeployer returns a Deployer that records the entries of the PutEvents requests it makes in
+// entries, and answers them with response.
+func newEventBridgeTestDeployer(t *testing.T, entries *[]eventEntry, status int, response string) *Deployer {
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "AWSEvents.PutEvents" {
+			t.Errorf("unexpected operation %s", target)
+		}
+		var input struct{ Entries []eventEntry }
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+		*entries = append(*entries, input.Entries...)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	})
+	return &Deployer{DefaultAWSConfig: cfg}
+}
+
+func TestPutEventBridgeEvent_sendsSummaryAsDetail(t *testing.T) {
+	var entries []eventEntry
+	d := newEventBridgeTestDeployer(t, &entries, http.StatusOK, `{"FailedEntryCount":0,"Entries":[{"EventId":"1"}]}`)
+
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	summaries := []DeploymentSummary{
+		{DeploymentID: "abc", Target: "my-site", FilesDeployed: 3, Status: StatusSucceeded, Timestamp: timestamp},
+		{DeploymentID: "def", Target: "my-site", Status: StatusFailed, Error: "access denied", Timestamp: timestamp},
+	}
+	for _, summary := range summaries {
+		if err := d.PutEventBridgeEvent(context.Background(), "deployments", summary); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected an event per deployment, got %d", len(entries))
+	}
+	for i, detailType := range []string{"Static File Deployment Succeeded", "Static File Deployment Failed"} {
+		entry := entries[i]
+		if entry.EventBusName != "deployments" || entry.Source != EventSource || entry.DetailType != detailType {
+			t.Errorf("expected a %q event from %s on deployments, got %+v", detailType, EventSource, entry)
+		}
+		var detail DeploymentSummary
+		if err := json.Unmarshal([]byte(entry.Detail), &detail); err != nil {
+			t.Fatalf("expected the detail to be JSON: %s", err)
+		}
+		if !reflect.DeepEqual(detail, summaries[i]) {
+			t.Errorf("expected the summary as detail, got %+v", detail)
+		}
+	}
+}
+
+func TestPutEventBridgeEvent_failsOnRejectedEvent(t *testing.T) {
+	var entries []eventEntry
+	d := newEventBridgeTestDeployer(t, &entries, http.StatusOK, `{"FailedEntryCount":1,"Entries":[{"ErrorCode":"InternalFailure","ErrorMessage":"try again"}]}`)
+
+	err := d.PutEventBridgeEvent(context.Background(), "deployments", DeploymentSummary{Status: StatusSucceeded})
+	if err == nil || !strings.Contains(err.Error(), "InternalFailure: try again") {
+		t.Errorf("expected the rejection of the event to be returned, got %v", err)
+	}
+}
+
+func TestPutEventBridgeEvent_failsOnError(t *testing.T) {
+	var entries []eventEntry
+	d := newEventBridgeTestDeployer(t, &entries, http.StatusBadRequest, `{"__type":"ResourceNotFoundException","message":"Event bus missing does not exist."}`)
+
+	err := d.PutEventBridgeEvent(context.Background(), "missing", DeploymentSummary{Status: StatusSucceeded})
+	if err == nil || !strings.Contains(err.Error(), "event bus (missing)") || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected an error for the missing event bus, got %v", err)
+	}
+}
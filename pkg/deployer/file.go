@@ -0,0 +1,96 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"strings"
+)
+
+// File is a single object deployed to a target bucket without an artifact, e.g. robots.txt or a maintenance page.
+// Its content is either given directly, or copied server-side from a source object.
+type File struct {
+	Bucket       string
+	Region       string
+	Key          string
+	Content      *string
+	SourceBucket string
+	SourceKey    string
+	ContentType  string
+	CacheControl string
+}
+
+// DeployedFile is the state of a deployed file in the target bucket.
+type DeployedFile struct {
+	ETag         string
+	ContentType  string
+	CacheControl string
+}
+
+// PutFile deploys a single file and returns its ETag.
+func (d *Deployer) PutFile(ctx context.Context, file File) (string, error) {
+	client := d.s3Client(file.Region)
+
+	if file.Content != nil {
+		result, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:       aws.String(file.Bucket),
+			Key:          aws.String(file.Key),
+			Body:         bytes.NewReader([]byte(*file.Content)),
+			ContentType:  optionalString(file.ContentType),
+			CacheControl: optionalString(file.CacheControl),
+		})
+		if err != nil {
+			return "", newObjectError("PutObject", file.Bucket, file.Key, err)
+		}
+		return strings.Trim(aws.ToString(result.ETag), "\""), nil
+	}
+
+	result, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(file.Bucket),
+		Key:               aws.String(file.Key),
+		CopySource:        aws.String(copySource(file.SourceBucket, file.SourceKey)),
+		MetadataDirective: types.MetadataDirectiveReplace,
+		ContentType:       optionalString(file.ContentType),
+		CacheControl:      optionalString(file.CacheControl),
+	})
+	if err != nil {
+		return "", newObjectError("CopyObject", file.Bucket, file.Key, err)
+	}
+	return strings.Trim(aws.ToString(result.CopyObjectResult.ETag), "\""), nil
+}
+
+// GetDeployedFile returns the state of a deployed file, or nil if it does not exist.
+func (d *Deployer) GetDeployedFile(ctx context.Context, region string, bucket string, key string) (*DeployedFile, error) {
+	head, err := d.s3Client(region).HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, newObjectError("HeadObject", bucket, key, err)
+	}
+
+	return &DeployedFile{
+		ETag:         strings.Trim(aws.ToString(head.ETag), "\""),
+		ContentType:  aws.ToString(head.ContentType),
+		CacheControl: aws.ToString(head.CacheControl),
+	}, nil
+}
+
+// DeleteFile deletes a deployed file.
+func (d *Deployer) DeleteFile(ctx context.Context, region string, bucket string, key string) error {
+	_, err := d.s3Client(region).DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return newObjectError("DeleteObject", bucket, key, err)
+	}
+	return nil
+}
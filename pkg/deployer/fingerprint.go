@@ -0,0 +1,98 @@
+package deployer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// fingerprintContent is everything a deployment writes to the target, so that two deployments with the same
+// fingerprint leave the target in the same state.
+type fingerprintContent struct {
+	// Files maps the keys of the deployed files to the fingerprint of their content and metadata.
+	Files       map[string]string `json:"files"`
+	VersionFile *VersionFile      `json:"version_file,omitempty"`
+	ObjectLock  *ObjectLock       `json:"object_lock,omitempty"`
+}
+
+// fingerprint returns the fingerprint of deploying the given files, keyed by file name.
+func (d *Deployment) fingerprint(hashes DeployedFiles) string {
+	content := fingerprintContent{
+		Files:       make(map[string]string, len(hashes)),
+		VersionFile: d.VersionFile,
+		ObjectLock:  d.ObjectLock,
+	}
+	// The SHA-256 hashes are used, as they are computed with every HashAlgorithm, so that changing it does not
+	// change the fingerprint.
+	for name := range hashes {
+		key := d.objectKey(name)
+		content.Files[key] = resumeFingerprint(d.contentHashes[name], d.metadataForKey(key))
+	}
+
+	// Maps are encoded with sorted keys, so the encoding is the same for the same content.
+	encoded, _ := json.Marshal(content)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns the fingerprint of the files and settings deployed by Deploy, to be passed as
+// PreviousFingerprint to the next deployment to the same target.
+func (d *Deployment) Fingerprint() string {
+	return d.deployedFingerprint
+}
+
+// ContentFingerprint returns a hash of the given file hashes, such as those returned by FileHashes, which only changes
+// when the name or content of a deployed file changes. Unlike Fingerprint, it is the same for deployments of the same
+// files with other settings or from another artifact.
+func ContentFingerprint(hashes map[string]string) string {
+	// Maps are encoded with sorted keys, so the encoding is the same for the same files.
+	encoded, _ := json.Marshal(hashes)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// isUpToDate returns whether deploying the files with the given hashes would not change the target: the deployment
+// has the fingerprint of the previous one, and the target still has every file with the same content and nothing
+// that would be deleted. Without MD5, the content of the objects cannot be compared by their ETags, so the SHA-256
+// hash stored in the metadata of each of them is read instead.
+func (d *Deployment) isUpToDate(ctx context.Context, hashes DeployedFiles, existingFiles DeployedFiles, deployedKeys map[string]bool) (bool, error) {
+	// Blue/green deployments always upload a new release.
+	if d.PreviousFingerprint == "" || d.BlueGreen != nil || d.fingerprint(hashes) != d.PreviousFingerprint {
+		return false, nil
+	}
+
+	for name, hash := range hashes {
+		key := d.objectKey(name)
+		existingHash, found := existingFiles[key]
+		if !found || (d.HashAlgorithm.usesMD5() && existingHash != hash) {
+			return false, nil
+		}
+	}
+	if d.VersionFile != nil {
+		if _, found := existingFiles[d.objectKey(d.VersionFile.Key)]; !found {
+			return false, nil
+		}
+	}
+	// A failed deployment has changed the target since.
+	if _, found := existingFiles[d.resumeProgressKey()]; found {
+		return false, nil
+	}
+	if d.DeleteRemovedFiles && len(removedKeys(existingFiles, deployedKeys, d.KeepFiles)) > 0 {
+		return false, nil
+	}
+
+	if !d.HashAlgorithm.usesMD5() {
+		for name := range hashes {
+			head, err := d.target.Head(ctx, d.objectKey(name))
+			if err != nil {
+				return false, err
+			}
+			if head == nil || head.Metadata.ContentSHA256 != d.contentHashes[name] {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
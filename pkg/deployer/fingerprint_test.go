@@ -0,0 +1,70 @@
+package deployer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsUpToDate(t *testing.T) {
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	hashes := DeployedFiles{"index.html": helloMD5}
+	deployedKeys := map[string]bool{"index.html": true}
+
+	previous := &Deployment{}
+	fingerprint := previous.fingerprint(hashes)
+
+	cases := map[string]struct {
+		deployment *Deployment
+		existing   DeployedFiles
+		expected   bool
+	}{
+		"unchanged": {
+			deployment: &Deployment{PreviousFingerprint: fingerprint},
+			existing:   DeployedFiles{"index.html": helloMD5},
+			expected:   true,
+		},
+		"no previous deployment": {
+			deployment: &Deployment{},
+			existing:   DeployedFiles{"index.html": helloMD5},
+			expected:   false,
+		},
+		"changed in the target": {
+			deployment: &Deployment{PreviousFingerprint: fingerprint},
+			existing:   DeployedFiles{"index.html": "changed"},
+			expected:   false,
+		},
+		"changed metadata": {
+			deployment: &Deployment{PreviousFingerprint: fingerprint, HashedAssetPattern: "assets/*"},
+			existing:   DeployedFiles{"index.html": helloMD5},
+			expected:   false,
+		},
+		"file to delete": {
+			deployment: &Deployment{PreviousFingerprint: fingerprint, DeleteRemovedFiles: true},
+			existing:   DeployedFiles{"index.html": helloMD5, "old.html": "old"},
+			expected:   false,
+		},
+	}
+
+	for name, c := range cases {
+		got, err := c.deployment.isUpToDate(context.Background(), hashes, c.existing, deployedKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected %t, got %t", name, c.expected, got)
+		}
+	}
+}
+
+func TestContentFingerprint(t *testing.T) {
+	fingerprint := ContentFingerprint(map[string]string{"index.html": "a", "app.js": "b"})
+	if fingerprint != ContentFingerprint(map[string]string{"app.js": "b", "index.html": "a"}) {
+		t.Error("expected the fingerprint of the same files to be the same")
+	}
+	if fingerprint == ContentFingerprint(map[string]string{"index.html": "a", "app.js": "c"}) {
+		t.Error("expected the fingerprint to change with the content of a file")
+	}
+	if fingerprint == ContentFingerprint(map[string]string{"index.html": "a", "main.js": "b"}) {
+		t.Error("expected the fingerprint to change with the name of a file")
+	}
+}
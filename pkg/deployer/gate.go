@@ -0,0 +1,121 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"sort"
+	"strings"
+)
+
+// maxDescribeAlarmNames is the highest number of alarm names CloudWatch describes in one request.
+const maxDescribeAlarmNames = 100
+
+// DeploymentGate stops deployments while something is wrong, e.g. during an incident.
+type DeploymentGate struct {
+	// AlarmArns are the ARNs of CloudWatch metric or composite alarms. The deployment is aborted before any files
+	// are uploaded if one of them is in the ALARM state.
+	AlarmArns []string
+}
+
+// DeploymentGateError is returned when a deployment is aborted because its DeploymentGate is closed.
+type DeploymentGateError struct {
+	// Alarms are the ARNs of the alarms in the ALARM state.
+	Alarms []string
+}
+
+func (e *DeploymentGateError) Error() string {
+	return fmt.Sprintf("deployment aborted, as the following alarms are in the ALARM state: %s", strings.Join(e.Alarms, ", "))
+}
+
+// newAlarmClient returns the client the alarms in the given region are described with.
+func (d *Deployment) newAlarmClient(region string) cloudwatch.DescribeAlarmsAPIClient {
+	if d.alarmClient != nil {
+		return d.alarmClient(region)
+	}
+	return cloudwatch.NewFromConfig(d.awsConfig, func(o *cloudwatch.Options) {
+		o.Region = region
+	})
+}
+
+// checkGate returns a DeploymentGateError if one of the alarms of the gate is in the ALARM state, and an error if
+// the alarms cannot be described or do not exist, so that a misconfigured gate does not silently let deployments
+// through.
+func (d *Deployment) checkGate(ctx context.Context) error {
+	// Alarms are described by name, so they are grouped by the region they live in.
+	arnsByName := make(map[string]map[string]string)
+	for _, alarmArn := range d.Gate.AlarmArns {
+		parsed, err := arn.Parse(alarmArn)
+		if err != nil || parsed.Service != "cloudwatch" || !strings.HasPrefix(parsed.Resource, "alarm:") {
+			return fmt.Errorf("invalid CloudWatch alarm ARN (%s)", alarmArn)
+		}
+		if arnsByName[parsed.Region] == nil {
+			arnsByName[parsed.Region] = make(map[string]string)
+		}
+		arnsByName[parsed.Region][strings.TrimPrefix(parsed.Resource, "alarm:")] = alarmArn
+	}
+
+	var alarming []string
+	for region, arns := range arnsByName {
+		states, err := d.describeAlarmStates(ctx, region, arns)
+		if err != nil {
+			return err
+		}
+		for name, alarmArn := range arns {
+			state, found := states[name]
+			if !found {
+				return fmt.Errorf("CloudWatch alarm (%s) of the deployment gate does not exist", alarmArn)
+			}
+			if state == types.StateValueAlarm {
+				alarming = append(alarming, alarmArn)
+			}
+		}
+	}
+
+	if len(alarming) > 0 {
+		sort.Strings(alarming)
+		return &DeploymentGateError{Alarms: alarming}
+	}
+
+	tflog.Debug(ctx, "Deployment gate is open", map[string]interface{}{
+		"alarms": len(d.Gate.AlarmArns),
+	})
+
+	return nil
+}
+
+// describeAlarmStates returns the states of the alarms with the given names in the given region, keyed by name.
+func (d *Deployment) describeAlarmStates(ctx context.Context, region string, arns map[string]string) (map[string]types.StateValue, error) {
+	names := make([]string, 0, len(arns))
+	for name := range arns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	client := d.newAlarmClient(region)
+	states := make(map[string]types.StateValue)
+	for start := 0; start < len(names); start += maxDescribeAlarmNames {
+		paginator := cloudwatch.NewDescribeAlarmsPaginator(client, &cloudwatch.DescribeAlarmsInput{
+			AlarmNames: names[start:min(start+maxDescribeAlarmNames, len(names))],
+			AlarmTypes: []types.AlarmType{types.AlarmTypeMetricAlarm, types.AlarmTypeCompositeAlarm},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe CloudWatch alarms in %s: %w", region, err)
+			}
+			for _, alarm := range page.MetricAlarms {
+				states[aws.ToString(alarm.AlarmName)] = alarm.StateValue
+			}
+			for _, alarm := range page.CompositeAlarms {
+				states[aws.ToString(alarm.AlarmName)] = alarm.StateValue
+			}
+		}
+	}
+
+	return states, nil
+}
@@ -0,0 +1,72 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+// alarmStore is a CloudWatch client describing alarms with the given states, keyed by name.
+type alarmStore map[string]types.StateValue
+
+func (s alarmStore) DescribeAlarms(ctx context.Context, input *cloudwatch.DescribeAlarmsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.DescribeAlarmsOutput, error) {
+	output := &cloudwatch.DescribeAlarmsOutput{}
+	for _, name := range input.AlarmNames {
+		if state, found := s[name]; found {
+			output.MetricAlarms = append(output.MetricAlarms, types.MetricAlarm{AlarmName: aws.String(name), StateValue: state})
+		}
+	}
+	return output, nil
+}
+
+func TestCheckGate(t *testing.T) {
+	alarms := alarmStore{
+		"errors":  types.StateValueAlarm,
+		"latency": types.StateValueOk,
+		"traffic": types.StateValueInsufficientData,
+	}
+	const errorsArn = "arn:aws:cloudwatch:eu-west-1:123456789012:alarm:errors"
+	const latencyArn = "arn:aws:cloudwatch:eu-west-1:123456789012:alarm:latency"
+	const trafficArn = "arn:aws:cloudwatch:eu-north-1:123456789012:alarm:traffic"
+
+	d, _ := newTestDeployment(t, s3fake.New(), nil)
+	d.alarmClient = func(region string) cloudwatch.DescribeAlarmsAPIClient { return alarms }
+
+	d.Gate = &DeploymentGate{AlarmArns: []string{latencyArn, trafficArn}}
+	if err := d.checkGate(context.Background()); err != nil {
+		t.Errorf("expected the gate to be open, got %v", err)
+	}
+
+	d.Gate = &DeploymentGate{AlarmArns: []string{latencyArn, errorsArn}}
+	err := d.checkGate(context.Background())
+	var gateErr *DeploymentGateError
+	if !errors.As(err, &gateErr) {
+		t.Fatalf("expected a DeploymentGateError, got %v", err)
+	}
+	if !reflect.DeepEqual(gateErr.Alarms, []string{errorsArn}) {
+		t.Errorf("unexpected alarms: %v", gateErr.Alarms)
+	}
+}
+
+func TestCheckGate_invalidAlarms(t *testing.T) {
+	d, _ := newTestDeployment(t, s3fake.New(), nil)
+	d.alarmClient = func(region string) cloudwatch.DescribeAlarmsAPIClient { return alarmStore{} }
+
+	for _, alarmArn := range []string{
+		"errors",
+		"arn:aws:sns:eu-west-1:123456789012:topic",
+		"arn:aws:cloudwatch:eu-west-1:123456789012:alarm:missing",
+	} {
+		d.Gate = &DeploymentGate{AlarmArns: []string{alarmArn}}
+		err := d.checkGate(context.Background())
+		var gateErr *DeploymentGateError
+		if err == nil || errors.As(err, &gateErr) {
+			t.Errorf("expected the gate check with %s to fail, got %v", alarmArn, err)
+		}
+	}
+}
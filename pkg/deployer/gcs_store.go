@@ -0,0 +1,190 @@
+package deployer
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"io"
+	"net/http"
+)
+
+// gcsScheme is the URL scheme of Google Cloud Storage buckets.
+const gcsScheme = "gs"
+
+// gcsStore is a TargetStore for a Google Cloud Storage bucket.
+type gcsStore struct {
+	bucket *storage.BucketHandle
+	name   string
+}
+
+// NewGCSTarget returns a TargetStore for the Google Cloud Storage bucket with the given name.
+// Credentials are found the same way as the gcloud CLI does, see https://cloud.google.com/docs/authentication/application-default-credentials.
+func (d *Deployer) NewGCSTarget(ctx context.Context, bucket string) (TargetStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{bucket: client.Bucket(bucket), name: bucket}, nil
+}
+
+func (s *gcsStore) Name() string {
+	return s.name
+}
+
+// newObjectError wraps an error returned by the GCS client with the context of the failed operation.
+func (s *gcsStore) newObjectError(operation string, key string, err error) *ObjectError {
+	return &ObjectError{Operation: operation, Scheme: gcsScheme, Bucket: s.name, Key: key, Err: err}
+}
+
+func (s *gcsStore) List(ctx context.Context, prefix string) (DeployedFiles, error) {
+	query := &storage.Query{Prefix: prefix}
+	err := query.SetAttrSelection([]string{"Name", "MD5"})
+	if err != nil {
+		return nil, err
+	}
+
+	foundFiles := make(DeployedFiles)
+	objects := s.bucket.Objects(ctx, query)
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, s.newObjectError("objects.list", "", err)
+		}
+
+		// Composite objects have no MD5 hash, and are always uploaded again.
+		foundFiles[attrs.Name] = hex.EncodeToString(attrs.MD5)
+	}
+
+	return foundFiles, nil
+}
+
+func (s *gcsStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	attrs, err := s.bucket.Object(key).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, nil
+		}
+		return nil, s.newObjectError("objects.get", key, err)
+	}
+
+	return &ObjectInfo{
+		Size: attrs.Size,
+		Metadata: ObjectMetadata{
+			ContentType:        attrs.ContentType,
+			ContentDisposition: attrs.ContentDisposition,
+			ContentLanguage:    attrs.ContentLanguage,
+			CacheControl:       attrs.CacheControl,
+			ContentSHA256:      attrs.Metadata[ContentHashMetadataKey],
+		},
+	}, nil
+}
+
+func (s *gcsStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, nil
+		}
+		return nil, s.newObjectError("objects.get", key, err)
+	}
+
+	return reader, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, input PutInput) error {
+	if input.ObjectLock != nil {
+		return s.newObjectError("objects.insert", input.Key, errObjectLockNotSupported)
+	}
+
+	object := s.bucket.Object(input.Key)
+	if input.IfNoneMatch {
+		object = object.If(storage.Conditions{DoesNotExist: true})
+	}
+	if input.IfMatch != "" {
+		// GCS preconditions refer to generations rather than hashes, so the hash is checked first, and the upload
+		// is made conditional on the generation that had it.
+		attrs, err := object.Attrs(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return s.newObjectError("objects.get", input.Key, err)
+		}
+		if err != nil || hex.EncodeToString(attrs.MD5) != input.IfMatch {
+			return &ConflictError{Scheme: gcsScheme, Bucket: s.name, Key: input.Key, Err: errors.New("the object does not have the expected hash")}
+		}
+		object = object.If(storage.Conditions{GenerationMatch: attrs.Generation})
+	}
+
+	// Cancelling the context aborts the upload, so that a failed copy does not leave a partial object behind.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := object.NewWriter(ctx)
+	writer.ContentType = input.Metadata.ContentType
+	writer.ContentDisposition = input.Metadata.ContentDisposition
+	writer.ContentLanguage = input.Metadata.ContentLanguage
+	writer.CacheControl = input.Metadata.CacheControl
+	writer.Metadata = input.Metadata.userMetadata()
+	if input.MD5 != "" {
+		writer.MD5, _ = hex.DecodeString(input.MD5)
+	}
+
+	_, err := io.Copy(writer, input.Body)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return &ConflictError{Scheme: gcsScheme, Bucket: s.name, Key: input.Key, Err: err}
+		}
+		return s.newObjectError("objects.insert", input.Key, err)
+	}
+
+	return nil
+}
+
+func (s *gcsStore) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	_, err := s.bucket.Object(destinationKey).CopierFrom(s.bucket.Object(sourceKey)).Run(ctx)
+	if err != nil {
+		return s.newObjectError("objects.rewrite", destinationKey, err)
+	}
+
+	return nil
+}
+
+func (s *gcsStore) UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error {
+	if lock != nil {
+		return s.newObjectError("objects.patch", key, errObjectLockNotSupported)
+	}
+
+	// Empty values remove the metadata from the object. User-defined metadata is merged with that of the object.
+	_, err := s.bucket.Object(key).Update(ctx, storage.ObjectAttrsToUpdate{
+		ContentType:        metadata.ContentType,
+		ContentDisposition: metadata.ContentDisposition,
+		ContentLanguage:    metadata.ContentLanguage,
+		CacheControl:       metadata.CacheControl,
+		Metadata:           metadata.userMetadata(),
+	})
+	if err != nil {
+		return s.newObjectError("objects.patch", key, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the objects one at a time, as the GCS JSON API has no multi-object delete.
+func (s *gcsStore) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		err := s.bucket.Object(key).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return s.newObjectError("objects.delete", key, err)
+		}
+	}
+
+	return nil
+}
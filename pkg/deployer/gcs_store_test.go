@@ -0,0 +1,276 @@
+package deployer
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"google.golang.org/api/option"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gcsPageSize is the number of objects per page of the listings of fakeGCS.
+const gcsPageSize = 2
+
+// gcsObject is an object of fakeGCS, in the format of the GCS JSON API.
+type gcsObject struct {
+	Name               string            `json:"name"`
+	Bucket             string            `json:"bucket"`
+	Size               string            `json:"size"`
+	MD5Hash            string            `json:"md5Hash"`
+	Generation         string            `json:"generation"`
+	ContentType        string            `json:"contentType,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	ContentLanguage    string            `json:"contentLanguage,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// fakeGCS implements the parts of the GCS JSON API used by gcsStore for a single bucket, keeping objects in memory.
+// Listings return gcsPageSize objects per page.
+type fakeGCS struct {
+	bucket string
+
+	mu         sync.Mutex
+	objects    map[string]gcsObject
+	bodies     map[string]string
+	generation int
+	pages      int
+}
+
+// newGCSTestStore returns a gcsStore for the bucket of a new fakeGCS.
+func newGCSTestStore(t *testing.T, bucket string) (*gcsStore, *fakeGCS) {
+	t.Helper()
+
+	fake := &fakeGCS{bucket: bucket, objects: map[string]gcsObject{}, bodies: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := storage.NewClient(context.Background(), option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return &gcsStore{bucket: client.Bucket(bucket), name: bucket}, fake
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objectsPath := "/storage/v1/b/" + f.bucket + "/o"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == objectsPath:
+		f.list(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, objectsPath+"/"):
+		object, found := f.objects[strings.TrimPrefix(r.URL.Path, objectsPath+"/")]
+		if !found {
+			writeGCSError(w, http.StatusNotFound, "No such object")
+			return
+		}
+		writeGCSJSON(w, http.StatusOK, object)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, objectsPath+"/"):
+		key := strings.TrimPrefix(r.URL.Path, objectsPath+"/")
+		if _, found := f.objects[key]; !found {
+			writeGCSError(w, http.StatusNotFound, "No such object")
+			return
+		}
+		delete(f.objects, key)
+		delete(f.bodies, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/upload"+objectsPath:
+		f.insert(w, r)
+	default:
+		writeGCSError(w, http.StatusNotImplemented, r.Method+" "+r.URL.Path+" is not implemented")
+	}
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request) {
+	f.pages++
+	var names []string
+	for name := range f.objects {
+		if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// The page token is the index of the first object of the page.
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	page := map[string]interface{}{"kind": "storage#objects"}
+	items := []gcsObject{}
+	for i := start; i < len(names) && i < start+gcsPageSize; i++ {
+		items = append(items, f.objects[names[i]])
+	}
+	page["items"] = items
+	if start+gcsPageSize < len(names) {
+		page["nextPageToken"] = strconv.Itoa(start + gcsPageSize)
+	}
+	writeGCSJSON(w, http.StatusOK, page)
+}
+
+// insert stores an object uploaded in a multipart request, its metadata followed by its content.
+func (f *fakeGCS) insert(w http.ResponseWriter, r *http.Request) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || r.URL.Query().Get("uploadType") != "multipart" {
+		writeGCSError(w, http.StatusBadRequest, "expected a multipart upload")
+		return
+	}
+	parts := multipart.NewReader(r.Body, params["boundary"])
+	var object gcsObject
+	metadata, err := parts.NextPart()
+	if err == nil {
+		err = json.NewDecoder(metadata).Decode(&object)
+	}
+	var body []byte
+	if err == nil {
+		var media *multipart.Part
+		media, err = parts.NextPart()
+		if err == nil {
+			body, err = io.ReadAll(media)
+		}
+	}
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, exists := f.objects[object.Name]
+	if generation := r.URL.Query().Get("ifGenerationMatch"); generation != "" && (generation == "0" && exists || generation != "0" && generation != existing.Generation) {
+		writeGCSError(w, http.StatusPreconditionFailed, "At least one of the pre-conditions you specified did not hold.")
+		return
+	}
+	sum := md5.Sum(body)
+	if object.MD5Hash != "" && object.MD5Hash != base64.StdEncoding.EncodeToString(sum[:]) {
+		writeGCSError(w, http.StatusBadRequest, "Provided MD5 hash does not match calculated MD5 hash")
+		return
+	}
+
+	f.generation++
+	object.Bucket = f.bucket
+	object.Size = strconv.Itoa(len(body))
+	object.MD5Hash = base64.StdEncoding.EncodeToString(sum[:])
+	object.Generation = strconv.Itoa(f.generation)
+	f.objects[object.Name] = object
+	f.bodies[object.Name] = string(body)
+	writeGCSJSON(w, http.StatusOK, object)
+}
+
+func writeGCSJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeGCSError(w http.ResponseWriter, status int, message string) {
+	writeGCSJSON(w, status, map[string]interface{}{"error": map[string]interface{}{"code": status, "message": message}})
+}
+
+func TestGCSStore_list(t *testing.T) {
+	store, fake := newGCSTestStore(t, "site")
+	for _, key := range []string{"site/index.html", "site/app.js", "site/style.css", "other/index.html"} {
+		if err := store.Put(context.Background(), PutInput{Key: key, Body: strings.NewReader("hello")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := store.List(context.Background(), "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DeployedFiles{"site/app.js": helloMD5, "site/index.html": helloMD5, "site/style.css": helloMD5}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+	if fake.pages != 2 {
+		t.Errorf("expected the objects to be listed in 2 pages, got %d", fake.pages)
+	}
+}
+
+func TestGCSStore_putAndHead(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newGCSTestStore(t, "site")
+
+	metadata := ObjectMetadata{ContentType: "text/html; charset=utf-8", CacheControl: "no-cache", ContentSHA256: "2cf24dba"}
+	err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("hello"), Metadata: metadata, MD5: helloMD5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.bodies["index.html"] != "hello" {
+		t.Errorf("expected the content to be uploaded, got %q", fake.bodies["index.html"])
+	}
+
+	info, err := store.Head(ctx, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Size != 5 || info.Metadata != metadata {
+		t.Errorf("expected the size and metadata of the upload, got %+v", info)
+	}
+	if info, err := store.Head(ctx, "missing.html"); info != nil || err != nil {
+		t.Errorf("expected a missing object to be reported as nil, got %+v, %v", info, err)
+	}
+
+	// A different MD5 hash means that the content was corrupted on the way.
+	err = store.Put(ctx, PutInput{Key: "app.js", Body: strings.NewReader("app"), MD5: helloMD5})
+	var objectErr *ObjectError
+	if !errors.As(err, &objectErr) || objectErr.Scheme != gcsScheme || objectErr.Key != "app.js" {
+		t.Errorf("expected an ObjectError for the corrupted upload, got %v", err)
+	}
+}
+
+func TestGCSStore_putConditions(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newGCSTestStore(t, "site")
+	if err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, input := range map[string]PutInput{
+		"if none match": {Key: "index.html", Body: strings.NewReader("new"), IfNoneMatch: true},
+		"if match":      {Key: "index.html", Body: strings.NewReader("new"), IfMatch: "0123456789abcdef0123456789abcdef"},
+	} {
+		var conflictErr *ConflictError
+		if err := store.Put(ctx, input); !errors.As(err, &conflictErr) {
+			t.Errorf("%s: expected a ConflictError, got %v", name, err)
+		}
+	}
+	if fake.bodies["index.html"] != "hello" {
+		t.Errorf("expected the object to be unchanged, got %q", fake.bodies["index.html"])
+	}
+
+	err := store.Put(ctx, PutInput{Key: "index.html", Body: strings.NewReader("new"), IfMatch: helloMD5})
+	if err != nil || fake.bodies["index.html"] != "new" {
+		t.Errorf("expected an upload matching the hash of the object to replace it, got %q, %v", fake.bodies["index.html"], err)
+	}
+}
+
+func TestGCSStore_delete(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newGCSTestStore(t, "site")
+	for _, key := range []string{"index.html", "app.js", "upload.png"} {
+		if err := store.Put(ctx, PutInput{Key: key, Body: strings.NewReader(key)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Objects that were already deleted are skipped.
+	if err := store.Delete(ctx, []string{"index.html", "missing.html", "app.js"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 1 || fake.bodies["upload.png"] != "upload.png" {
+		t.Errorf("expected only upload.png to be kept, got %v", fake.bodies)
+	}
+}
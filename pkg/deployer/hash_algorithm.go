@@ -0,0 +1,96 @@
+package deployer
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cespare/xxhash/v2"
+	"hash"
+	"strings"
+)
+
+// HashAlgorithm is the algorithm of the file hashes returned by ObjectHashes and sent to deployment hooks. With MD5,
+// deployed objects are compared with the files by their ETags, which S3 computes with MD5. With any other algorithm,
+// MD5 is not used at all: objects are compared by the SHA-256 hash stored in their metadata, and uploads are checked
+// with SHA-256 checksums instead of Content-MD5.
+type HashAlgorithm string
+
+const (
+	HashAlgorithmMD5      HashAlgorithm = "md5"
+	HashAlgorithmSHA1     HashAlgorithm = "sha1"
+	HashAlgorithmSHA256   HashAlgorithm = "sha256"
+	HashAlgorithmXXHash64 HashAlgorithm = "xxhash64"
+)
+
+// HashAlgorithms are the supported hash algorithms, MD5 being the default.
+var HashAlgorithms = []HashAlgorithm{HashAlgorithmMD5, HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmXXHash64}
+
+// ParseHashAlgorithm returns the hash algorithm with the given name, or MD5 if the name is empty.
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	if name == "" {
+		return HashAlgorithmMD5, nil
+	}
+	for _, algorithm := range HashAlgorithms {
+		if strings.EqualFold(name, string(algorithm)) {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported hash algorithm %q", name)
+}
+
+// newMD5 returns a new MD5 hash. Tests replace it to make sure MD5 is not used with other algorithms.
+var newMD5 = md5.New
+
+// usesMD5 returns whether files are hashed with MD5 to compare them with the ETags of deployed objects.
+func (a HashAlgorithm) usesMD5() bool {
+	return a == "" || a == HashAlgorithmMD5
+}
+
+// UseHashAlgorithm sets the HashAlgorithm of the deployment. For algorithms other than MD5, S3 targets are also made
+// to check uploads and the requests S3 requires a checksum for with SHA-256, which the AWS SDK otherwise computes
+// Content-MD5 headers for.
+func (d *Deployment) UseHashAlgorithm(algorithm HashAlgorithm) {
+	d.HashAlgorithm = algorithm
+	store, ok := d.target.(*s3Store)
+	if !ok || algorithm.usesMD5() {
+		return
+	}
+	withChecksums := *store
+	withChecksums.checksumAlgorithm = types.ChecksumAlgorithmSha256
+	d.target = &withChecksums
+}
+
+// newHash returns a hash for the algorithm, or nil for MD5 and SHA-256, which every file is hashed with anyway.
+func (a HashAlgorithm) newHash() hash.Hash {
+	switch a {
+	case HashAlgorithmSHA1:
+		return sha1.New()
+	case HashAlgorithmXXHash64:
+		return xxhash.New()
+	}
+	return nil
+}
+
+// format returns the hash with the given hex encoded digest. Hashes other than MD5 are prefixed with the algorithm,
+// e.g. "sha256:...", so that hashes in the state tell which algorithm they were computed with. MD5 hashes are not,
+// so that they stay the same as those of deployments made before the algorithm could be chosen.
+func (a HashAlgorithm) format(digest string) string {
+	if a.usesMD5() {
+		return digest
+	}
+	return string(a) + ":" + digest
+}
+
+// stateHashes returns the given content hashes of artifact files, keyed by file name, as hashes in the HashAlgorithm of
+// the deployment.
+func (d *Deployment) stateHashes(files DeployedFiles) DeployedFiles {
+	hashes := make(DeployedFiles, len(files))
+	for name, hash := range files {
+		if stateHash, found := d.fileStateHashes[name]; found {
+			hash = stateHash
+		}
+		hashes[name] = hash
+	}
+	return hashes
+}
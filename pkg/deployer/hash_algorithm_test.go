@@ -0,0 +1,149 @@
+package deployer
+
+import (
+	"context"
+	"crypto/md5"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"hash"
+	"testing"
+)
+
+// failOnMD5 makes the test fail if files are hashed with MD5 until it ends.
+func failOnMD5(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { newMD5 = md5.New })
+	newMD5 = func() hash.Hash {
+		t.Error("expected MD5 not to be used")
+		return md5.New()
+	}
+}
+
+func TestDeploy_hashAlgorithm(t *testing.T) {
+	for algorithm, expected := range map[HashAlgorithm]string{
+		HashAlgorithmMD5:      "5d41402abc4b2a76b9719d911017c592",
+		HashAlgorithmSHA1:     "sha1:aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		HashAlgorithmSHA256:   "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		HashAlgorithmXXHash64: "xxhash64:26c7827d889f6da3",
+	} {
+		d, source := newTestDeployment(t, s3fake.New(), map[string]string{"index.html": "hello"})
+		d.HashAlgorithm = algorithm
+		files, err := d.Deploy(context.Background(), source, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hash := d.ObjectHashes(files)["index.html"]; hash != expected {
+			t.Errorf("expected the %s hash %s, got %s", algorithm, expected, hash)
+		}
+	}
+}
+
+func TestDeploy_changedHashAlgorithmDeploysNothing(t *testing.T) {
+	client := s3fake.New()
+
+	previous, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+	if _, err := previous.Deploy(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+	d.HashAlgorithm = HashAlgorithmSHA256
+	d.PreviousFingerprint = previous.Fingerprint()
+	files, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.unchanged {
+		t.Error("expected changing the hash algorithm to not deploy the files again")
+	}
+	if hash := d.ObjectHashes(files)["index.html"]; hash != "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("expected the hashes in the new algorithm, got %s", hash)
+	}
+}
+
+func TestDeploy_withoutMD5(t *testing.T) {
+	failOnMD5(t)
+	client := &recordingS3API{Client: s3fake.New()}
+	putTestObjects(t, client.Client, "target", map[string]string{"old.html": ""})
+
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+	d.DeleteRemovedFiles = true
+	d.UseHashAlgorithm(HashAlgorithmSHA256)
+	if _, err := d.Deploy(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.puts) != 1 {
+		t.Fatalf("expected the file to be uploaded, got %d uploads", len(client.puts))
+	}
+	put := client.puts[0]
+	if put.ContentMD5 != nil || put.ChecksumAlgorithm != types.ChecksumAlgorithmSha256 || aws.ToString(put.ChecksumSHA256) != "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+		t.Errorf("expected the upload to be checked with SHA-256 instead of MD5, got Content-MD5 %v and %s checksum %v", put.ContentMD5, put.ChecksumAlgorithm, aws.ToString(put.ChecksumSHA256))
+	}
+	if len(client.deletes) != 1 || client.deletes[0].ChecksumAlgorithm != types.ChecksumAlgorithmSha256 {
+		t.Error("expected the removed file to be deleted with a SHA-256 checksum instead of Content-MD5")
+	}
+
+	// The next deployment of the same files compares them by the SHA-256 hash in the metadata of the objects.
+	next, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+	next.PreviousFingerprint = d.Fingerprint()
+	next.UseHashAlgorithm(HashAlgorithmSHA256)
+	if _, err := next.Deploy(context.Background(), source, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !next.unchanged || len(client.puts) != 1 {
+		t.Error("expected the deployed files to be found unchanged")
+	}
+}
+
+func TestDeploy_withoutMD5ComparesWithPreviousDeployment(t *testing.T) {
+	failOnMD5(t)
+
+	const (
+		helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+		helloSHA256 = "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="
+	)
+	for name, c := range map[string]struct {
+		previousObjectHash string
+		previousFileHash   string
+		uploaded           bool
+	}{
+		"unchanged":             {helloMD5, helloSHA256, false},
+		"changed in the target": {"0123456789abcdef0123456789abcdef", helloSHA256, true},
+		"changed in the source": {helloMD5, "", true},
+	} {
+		client := &recordingS3API{Client: s3fake.New()}
+		// The object was deployed with MD5 before the SHA-256 hash was stored in its metadata.
+		putTestObjects(t, client.Client, "target", map[string]string{"index.html": "hello"})
+
+		d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+		d.PreviousObjectHashes = DeployedFiles{"index.html": c.previousObjectHash}
+		d.PreviousFileHashes = map[string]string{"index.html": c.previousFileHash}
+		d.UseHashAlgorithm(HashAlgorithmSHA256)
+		if _, err := d.Deploy(context.Background(), source, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if uploaded := len(client.puts) > 0; uploaded != c.uploaded {
+			t.Errorf("%s: expected the file to be uploaded: %t, got %t", name, c.uploaded, uploaded)
+		}
+		if stored := client.Object("target", "index.html").Metadata[ContentHashMetadataKey]; stored != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+			t.Errorf("%s: expected the SHA-256 hash to be stored with the object, got %q", name, stored)
+		}
+	}
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	if algorithm, err := ParseHashAlgorithm(""); err != nil || algorithm != HashAlgorithmMD5 {
+		t.Errorf("expected MD5 by default, got %q, %v", algorithm, err)
+	}
+	if algorithm, err := ParseHashAlgorithm("SHA256"); err != nil || algorithm != HashAlgorithmSHA256 {
+		t.Errorf("expected SHA-256, got %q, %v", algorithm, err)
+	}
+	if _, err := ParseHashAlgorithm("crc32"); err == nil {
+		t.Error("expected an unsupported algorithm to be rejected")
+	}
+}
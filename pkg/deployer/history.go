@@ -0,0 +1,48 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"strconv"
+	"time"
+)
+
+// RecordDeploymentHistory appends a record of the deployment to the given DynamoDB table.
+//
+// The table must use `target` (string) as partition key and `deployed_at` (string) as sort key,
+// so that all deployments to a target can be listed in chronological order.
+func (d *Deployer) RecordDeploymentHistory(ctx context.Context, tableName string, summary DeploymentSummary) error {
+	deployedBy := "unknown"
+	identity, err := sts.NewFromConfig(d.DefaultAWSConfig).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err == nil {
+		deployedBy = aws.ToString(identity.Arn)
+	}
+
+	item := map[string]types.AttributeValue{
+		"target":         &types.AttributeValueMemberS{Value: summary.Target},
+		"deployed_at":    &types.AttributeValueMemberS{Value: summary.Timestamp.Format(time.RFC3339Nano)},
+		"deployment_id":  &types.AttributeValueMemberS{Value: summary.DeploymentID},
+		"deployed_by":    &types.AttributeValueMemberS{Value: deployedBy},
+		"source":         &types.AttributeValueMemberS{Value: summary.Source},
+		"source_version": &types.AttributeValueMemberS{Value: summary.SourceVersion},
+		"files_deployed": &types.AttributeValueMemberN{Value: strconv.Itoa(summary.FilesDeployed)},
+		"status":         &types.AttributeValueMemberS{Value: summary.Status},
+	}
+	if summary.Error != "" {
+		item["error"] = &types.AttributeValueMemberS{Value: summary.Error}
+	}
+
+	_, err = dynamodb.NewFromConfig(d.DefaultAWSConfig).PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record deployment in DynamoDB table (%s): %w", tableName, err)
+	}
+
+	return nil
+}
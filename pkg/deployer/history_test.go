@@ -0,0 +1,121 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// historyTable is a DynamoDB table with the key schema required by RecordDeploymentHistory, keeping items in memory.
+type historyTable struct {
+	name  string
+	items map[string]map[string]map[string]string
+}
+
+// newHistoryTestDeployer returns a Deployer that records deployment history in table. The deployer's identity is
+// callerArn, or unknown to STS if callerArn is empty.
+func newHistoryTestDeployer(t *testing.T, table *historyTable, callerArn string) *Deployer {
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "":
+			// STS uses the query protocol, without a target header.
+			w.Header().Set("Content-Type", "text/xml")
+			if callerArn == "" {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`)
+				return
+			}
+			fmt.Fprintf(w, `<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>%s</Arn><UserId>AROA</UserId><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`, callerArn)
+		case "DynamoDB_20120810.PutItem":
+			var input struct {
+				TableName string
+				Item      map[string]map[string]string
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				t.Error(err)
+			}
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			if input.TableName != table.name {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`)
+				return
+			}
+			table.items[input.Item["target"]["S"]+"/"+input.Item["deployed_at"]["S"]] = input.Item
+			fmt.Fprint(w, `{}`)
+		default:
+			t.Errorf("unexpected operation %s", target)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	return &Deployer{DefaultAWSConfig: cfg}
+}
+
+func TestRecordDeploymentHistory_appendsRecords(t *testing.T) {
+	table := &historyTable{name: "deployments", items: map[string]map[string]map[string]string{}}
+	d := newHistoryTestDeployer(t, table, "arn:aws:sts::123456789012:assumed-role/deploy/ci")
+
+	first := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	summaries := []DeploymentSummary{
+		{DeploymentID: "abc", Source: "artifacts/site.zip", SourceVersion: "v1", Target: "my-site", FilesDeployed: 3, Status: StatusSucceeded, Timestamp: first},
+		{DeploymentID: "def", Source: "artifacts/site.zip", SourceVersion: "v2", Target: "my-site", Status: StatusFailed, Error: "access denied", Timestamp: first.Add(time.Millisecond)},
+	}
+	for _, summary := range summaries {
+		if err := d.RecordDeploymentHistory(context.Background(), "deployments", summary); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// Every deployment is a new item, sorted by the time of the deployment.
+	expected := map[string]map[string]map[string]string{
+		"my-site/2024-05-01T12:00:00Z": {
+			"target":         {"S": "my-site"},
+			"deployed_at":    {"S": "2024-05-01T12:00:00Z"},
+			"deployment_id":  {"S": "abc"},
+			"deployed_by":    {"S": "arn:aws:sts::123456789012:assumed-role/deploy/ci"},
+			"source":         {"S": "artifacts/site.zip"},
+			"source_version": {"S": "v1"},
+			"files_deployed": {"N": "3"},
+			"status":         {"S": StatusSucceeded},
+		},
+		"my-site/2024-05-01T12:00:00.001Z": {
+			"target":         {"S": "my-site"},
+			"deployed_at":    {"S": "2024-05-01T12:00:00.001Z"},
+			"deployment_id":  {"S": "def"},
+			"deployed_by":    {"S": "arn:aws:sts::123456789012:assumed-role/deploy/ci"},
+			"source":         {"S": "artifacts/site.zip"},
+			"source_version": {"S": "v2"},
+			"files_deployed": {"N": "0"},
+			"status":         {"S": StatusFailed},
+			"error":          {"S": "access denied"},
+		},
+	}
+	if !reflect.DeepEqual(table.items, expected) {
+		t.Errorf("expected the history %v, got %v", expected, table.items)
+	}
+}
+
+func TestRecordDeploymentHistory_unknownCaller(t *testing.T) {
+	table := &historyTable{name: "deployments", items: map[string]map[string]map[string]string{}}
+	d := newHistoryTestDeployer(t, table, "")
+
+	summary := DeploymentSummary{DeploymentID: "abc", Target: "my-site", Status: StatusSucceeded, Timestamp: time.Now()}
+	if err := d.RecordDeploymentHistory(context.Background(), "deployments", summary); err != nil {
+		t.Fatalf("expected the deployment to be recorded without the caller identity, got %s", err)
+	}
+	if len(table.items) != 1 {
+		t.Fatalf("expected the deployment to be recorded, got %v", table.items)
+	}
+	for _, item := range table.items {
+		if item["deployed_by"]["S"] != "unknown" {
+			t.Errorf("expected the caller to be unknown, got %v", item["deployed_by"])
+		}
+	}
+
+	if err := d.RecordDeploymentHistory(context.Background(), "missing", summary); err == nil {
+		t.Error("expected an error for a missing table")
+	}
+}
@@ -0,0 +1,170 @@
+package deployer
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InventoryReports are the S3 Inventory reports of the target bucket, which PlanChanges reads the objects in the
+// target from instead of listing them. Listing buckets with hundreds of thousands of objects is slow and expensive,
+// while the reports are at most a day or a week old, which is good enough to preview the changes of a deployment.
+// Deployments always list the target, as files deleted since the report would otherwise not be uploaded again.
+type InventoryReports struct {
+	// Store is the bucket the reports are delivered to.
+	Store TargetStore
+	// Prefix is the prefix the reports of the inventory configuration are delivered under, followed by the time of
+	// each report, e.g. "inventory/source-bucket/config-id/".
+	Prefix string
+}
+
+// inventoryManifest is the manifest.json of an S3 Inventory report.
+type inventoryManifest struct {
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// latestManifestKey returns the key of the manifest of the latest complete report. Reports are delivered under the
+// time they were created, e.g. "2024-01-31T01-00Z/", so the latest sorts last. A report is complete once its
+// manifest.checksum has been written.
+func (r *InventoryReports) latestManifestKey(ctx context.Context) (string, error) {
+	objects, err := r.Store.List(ctx, r.Prefix)
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+	for key := range objects {
+		report, found := strings.CutSuffix(strings.TrimPrefix(key, r.Prefix), "/manifest.json")
+		if !found || strings.Contains(report, "/") {
+			continue
+		}
+		if _, complete := objects[r.Prefix+report+"/manifest.checksum"]; complete && key > latest {
+			latest = key
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no complete S3 Inventory report found under %s in %s", r.Prefix, r.Store.Name())
+	}
+	return latest, nil
+}
+
+// files returns the objects in the latest report whose keys start with the given prefix, with their ETags, and the
+// time the report was created.
+func (r *InventoryReports) files(ctx context.Context, prefix string) (DeployedFiles, time.Time, error) {
+	manifestKey, err := r.latestManifestKey(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	body, err := r.Store.Get(ctx, manifestKey)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if body == nil {
+		return nil, time.Time{}, fmt.Errorf("S3 Inventory manifest %s no longer exists", manifestKey)
+	}
+	defer body.Close()
+
+	var manifest inventoryManifest
+	err = json.NewDecoder(body).Decode(&manifest)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode S3 Inventory manifest %s: %w", manifestKey, err)
+	}
+	if manifest.FileFormat != "CSV" {
+		return nil, time.Time{}, fmt.Errorf("S3 Inventory report %s is in %s format, only CSV is supported", manifestKey, manifest.FileFormat)
+	}
+
+	columns := make(map[string]int)
+	for i, field := range strings.Split(manifest.FileSchema, ",") {
+		columns[strings.TrimSpace(field)] = i
+	}
+	if _, found := columns["Key"]; !found {
+		return nil, time.Time{}, errors.New("S3 Inventory reports must include the Key field")
+	}
+	if _, found := columns["ETag"]; !found {
+		return nil, time.Time{}, errors.New("S3 Inventory reports must include the ETag field to compare files with")
+	}
+
+	files := make(DeployedFiles)
+	for _, file := range manifest.Files {
+		err = r.readFile(ctx, file.Key, columns, prefix, files)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	var createdAt time.Time
+	if millis, err := strconv.ParseInt(manifest.CreationTimestamp, 10, 64); err == nil {
+		createdAt = time.UnixMilli(millis).UTC()
+	}
+	tflog.Info(ctx, "Read objects from S3 Inventory report", map[string]interface{}{
+		"manifest":   manifestKey,
+		"created_at": createdAt.Format(time.RFC3339),
+		"files":      len(files),
+	})
+
+	return files, createdAt, nil
+}
+
+// readFile adds the current objects in a gzipped CSV file of a report whose keys start with the given prefix to
+// files. Reports of versioned buckets also list earlier versions and delete markers, which are skipped.
+func (r *InventoryReports) readFile(ctx context.Context, key string, columns map[string]int, prefix string, files DeployedFiles) error {
+	body, err := r.Store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return fmt.Errorf("S3 Inventory file %s no longer exists", key)
+	}
+	defer body.Close()
+
+	uncompressed, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to read S3 Inventory file %s: %w", key, err)
+	}
+	reader := csv.NewReader(uncompressed)
+	reader.FieldsPerRecord = len(columns)
+
+	field := func(record []string, name string) string {
+		if i, found := columns[name]; found {
+			return record[i]
+		}
+		return ""
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read S3 Inventory file %s: %w", key, err)
+		}
+		if field(record, "IsLatest") == "false" || field(record, "IsDeleteMarker") == "true" {
+			continue
+		}
+
+		// Keys are URL-encoded in reports.
+		objectKey, err := url.QueryUnescape(field(record, "Key"))
+		if err != nil {
+			return fmt.Errorf("invalid key %q in S3 Inventory file %s: %w", field(record, "Key"), key, err)
+		}
+		if strings.HasPrefix(objectKey, prefix) {
+			files[objectKey] = field(record, "ETag")
+		}
+	}
+}
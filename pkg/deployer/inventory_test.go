@@ -0,0 +1,69 @@
+package deployer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestInventoryFile returns a gzipped CSV file of an S3 Inventory report with the given lines.
+func newTestInventoryFile(t *testing.T, lines string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	if _, err := writer.Write([]byte(lines)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testETag(content string) string {
+	hash := md5.Sum([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestInventoryReports_files(t *testing.T) {
+	const prefix = "inventory/site/daily/"
+	client := s3fake.New()
+	putTestObjects(t, client, "inventory", map[string]string{
+		// The latest report is incomplete, so the one before it is read.
+		prefix + "2024-01-02T01-00Z/manifest.json":     `{"fileFormat": "Parquet"}`,
+		prefix + "2024-01-01T01-00Z/manifest.checksum": "checksum",
+		prefix + "2024-01-01T01-00Z/manifest.json": `{
+			"fileFormat": "CSV",
+			"fileSchema": "Bucket, Key, Size, ETag, IsLatest, IsDeleteMarker",
+			"creationTimestamp": "1704070800000",
+			"files": [{"key": "inventory/site/daily/data/report.csv.gz"}]
+		}`,
+		prefix + "data/report.csv.gz": string(newTestInventoryFile(t, ""+
+			`"site","site/index.html","3","`+testETag("new")+`","true","false"`+"\n"+
+			`"site","site/old%20page.html","3","`+testETag("old")+`","true","false"`+"\n"+
+			`"site","site/about.html","3","`+testETag("old")+`","false","false"`+"\n"+
+			`"site","site/deleted.html","0","","true","true"`+"\n"+
+			`"site","other/index.html","3","`+testETag("new")+`","true","false"`+"\n")),
+	})
+	reports := &InventoryReports{Store: NewS3Store(client, "inventory"), Prefix: prefix}
+
+	files, createdAt, err := reports.files(context.Background(), "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := DeployedFiles{"site/index.html": testETag("new"), "site/old page.html": testETag("old")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+	if !createdAt.Equal(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected report time %s", createdAt)
+	}
+}
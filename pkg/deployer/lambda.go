@@ -0,0 +1,78 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// HookPhasePreDeploy is the phase of a hook that is invoked before any files are uploaded.
+	HookPhasePreDeploy = "pre_deploy"
+	// HookPhasePostDeploy is the phase of a hook that is invoked after all files are uploaded.
+	HookPhasePostDeploy = "post_deploy"
+)
+
+// DeploymentManifest is the payload sent to deployment hooks.
+type DeploymentManifest struct {
+	DeploymentID string `json:"deployment_id"`
+	Phase        string `json:"phase"`
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	// Prefix is the prefix the files were uploaded under, if any.
+	Prefix string        `json:"prefix,omitempty"`
+	Files  DeployedFiles `json:"files"`
+}
+
+// manifest returns the manifest of this deployment for the given hook phase.
+func (d *Deployment) manifest(phase string, sourceKey string, files DeployedFiles) DeploymentManifest {
+	return DeploymentManifest{
+		DeploymentID: d.ID,
+		Phase:        phase,
+		Source:       d.sourceName(sourceKey),
+		Target:       d.TargetBucket,
+		Prefix:       d.keyPrefix(),
+		Files:        files,
+	}
+}
+
+// invokeLambdaHook synchronously invokes the given Lambda function with the manifest as payload.
+// An error is returned if the invocation fails or if the function itself returns an error.
+func (d *Deployment) invokeLambdaHook(ctx context.Context, functionArn string, manifest DeploymentManifest) error {
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode deployment manifest: %w", err)
+	}
+
+	tflog.Info(ctx, "Invoking deployment hook", map[string]interface{}{
+		"phase":    manifest.Phase,
+		"function": functionArn,
+	})
+
+	client := lambda.NewFromConfig(d.awsConfig, func(o *lambda.Options) {
+		// The function may live in another region than the provider's default one.
+		if parsedArn, err := arn.Parse(functionArn); err == nil {
+			o.Region = parsedArn.Region
+		}
+	})
+
+	result, err := client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(functionArn),
+		InvocationType: types.InvocationTypeRequestResponse,
+		Payload:        payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to invoke %s hook (%s): %w", manifest.Phase, functionArn, err)
+	}
+
+	if result.FunctionError != nil {
+		return fmt.Errorf("%s hook (%s) returned an error: %s: %s", manifest.Phase, functionArn, aws.ToString(result.FunctionError), result.Payload)
+	}
+
+	return nil
+}
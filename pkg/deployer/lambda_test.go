@@ -0,0 +1,92 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hookInvocation is an invocation of a Lambda function by a deployment hook.
+type hookInvocation struct {
+	Path           string
+	InvocationType string
+	Manifest       DeploymentManifest
+}
+
+// newHookTestDeployment returns a deployment of the given files whose hooks are invoked through a fake Lambda API,
+// which records the invocations and answers them with functionError, if set, as the error of the function.
+func newHookTestDeployment(t *testing.T, client S3API, files map[string]string, invocations *[]hookInvocation, functionError string) (*Deployment, string) {
+	t.Helper()
+
+	d, key := newTestDeployment(t, client, files)
+	d.awsConfig = newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		invocation := hookInvocation{Path: r.URL.Path, InvocationType: r.Header.Get("X-Amz-Invocation-Type")}
+		if err := json.NewDecoder(r.Body).Decode(&invocation.Manifest); err != nil {
+			t.Error(err)
+		}
+		*invocations = append(*invocations, invocation)
+
+		if functionError != "" {
+			w.Header().Set("X-Amz-Function-Error", functionError)
+			_, _ = w.Write([]byte(`{"errorMessage":"smoke test failed"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`null`))
+	})
+	return d, key
+}
+
+func TestDeploy_invokesHooksWithManifest(t *testing.T) {
+	client := s3fake.New()
+	var invocations []hookInvocation
+	d, key := newHookTestDeployment(t, client, map[string]string{"index.html": "hello", "app.js": "app"}, &invocations, "")
+	d.TargetPrefix = "site/"
+	d.PreDeployLambdaArn = "arn:aws:lambda:eu-west-1:123456789012:function:pre"
+	d.PostDeployLambdaArn = "arn:aws:lambda:eu-west-1:123456789012:function:post"
+
+	files, err := d.Deploy(context.Background(), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(invocations) != 2 {
+		t.Fatalf("expected both hooks to be invoked, got %d invocations", len(invocations))
+	}
+	for i, phase := range []string{HookPhasePreDeploy, HookPhasePostDeploy} {
+		invocation := invocations[i]
+		functionArn := []string{d.PreDeployLambdaArn, d.PostDeployLambdaArn}[i]
+		if invocation.Path != "/2015-03-31/functions/"+functionArn+"/invocations" {
+			t.Errorf("expected %s to invoke %s, got %s", phase, functionArn, invocation.Path)
+		}
+		// Hooks are invoked synchronously, so that a failing hook fails the deployment.
+		if invocation.InvocationType != "RequestResponse" {
+			t.Errorf("expected %s to be invoked synchronously, got %q", phase, invocation.InvocationType)
+		}
+		manifest := invocation.Manifest
+		if manifest.Phase != phase || manifest.DeploymentID != d.ID || manifest.Source != key || manifest.Target != "target" || manifest.Prefix != "site/" {
+			t.Errorf("unexpected %s manifest %+v", phase, manifest)
+		}
+		if !reflect.DeepEqual(manifest.Files, files) {
+			t.Errorf("expected the %s manifest to list the deployed files %v, got %v", phase, files, manifest.Files)
+		}
+	}
+}
+
+func TestDeploy_failsOnHookFunctionError(t *testing.T) {
+	client := s3fake.New()
+	var invocations []hookInvocation
+	d, key := newHookTestDeployment(t, client, map[string]string{"index.html": "hello"}, &invocations, "Unhandled")
+	d.PreDeployLambdaArn = "arn:aws:lambda:eu-west-1:123456789012:function:pre"
+
+	_, err := d.Deploy(context.Background(), key, nil)
+	if err == nil || !strings.Contains(err.Error(), "pre_deploy hook ("+d.PreDeployLambdaArn+") returned an error: Unhandled") || !strings.Contains(err.Error(), "smoke test failed") {
+		t.Fatalf("expected the error of the function to fail the deployment, got %v", err)
+	}
+	if objects := testObjects(client, "target"); len(objects) != 0 {
+		t.Errorf("expected no files to be deployed after the pre-deploy hook failed, got %v", objects)
+	}
+}
@@ -0,0 +1,139 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"net/url"
+	"strings"
+)
+
+const (
+	// ImmutableCacheControl is the Cache-Control of files matching the hashed asset pattern. Their names change whenever
+	// their content does, so they can be cached forever.
+	ImmutableCacheControl = "public, max-age=31536000, immutable"
+	// ShortCacheControl is the Cache-Control of other files when a hashed asset pattern is configured, so that
+	// entry points such as index.html referring to the hashed assets are picked up quickly after a deployment.
+	ShortCacheControl = "public, max-age=60"
+)
+
+// MetadataRule sets a metadata value on objects whose key matches Pattern.
+// Patterns are globs where "*" matches any sequence of characters including "/".
+type MetadataRule struct {
+	Pattern string
+	Value   string
+}
+
+// valueForKey returns the value of the first rule matching the given key, or an empty string if none match.
+func valueForKey(rules []MetadataRule, key string) string {
+	for _, rule := range rules {
+		if globRegexp(rule.Pattern).MatchString(key) {
+			return rule.Value
+		}
+	}
+	return ""
+}
+
+// ContentHashMetadataKey is the user-defined metadata key the SHA-256 hash of the content of deployed files is stored
+// under, e.g. as x-amz-meta-sfd-hash on S3. Azure only allows C# identifiers as metadata names, so it uses
+// azureContentHashMetadataKey instead.
+const ContentHashMetadataKey = "sfd-hash"
+
+// ObjectMetadata is the metadata a deployed object should have.
+type ObjectMetadata struct {
+	ContentType        string
+	ContentDisposition string
+	ContentLanguage    string
+	CacheControl       string
+	// ContentSHA256 is the hex encoded SHA-256 hash of the content of the object, if known. Unlike ETags, it does not
+	// depend on how the object was encrypted or uploaded. It is not compared by matches, as the content is compared
+	// before the metadata.
+	ContentSHA256 string
+}
+
+// userMetadata returns the user-defined metadata of objects with this metadata, or nil if there is none.
+func (m ObjectMetadata) userMetadata() map[string]string {
+	if m.ContentSHA256 == "" {
+		return nil
+	}
+	return map[string]string{ContentHashMetadataKey: m.ContentSHA256}
+}
+
+// metadataForKey returns the metadata the object with the given key should be deployed with.
+func (d *Deployment) metadataForKey(key string) ObjectMetadata {
+	return ObjectMetadata{
+		ContentType:        d.contentTypeForKey(key),
+		ContentDisposition: valueForKey(d.ContentDispositionRules, key),
+		ContentLanguage:    valueForKey(d.ContentLanguageRules, key),
+		CacheControl:       d.cacheControlForKey(key),
+	}
+}
+
+// cacheControlForKey returns the Cache-Control the object with the given key should be deployed with.
+func (d *Deployment) cacheControlForKey(key string) string {
+	if d.HashedAssetPattern == "" {
+		return ""
+	}
+	if globRegexp(d.HashedAssetPattern).MatchString(key) {
+		return ImmutableCacheControl
+	}
+	return ShortCacheControl
+}
+
+// matches returns whether an existing object with the given metadata already has this metadata.
+func (m ObjectMetadata) matches(existing ObjectMetadata) bool {
+	return (m.ContentType == "" || existing.ContentType == m.ContentType) &&
+		existing.ContentDisposition == m.ContentDisposition &&
+		existing.ContentLanguage == m.ContentLanguage &&
+		existing.CacheControl == m.CacheControl
+}
+
+// optionalString returns a pointer to s, or nil if s is empty, for metadata that should not be set when empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// copySource returns the URL-encoded CopySource value for the given object.
+func copySource(bucket string, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		// QueryEscape also encodes "+", which S3 would otherwise decode as a space.
+		segments[i] = strings.ReplaceAll(url.QueryEscape(segment), "+", "%20")
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
+// refreshObjectMetadata makes sure an object whose content is already deployed has the expected metadata.
+// If the metadata differs, it is updated in place instead of the object being re-uploaded.
+// It returns whether the metadata was updated.
+func (d *Deployment) refreshObjectMetadata(ctx context.Context, key string, metadata ObjectMetadata) (bool, error) {
+	head, err := d.target.Head(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	// Without MD5, objects are only compared by the SHA-256 hash in their metadata, so it is added to objects that
+	// were deployed before it was stored.
+	hasContentHash := d.HashAlgorithm.usesMD5() || head == nil || head.Metadata.ContentSHA256 == metadata.ContentSHA256
+	if head != nil && metadata.matches(head.Metadata) && hasContentHash {
+		return false, nil
+	}
+
+	err = d.recordWrite(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	err = d.target.UpdateMetadata(ctx, key, metadata, d.ObjectLock)
+	if err != nil {
+		return false, err
+	}
+
+	tflog.Debug(ctx, "Updated metadata of unchanged file", map[string]interface{}{
+		"key": key,
+	})
+
+	return true, nil
+}
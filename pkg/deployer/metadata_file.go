@@ -0,0 +1,38 @@
+package deployer
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// MetadataFile is a file that is deployed together with the artifact, e.g. a JSON document with the git SHA and
+// time of the build for the frontend to show.
+type MetadataFile struct {
+	// Key is the name of the file, relative to the key prefix of the deployment like the names of artifact files.
+	Key     string
+	Content []byte
+}
+
+// addMetadataFiles adds the metadata files to the artifact entries, replacing artifact files with the same names.
+// They are then deployed, compared and kept like any other file.
+func (d *Deployment) addMetadataFiles(artifactZip *zip.Reader) error {
+	names := make([]string, len(d.MetadataFiles))
+	contents := make(map[string][]byte, len(d.MetadataFiles))
+	for i, file := range d.MetadataFiles {
+		names[i] = file.Key
+		contents[file.Key] = file.Content
+	}
+	entries, err := memoryEntries(names, contents)
+	if err != nil {
+		return fmt.Errorf("failed to add metadata files: %w", err)
+	}
+
+	files := artifactZip.File[:0]
+	for _, file := range artifactZip.File {
+		if _, replaced := contents[file.Name]; !replaced {
+			files = append(files, file)
+		}
+	}
+	artifactZip.File = append(files, entries...)
+	return nil
+}
@@ -0,0 +1,43 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestDeploy_metadataFiles(t *testing.T) {
+	client := s3fake.New()
+	deploy := func(content string) (*Deployment, DeployedFiles, error) {
+		d, source := newTestDeployment(t, client, map[string]string{"index.html": "app", "build-info.json": "{}"})
+		d.MetadataFiles = []MetadataFile{{Key: "build-info.json", Content: []byte(content)}}
+		files, err := d.Deploy(context.Background(), source, nil)
+		return d, files, err
+	}
+
+	_, files, err := deploy(`{"sha":"abc"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(client.Object("target", "build-info.json").Body); got != `{"sha":"abc"}` {
+		t.Errorf("expected the metadata file to replace the artifact file, got %q", got)
+	}
+	if got := client.Object("target", "build-info.json").ContentType; got != "application/json" {
+		t.Errorf("expected the metadata file to be deployed as JSON, got %q", got)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected the metadata file to be deployed once, got %v", files)
+	}
+
+	// A changed metadata file is deployed like a changed artifact file.
+	again, _, err := deploy(`{"sha":"def"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(client.Object("target", "build-info.json").Body); got != `{"sha":"def"}` {
+		t.Errorf("expected the changed metadata file to be deployed, got %q", got)
+	}
+	if again.filesChanged != 1 || again.filesSkipped != 1 {
+		t.Errorf("expected only the metadata file to change, got %d changed and %d skipped", again.filesChanged, again.filesSkipped)
+	}
+}
@@ -0,0 +1,30 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+)
+
+// MetadataService configures how the AWS SDK uses the EC2 instance metadata service (IMDS) to find credentials and
+// the region. Outside of EC2 the SDK only gives up on it after several seconds, which can be avoided by disabling it.
+type MetadataService struct {
+	// Disabled stops the SDK from sending any requests to the instance metadata service.
+	Disabled bool
+	// Endpoint, if set, is the URL of the instance metadata service, e.g. `http://[fd00:ec2::254]` for IPv6 only
+	// instances.
+	Endpoint string
+}
+
+// ConfigOptions returns the options that make an AWS configuration loaded with config.LoadDefaultConfig use the
+// instance metadata service as configured. Settings left empty are read from the environment and shared config
+// files as usual, e.g. AWS_EC2_METADATA_DISABLED.
+func (m MetadataService) ConfigOptions() []func(*config.LoadOptions) error {
+	var options []func(*config.LoadOptions) error
+	if m.Disabled {
+		options = append(options, config.WithEC2IMDSClientEnableState(imds.ClientDisabled))
+	}
+	if m.Endpoint != "" {
+		options = append(options, config.WithEC2IMDSEndpoint(m.Endpoint))
+	}
+	return options
+}
@@ -0,0 +1,34 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"testing"
+)
+
+func TestMetadataService_configOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  MetadataService
+		state    imds.ClientEnableState
+		endpoint string
+	}{
+		{name: "defaults", service: MetadataService{}, state: imds.ClientDefaultEnableState},
+		{name: "disabled", service: MetadataService{Disabled: true}, state: imds.ClientDisabled},
+		{name: "custom endpoint", service: MetadataService{Endpoint: "http://[fd00:ec2::254]"}, state: imds.ClientDefaultEnableState, endpoint: "http://[fd00:ec2::254]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var options config.LoadOptions
+			for _, option := range test.service.ConfigOptions() {
+				if err := option(&options); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if options.EC2IMDSClientEnableState != test.state || options.EC2IMDSEndpoint != test.endpoint {
+				t.Errorf("unexpected options: state %v, endpoint %q", options.EC2IMDSClientEnableState, options.EC2IMDSEndpoint)
+			}
+		})
+	}
+}
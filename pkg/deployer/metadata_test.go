@@ -0,0 +1,76 @@
+package deployer
+
+import (
+	"testing"
+)
+
+func TestCopySource_escapesKeySegments(t *testing.T) {
+	got := copySource("my-bucket", "assets/some file+1.js")
+	if got != "my-bucket/assets/some%20file%2B1.js" {
+		t.Errorf("unexpected copy source: %s", got)
+	}
+}
+
+func TestMetadataForKey_contentDispositionRules(t *testing.T) {
+	d := &Deployment{ContentDispositionRules: []MetadataRule{
+		{Pattern: "downloads/*.pdf", Value: "inline"},
+		{Pattern: "downloads/*", Value: "attachment"},
+	}}
+
+	cases := map[string]string{
+		"downloads/manual.pdf":     "inline",
+		"downloads/2024/setup.exe": "attachment",
+		"index.html":               "",
+	}
+
+	for key, expected := range cases {
+		if got := d.metadataForKey(key).ContentDisposition; got != expected {
+			t.Errorf("content disposition of %q = %q, expected %q", key, got, expected)
+		}
+	}
+}
+
+func TestObjectMetadata_matches(t *testing.T) {
+	metadata := ObjectMetadata{ContentType: "application/pdf", ContentDisposition: "attachment"}
+
+	if !metadata.matches(ObjectMetadata{ContentType: "application/pdf", ContentDisposition: "attachment"}) {
+		t.Error("expected identical metadata to match")
+	}
+	if metadata.matches(ObjectMetadata{ContentType: "application/pdf"}) {
+		t.Error("expected a missing content disposition not to match")
+	}
+}
+
+func TestMetadataForKey_contentLanguageRules(t *testing.T) {
+	d := &Deployment{ContentLanguageRules: []MetadataRule{
+		{Pattern: "no/**", Value: "nb-NO"},
+		{Pattern: "en/*", Value: "en"},
+	}}
+
+	cases := map[string]string{
+		"no/index.html":       "nb-NO",
+		"no/reise/index.html": "nb-NO",
+		"en/index.html":       "en",
+		"index.html":          "",
+	}
+
+	for key, expected := range cases {
+		if got := d.metadataForKey(key).ContentLanguage; got != expected {
+			t.Errorf("content language of %q = %q, expected %q", key, got, expected)
+		}
+	}
+}
+
+func TestMetadataForKey_hashedAssetPattern(t *testing.T) {
+	d := &Deployment{HashedAssetPattern: "assets/*"}
+
+	if got := d.metadataForKey("assets/app.3f2a1b.js").CacheControl; got != ImmutableCacheControl {
+		t.Errorf("unexpected cache control of hashed asset: %q", got)
+	}
+	if got := d.metadataForKey("index.html").CacheControl; got != ShortCacheControl {
+		t.Errorf("unexpected cache control of entry point: %q", got)
+	}
+	if got := (&Deployment{}).metadataForKey("index.html").CacheControl; got != "" {
+		t.Errorf("expected no cache control without a hashed asset pattern, got %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// PutDeploymentMetrics emits custom CloudWatch metrics for the deployment under the given namespace,
+// with the target bucket as dimension.
+func (d *Deployer) PutDeploymentMetrics(ctx context.Context, namespace string, summary DeploymentSummary) error {
+	dimensions := []types.Dimension{
+		{Name: aws.String("Target"), Value: aws.String(summary.Target)},
+	}
+
+	failures := 0.0
+	if summary.Status == StatusFailed {
+		failures = 1
+	}
+
+	datum := func(name string, value float64, unit types.StandardUnit) types.MetricDatum {
+		return types.MetricDatum{
+			MetricName: aws.String(name),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(summary.Timestamp),
+			Value:      aws.Float64(value),
+			Unit:       unit,
+		}
+	}
+
+	_, err := cloudwatch.NewFromConfig(d.DefaultAWSConfig).PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace: aws.String(namespace),
+		MetricData: []types.MetricDatum{
+			datum("FilesUploaded", float64(summary.FilesDeployed), types.StandardUnitCount),
+			datum("BytesUploaded", float64(summary.BytesUploaded), types.StandardUnitBytes),
+			datum("Duration", float64(summary.DurationMs), types.StandardUnitMilliseconds),
+			datum("Failures", failures, types.StandardUnitCount),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put metrics in namespace (%s): %w", namespace, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package deployer
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metricDatum is a metric datum of a CloudWatch PutMetricData request.
+type metricDatum struct {
+	Name       string
+	Dimensions map[string]string
+	Value      string
+	Unit       string
+	Timestamp  string
+}
+
+// metricData returns the namespace and the metric data of the form of a CloudWatch PutMetricData request.
+func metricData(form url.Values) (string, []metricDatum) {
+	var data []metricDatum
+	for i := 1; form.Get(fmt.Sprintf("MetricData.member.%d.MetricName", i)) != ""; i++ {
+		member := fmt.Sprintf("MetricData.member.%d.", i)
+		datum := metricDatum{
+			Name:       form.Get(member + "MetricName"),
+			Dimensions: map[string]string{},
+			Value:      form.Get(member + "Value"),
+			Unit:       form.Get(member + "Unit"),
+			Timestamp:  form.Get(member + "Timestamp"),
+		}
+		for j := 1; form.Get(fmt.Sprintf("%sDimensions.member.%d.Name", member, j)) != ""; j++ {
+			dimension := fmt.Sprintf("%sDimensions.member.%d.", member, j)
+			datum.Dimensions[form.Get(dimension+"Name")] = form.Get(dimension + "Value")
+		}
+		data = append(data, datum)
+	}
+	return form.Get("Namespace"), data
+}
+
+func TestPutDeploymentMetrics(t *testing.T) {
+	var namespaces []string
+	var data [][]metricDatum
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		// The client compresses metric data.
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.Body = body
+		}
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "PutMetricData" {
+			t.Errorf("unexpected request %v: %v", r.Form, err)
+		}
+		namespace, metrics := metricData(r.Form)
+		namespaces = append(namespaces, namespace)
+		data = append(data, metrics)
+
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<PutMetricDataResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></PutMetricDataResponse>`)
+	})
+	d := &Deployer{DefaultAWSConfig: cfg}
+
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	summaries := []DeploymentSummary{
+		{Target: "my-site", FilesDeployed: 3, BytesUploaded: 2048, DurationMs: 1500, Status: StatusSucceeded, Timestamp: timestamp},
+		{Target: "my-site", Status: StatusFailed, Timestamp: timestamp},
+	}
+	for _, summary := range summaries {
+		if err := d.PutDeploymentMetrics(context.Background(), "StaticFileDeploy", summary); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if !reflect.DeepEqual(namespaces, []string{"StaticFileDeploy", "StaticFileDeploy"}) {
+		t.Errorf("expected the metrics to be put in the namespace, got %v", namespaces)
+	}
+	target := map[string]string{"Target": "my-site"}
+	expected := [][]metricDatum{
+		{
+			{Name: "FilesUploaded", Dimensions: target, Value: "3", Unit: "Count", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "BytesUploaded", Dimensions: target, Value: "2048", Unit: "Bytes", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "Duration", Dimensions: target, Value: "1500", Unit: "Milliseconds", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "Failures", Dimensions: target, Value: "0", Unit: "Count", Timestamp: "2024-05-01T12:00:00Z"},
+		},
+		{
+			{Name: "FilesUploaded", Dimensions: target, Value: "0", Unit: "Count", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "BytesUploaded", Dimensions: target, Value: "0", Unit: "Bytes", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "Duration", Dimensions: target, Value: "0", Unit: "Milliseconds", Timestamp: "2024-05-01T12:00:00Z"},
+			{Name: "Failures", Dimensions: target, Value: "1", Unit: "Count", Timestamp: "2024-05-01T12:00:00Z"},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected the metrics %v, got %v", expected, data)
+	}
+}
+
+func TestPutDeploymentMetrics_failsOnError(t *testing.T) {
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidParameterValue</Code><Message>The value AWS/ for parameter Namespace is invalid.</Message></Error></ErrorResponse>`)
+	})
+
+	err := (&Deployer{DefaultAWSConfig: cfg}).PutDeploymentMetrics(context.Background(), "AWS/S3", DeploymentSummary{})
+	if err == nil || !strings.Contains(err.Error(), "namespace (AWS/S3)") || !strings.Contains(err.Error(), "InvalidParameterValue") {
+		t.Errorf("expected an error for the invalid namespace, got %v", err)
+	}
+}
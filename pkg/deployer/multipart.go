@@ -0,0 +1,72 @@
+package deployer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultMultipartThreshold is the size from which files are uploaded to S3 in parts.
+	DefaultMultipartThreshold = 100 << 20
+	// DefaultMultipartPartSize is the size of each part of a multipart upload.
+	DefaultMultipartPartSize = 16 << 20
+	// DefaultMultipartConcurrency is how many parts of a file are uploaded at the same time.
+	DefaultMultipartConcurrency = 5
+)
+
+// MultipartUpload configures how large files are uploaded to S3 in parts. Zero values are replaced by the defaults.
+// Other target stores upload large files in parts on their own.
+type MultipartUpload struct {
+	Threshold   int64
+	PartSize    int64
+	Concurrency int
+}
+
+// withDefaults returns the configuration with the defaults filled in.
+func (m MultipartUpload) withDefaults() MultipartUpload {
+	if m.Threshold == 0 {
+		m.Threshold = DefaultMultipartThreshold
+	}
+	if m.PartSize == 0 {
+		m.PartSize = DefaultMultipartPartSize
+	}
+	if m.Concurrency == 0 {
+		m.Concurrency = DefaultMultipartConcurrency
+	}
+	return m
+}
+
+// multipartPartSizer is implemented by target stores that can tell the part size of objects uploaded in parts.
+type multipartPartSizer interface {
+	// PartSize returns the size of the parts of an object uploaded in parts, or zero if it was not.
+	PartSize(ctx context.Context, key string) (int64, error)
+}
+
+// multipartETag returns the ETag S3 gives an object uploaded in parts of the given size: the MD5 hash of the
+// concatenated MD5 hashes of the parts, followed by the number of parts.
+func multipartETag(content io.Reader, partSize int64) (string, error) {
+	var partHashes []byte
+	parts := 0
+	for {
+		hasher := newMD5()
+		n, err := io.CopyN(hasher, content, partSize)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if n == 0 && parts > 0 {
+			break
+		}
+
+		partHashes = hasher.Sum(partHashes)
+		parts++
+		if n < partSize {
+			break
+		}
+	}
+
+	hasher := newMD5()
+	hasher.Write(partHashes)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(hasher.Sum(nil)), parts), nil
+}
@@ -0,0 +1,101 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+func TestMultipartETag(t *testing.T) {
+	cases := map[string]string{
+		"abcdefghij": "446feba4c1b5cc7ad93bf4d44a0e36ac-3",
+		// The content fills the last part exactly.
+		"abcdefgh": "cb93ad6c9c920e2602b79a11ded63ddb-2",
+	}
+
+	for content, expected := range cases {
+		etag, err := multipartETag(strings.NewReader(content), 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if etag != expected {
+			t.Errorf("ETag of %q = %s, expected %s", content, etag, expected)
+		}
+	}
+}
+
+func TestMultipartUpload_withDefaults(t *testing.T) {
+	multipart := MultipartUpload{PartSize: 8 << 20}.withDefaults()
+
+	if multipart.PartSize != 8<<20 {
+		t.Errorf("expected the configured part size to be kept, got %d", multipart.PartSize)
+	}
+	if multipart.Threshold != DefaultMultipartThreshold || multipart.Concurrency != DefaultMultipartConcurrency {
+		t.Errorf("expected defaults for the other settings, got %+v", multipart)
+	}
+}
+
+func TestMatchesExistingHash_usesPartSizeOfObject(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"video.mp4": "abcdefghij"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The object was uploaded in parts of 4 bytes rather than the configured part size.
+	client := s3fake.New()
+	uploadInParts(t, client, "target", "video.mp4", "abcd", "efgh", "ij")
+	d, _ := newTestDeployment(t, client, nil)
+	etag := client.Object("target", "video.mp4").ETag
+	if etag != "446feba4c1b5cc7ad93bf4d44a0e36ac-3" {
+		t.Fatalf("unexpected ETag of the object uploaded in parts: %s", etag)
+	}
+	matches, err := d.matchesExistingHash(context.Background(), reader.File[0], "video.mp4", artifactFileHashes{}, etag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matches {
+		t.Error("expected the multipart ETag to match the content")
+	}
+}
+
+// uploadInParts uploads an object with the given parts to the bucket.
+func uploadInParts(t *testing.T, client *s3fake.Client, bucket string, key string, parts ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	upload, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var completed []types.CompletedPart
+	for i, part := range parts {
+		partNumber := aws.Int32(int32(i + 1))
+		uploaded, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(key),
+			UploadId:   upload.UploadId,
+			PartNumber: partNumber,
+			Body:       strings.NewReader(part),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		completed = append(completed, types.CompletedPart{ETag: uploaded.ETag, PartNumber: partNumber})
+	}
+	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
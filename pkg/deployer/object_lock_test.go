@@ -0,0 +1,40 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"testing"
+	"time"
+)
+
+func TestObjectLock_applyToPutObject(t *testing.T) {
+	retainUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	lock := &ObjectLock{Mode: types.ObjectLockModeCompliance, RetainUntil: &retainUntil, LegalHold: true}
+
+	input := &s3.PutObjectInput{}
+	lock.applyToPutObject(input)
+
+	if input.ObjectLockMode != types.ObjectLockModeCompliance {
+		t.Errorf("unexpected mode: %s", input.ObjectLockMode)
+	}
+	if input.ObjectLockRetainUntilDate == nil || !input.ObjectLockRetainUntilDate.Equal(retainUntil) {
+		t.Errorf("unexpected retain until date: %v", input.ObjectLockRetainUntilDate)
+	}
+	if input.ObjectLockLegalHoldStatus != types.ObjectLockLegalHoldStatusOn {
+		t.Errorf("unexpected legal hold status: %s", input.ObjectLockLegalHoldStatus)
+	}
+	if input.ChecksumAlgorithm == "" {
+		t.Error("expected a checksum algorithm to be set")
+	}
+}
+
+func TestObjectLock_nilLeavesInputUnchanged(t *testing.T) {
+	var lock *ObjectLock
+
+	input := &s3.PutObjectInput{}
+	lock.applyToPutObject(input)
+
+	if input.ObjectLockMode != "" || input.ObjectLockRetainUntilDate != nil || input.ObjectLockLegalHoldStatus != "" || input.ChecksumAlgorithm != "" {
+		t.Errorf("expected no Object Lock settings, got %+v", input)
+	}
+}
@@ -0,0 +1,83 @@
+package deployer
+
+import (
+	"archive/zip"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+)
+
+// PathRewrite replaces matches of From in the names of artifact entries with To before they are deployed.
+// To may refer to capture groups of From, e.g. "${1}".
+type PathRewrite struct {
+	From *regexp.Regexp
+	To   string
+}
+
+// normalizeEntryName makes entry names of archives built on different platforms consistent, by replacing
+// Windows path separators and converting the name to Unicode normalization form C.
+func normalizeEntryName(name string) string {
+	return norm.NFC.String(strings.ReplaceAll(name, "\\", "/"))
+}
+
+// rewriteEntryNames normalizes the names of the artifact entries, selects the entries under SourceRoot, stripping it
+// from their names, and applies the path rewrites to the names in order.
+// Entries whose name is rewritten to an empty string, or whose key matches one of the UnmanagedPaths, are not
+// deployed.
+func (d *Deployment) rewriteEntryNames(artifactZip *zip.Reader) {
+	unmanaged := d.unmanagedPatterns()
+	sourceRoot := d.SourceRoot
+	if sourceRoot != "" && !strings.HasSuffix(sourceRoot, "/") {
+		sourceRoot += "/"
+	}
+
+	files := artifactZip.File[:0]
+	for _, file := range artifactZip.File {
+		file.Name = normalizeEntryName(file.Name)
+
+		if !strings.HasPrefix(file.Name, sourceRoot) {
+			continue
+		}
+		file.Name = strings.TrimPrefix(file.Name, sourceRoot)
+
+		for _, rewrite := range d.PathRewrites {
+			file.Name = rewrite.From.ReplaceAllString(file.Name, rewrite.To)
+		}
+		if file.Name != "" && !matchesAny(unmanaged, d.objectKey(file.Name)) {
+			files = append(files, file)
+		}
+	}
+	artifactZip.File = files
+}
+
+// GlobSyntaxDescription describes the syntax of the glob patterns matched by globRegexp, for use in the documentation
+// of attributes taking such patterns.
+const GlobSyntaxDescription = "`*` matches any characters including `/`, and `?` matches a single character."
+
+// globRegexp converts a glob pattern to a regular expression. As with the --exclude option of
+// "aws s3 sync", "*" matches any sequence of characters including "/", and "?" matches any single character.
+func globRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+func matchesAny(patterns []*regexp.Regexp, key string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
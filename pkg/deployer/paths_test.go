@@ -0,0 +1,102 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// newTestZip returns a zip reader with empty entries with the given names.
+func newTestZip(t *testing.T, names ...string) *zip.Reader {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for _, name := range names {
+		if _, err := writer.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+func entryNames(artifactZip *zip.Reader) []string {
+	var names []string
+	for _, file := range artifactZip.File {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestRewriteEntryNames(t *testing.T) {
+	artifactZip := newTestZip(t, "build/", "build/index.html", "build/assets/app.js")
+	d := &Deployment{PathRewrites: []PathRewrite{
+		{From: regexp.MustCompile("^build/"), To: ""},
+		{From: regexp.MustCompile(`^assets/(.*)\.js$`), To: "static/${1}.js"},
+	}}
+
+	d.rewriteEntryNames(artifactZip)
+
+	expected := []string{"index.html", "static/app.js"}
+	if got := entryNames(artifactZip); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRewriteEntryNames_sourceRoot(t *testing.T) {
+	artifactZip := newTestZip(t, "README.md", "dist/", "dist/index.html", "dist/assets/app.js", "distribution.txt")
+	d := &Deployment{SourceRoot: "dist"}
+
+	d.rewriteEntryNames(artifactZip)
+
+	expected := []string{"index.html", "assets/app.js"}
+	if got := entryNames(artifactZip); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestNormalizeEntryName(t *testing.T) {
+	cases := map[string]string{
+		"assets\\app.js":       "assets/app.js",
+		"caf\u0065\u0301.html": "caf\u00e9.html",
+		"index.html":           "index.html",
+	}
+
+	for name, expected := range cases {
+		if got := normalizeEntryName(name); got != expected {
+			t.Errorf("normalizeEntryName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"uploads/*", "uploads/avatar.png", true},
+		{"uploads/*", "uploads/2024/avatar.png", true},
+		{"uploads/*", "assets/uploads/avatar.png", false},
+		{".well-known/*", ".well-known/security.txt", true},
+		{"*.pdf", "docs/manual.pdf", true},
+		{"robots.tx?", "robots.txt", true},
+		{"robots.txt", "robots_txt", false},
+	}
+
+	for _, c := range cases {
+		if got := globRegexp(c.pattern).MatchString(c.key); got != c.match {
+			t.Errorf("globRegexp(%q) matching %q = %t, expected %t", c.pattern, c.key, got, c.match)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package deployer
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// PlannedChanges are the files a deployment would add to, change in, and delete from the target, by key.
+type PlannedChanges struct {
+	Added   []string
+	Changed []string
+	Deleted []string
+	// ReportedAt is the time the S3 Inventory report the target was compared with was created, if it was read from
+	// InventoryReports.
+	ReportedAt time.Time
+}
+
+// Empty returns whether the deployment would not change any files.
+func (c *PlannedChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Deleted) == 0
+}
+
+// PlanChanges returns the files deploying the artifact with the given key would change, without changing the
+// target. Only the content of files is compared, so files whose metadata would be updated are not included.
+func (d *Deployment) PlanChanges(ctx context.Context, key string, version *string) (*PlannedChanges, error) {
+	downloaded, err := d.getDeploymentArtifact(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+	defer downloaded.Close()
+	artifactZip := downloaded.Reader
+
+	hashes, err := d.getDeploymentArtifactFileHashes(ctx, artifactZip)
+	if err != nil {
+		return nil, err
+	}
+	changes := &PlannedChanges{}
+	var existingFiles DeployedFiles
+	if d.Inventory != nil {
+		existingFiles, changes.ReportedAt, err = d.Inventory.files(ctx, d.keyPrefix())
+		existingFiles = d.withoutUnmanaged(existingFiles)
+	} else {
+		existingFiles, err = d.HashesForDeployedFiles(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range artifactZip.File {
+		key := d.objectKey(file.Name)
+		existingHash, found := existingFiles[key]
+		if !found {
+			changes.Added = append(changes.Added, key)
+			continue
+		}
+
+		unchanged, err := d.matchesExistingHash(ctx, file, key, d.knownFileHashes(file.Name, hashes), existingHash)
+		if err != nil {
+			return nil, err
+		}
+		if !unchanged {
+			changes.Changed = append(changes.Changed, key)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Changed)
+
+	// Blue/green deployments upload to a new prefix, so there is nothing to delete.
+	if d.DeleteRemovedFiles && d.BlueGreen == nil {
+		changes.Deleted = removedKeys(existingFiles, d.deployedKeys(artifactZip), d.KeepFiles)
+	}
+
+	return changes, nil
+}
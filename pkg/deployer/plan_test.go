@@ -0,0 +1,28 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+func TestPlanChanges(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "old", "about.html": "about", "old.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about", "new.html": "new"})
+	d.DeleteRemovedFiles = true
+
+	changes, err := d.PlanChanges(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &PlannedChanges{Added: []string{"new.html"}, Changed: []string{"index.html"}, Deleted: []string{"old.html"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "old", "about.html": "about", "old.html": "old"}) {
+		t.Errorf("expected the target to be left unchanged, got %q", objects)
+	}
+}
@@ -0,0 +1,164 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PreflightKey is the key of the object written to and deleted from the target before a deployment with
+// PreflightWriteProbe uploads any files, under the TargetPrefix of the deployment.
+const PreflightKey = ".staticfiledeploy-preflight"
+
+// PreflightCheck is the part of a deployment a preflight check failed for.
+type PreflightCheck string
+
+const (
+	// PreflightSource is the check that the artifact can be read from the source.
+	PreflightSource PreflightCheck = "source"
+	// PreflightTarget is the check that the target can be accessed, and with PreflightWriteProbe that objects can be
+	// written to and deleted from it.
+	PreflightTarget PreflightCheck = "target"
+)
+
+// PreflightError is returned when a deployment fails its preflight checks, before any files were deployed.
+type PreflightError struct {
+	Check PreflightCheck
+	// Hint describes the likely cause of the error, if it is known.
+	Hint string
+	Err  error
+}
+
+func (e *PreflightError) Error() string {
+	message := fmt.Sprintf("preflight check of the %s failed: %s", e.Check, e.Err)
+	if e.Hint != "" {
+		message += "\n\n" + e.Hint
+	}
+	return message
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// SourceChecker is implemented by source fetchers that can check that an artifact can be read without downloading it.
+type SourceChecker interface {
+	// Check returns the ETag of the artifact at the given location, or an empty string if the source does not have
+	// one, and an error if it cannot be read.
+	Check(ctx context.Context, location *url.URL, version *string) (string, error)
+}
+
+// preflight checks that the artifact with the given key can be read from the source, that an S3 target exists and
+// its settings allow the ACL of the deployment, and with PreflightWriteProbe that the target accepts objects like
+// those of the deployment, so that missing permissions fail the deployment before anything is deployed rather than
+// after some of the files.
+func (d *Deployment) preflight(ctx context.Context, key string, version *string) error {
+	location, err := d.sourceLocation(key)
+	if err != nil {
+		return fmt.Errorf("invalid source %q: %w", key, err)
+	}
+	fetcher, err := d.Sources.fetcher(location)
+	if err != nil {
+		return err
+	}
+	if checker, ok := fetcher.(SourceChecker); ok {
+		_, err = checker.Check(ctx, location, version)
+		if err != nil {
+			return &PreflightError{Check: PreflightSource, Hint: preflightHint(PreflightSource, err), Err: err}
+		}
+	}
+
+	if store, ok := d.target.(*s3Store); ok {
+		if err := store.checkBucket(ctx); err != nil {
+			return &PreflightError{Check: PreflightTarget, Hint: preflightHint(PreflightTarget, err), Err: err}
+		}
+		if err := store.checkACL(ctx); err != nil {
+			return &PreflightError{Check: PreflightTarget, Err: err}
+		}
+	}
+	if !d.PreflightWriteProbe {
+		return nil
+	}
+
+	// Writing an object also checks that the KMS key the target encrypts objects with can be used.
+	content := []byte(time.Now().UTC().Format(time.RFC3339))
+	probeKey := d.TargetPrefix + PreflightKey
+	err = d.target.Put(ctx, PutInput{
+		Key:      probeKey,
+		Body:     bytes.NewReader(content),
+		Size:     int64(len(content)),
+		Metadata: ObjectMetadata{ContentType: "text/plain", CacheControl: "no-store"},
+	})
+	if err == nil {
+		err = d.target.Delete(ctx, []string{probeKey})
+	}
+	if err != nil {
+		return &PreflightError{Check: PreflightTarget, Hint: preflightHint(PreflightTarget, err), Err: err}
+	}
+
+	return nil
+}
+
+// preflightHint returns the likely cause of an error returned by a preflight check, or an empty string if it is
+// not known.
+func preflightHint(check PreflightCheck, err error) string {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	code := apiErr.ErrorCode()
+
+	switch {
+	case strings.HasPrefix(code, "KMS.") || (code == "AccessDenied" && strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "kms")):
+		return "The KMS key the bucket encrypts objects with cannot be used. Check that it is enabled, and that its key policy allows the provider's credentials to use it, e.g. with kms:GenerateDataKey and kms:Decrypt."
+	case code == "AccessControlListNotSupported":
+		return "The bucket enforces BucketOwnerEnforced object ownership, which disables ACLs. Remove acl."
+	case code == "AccessDenied" || code == "Forbidden":
+		if check == PreflightSource {
+			return "The provider's credentials are not allowed to read the source. Check that they have s3:GetObject, and s3:GetObjectVersion for versioned sources, and that no bucket policy denies it."
+		}
+		return "The provider's credentials are not allowed to access the target. Check that they have s3:ListBucket and s3:GetEncryptionConfiguration, and s3:PutObject and s3:DeleteObject for preflight_write_probe, and that no bucket policy denies it."
+	case code == "NoSuchBucket" || (check == PreflightTarget && code == "NotFound"):
+		return "The bucket does not exist."
+	case code == "NoSuchKey" || code == "NotFound" || code == "NoSuchVersion":
+		return "The source does not exist. Check the key, and the version if one is given."
+	case code == "PermanentRedirect" || code == "MovedPermanently" || code == "AuthorizationHeaderMalformed" || code == "IllegalLocationConstraintException":
+		if check == PreflightSource {
+			return "The bucket is in another region than the default one the source is read in. Check that the default region is the region of the bucket."
+		}
+		return "The bucket is in another region than the one configured for the target. Check that the region of the target is the region of the bucket."
+	}
+
+	return ""
+}
+
+// checkBucket checks that the bucket exists and that its default encryption can be read, without writing to it.
+// Clients without BucketSettingsAPI are not checked, and neither are stores that do not support default encryption.
+func (s *s3Store) checkBucket(ctx context.Context) error {
+	client, ok := s.client.(BucketSettingsAPI)
+	if !ok {
+		return nil
+	}
+
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err != nil {
+		return newObjectError("HeadBucket", s.bucket, "", err)
+	}
+
+	_, err = client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: aws.String(s.bucket)})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "ServerSideEncryptionConfigurationNotFoundError" || apiErr.ErrorCode() == "NotImplemented") {
+		return nil
+	}
+	if err != nil {
+		return newObjectError("GetBucketEncryption", s.bucket, "", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreflight_checksSourceAndTarget(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	client := &recordingS3API{Client: s3fake.New(), failKey: "site/" + PreflightKey}
+	d, _ := newTestDeployment(t, client, nil)
+	d.TargetPrefix = "site/"
+	d.PreflightWriteProbe = true
+
+	err := d.preflight(context.Background(), "file://"+artifactPath, nil)
+	var preflightErr *PreflightError
+	if !errors.As(err, &preflightErr) || preflightErr.Check != PreflightSource {
+		t.Fatalf("expected the source check to fail, got %v", err)
+	}
+
+	if err := os.WriteFile(artifactPath, newTestArtifact(t, map[string]string{"index.html": "hello"}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err = d.preflight(context.Background(), "file://"+artifactPath, nil)
+	if !errors.As(err, &preflightErr) || preflightErr.Check != PreflightTarget {
+		t.Fatalf("expected the target check to fail, got %v", err)
+	}
+}
+
+func TestPreflight_onlyWritesWithProbe(t *testing.T) {
+	client := &recordingS3API{Client: s3fake.New()}
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+
+	err := d.preflight(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := client.putKeys(); len(keys) != 0 {
+		t.Errorf("expected nothing to be written without the write probe, got %v", keys)
+	}
+}
+
+// bucketSettingsS3API is an s3fake.Client that implements the operations reading bucket settings, failing HeadBucket
+// with headErr and GetBucketEncryption with encryptionErr.
+type bucketSettingsS3API struct {
+	*s3fake.Client
+	headErr       error
+	encryptionErr error
+}
+
+func (c *bucketSettingsS3API) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, c.headErr
+}
+
+func (c *bucketSettingsS3API) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return &s3.GetBucketEncryptionOutput{}, c.encryptionErr
+}
+
+func TestPreflight_checksBucket(t *testing.T) {
+	cases := []struct {
+		headErr       error
+		encryptionErr error
+		contains      string
+	}{
+		{nil, nil, ""},
+		{nil, &smithy.GenericAPIError{Code: "ServerSideEncryptionConfigurationNotFoundError"}, ""},
+		{&smithy.GenericAPIError{Code: "NotFound"}, nil, "does not exist"},
+		{nil, &smithy.GenericAPIError{Code: "AccessDenied"}, "s3:GetEncryptionConfiguration"},
+	}
+	for _, c := range cases {
+		client := &bucketSettingsS3API{Client: s3fake.New(), headErr: c.headErr, encryptionErr: c.encryptionErr}
+		d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+
+		err := d.preflight(context.Background(), source, nil)
+		if c.contains == "" {
+			if err != nil {
+				t.Errorf("expected the check to pass, got %v", err)
+			}
+			continue
+		}
+		var preflightErr *PreflightError
+		if !errors.As(err, &preflightErr) || preflightErr.Check != PreflightTarget || !strings.Contains(err.Error(), c.contains) {
+			t.Errorf("expected an error about %s, got %v", c.contains, err)
+		}
+	}
+}
+
+func TestPreflight_removesProbe(t *testing.T) {
+	client := s3fake.New()
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "hello"})
+	d.PreflightWriteProbe = true
+
+	err := d.preflight(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := client.Keys("target"); len(keys) != 0 {
+		t.Errorf("expected the probe object to be deleted, got %v", keys)
+	}
+}
+
+func TestPreflightHint(t *testing.T) {
+	cases := []struct {
+		check    PreflightCheck
+		err      error
+		contains string
+	}{
+		{PreflightTarget, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}, "s3:PutObject"},
+		{PreflightSource, &smithy.GenericAPIError{Code: "Forbidden"}, "s3:GetObject"},
+		{PreflightTarget, &smithy.GenericAPIError{Code: "AccessDenied", Message: "User is not authorized to perform: kms:GenerateDataKey"}, "KMS key"},
+		{PreflightTarget, &smithy.GenericAPIError{Code: "PermanentRedirect"}, "region of the target"},
+		{PreflightSource, &smithy.GenericAPIError{Code: "AuthorizationHeaderMalformed"}, "default region"},
+		{PreflightTarget, &smithy.GenericAPIError{Code: "AccessControlListNotSupported"}, "Remove acl"},
+		{PreflightTarget, errors.New("connection refused"), ""},
+	}
+
+	for _, c := range cases {
+		hint := preflightHint(c.check, c.err)
+		if c.contains == "" && hint != "" || !strings.Contains(hint, c.contains) {
+			t.Errorf("unexpected hint for %v: %q", c.err, hint)
+		}
+	}
+}
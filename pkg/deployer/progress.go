@@ -0,0 +1,119 @@
+package deployer
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// uploadProgressInterval is how often the progress of the upload of an artifact is logged.
+var uploadProgressInterval = 10 * time.Second
+
+// uploadProgress tracks how much of an artifact has been uploaded, so that the progress of long-running deployments
+// can be logged with the transfer rate and an estimate of the remaining time. The size of a file is counted once it
+// is done, so a large file only adds to the progress after it has been uploaded completely.
+type uploadProgress struct {
+	totalBytes int64
+	totalFiles int
+	startedAt  time.Time
+	// uploadedBytes is the size of the uploaded files, and skippedBytes the size of the files that did not have to be
+	// uploaded. processedFiles is how many files are done, whether they were uploaded or not.
+	uploadedBytes  atomic.Int64
+	skippedBytes   atomic.Int64
+	processedFiles atomic.Int64
+}
+
+// newUploadProgress returns the progress of uploading the given files, starting now.
+func newUploadProgress(files []*zip.File) *uploadProgress {
+	p := &uploadProgress{totalFiles: len(files), startedAt: time.Now()}
+	for _, file := range files {
+		p.totalBytes += int64(file.UncompressedSize64)
+	}
+	return p
+}
+
+// fileDone records that a file was processed. The size of files that were not uploaded counts as processed without
+// being transferred.
+func (p *uploadProgress) fileDone(file *zip.File, uploaded bool) {
+	if uploaded {
+		p.uploadedBytes.Add(int64(file.UncompressedSize64))
+	} else {
+		p.skippedBytes.Add(int64(file.UncompressedSize64))
+	}
+	p.processedFiles.Add(1)
+}
+
+// status returns a message and fields describing the progress after the given time has elapsed. The remaining time
+// is estimated from the transfer rate so far, assuming every file that is left has to be uploaded.
+func (p *uploadProgress) status(elapsed time.Duration) (string, map[string]interface{}) {
+	uploaded := p.uploadedBytes.Load()
+	processed := uploaded + p.skippedBytes.Load()
+	remaining := max(p.totalBytes-processed, 0)
+	fields := map[string]interface{}{
+		"bytes_uploaded":  uploaded,
+		"bytes_processed": processed,
+		"bytes_total":     p.totalBytes,
+		"files_processed": p.processedFiles.Load(),
+		"files_total":     p.totalFiles,
+		"elapsed_ms":      elapsed.Milliseconds(),
+	}
+	message := fmt.Sprintf("%d/%d files processed, %s of %s uploaded", p.processedFiles.Load(), p.totalFiles, formatBytes(uploaded), formatBytes(p.totalBytes))
+	if seconds := elapsed.Seconds(); seconds > 0 && uploaded > 0 {
+		rate := float64(uploaded) / seconds
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second)
+		fields["rate_bytes_per_second"] = int64(rate)
+		fields["eta_seconds"] = int64(eta.Seconds())
+		message += fmt.Sprintf(" at %s/s, about %s remaining", formatBytes(int64(rate)), eta)
+	}
+	return message, fields
+}
+
+// log logs the current progress at INFO level.
+func (p *uploadProgress) log(ctx context.Context) {
+	message, fields := p.status(time.Since(p.startedAt))
+	tflog.Info(ctx, message, fields)
+}
+
+// logEvery logs the progress every interval until the returned function is called.
+func (p *uploadProgress) logEvery(ctx context.Context, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.log(ctx)
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// formatBytes formats a number of bytes with a binary unit, e.g. "1.5 GiB".
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exponent := float64(bytes)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exponent])
+}
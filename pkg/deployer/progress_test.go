@@ -0,0 +1,48 @@
+package deployer
+
+import (
+	"archive/zip"
+	"testing"
+	"time"
+)
+
+func TestUploadProgress_status(t *testing.T) {
+	files := []*zip.File{
+		{FileHeader: zip.FileHeader{Name: "index.html", UncompressedSize64: 1 << 20}},
+		{FileHeader: zip.FileHeader{Name: "video.mp4", UncompressedSize64: 4 << 20}},
+		{FileHeader: zip.FileHeader{Name: "logo.png", UncompressedSize64: 5 << 20}},
+	}
+	progress := newUploadProgress(files)
+
+	message, fields := progress.status(time.Second)
+	if message != "0/3 files processed, 0 B of 10.0 MiB uploaded" {
+		t.Errorf("unexpected message %q", message)
+	}
+	if _, found := fields["eta_seconds"]; found {
+		t.Errorf("expected no estimate before anything is uploaded, got %v", fields)
+	}
+
+	progress.fileDone(files[0], false)
+	progress.fileDone(files[1], true)
+	message, fields = progress.status(2 * time.Second)
+	if message != "2/3 files processed, 4.0 MiB of 10.0 MiB uploaded at 2.0 MiB/s, about 3s remaining" {
+		t.Errorf("unexpected message %q", message)
+	}
+	if fields["bytes_uploaded"] != int64(4<<20) || fields["bytes_processed"] != int64(5<<20) || fields["eta_seconds"] != int64(3) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for bytes, expected := range map[int64]string{
+		512:                "512 B",
+		1536:               "1.5 KiB",
+		3 << 30:            "3.0 GiB",
+		5 << 50:            "5.0 PiB",
+		int64(1.25 * 1e12): "1.1 TiB",
+	} {
+		if got := formatBytes(bytes); got != expected {
+			t.Errorf("expected %d bytes to be formatted as %q, got %q", bytes, expected, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/time/rate"
+	"io"
+)
+
+// rateLimitedStore is a TargetStore that sends at most a given number of requests per second to the store it wraps.
+// Each call counts as one request, even where the store makes several, such as for files uploaded in parts.
+type rateLimitedStore struct {
+	TargetStore
+	limiter *rate.Limiter
+}
+
+// newRateLimitedStore returns a TargetStore sending at most requestsPerSecond requests per second to target.
+// Requests are spread evenly rather than sent in bursts, so that other writers to the bucket are not starved.
+func newRateLimitedStore(target TargetStore, requestsPerSecond int) *rateLimitedStore {
+	return &rateLimitedStore{
+		TargetStore: target,
+		limiter:     rate.NewLimiter(rate.Limit(requestsPerSecond), 1),
+	}
+}
+
+func (s *rateLimitedStore) List(ctx context.Context, prefix string) (DeployedFiles, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.TargetStore.List(ctx, prefix)
+}
+
+func (s *rateLimitedStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.TargetStore.Head(ctx, key)
+}
+
+func (s *rateLimitedStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.TargetStore.Get(ctx, key)
+}
+
+// PartSize returns the part size of the object if the wrapped store can tell it, and zero otherwise.
+func (s *rateLimitedStore) PartSize(ctx context.Context, key string) (int64, error) {
+	sizer, ok := s.TargetStore.(multipartPartSizer)
+	if !ok {
+		return 0, nil
+	}
+	if err := s.limiter.Wait(ctx); err != nil {
+		return 0, err
+	}
+	return sizer.PartSize(ctx, key)
+}
+
+// Tags returns the tags of the object if the wrapped store supports them.
+func (s *rateLimitedStore) Tags(ctx context.Context, key string) (map[string]string, error) {
+	tagger, ok := s.TargetStore.(objectTagger)
+	if !ok {
+		return nil, errTaggingNotSupported
+	}
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return tagger.Tags(ctx, key)
+}
+
+// SetTags replaces the tags of the object if the wrapped store supports them.
+func (s *rateLimitedStore) SetTags(ctx context.Context, key string, tags map[string]string) error {
+	tagger, ok := s.TargetStore.(objectTagger)
+	if !ok {
+		return errTaggingNotSupported
+	}
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return tagger.SetTags(ctx, key, tags)
+}
+
+// PutVersion uploads an object, and returns the ID of the version it created if the wrapped store keeps versions.
+func (s *rateLimitedStore) PutVersion(ctx context.Context, input PutInput) (string, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	versioner, ok := s.TargetStore.(objectVersioner)
+	if !ok {
+		return "", s.TargetStore.Put(ctx, input)
+	}
+	return versioner.PutVersion(ctx, input)
+}
+
+// RestoreVersion restores a version of the object if the wrapped store keeps versions.
+func (s *rateLimitedStore) RestoreVersion(ctx context.Context, key string, versionID string) error {
+	versioner, ok := s.TargetStore.(objectVersioner)
+	if !ok {
+		return fmt.Errorf("%s does not keep versions of objects", s.Name())
+	}
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return versioner.RestoreVersion(ctx, key, versionID)
+}
+
+func (s *rateLimitedStore) Put(ctx context.Context, input PutInput) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.TargetStore.Put(ctx, input)
+}
+
+func (s *rateLimitedStore) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.TargetStore.Copy(ctx, sourceKey, destinationKey)
+}
+
+func (s *rateLimitedStore) UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.TargetStore.UpdateMetadata(ctx, key, metadata, lock)
+}
+
+func (s *rateLimitedStore) Delete(ctx context.Context, keys []string) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.TargetStore.Delete(ctx, keys)
+}
@@ -0,0 +1,37 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedStore_spreadsRequests(t *testing.T) {
+	client := &recordingS3API{Client: s3fake.New()}
+	store := newRateLimitedStore(NewS3Store(client, "target"), 20)
+
+	for i := 0; i < 5; i++ {
+		if _, err := store.Head(context.Background(), "index.html"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first request is sent right away, and the others 50 ms apart.
+	if elapsed := client.heads[4].Sub(client.heads[0]); elapsed < 190*time.Millisecond {
+		t.Errorf("expected 5 requests to take at least 200 ms, took %s", elapsed)
+	}
+}
+
+func TestRateLimitedStore_stopsWhenCancelled(t *testing.T) {
+	store := newRateLimitedStore(NewS3Store(s3fake.New(), "target"), 1)
+	if _, err := store.Head(context.Background(), "index.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Head(ctx, "index.html"); err == nil {
+		t.Error("expected a cancelled request to fail while waiting")
+	}
+}
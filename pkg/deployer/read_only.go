@@ -0,0 +1,69 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned when a read-only deployment tries to write to or delete from its target.
+var ErrReadOnly = errors.New("the target is read-only while refreshing or planning")
+
+// readOnlyStore is a TargetStore that refuses every request changing the store it wraps, so that refreshing and
+// planning a deployment can never modify the target, and only need read access to it.
+type readOnlyStore struct {
+	TargetStore
+}
+
+// PartSize returns the part size of the object if the wrapped store can tell it, and zero otherwise.
+func (s *readOnlyStore) PartSize(ctx context.Context, key string) (int64, error) {
+	sizer, ok := s.TargetStore.(multipartPartSizer)
+	if !ok {
+		return 0, nil
+	}
+	return sizer.PartSize(ctx, key)
+}
+
+// Tags returns the tags of the object if the wrapped store supports them.
+func (s *readOnlyStore) Tags(ctx context.Context, key string) (map[string]string, error) {
+	tagger, ok := s.TargetStore.(objectTagger)
+	if !ok {
+		return nil, errTaggingNotSupported
+	}
+	return tagger.Tags(ctx, key)
+}
+
+func (s *readOnlyStore) SetTags(ctx context.Context, key string, tags map[string]string) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyStore) PutVersion(ctx context.Context, input PutInput) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (s *readOnlyStore) RestoreVersion(ctx context.Context, key string, versionID string) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyStore) Put(ctx context.Context, input PutInput) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyStore) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyStore) UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error {
+	return ErrReadOnly
+}
+
+func (s *readOnlyStore) Delete(ctx context.Context, keys []string) error {
+	return ErrReadOnly
+}
+
+// ReadOnly makes every later request of the deployment that would change the target fail with ErrReadOnly. It is
+// used when refreshing and planning, which only read the source and the target.
+func (d *Deployment) ReadOnly() {
+	if _, ok := d.target.(*readOnlyStore); !ok {
+		d.target = &readOnlyStore{TargetStore: d.target}
+	}
+}
@@ -0,0 +1,43 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOnly_refusesChanges(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "hello"})
+	d, _ := newTestDeployment(t, client, nil)
+	d.ReadOnly()
+	ctx := context.Background()
+
+	files, err := d.HashesForDeployedFiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected the target to be listed, got %v", files)
+	}
+
+	err = d.target.Put(ctx, PutInput{Key: "new.html", Body: strings.NewReader("new")})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected the upload to be refused, got %v", err)
+	}
+	if err := d.target.Copy(ctx, "index.html", "copy.html"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected the copy to be refused, got %v", err)
+	}
+	if err := d.target.UpdateMetadata(ctx, "index.html", ObjectMetadata{}, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected the metadata update to be refused, got %v", err)
+	}
+	if err := d.Purge(ctx, DeployedFiles{"index.html": ""}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected the purge to be refused, got %v", err)
+	}
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "hello"}) {
+		t.Errorf("expected the target to be unchanged, got %q", objects)
+	}
+}
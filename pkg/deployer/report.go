@@ -0,0 +1,105 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DeploymentReport is the machine-readable report of a deployment, e.g. for CI pipelines to attach to build
+// summaries. It lists the keys of the objects the deployment added, changed, and deleted, and how long each of its
+// phases took.
+type DeploymentReport struct {
+	DeploymentSummary
+	PhaseDurationsMs map[string]int64 `json:"phase_durations_ms"`
+	Added            []string         `json:"added"`
+	Changed          []string         `json:"changed"`
+	Deleted          []string         `json:"deleted"`
+}
+
+// Report returns the report of the deployment with the given summary.
+func (d *Deployment) Report(summary DeploymentSummary) DeploymentReport {
+	report := DeploymentReport{
+		DeploymentSummary: summary,
+		PhaseDurationsMs:  make(map[string]int64, len(d.phaseDurations)),
+		Added:             sortedKeys(d.addedKeys),
+		Changed:           sortedKeys(d.changedKeys),
+		Deleted:           sortedKeys(d.deletedKeys),
+	}
+	for phase, duration := range d.phaseDurations {
+		report.PhaseDurationsMs[phase] = duration
+	}
+	return report
+}
+
+// sortedKeys returns a sorted copy of keys, which is empty rather than nil, so that it is encoded as a JSON array.
+func sortedKeys(keys []string) []string {
+	sorted := append(make([]string, 0, len(keys)), keys...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// MaxChangedPaths is how many paths ChangedPaths returns at most. It is the number of paths a CloudFront
+// invalidation can have without wildcards.
+const MaxChangedPaths = 3000
+
+// ChangedPaths returns the paths of the objects the deployment changed or deleted, sorted and with a leading slash,
+// e.g. to invalidate them in a CDN. Added objects are not included, since nothing can be cached for them. If more
+// than MaxChangedPaths objects were changed, it returns "/*" instead.
+func (d *Deployment) ChangedPaths() []string {
+	keys := sortedKeys(append(append([]string{}, d.changedKeys...), d.deletedKeys...))
+	paths := make([]string, 0, len(keys))
+	for i, key := range keys {
+		// A key that was both changed and deleted is only listed once.
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		paths = append(paths, "/"+strings.TrimPrefix(key, "/"))
+	}
+	if len(paths) > MaxChangedPaths {
+		return []string{"/*"}
+	}
+	return paths
+}
+
+// WriteDeploymentReport writes the report as JSON to the file at the given path, creating its directory if needed.
+// The report is written to a temporary file that replaces the file at the path, so that readers never see a
+// partially written report.
+func WriteDeploymentReport(path string, report DeploymentReport) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode deployment report: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create the directory of the deployment report: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write deployment report: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(append(content, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// Temporary files are only readable by their owner.
+		err = os.Chmod(file.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write deployment report to %s: %w", path, err)
+	}
+
+	return nil
+}
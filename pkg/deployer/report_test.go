@@ -0,0 +1,99 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteDeploymentReport(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"index.html": "old", "old.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.ID = "deployment"
+	d.DeleteRemovedFiles = true
+	files, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "reports", "deployment.json")
+	err = WriteDeploymentReport(reportPath, d.Report(d.Summary("site.zip", "v1", files, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report DeploymentReport
+	if err := json.Unmarshal(written, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Status != StatusSucceeded || report.DeploymentID != "deployment" {
+		t.Errorf("unexpected summary in report: %+v", report.DeploymentSummary)
+	}
+	if !reflect.DeepEqual(report.Added, []string{"about.html"}) || !reflect.DeepEqual(report.Changed, []string{"index.html"}) || !reflect.DeepEqual(report.Deleted, []string{"old.html"}) {
+		t.Errorf("unexpected files in report: added %v, changed %v, deleted %v", report.Added, report.Changed, report.Deleted)
+	}
+	if _, found := report.PhaseDurationsMs["download"]; !found {
+		t.Errorf("expected the duration of the download in the report, got %v", report.PhaseDurationsMs)
+	}
+}
+
+func TestDeployment_ChangedPaths(t *testing.T) {
+	d := &Deployment{changedKeys: []string{"site/index.html", "site/app.js"}, deletedKeys: []string{"site/old.js", "site/app.js"}, addedKeys: []string{"site/new.js"}}
+	if paths := d.ChangedPaths(); !reflect.DeepEqual(paths, []string{"/site/app.js", "/site/index.html", "/site/old.js"}) {
+		t.Errorf("unexpected paths: %v", paths)
+	}
+
+	d = &Deployment{}
+	if paths := d.ChangedPaths(); paths == nil || len(paths) != 0 {
+		t.Errorf("expected no paths, got %#v", paths)
+	}
+
+	// Up to MaxChangedPaths paths fit in an invalidation, counting keys that were both changed and deleted once.
+	d = &Deployment{}
+	for i := 0; i < MaxChangedPaths; i++ {
+		d.changedKeys = append(d.changedKeys, fmt.Sprintf("%04d.html", i))
+	}
+	d.deletedKeys = d.changedKeys[:10]
+	if paths := d.ChangedPaths(); len(paths) != MaxChangedPaths || paths[0] != "/0000.html" {
+		t.Errorf("expected %d paths, got %d", MaxChangedPaths, len(paths))
+	}
+	d.changedKeys = append(d.changedKeys, "index.html")
+	if paths := d.ChangedPaths(); !reflect.DeepEqual(paths, []string{"/*"}) {
+		t.Errorf("expected a wildcard when too many files changed, got %v", paths)
+	}
+}
+
+func TestDeployment_ChangedPathsUnderPrefix(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"other.html": "other"})
+	var d *Deployment
+	for _, files := range []map[string]string{
+		{"index.html": "old", "about.html": "about", "old.html": "old"},
+		{"index.html": "new", "about.html": "about", "app.js": "app"},
+	} {
+		var source string
+		d, source = newTestDeployment(t, client, files)
+		d.TargetPrefix = "site/"
+		d.DeleteRemovedFiles = true
+		if _, err := d.Deploy(context.Background(), source, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The paths are those of the objects in the target, under the prefix, so that they can be invalidated in a
+	// distribution serving the whole bucket.
+	if paths := d.ChangedPaths(); !reflect.DeepEqual(paths, []string{"/site/index.html", "/site/old.html"}) {
+		t.Errorf("expected the changed and deleted objects under the prefix, got %v", paths)
+	}
+}
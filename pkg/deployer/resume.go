@@ -0,0 +1,94 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"time"
+)
+
+// ResumeProgressKey is the key the progress of a failed deployment is saved to when ResumeFailedDeployments is set,
+// under the TargetPrefix of the deployment.
+const ResumeProgressKey = ".staticfiledeploy-progress.json"
+
+// resumeProgressKey returns the key the progress of a failed deployment is saved to.
+func (d *Deployment) resumeProgressKey() string {
+	return d.TargetPrefix + ResumeProgressKey
+}
+
+// resumeProgressContent is the progress of a failed deployment, saved so that the next deployment can resume it.
+type resumeProgressContent struct {
+	DeploymentID string    `json:"deployment_id"`
+	SavedAt      time.Time `json:"saved_at"`
+	// Files maps the keys of the files that were deployed to the fingerprint of their content and metadata.
+	Files map[string]string `json:"files"`
+}
+
+// resumeFingerprint identifies the content and metadata a file was deployed with, so that a file is only resumed
+// if neither has changed since.
+func resumeFingerprint(hash string, metadata ObjectMetadata) string {
+	content, _ := json.Marshal([]string{
+		hash,
+		metadata.ContentType,
+		metadata.ContentDisposition,
+		metadata.ContentLanguage,
+		metadata.CacheControl,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// readResumeProgress returns the files deployed by the last failed deployment, or an empty map if there is none.
+// The progress is removed again once the files of the next deployment have been uploaded.
+func (d *Deployment) readResumeProgress(ctx context.Context) (map[string]string, error) {
+	body, err := d.target.Get(ctx, d.resumeProgressKey())
+	if err != nil || body == nil {
+		return map[string]string{}, err
+	}
+	defer body.Close()
+
+	var progress resumeProgressContent
+	err = json.NewDecoder(body).Decode(&progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the progress of the last deployment in %s: %w", d.resumeProgressKey(), err)
+	}
+
+	tflog.Info(ctx, "Resuming failed deployment", map[string]interface{}{
+		"failed_deployment_id": progress.DeploymentID,
+		"files":                len(progress.Files),
+	})
+
+	return progress.Files, nil
+}
+
+// saveResumeProgress saves the files deployed so far, so that the next deployment can skip them. It is called
+// after the deployment failed, possibly because its context was cancelled, so it does not use the context for
+// cancellation.
+func (d *Deployment) saveResumeProgress(ctx context.Context) {
+	content, err := json.Marshal(resumeProgressContent{
+		DeploymentID: d.ID,
+		SavedAt:      time.Now().UTC(),
+		Files:        d.deployedProgress,
+	})
+	if err == nil {
+		err = d.target.Put(context.WithoutCancel(ctx), PutInput{
+			Key:  d.resumeProgressKey(),
+			Body: bytes.NewReader(content),
+			Size: int64(len(content)),
+			Metadata: ObjectMetadata{
+				ContentType:  "application/json",
+				CacheControl: "no-store",
+			},
+		})
+	}
+	if err != nil {
+		// Failing to save the progress only means that the next deployment checks every file again.
+		tflog.Warn(ctx, "Could not save the progress of the failed deployment", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
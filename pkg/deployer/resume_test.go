@@ -0,0 +1,53 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestResumeProgress_roundTrip(t *testing.T) {
+	client := s3fake.New()
+
+	failed, _ := newTestDeployment(t, client, nil)
+	failed.deployedProgress = map[string]string{"index.html": "fingerprint"}
+	failed.saveResumeProgress(context.Background())
+
+	next, _ := newTestDeployment(t, client, nil)
+	progress, err := next.readResumeProgress(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress["index.html"] != "fingerprint" {
+		t.Errorf("unexpected progress: %v", progress)
+	}
+}
+
+func TestUploadDeploymentArtifactFiles_resumesUnchangedFiles(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello"})
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	client := &recordingS3API{Client: s3fake.New()}
+	putTestObjects(t, client, "target", map[string]string{"index.html": "hello"})
+	d, _ := newTestDeployment(t, client, nil)
+	d.deployedProgress = map[string]string{}
+	d.resumedProgress = map[string]string{"index.html": resumeFingerprint(helloMD5, d.metadataForKey("index.html"))}
+
+	err = d.uploadDeploymentArtifactFiles(context.Background(), reader, DeployedFiles{}, DeployedFiles{"index.html": helloMD5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.filesResumed != 1 || len(client.heads) != 0 {
+		t.Errorf("expected the file to be resumed without a HEAD request, got %d resumed and %d requests", d.filesResumed, len(client.heads))
+	}
+	if d.deployedProgress["index.html"] == "" {
+		t.Error("expected the resumed file to be part of the progress of this deployment")
+	}
+}
@@ -0,0 +1,82 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"time"
+)
+
+// DefaultMaxRetries is how many times a failed request is retried unless configured.
+const DefaultMaxRetries = 5
+
+// maxObjectRetryBackoff is the longest time to wait before uploading a throttled object again.
+const maxObjectRetryBackoff = 30 * time.Second
+
+// objectRetryBackoff computes how long to wait before uploading a throttled object again. It is replaced in tests.
+var objectRetryBackoff retry.BackoffDelayer = retry.NewExponentialJitterBackoff(maxObjectRetryBackoff)
+
+// RetryPolicy configures how requests to AWS are retried.
+type RetryPolicy struct {
+	// MaxRetries is how many times the AWS SDK retries a failed request, and how many times a deployment uploads an
+	// object again when the SDK gave up because it was throttled. Zero means DefaultMaxRetries.
+	MaxRetries int
+	// Mode is the retry mode of the AWS SDK. Adaptive mode also slows down requests when they are throttled.
+	// Empty means aws.RetryModeStandard.
+	Mode aws.RetryMode
+}
+
+// maxRetries returns MaxRetries, or the default if it is not set.
+func (p RetryPolicy) maxRetries() int {
+	if p.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return p.MaxRetries
+}
+
+// ConfigOptions returns the options that make an AWS configuration loaded with config.LoadDefaultConfig use the policy.
+func (p RetryPolicy) ConfigOptions() []func(*config.LoadOptions) error {
+	mode := p.Mode
+	if mode == "" {
+		mode = aws.RetryModeStandard
+	}
+	return []func(*config.LoadOptions) error{
+		config.WithRetryMaxAttempts(p.maxRetries() + 1),
+		config.WithRetryMode(mode),
+	}
+}
+
+// isThrottlingError returns whether the error is S3 asking for requests to be sent at a lower rate, e.g. SlowDown.
+func isThrottlingError(err error) bool {
+	return retry.ThrottleErrorCode{Codes: retry.DefaultThrottleErrorCodes}.IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// retryThrottled calls upload until it succeeds or fails with an error other than throttling, waiting longer between
+// each attempt. This lets a deployment of many files get past a burst of throttling that the AWS SDK gave up on,
+// instead of failing because of a single object.
+func (d *Deployment) retryThrottled(ctx context.Context, key string, upload func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := upload()
+		if err == nil || attempt > d.Retry.maxRetries() || !isThrottlingError(err) {
+			return err
+		}
+
+		delay, backoffErr := objectRetryBackoff.BackoffDelay(attempt, err)
+		if backoffErr != nil {
+			return err
+		}
+		tflog.Warn(ctx, "Throttled while deploying file, trying again", map[string]interface{}{
+			"key":      key,
+			"attempt":  attempt,
+			"delay_ms": delay.Milliseconds(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"testing"
+	"time"
+)
+
+func TestRetryThrottled(t *testing.T) {
+	objectRetryBackoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+	defer func() { objectRetryBackoff = retry.NewExponentialJitterBackoff(maxObjectRetryBackoff) }()
+
+	slowDown := &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		failed   bool
+	}{
+		{name: "succeeds after throttling", errs: []error{slowDown, slowDown, nil}, attempts: 3},
+		{name: "gives up after max retries", errs: []error{slowDown, slowDown, slowDown, slowDown}, attempts: 3, failed: true},
+		{name: "does not retry other errors", errs: []error{errors.New("access denied"), nil}, attempts: 1, failed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Deployment{Retry: RetryPolicy{MaxRetries: 2}}
+
+			attempts := 0
+			err := d.retryThrottled(context.Background(), "index.html", func() error {
+				attempts++
+				return test.errs[attempts-1]
+			})
+
+			if attempts != test.attempts || (err != nil) != test.failed {
+				t.Errorf("got %d attempts and error %v", attempts, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+)
+
+// RollbackPrefix is the prefix objects are backed up under before they are overwritten by a deployment with
+// RollbackOnFailure, followed by the deployment ID.
+const RollbackPrefix = ".staticfiledeploy-rollback/"
+
+// rollbackJournal records the objects a deployment writes, so that its changes can be undone if it fails.
+type rollbackJournal struct {
+	// existing is the state of the target before the deployment started.
+	existing DeployedFiles
+	recorded map[string]bool
+	// backedUp are the keys of overwritten objects, and added those of objects that did not exist before.
+	backedUp []string
+	added    []string
+	// versions are the versions overwritten objects are restored to, in targets that keep versions of objects.
+	// They are not backed up.
+	versions []objectVersion
+}
+
+// backupKey returns the key an object is backed up to before the deployment overwrites it.
+func (d *Deployment) backupKey(key string) string {
+	return RollbackPrefix + d.ID + "/" + key
+}
+
+// recordWrite prepares for the object with the given key to be written by the deployment. If the object exists,
+// the version it has is recorded if the target keeps versions of it, and otherwise it is backed up, so that it can
+// be restored by rollBack.
+func (d *Deployment) recordWrite(ctx context.Context, key string) error {
+	journal := d.rollback
+	if journal == nil || journal.recorded[key] {
+		return nil
+	}
+
+	// The existing files are those under the key prefix, so objects outside of it, such as the release pointer,
+	// are looked up.
+	_, exists := journal.existing[key]
+	if !exists && !strings.HasPrefix(key, d.keyPrefix()) {
+		head, err := d.target.Head(ctx, key)
+		if err != nil {
+			return err
+		}
+		exists = head != nil
+	}
+
+	var versionID string
+	if exists {
+		var err error
+		versionID, err = d.currentVersion(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if versionID != "" {
+		journal.versions = append(journal.versions, objectVersion{key: key, versionID: versionID})
+	} else if exists {
+		err := d.target.Copy(ctx, key, d.backupKey(key))
+		if err != nil {
+			return err
+		}
+		journal.backedUp = append(journal.backedUp, key)
+	} else {
+		journal.added = append(journal.added, key)
+	}
+	journal.recorded[key] = true
+
+	return nil
+}
+
+// rollBack undoes the changes the deployment made to the target after it failed with the given error: overwritten
+// objects are restored from their backups, and added objects are deleted. It returns the error to fail the
+// deployment with. The deployment may have failed because its context was cancelled, so the context is not used
+// for cancellation.
+func (d *Deployment) rollBack(ctx context.Context, cause error) error {
+	journal := d.rollback
+	d.rollback = nil
+	ctx = context.WithoutCancel(ctx)
+
+	tflog.Warn(ctx, "Deployment failed, rolling back", map[string]interface{}{
+		"files_restored": len(journal.backedUp) + len(journal.versions),
+		"files_removed":  len(journal.added),
+	})
+
+	for _, version := range journal.versions {
+		err := d.target.(objectVersioner).RestoreVersion(ctx, version.key, version.versionID)
+		if err != nil {
+			return fmt.Errorf("%w, and rolling back the deployment failed: %v", cause, err)
+		}
+	}
+
+	for _, key := range journal.backedUp {
+		err := d.target.Copy(ctx, d.backupKey(key), key)
+		if err != nil {
+			return fmt.Errorf("%w, and rolling back the deployment failed: %v", cause, err)
+		}
+	}
+
+	err := d.deleteKeys(ctx, journal.added)
+	if err == nil {
+		err = d.deleteBackups(ctx, journal)
+	}
+	if err != nil {
+		return fmt.Errorf("%w, and rolling back the deployment failed: %v", cause, err)
+	}
+
+	return fmt.Errorf("%w (the changes to the target were rolled back)", cause)
+}
+
+// commitRollback deletes the backups once the deployment can no longer be rolled back.
+func (d *Deployment) commitRollback(ctx context.Context) error {
+	journal := d.rollback
+	d.rollback = nil
+	return d.deleteBackups(ctx, journal)
+}
+
+// deleteBackups deletes the backups of the objects the deployment overwrote.
+func (d *Deployment) deleteBackups(ctx context.Context, journal *rollbackJournal) error {
+	backups := make([]string, len(journal.backedUp))
+	for i, key := range journal.backedUp {
+		backups[i] = d.backupKey(key)
+	}
+	return d.deleteKeys(ctx, backups)
+}
+
+// deleteKeys deletes the objects with the given keys, as many at a time as the target accepts.
+func (d *Deployment) deleteKeys(ctx context.Context, keys []string) error {
+	return deleteKeysFrom(ctx, d.target, keys)
+}
+
+// deleteKeysFrom deletes the objects with the given keys from the store, as many at a time as it accepts.
+func deleteKeysFrom(ctx context.Context, store TargetStore, keys []string) error {
+	for start := 0; start < len(keys); start += maxDeleteObjects {
+		err := store.Delete(ctx, keys[start:min(start+maxDeleteObjects, len(keys))])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+func TestDeploy_rollsBackOnFailure(t *testing.T) {
+	// The version file is uploaded after all files of the artifact.
+	client := &recordingS3API{Client: s3fake.New(), failKey: "version.json"}
+	putTestObjects(t, client.Client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.RollbackOnFailure = true
+	d.VersionFile = &VersionFile{Key: "version.json"}
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err == nil {
+		t.Fatal("expected the deployment to fail")
+	}
+
+	if objects := testObjects(client.Client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "old"}) {
+		t.Errorf("expected only the original object to be left, got %q", objects)
+	}
+}
@@ -0,0 +1,117 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// GetObjectAPI is a client that implements the GetObject operation.
+type GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// HeadObjectAPI is a client that implements the HeadObject operation.
+type HeadObjectAPI interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// PutObjectAPI is a client that implements the PutObject operation.
+type PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// ListObjectsAPI is a client that implements the ListObjectsV2 operation.
+type ListObjectsAPI interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// CopyObjectAPI is a client that implements the CopyObject operation.
+type CopyObjectAPI interface {
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+}
+
+// DeleteObjectsAPI is a client that implements the DeleteObject and DeleteObjects operations.
+type DeleteObjectsAPI interface {
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// ObjectTaggingAPI is a client that implements the GetObjectTagging and PutObjectTagging operations.
+type ObjectTaggingAPI interface {
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+}
+
+// ListObjectVersionsAPI is a client that implements the ListObjectVersions operation.
+type ListObjectVersionsAPI interface {
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+}
+
+// BucketOwnershipAPI is a client that implements the operations reading the settings that decide whether objects
+// can be written with ACLs. It is not part of S3API, so that clients without it skip the checks that need it.
+type BucketOwnershipAPI interface {
+	GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+	GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
+}
+
+// BucketSettingsAPI is a client that implements the operations the preflight checks read the target bucket with. It
+// is not part of S3API, so that clients without it skip the checks that need it.
+type BucketSettingsAPI interface {
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+}
+
+// MultipartUploadAPI is a client that implements the operations large objects are uploaded in parts with.
+type MultipartUploadAPI interface {
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// S3SourceAPI is the part of the S3 API artifacts are downloaded from S3 with.
+type S3SourceAPI interface {
+	GetObjectAPI
+	HeadObjectAPI
+}
+
+// S3API is the part of the S3 API the deployer uses. It is implemented by *s3.Client, and by s3fake.Client for
+// unit tests without AWS, and can be implemented by other clients, e.g. to instrument requests.
+type S3API interface {
+	GetObjectAPI
+	HeadObjectAPI
+	PutObjectAPI
+	ListObjectsAPI
+	CopyObjectAPI
+	DeleteObjectsAPI
+	ObjectTaggingAPI
+	MultipartUploadAPI
+}
+
+var _ S3API = (*s3.Client)(nil)
+var _ BucketOwnershipAPI = (*s3.Client)(nil)
+var _ BucketSettingsAPI = (*s3.Client)(nil)
+
+// s3Client returns the client for S3 buckets in the given region, or in the region of DefaultAWSConfig if it is
+// empty.
+func (d *Deployer) s3Client(region string) S3API {
+	if d.S3Client != nil {
+		return d.S3Client(region)
+	}
+	return d.newS3Client(d.DefaultAWSConfig, region)
+}
+
+// newS3Client returns a client for S3 buckets in the given region, or in the region of cfg if it is empty, which
+// sends requests to S3Endpoint if it is set.
+func (d *Deployer) newS3Client(cfg aws.Config, region string) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+		if d.S3Endpoint != "" {
+			o.BaseEndpoint = aws.String(d.S3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package deployer
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// s3Store is a TargetStore for an S3 bucket.
+type s3Store struct {
+	client S3API
+	bucket string
+	// customerKey, if set, is the customer-provided key the objects are encrypted with.
+	customerKey *CustomerKey
+	// acl, if set, is the canned ACL objects are written with. Copies are written with it too, as S3 does not copy
+	// the ACL of the source object.
+	acl types.ObjectCannedACL
+	// encryption, if set, is the server-side encryption objects are written with, including copies.
+	encryption *Encryption
+	// checksumAlgorithm, if set, is the algorithm uploads without an MD5 hash, and the requests S3 requires a
+	// checksum for, are checked with, instead of the Content-MD5 header the AWS SDK otherwise computes for the latter.
+	checksumAlgorithm types.ChecksumAlgorithm
+}
+
+// NewS3Store returns a TargetStore for the S3 bucket with the given name, sending requests with the given client.
+func NewS3Store(client S3API, bucket string) TargetStore {
+	return &s3Store{client: client, bucket: bucket}
+}
+
+// NewS3Target returns a TargetStore for the S3 bucket with the given name in the given region.
+func (d *Deployer) NewS3Target(bucket string, region string) TargetStore {
+	return NewS3Store(d.s3Client(region), bucket)
+}
+
+// NewS3TargetWithRole returns a TargetStore for the S3 bucket with the given name in the given region, which is
+// accessed with the credentials of the given IAM role, e.g. a role in the account that owns the bucket. The role is
+// assumed with the credentials of DefaultAWSConfig. Deployers with an S3Client use it as it is.
+func (d *Deployer) NewS3TargetWithRole(bucket string, region string, roleARN string) TargetStore {
+	if d.S3Client != nil {
+		return d.NewS3Target(bucket, region)
+	}
+	cfg := d.DefaultAWSConfig.Copy()
+	cfg.Credentials = AssumeRole{RoleARN: roleARN}.Credentials(d.DefaultAWSConfig)
+	return NewS3Store(d.newS3Client(cfg, region), bucket)
+}
+
+func (s *s3Store) Name() string {
+	return s.bucket
+}
+
+// List reads every page of the listing, so that buckets with more than 1000 objects are compared in full.
+func (s *s3Store) List(ctx context.Context, prefix string) (DeployedFiles, error) {
+	foundFiles := make(DeployedFiles)
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, newObjectError("ListObjectsV2", s.bucket, "", err)
+		}
+
+		for _, object := range page.Contents {
+			// The AWS SDK returns the ETag with surrounding quotes for some reason
+			foundFiles[aws.ToString(object.Key)] = strings.Trim(aws.ToString(object.ETag), "\"")
+		}
+	}
+
+	return foundFiles, nil
+}
+
+func (s *s3Store) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	s.customerKey.applyToHeadObject(headObjectInput)
+	head, err := s.client.HeadObject(ctx, headObjectInput)
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, newObjectError("HeadObject", s.bucket, key, err)
+	}
+
+	return &ObjectInfo{
+		Size:      aws.ToInt64(head.ContentLength),
+		VersionID: aws.ToString(head.VersionId),
+		Metadata: ObjectMetadata{
+			ContentType:        aws.ToString(head.ContentType),
+			ContentDisposition: aws.ToString(head.ContentDisposition),
+			ContentLanguage:    aws.ToString(head.ContentLanguage),
+			CacheControl:       aws.ToString(head.CacheControl),
+			ContentSHA256:      head.Metadata[ContentHashMetadataKey],
+		},
+	}, nil
+}
+
+// PartSize returns the size of the first part of the object. Objects are uploaded in parts of the same size, except
+// for the last one, both by this provider and by other tools such as the AWS CLI.
+func (s *s3Store) PartSize(ctx context.Context, key string) (int64, error) {
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(key),
+		PartNumber: aws.Int32(1),
+	}
+	s.customerKey.applyToHeadObject(headObjectInput)
+	head, err := s.client.HeadObject(ctx, headObjectInput)
+	if err != nil {
+		return 0, newObjectError("HeadObject", s.bucket, key, err)
+	}
+	if aws.ToInt32(head.PartsCount) == 0 {
+		return 0, nil
+	}
+
+	return aws.ToInt64(head.ContentLength), nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	s.customerKey.applyToGetObject(getObjectInput)
+	result, err := s.client.GetObject(ctx, getObjectInput)
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil
+		}
+		return nil, newObjectError("GetObject", s.bucket, key, err)
+	}
+
+	return result.Body, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, input PutInput) error {
+	_, err := s.PutVersion(ctx, input)
+	return err
+}
+
+// PutVersion uploads an object, and returns the ID of the version S3 created for it, or an empty string if the
+// bucket is not versioned.
+func (s *s3Store) PutVersion(ctx context.Context, input PutInput) (string, error) {
+	putObjectInput := &s3.PutObjectInput{
+		Bucket:             aws.String(s.bucket),
+		Key:                aws.String(input.Key),
+		Body:               input.Body,
+		ContentLength:      aws.Int64(input.Size),
+		ContentType:        aws.String(input.Metadata.ContentType),
+		ContentDisposition: optionalString(input.Metadata.ContentDisposition),
+		ContentLanguage:    optionalString(input.Metadata.ContentLanguage),
+		CacheControl:       optionalString(input.Metadata.CacheControl),
+		Metadata:           input.Metadata.userMetadata(),
+	}
+	if input.IfMatch != "" {
+		putObjectInput.IfMatch = aws.String("\"" + input.IfMatch + "\"")
+	}
+	if input.IfNoneMatch {
+		putObjectInput.IfNoneMatch = aws.String("*")
+	}
+	if len(input.Tags) > 0 {
+		putObjectInput.Tagging = aws.String(encodeTags(input.Tags))
+	}
+	putObjectInput.ACL = s.acl
+	s.encryption.applyToPutObject(putObjectInput)
+	input.ObjectLock.applyToPutObject(putObjectInput)
+	s.customerKey.applyToPutObject(putObjectInput)
+	// The checksum also satisfies Object Lock, which otherwise has uploads checked with CRC32.
+	if input.MD5 == "" && s.checksumAlgorithm != "" {
+		putObjectInput.ChecksumAlgorithm = s.checksumAlgorithm
+	}
+
+	var versionID *string
+	var err error
+	if input.Multipart != nil && input.Size >= input.Multipart.Threshold {
+		// The uploader reads the body in parts, and sets the length of each of them.
+		putObjectInput.ContentLength = nil
+		// Parts are uploaded without Content-MD5 or the SHA-256 hash, as both are of the whole object. The uploader
+		// computes the checksum of each part instead, if the checksum algorithm is set.
+		uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
+			u.PartSize = input.Multipart.PartSize
+			u.Concurrency = input.Multipart.Concurrency
+		})
+		var output *manager.UploadOutput
+		output, err = uploader.Upload(ctx, putObjectInput)
+		if err == nil {
+			versionID = output.VersionID
+		}
+	} else {
+		putObjectInput.ContentMD5 = contentMD5(input.MD5)
+		if putObjectInput.ChecksumAlgorithm == types.ChecksumAlgorithmSha256 {
+			putObjectInput.ChecksumSHA256 = checksumSHA256(input.SHA256)
+		}
+		var output *s3.PutObjectOutput
+		output, err = s.client.PutObject(ctx, putObjectInput)
+		if err == nil {
+			versionID = output.VersionId
+		}
+	}
+	if err != nil {
+		if isConditionalWriteConflict(err) {
+			return "", &ConflictError{Bucket: s.bucket, Key: input.Key, Err: err}
+		}
+		return "", newObjectError("PutObject", s.bucket, input.Key, err)
+	}
+
+	return aws.ToString(versionID), nil
+}
+
+// contentMD5 returns the Content-MD5 header value for the given hex encoded MD5 hash, so that S3 rejects uploads
+// whose body was corrupted on the way, or nil if the hash is not set.
+func contentMD5(hash string) *string {
+	sum, err := hex.DecodeString(hash)
+	if err != nil || len(sum) != md5.Size {
+		return nil
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(sum))
+}
+
+// checksumSHA256 returns the x-amz-checksum-sha256 header value for the given hex encoded SHA-256 hash, or nil if
+// the hash is not set, in which case the AWS SDK computes it.
+func checksumSHA256(hash string) *string {
+	sum, err := hex.DecodeString(hash)
+	if err != nil || len(sum) != sha256.Size {
+		return nil
+	}
+	return aws.String(base64.StdEncoding.EncodeToString(sum))
+}
+
+// RestoreVersion copies the given version of the object with the given key over its current version.
+func (s *s3Store) RestoreVersion(ctx context.Context, key string, versionID string) error {
+	copyObjectInput := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(key),
+		CopySource: aws.String(copySource(s.bucket, key) + "?versionId=" + url.QueryEscape(versionID)),
+	}
+	copyObjectInput.ACL = s.acl
+	s.encryption.applyToCopyObject(copyObjectInput)
+	s.customerKey.applyToCopyObject(copyObjectInput)
+	_, err := s.client.CopyObject(ctx, copyObjectInput)
+	if err != nil {
+		return newObjectError("CopyObject", s.bucket, key, err)
+	}
+
+	return nil
+}
+
+// Tags returns the tags of the object with the given key.
+func (s *s3Store) Tags(ctx context.Context, key string) (map[string]string, error) {
+	result, err := s.client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, newObjectError("GetObjectTagging", s.bucket, key, err)
+	}
+
+	tags := make(map[string]string, len(result.TagSet))
+	for _, tag := range result.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// SetTags replaces the tags of the object with the given key, without copying the object.
+func (s *s3Store) SetTags(ctx context.Context, key string, tags map[string]string) error {
+	tagSet := make([]types.Tag, 0, len(tags))
+	for tagKey, value := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(tagKey), Value: aws.String(value)})
+	}
+
+	_, err := s.client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		Tagging: &types.Tagging{TagSet: tagSet},
+		// S3 requires a checksum for the request.
+		ChecksumAlgorithm: s.checksumAlgorithm,
+	})
+	if err != nil {
+		return newObjectError("PutObjectTagging", s.bucket, key, err)
+	}
+
+	return nil
+}
+
+func (s *s3Store) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	copyObjectInput := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(destinationKey),
+		CopySource: aws.String(copySource(s.bucket, sourceKey)),
+	}
+	copyObjectInput.ACL = s.acl
+	s.encryption.applyToCopyObject(copyObjectInput)
+	s.customerKey.applyToCopyObject(copyObjectInput)
+	_, err := s.client.CopyObject(ctx, copyObjectInput)
+	if err != nil {
+		return newObjectError("CopyObject", s.bucket, destinationKey, err)
+	}
+
+	return nil
+}
+
+// UpdateMetadata copies the object onto itself server-side with the new metadata.
+func (s *s3Store) UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error {
+	copyObjectInput := &s3.CopyObjectInput{
+		Bucket:             aws.String(s.bucket),
+		Key:                aws.String(key),
+		CopySource:         aws.String(copySource(s.bucket, key)),
+		MetadataDirective:  types.MetadataDirectiveReplace,
+		ContentType:        aws.String(metadata.ContentType),
+		ContentDisposition: optionalString(metadata.ContentDisposition),
+		ContentLanguage:    optionalString(metadata.ContentLanguage),
+		CacheControl:       optionalString(metadata.CacheControl),
+		Metadata:           metadata.userMetadata(),
+	}
+	lock.applyToCopyObject(copyObjectInput)
+	copyObjectInput.ACL = s.acl
+	s.encryption.applyToCopyObject(copyObjectInput)
+	s.customerKey.applyToCopyObject(copyObjectInput)
+
+	_, err := s.client.CopyObject(ctx, copyObjectInput)
+	if err != nil {
+		return newObjectError("CopyObject", s.bucket, key, err)
+	}
+
+	return nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, keys []string) error {
+	objects := make([]types.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+	}
+
+	result, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Delete: &types.Delete{
+			Objects: objects,
+			Quiet:   aws.Bool(true),
+		},
+		// S3 requires a checksum for the request.
+		ChecksumAlgorithm: s.checksumAlgorithm,
+	})
+	if err != nil {
+		return newObjectError("DeleteObjects", s.bucket, "", err)
+	}
+	if len(result.Errors) > 0 {
+		failed := result.Errors[0]
+		return newObjectError("DeleteObjects", s.bucket, aws.ToString(failed.Key), &deleteObjectError{failed: failed})
+	}
+
+	return nil
+}
+
+// deleteObjectError is the error S3 reports for a single key in a DeleteObjects response.
+type deleteObjectError struct {
+	failed types.Error
+}
+
+func (e *deleteObjectError) Error() string {
+	return aws.ToString(e.failed.Code) + ": " + aws.ToString(e.failed.Message)
+}
+
+// encodeTags encodes tags as the query string PutObject expects them in.
+func encodeTags(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
@@ -0,0 +1,181 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var _ S3API = (*s3fake.Client)(nil)
+
+func TestNewS3Store(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "bucket", map[string]string{"index.html": "hello"})
+	store := NewS3Store(client, "bucket")
+
+	files, err := store.List(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Name() != "bucket" || files["index.html"] != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("unexpected listing of %s: %v", store.Name(), files)
+	}
+}
+
+// pageCountingS3API is an s3fake.Client that counts the pages of object listings it returned.
+type pageCountingS3API struct {
+	*s3fake.Client
+	pages int
+}
+
+func (c *pageCountingS3API) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.pages++
+	return c.Client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func TestS3Store_listsEveryPage(t *testing.T) {
+	client := &pageCountingS3API{Client: s3fake.New()}
+	objects := make(map[string]string, 2500)
+	for i := 0; i < 2500; i++ {
+		objects[fmt.Sprintf("site/%04d.html", i)] = "hello"
+	}
+	objects["other/index.html"] = "hello"
+	putTestObjects(t, client.Client, "target", objects)
+
+	// S3 returns up to 1000 objects per page.
+	files, err := NewS3Store(client, "target").List(context.Background(), "site/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.pages != 3 || len(files) != 2500 {
+		t.Fatalf("expected 2500 objects in 3 pages, got %d objects in %d pages", len(files), client.pages)
+	}
+	for _, key := range []string{"site/0000.html", "site/1000.html", "site/2499.html"} {
+		if files[key] != "5d41402abc4b2a76b9719d911017c592" {
+			t.Errorf("expected %s to be listed with its ETag, got %q", key, files[key])
+		}
+	}
+}
+
+func TestContentMD5(t *testing.T) {
+	// The MD5 hash of "hello".
+	if header := aws.ToString(contentMD5("5d41402abc4b2a76b9719d911017c592")); header != "XUFAKrxLKna5cZ2REBfFkg==" {
+		t.Errorf("unexpected Content-MD5 header %q", header)
+	}
+	if header := contentMD5(""); header != nil {
+		t.Errorf("expected no Content-MD5 header without a hash, got %q", *header)
+	}
+}
+
+func TestDeploy_s3Fake(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	artifact := newTestArtifact(t, map[string]string{"index.html": "hello", "large.bin": strings.Repeat("x", 6<<20)})
+	for key, content := range map[string][]byte{"site.zip": artifact, "old.html": []byte("old")} {
+		bucket := "target"
+		if key == "site.zip" {
+			bucket = "source"
+		}
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: bytes.NewReader(content)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newDeployment := func() *Deployment {
+		d := (&Deployer{S3Client: func(region string) S3API { return client }}).NewDeployment("source", "target", "eu-north-1")
+		d.DeleteRemovedFiles = true
+		d.MultipartUpload = MultipartUpload{Threshold: 5 << 20, PartSize: 5 << 20}
+		return d
+	}
+
+	if _, err := newDeployment().Deploy(ctx, "site.zip", nil); err != nil {
+		t.Fatal(err)
+	}
+	if keys := client.Keys("target"); !reflect.DeepEqual(keys, []string{"index.html", "large.bin"}) {
+		t.Errorf("unexpected objects in the target: %v", keys)
+	}
+	if index := client.Object("target", "index.html"); string(index.Body) != "hello" || index.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("unexpected index.html: %q with content type %s", index.Body, index.ContentType)
+	}
+	if large := client.Object("target", "large.bin"); len(large.PartSizes) != 2 {
+		t.Errorf("expected large.bin to be uploaded in 2 parts, got %v", large.PartSizes)
+	}
+
+	// Deploying the same artifact again finds every file deployed, including the one uploaded in parts.
+	again := newDeployment()
+	if _, err := again.Deploy(ctx, "site.zip", nil); err != nil {
+		t.Fatal(err)
+	}
+	if again.filesSkipped != 2 || again.bytesUploaded != 0 {
+		t.Errorf("expected both files to be skipped, got %d skipped and %d bytes uploaded", again.filesSkipped, again.bytesUploaded)
+	}
+}
+
+func TestDeployer_s3Endpoint(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Host+r.URL.Path)
+		w.Header().Set("ETag", "\"d41d8cd98f00b204e9800998ecf8427e\"")
+	}))
+	defer server.Close()
+
+	d := &Deployer{
+		DefaultAWSConfig: aws.Config{
+			Region:      "us-east-1",
+			Credentials: credentials.NewStaticCredentialsProvider("test", "test", ""),
+		},
+		S3Endpoint: server.URL,
+	}
+	_, err := d.s3Client("").HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("target"),
+		Key:    aws.String("index.html"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{strings.TrimPrefix(server.URL, "http://") + "/target/index.html"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+}
+
+func TestNewS3TargetWithRole_usesRoleCredentials(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/" {
+			// The STS AssumeRole request.
+			fmt.Fprint(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ROLEKEY</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>2100-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("DEFAULTKEY", "secret", ""),
+	}
+	d := &Deployer{DefaultAWSConfig: cfg, S3Endpoint: server.URL}
+
+	target := d.NewS3TargetWithRole("www", "", "arn:aws:iam::123456789012:role/deploy")
+	if _, err := target.Head(context.Background(), "index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(authorization, "Credential=ROLEKEY/") {
+		t.Errorf("expected the request to be signed with the credentials of the role, got %q", authorization)
+	}
+}
@@ -0,0 +1,594 @@
+// Package s3fake is an in-memory implementation of the S3 API used by the deployer, so that deployments can be
+// tested without AWS. It keeps the content, metadata, tags and, if enabled, versions of objects, and reports
+// missing objects and failed conditions with the same errors as S3. It does not check permissions, Object Lock
+// settings, or checksums other than Content-MD5.
+package s3fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxListKeys is the number of objects returned by ListObjectsV2 when MaxKeys is not set.
+const maxListKeys = 1000
+
+// Object is an object stored in the fake.
+type Object struct {
+	Body               []byte
+	ContentType        string
+	ContentDisposition string
+	ContentLanguage    string
+	CacheControl       string
+	Metadata           map[string]string
+	Tags               map[string]string
+	// ETag is the entity tag of the object without quotes: the MD5 hash of the body, or, for objects uploaded in
+	// parts, the MD5 hash of the hashes of the parts followed by the number of parts.
+	ETag string
+	// VersionID is the ID of the version, if the client keeps versions.
+	VersionID string
+	// PartSizes are the sizes of the parts the object was uploaded in, if it was.
+	PartSizes []int64
+}
+
+// multipartUpload is an upload in parts that has not been completed yet.
+type multipartUpload struct {
+	bucket string
+	object Object
+	key    string
+	parts  map[int32][]byte
+}
+
+// Client is an in-memory S3 client. Buckets are created when the first object is written to them. The zero value
+// is not usable; create clients with New.
+type Client struct {
+	// Versioning makes the client keep every version of an object, and return their IDs.
+	Versioning bool
+
+	mu      sync.Mutex
+	buckets map[string]map[string][]*Object
+	uploads map[string]*multipartUpload
+	nextID  int
+}
+
+// New returns an empty client.
+func New() *Client {
+	return &Client{
+		buckets: make(map[string]map[string][]*Object),
+		uploads: make(map[string]*multipartUpload),
+	}
+}
+
+// Object returns a copy of the current version of the object with the given key, or nil if it does not exist.
+func (c *Client) Object(bucket string, key string) *Object {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object := c.current(bucket, key)
+	if object == nil {
+		return nil
+	}
+	copied := *object
+	return &copied
+}
+
+// Keys returns the sorted keys of the objects in the bucket.
+func (c *Client) Keys(bucket string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sortedKeys(bucket, "")
+}
+
+func (c *Client) current(bucket string, key string) *Object {
+	versions := c.buckets[bucket][key]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+func (c *Client) sortedKeys(bucket string, prefix string) []string {
+	var keys []string
+	for key := range c.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// store makes the object the current version of the object with the given key.
+func (c *Client) store(bucket string, key string, object *Object) {
+	if c.buckets[bucket] == nil {
+		c.buckets[bucket] = make(map[string][]*Object)
+	}
+	if c.Versioning {
+		c.nextID++
+		object.VersionID = "v" + strconv.Itoa(c.nextID)
+		c.buckets[bucket][key] = append(c.buckets[bucket][key], object)
+	} else {
+		c.buckets[bucket][key] = []*Object{object}
+	}
+}
+
+// apiError returns an error with the given code, like those S3 returns.
+func apiError(code string, message string) error {
+	return &smithy.GenericAPIError{Code: code, Message: message}
+}
+
+func noSuchKey(bucket string, key string) error {
+	return &types.NoSuchKey{Message: aws.String(fmt.Sprintf("%s/%s does not exist", bucket, key))}
+}
+
+func quote(etag string) *string {
+	return aws.String("\"" + etag + "\"")
+}
+
+// checkConditions returns an error if the current object does not match the If-Match or If-None-Match condition.
+func checkConditions(current *Object, ifMatch *string, ifNoneMatch *string) error {
+	if ifMatch != nil && (current == nil || strings.Trim(*ifMatch, "\"") != current.ETag) {
+		return apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	if aws.ToString(ifNoneMatch) == "*" && current != nil {
+		return apiError("PreconditionFailed", "At least one of the pre-conditions you specified did not hold")
+	}
+	return nil
+}
+
+// parseTagging parses tags in the query string format PutObject takes them in.
+func parseTagging(tagging *string) (map[string]string, error) {
+	if tagging == nil {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(*tagging)
+	if err != nil {
+		return nil, apiError("InvalidArgument", err.Error())
+	}
+	tags := make(map[string]string, len(values))
+	for key := range values {
+		tags[key] = values.Get(key)
+	}
+	return tags, nil
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	bucket, key := aws.ToString(params.Bucket), aws.ToString(params.Key)
+	var body []byte
+	if params.Body != nil {
+		var err error
+		body, err = io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.ContentMD5 != nil {
+		sum := md5.Sum(body)
+		if *params.ContentMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, apiError("BadDigest", "The Content-MD5 you specified did not match what we received")
+		}
+	}
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err = checkConditions(c.current(bucket, key), params.IfMatch, params.IfNoneMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &Object{
+		Body:               body,
+		ContentType:        aws.ToString(params.ContentType),
+		ContentDisposition: aws.ToString(params.ContentDisposition),
+		ContentLanguage:    aws.ToString(params.ContentLanguage),
+		CacheControl:       aws.ToString(params.CacheControl),
+		Metadata:           params.Metadata,
+		Tags:               tags,
+		ETag:               md5Hex(body),
+	}
+	c.store(bucket, key, object)
+
+	return &s3.PutObjectOutput{ETag: quote(object.ETag), VersionId: optionalString(object.VersionID)}, nil
+}
+
+func (c *Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	bucket, key := aws.ToString(params.Bucket), aws.ToString(params.Key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object, err := c.version(bucket, key, params.VersionId)
+	if err != nil {
+		return nil, &types.NotFound{Message: aws.String(err.Error())}
+	}
+
+	output := &s3.HeadObjectOutput{
+		ContentLength:      aws.Int64(int64(len(object.Body))),
+		ContentType:        aws.String(object.ContentType),
+		ContentDisposition: optionalString(object.ContentDisposition),
+		ContentLanguage:    optionalString(object.ContentLanguage),
+		CacheControl:       optionalString(object.CacheControl),
+		Metadata:           object.Metadata,
+		ETag:               quote(object.ETag),
+		VersionId:          optionalString(object.VersionID),
+	}
+	if params.PartNumber != nil && len(object.PartSizes) > 0 {
+		output.ContentLength = aws.Int64(object.PartSizes[*params.PartNumber-1])
+		output.PartsCount = aws.Int32(int32(len(object.PartSizes)))
+	}
+	return output, nil
+}
+
+// version returns the given version of the object with the given key, or the current one if versionID is nil.
+func (c *Client) version(bucket string, key string, versionID *string) (*Object, error) {
+	if versionID == nil {
+		if object := c.current(bucket, key); object != nil {
+			return object, nil
+		}
+		return nil, noSuchKey(bucket, key)
+	}
+	for _, object := range c.buckets[bucket][key] {
+		if object.VersionID == *versionID {
+			return object, nil
+		}
+	}
+	return nil, apiError("NoSuchVersion", fmt.Sprintf("%s/%s has no version %s", bucket, key, *versionID))
+}
+
+func (c *Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	bucket, key := aws.ToString(params.Bucket), aws.ToString(params.Key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object, err := c.version(bucket, key, params.VersionId)
+	if err != nil {
+		return nil, err
+	}
+	err = checkConditions(object, params.IfMatch, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body := object.Body
+	output := &s3.GetObjectOutput{
+		ContentType:        aws.String(object.ContentType),
+		ContentDisposition: optionalString(object.ContentDisposition),
+		ContentLanguage:    optionalString(object.ContentLanguage),
+		CacheControl:       optionalString(object.CacheControl),
+		Metadata:           object.Metadata,
+		ETag:               quote(object.ETag),
+		VersionId:          optionalString(object.VersionID),
+	}
+	if params.Range != nil {
+		start, end, err := parseRange(*params.Range, int64(len(body)))
+		if err != nil {
+			return nil, err
+		}
+		output.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, len(body)))
+		body = body[start : end+1]
+	}
+	output.ContentLength = aws.Int64(int64(len(body)))
+	output.Body = io.NopCloser(bytes.NewReader(body))
+
+	return output, nil
+}
+
+// parseRange returns the first and last byte of a range in the format "bytes=<first>-<last>".
+func parseRange(rangeHeader string, size int64) (int64, int64, error) {
+	first, last, found := strings.Cut(strings.TrimPrefix(rangeHeader, "bytes="), "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if !found || err != nil || start >= size {
+		return 0, 0, apiError("InvalidRange", fmt.Sprintf("the range %s is not satisfiable", rangeHeader))
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil {
+			return 0, 0, apiError("InvalidRange", fmt.Sprintf("the range %s is not satisfiable", rangeHeader))
+		}
+		end = min(end, size-1)
+	}
+	return start, end, nil
+}
+
+func (c *Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	bucket := aws.ToString(params.Bucket)
+	maxKeys := maxListKeys
+	if params.MaxKeys != nil {
+		maxKeys = int(*params.MaxKeys)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	for _, key := range c.sortedKeys(bucket, aws.ToString(params.Prefix)) {
+		// The continuation token is the last key of the previous page.
+		if params.ContinuationToken != nil && key <= *params.ContinuationToken {
+			continue
+		}
+		if len(output.Contents) == maxKeys {
+			output.IsTruncated = aws.Bool(true)
+			output.NextContinuationToken = output.Contents[len(output.Contents)-1].Key
+			break
+		}
+		object := c.current(bucket, key)
+		output.Contents = append(output.Contents, types.Object{
+			Key:  aws.String(key),
+			ETag: quote(object.ETag),
+			Size: aws.Int64(int64(len(object.Body))),
+		})
+	}
+	output.KeyCount = aws.Int32(int32(len(output.Contents)))
+
+	return output, nil
+}
+
+// ListObjectVersions returns every version of the objects with the prefix in a single page, the versions of each key
+// from newest to oldest. Objects written without versioning have the version "null", as on S3.
+func (c *Client) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	bucket := aws.ToString(params.Bucket)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(false)}
+	for _, key := range c.sortedKeys(bucket, aws.ToString(params.Prefix)) {
+		versions := c.buckets[bucket][key]
+		for i := len(versions) - 1; i >= 0; i-- {
+			versionID := versions[i].VersionID
+			if versionID == "" {
+				versionID = "null"
+			}
+			output.Versions = append(output.Versions, types.ObjectVersion{
+				Key:       aws.String(key),
+				VersionId: aws.String(versionID),
+				IsLatest:  aws.Bool(i == len(versions)-1),
+				ETag:      quote(versions[i].ETag),
+				Size:      aws.Int64(int64(len(versions[i].Body))),
+			})
+		}
+	}
+	return output, nil
+}
+
+// parseCopySource returns the bucket, key and version of a CopySource value, "bucket/key[?versionId=id]".
+func parseCopySource(copySource string) (string, string, *string, error) {
+	source, query, _ := strings.Cut(copySource, "?")
+	bucket, escapedKey, found := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	key, err := url.PathUnescape(escapedKey)
+	if !found || err != nil {
+		return "", "", nil, apiError("InvalidArgument", fmt.Sprintf("invalid copy source %s", copySource))
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", nil, apiError("InvalidArgument", fmt.Sprintf("invalid copy source %s", copySource))
+	}
+	if versionID := values.Get("versionId"); versionID != "" {
+		return bucket, key, &versionID, nil
+	}
+	return bucket, key, nil, nil
+}
+
+func (c *Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	sourceBucket, sourceKey, sourceVersion, err := parseCopySource(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	source, err := c.version(sourceBucket, sourceKey, sourceVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	object := *source
+	object.VersionID = ""
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		object.ContentType = aws.ToString(params.ContentType)
+		object.ContentDisposition = aws.ToString(params.ContentDisposition)
+		object.ContentLanguage = aws.ToString(params.ContentLanguage)
+		object.CacheControl = aws.ToString(params.CacheControl)
+		object.Metadata = params.Metadata
+	}
+	if params.TaggingDirective == types.TaggingDirectiveReplace {
+		object.Tags = tags
+	}
+	// Copies are written in one request, so their ETag is that of their content.
+	object.ETag = md5Hex(object.Body)
+	object.PartSizes = nil
+	c.store(aws.ToString(params.Bucket), aws.ToString(params.Key), &object)
+
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{ETag: quote(object.ETag)},
+		VersionId:        optionalString(object.VersionID),
+	}, nil
+}
+
+func (c *Client) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.buckets[aws.ToString(params.Bucket)], aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (c *Client) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &s3.DeleteObjectsOutput{}
+	for _, object := range params.Delete.Objects {
+		delete(c.buckets[aws.ToString(params.Bucket)], aws.ToString(object.Key))
+		if !aws.ToBool(params.Delete.Quiet) {
+			output.Deleted = append(output.Deleted, types.DeletedObject{Key: object.Key})
+		}
+	}
+	return output, nil
+}
+
+func (c *Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	bucket, key := aws.ToString(params.Bucket), aws.ToString(params.Key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object, err := c.version(bucket, key, params.VersionId)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &s3.GetObjectTaggingOutput{}
+	for key, value := range object.Tags {
+		output.TagSet = append(output.TagSet, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return output, nil
+}
+
+func (c *Client) PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	bucket, key := aws.ToString(params.Bucket), aws.ToString(params.Key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	object, err := c.version(bucket, key, params.VersionId)
+	if err != nil {
+		return nil, err
+	}
+
+	object.Tags = make(map[string]string, len(params.Tagging.TagSet))
+	for _, tag := range params.Tagging.TagSet {
+		object.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+func (c *Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	uploadID := "upload-" + strconv.Itoa(c.nextID)
+	c.uploads[uploadID] = &multipartUpload{
+		bucket: aws.ToString(params.Bucket),
+		key:    aws.ToString(params.Key),
+		object: Object{
+			ContentType:        aws.ToString(params.ContentType),
+			ContentDisposition: aws.ToString(params.ContentDisposition),
+			ContentLanguage:    aws.ToString(params.ContentLanguage),
+			CacheControl:       aws.ToString(params.CacheControl),
+			Metadata:           params.Metadata,
+			Tags:               tags,
+		},
+		parts: make(map[int32][]byte),
+	}
+
+	return &s3.CreateMultipartUploadOutput{Bucket: params.Bucket, Key: params.Key, UploadId: aws.String(uploadID)}, nil
+}
+
+func (c *Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upload, found := c.uploads[aws.ToString(params.UploadId)]
+	if !found {
+		return nil, &types.NoSuchUpload{Message: aws.String("the upload does not exist")}
+	}
+	upload.parts[aws.ToInt32(params.PartNumber)] = body
+
+	return &s3.UploadPartOutput{ETag: quote(md5Hex(body))}, nil
+}
+
+func (c *Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uploadID := aws.ToString(params.UploadId)
+	upload, found := c.uploads[uploadID]
+	if !found {
+		return nil, &types.NoSuchUpload{Message: aws.String("the upload does not exist")}
+	}
+	err := checkConditions(c.current(upload.bucket, upload.key), params.IfMatch, params.IfNoneMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	object := upload.object
+	var partHashes []byte
+	for _, part := range params.MultipartUpload.Parts {
+		body, found := upload.parts[aws.ToInt32(part.PartNumber)]
+		if !found {
+			return nil, apiError("InvalidPart", fmt.Sprintf("part %d was not uploaded", aws.ToInt32(part.PartNumber)))
+		}
+		object.Body = append(object.Body, body...)
+		object.PartSizes = append(object.PartSizes, int64(len(body)))
+		sum := md5.Sum(body)
+		partHashes = append(partHashes, sum[:]...)
+	}
+	object.ETag = md5Hex(partHashes) + "-" + strconv.Itoa(len(object.PartSizes))
+	c.store(upload.bucket, upload.key, &object)
+	delete(c.uploads, uploadID)
+
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket:    params.Bucket,
+		Key:       params.Key,
+		ETag:      quote(object.ETag),
+		VersionId: optionalString(object.VersionID),
+	}, nil
+}
+
+func (c *Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.uploads, aws.ToString(params.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
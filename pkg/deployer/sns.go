@@ -0,0 +1,38 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+// PublishSNSNotification publishes the deployment summary as a JSON message to the given SNS topic.
+func (d *Deployer) PublishSNSNotification(ctx context.Context, topicArn string, summary DeploymentSummary) error {
+	parsedArn, err := arn.Parse(topicArn)
+	if err != nil {
+		return fmt.Errorf("invalid SNS topic ARN (%s): %w", topicArn, err)
+	}
+
+	message, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode deployment summary: %w", err)
+	}
+
+	// The topic may live in another region than the provider's default one.
+	client := sns.NewFromConfig(d.DefaultAWSConfig, func(o *sns.Options) {
+		o.Region = parsedArn.Region
+	})
+
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(topicArn),
+		Message:  aws.String(string(message)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish notification to SNS topic (%s): %w", topicArn, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/codepipeline"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultDownloadConcurrency is how many parts of an artifact are downloaded at the same time unless configured.
+const DefaultDownloadConcurrency = 5
+
+// errVersionNotSupported is returned when a version is requested from a source that is not versioned.
+var errVersionNotSupported = errors.New("versions are only supported for S3 sources")
+
+// SourceArtifact is a downloaded artifact. Its body must be closed by the caller.
+type SourceArtifact struct {
+	Body io.ReadCloser
+	// ETag is the entity tag of the artifact, or empty if the source does not have one.
+	ETag string
+}
+
+// SourceFetcher downloads artifacts from one kind of source.
+type SourceFetcher interface {
+	// Fetch downloads the artifact at the given location. If version is nil, the latest version is returned.
+	Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error)
+}
+
+// SourceDownloader is implemented by source fetchers that can download artifacts faster by writing several parts
+// of them at the same time, e.g. with concurrent ranged requests.
+type SourceDownloader interface {
+	// Download writes the artifact at the given location to w, with at most concurrency parts being downloaded at the
+	// same time. It returns the ETag of the artifact, or an empty string if the source does not have one, and its size.
+	Download(ctx context.Context, location *url.URL, version *string, w io.WriterAt, concurrency int) (string, int64, error)
+}
+
+// SourceVersionResolver is implemented by source fetchers of sources that keep versions of artifacts.
+type SourceVersionResolver interface {
+	// ResolveVersion returns the ID of the current version of the artifact at the given location, or an empty string
+	// if the source does not keep versions of it.
+	ResolveVersion(ctx context.Context, location *url.URL) (string, error)
+}
+
+// SourceTagResolver is implemented by source fetchers of sources that keep versions of artifacts with tags.
+type SourceTagResolver interface {
+	// ResolveTaggedVersion returns the ID of the newest version of the artifact at the given location with the given
+	// tag, or an empty string if it is a version that cannot be fetched by its ID, e.g. one written before versioning
+	// was enabled.
+	ResolveTaggedVersion(ctx context.Context, location *url.URL, tagKey string, tagValue string) (string, error)
+}
+
+// SourceFetchers is a registry of source fetchers, keyed by the URI scheme of the locations they fetch.
+type SourceFetchers map[string]SourceFetcher
+
+// fetcher returns the fetcher registered for the scheme of the given location.
+func (f SourceFetchers) fetcher(location *url.URL) (SourceFetcher, error) {
+	fetcher, found := f[location.Scheme]
+	if !found {
+		return nil, fmt.Errorf("unsupported source %s: no fetcher for the %q scheme", location, location.Scheme)
+	}
+	return fetcher, nil
+}
+
+// Fetch downloads the artifact at the given location with the fetcher registered for its scheme.
+func (f SourceFetchers) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	fetcher, err := f.fetcher(location)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.Fetch(ctx, location, version)
+}
+
+// defaultSourceFetchers returns the fetchers for S3, CodePipeline, HTTP and file sources.
+func (d *Deployer) defaultSourceFetchers() SourceFetchers {
+	httpFetcher := &httpSourceFetcher{client: http.DefaultClient}
+	return SourceFetchers{
+		"s3":           NewS3SourceFetcher(d.s3Client("")),
+		"codepipeline": NewCodePipelineSourceFetcher(codepipeline.NewFromConfig(d.DefaultAWSConfig), d.s3Client("")),
+		"http":         httpFetcher,
+		"https":        httpFetcher,
+		"file":         fileSourceFetcher{},
+	}
+}
+
+// s3SourceFetcher fetches artifacts from "s3://bucket/key" locations.
+type s3SourceFetcher struct {
+	client S3SourceAPI
+	// customerKey, if set, is the customer-provided key the artifacts are encrypted with.
+	customerKey *CustomerKey
+}
+
+// NewS3SourceFetcher returns a SourceFetcher for "s3://bucket/key" locations, downloading artifacts with the given
+// client.
+func NewS3SourceFetcher(client S3SourceAPI) SourceFetcher {
+	return &s3SourceFetcher{client: client}
+}
+
+func (f *s3SourceFetcher) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+
+	getObjectInput := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: version,
+	}
+	f.customerKey.applyToGetObject(getObjectInput)
+	result, err := f.client.GetObject(ctx, getObjectInput)
+	if err != nil {
+		return nil, newObjectError("GetObject", bucket, key, err)
+	}
+
+	return &SourceArtifact{
+		Body: result.Body,
+		ETag: strings.Trim(aws.ToString(result.ETag), "\""),
+	}, nil
+}
+
+// Check checks that the artifact can be read with a HEAD request.
+func (f *s3SourceFetcher) Check(ctx context.Context, location *url.URL, version *string) (string, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: version,
+	}
+	f.customerKey.applyToHeadObject(headObjectInput)
+	head, err := f.client.HeadObject(ctx, headObjectInput)
+	if err != nil {
+		return "", newObjectError("HeadObject", bucket, key, err)
+	}
+
+	return strings.Trim(aws.ToString(head.ETag), "\""), nil
+}
+
+// ResolveVersion returns the version ID of the artifact. Objects in buckets without versioning, and those written
+// while versioning was suspended, have no version that cannot be overwritten, so an empty string is returned.
+func (f *s3SourceFetcher) ResolveVersion(ctx context.Context, location *url.URL) (string, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	f.customerKey.applyToHeadObject(headObjectInput)
+	head, err := f.client.HeadObject(ctx, headObjectInput)
+	if err != nil {
+		return "", newObjectError("HeadObject", bucket, key, err)
+	}
+
+	versionID := aws.ToString(head.VersionId)
+	if versionID == "null" {
+		return "", nil
+	}
+	return versionID, nil
+}
+
+// ResolveTaggedVersion lists the versions of the artifact from newest to oldest, and returns the ID of the first one
+// with the tag. It requires a client that can list object versions and get their tags, such as *s3.Client.
+func (f *s3SourceFetcher) ResolveTaggedVersion(ctx context.Context, location *url.URL, tagKey string, tagValue string) (string, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+
+	client, ok := f.client.(interface {
+		ListObjectVersionsAPI
+		GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	})
+	if !ok {
+		return "", fmt.Errorf("the S3 client of %s/%s cannot list object versions and tags", bucket, key)
+	}
+
+	paginator := s3.NewListObjectVersionsPaginator(client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", newObjectError("ListObjectVersions", bucket, key, err)
+		}
+		for _, version := range page.Versions {
+			// Other objects may share the key as prefix.
+			if aws.ToString(version.Key) != key {
+				continue
+			}
+			tagging, err := client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+				Bucket:    aws.String(bucket),
+				Key:       aws.String(key),
+				VersionId: version.VersionId,
+			})
+			if err != nil {
+				return "", newObjectError("GetObjectTagging", bucket, key, err)
+			}
+			for _, tag := range tagging.TagSet {
+				if aws.ToString(tag.Key) != tagKey || aws.ToString(tag.Value) != tagValue {
+					continue
+				}
+				if versionID := aws.ToString(version.VersionId); versionID != "null" {
+					return versionID, nil
+				}
+				return "", nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no version of %s/%s is tagged %s=%s", bucket, key, tagKey, tagValue)
+}
+
+// downloadPartSize is the size of each ranged request when an artifact is downloaded from S3.
+const downloadPartSize = 16 << 20
+
+// Download downloads the artifact with concurrent ranged requests. Every request is made conditional on the ETag
+// of the artifact, so that the parts cannot come from different versions of it.
+func (f *s3SourceFetcher) Download(ctx context.Context, location *url.URL, version *string, w io.WriterAt, concurrency int) (string, int64, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: version,
+	}
+	f.customerKey.applyToHeadObject(headObjectInput)
+	head, err := f.client.HeadObject(ctx, headObjectInput)
+	if err != nil {
+		return "", 0, newObjectError("HeadObject", bucket, key, err)
+	}
+
+	downloader := manager.NewDownloader(f.client, func(d *manager.Downloader) {
+		d.PartSize = downloadPartSize
+		d.Concurrency = concurrency
+	})
+	getObjectInput := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: version,
+		IfMatch:   head.ETag,
+	}
+	f.customerKey.applyToGetObject(getObjectInput)
+	n, err := downloader.Download(ctx, w, getObjectInput)
+	if err != nil {
+		return "", 0, newObjectError("GetObject", bucket, key, err)
+	}
+
+	return strings.Trim(aws.ToString(head.ETag), "\""), n, nil
+}
+
+// httpSourceFetcher fetches artifacts from "http://" and "https://" locations.
+type httpSourceFetcher struct {
+	client *http.Client
+}
+
+func (f *httpSourceFetcher) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	if version != nil {
+		return nil, errVersionNotSupported
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location.Redacted(), err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: unexpected status %s", location.Redacted(), resp.Status)
+	}
+
+	return &SourceArtifact{
+		Body: resp.Body,
+		ETag: strings.Trim(strings.TrimPrefix(resp.Header.Get("ETag"), "W/"), "\""),
+	}, nil
+}
+
+// fileSourceFetcher fetches artifacts from "file:///path/to/artifact.zip" locations on the local file system.
+type fileSourceFetcher struct{}
+
+func (fileSourceFetcher) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	if version != nil {
+		return nil, errVersionNotSupported
+	}
+
+	file, err := os.Open(location.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SourceArtifact{Body: file}, nil
+}
+
+// Check checks that the artifact exists. Files have no ETag.
+func (fileSourceFetcher) Check(ctx context.Context, location *url.URL, version *string) (string, error) {
+	if version != nil {
+		return "", errVersionNotSupported
+	}
+
+	_, err := os.Stat(location.Path)
+	return "", err
+}
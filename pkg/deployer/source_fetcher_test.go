@@ -0,0 +1,309 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestArtifact returns the content of a ZIP file with the given files.
+func newTestArtifact(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHashesForArtifact_fileSource(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	if err := os.WriteFile(artifactPath, newTestArtifact(t, map[string]string{"index.html": "hello"}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}}
+	hashes, err := d.HashesForArtifact(context.Background(), "file://"+artifactPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The MD5 hash of "hello".
+	if hashes["index.html"] != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("unexpected hashes: %v", hashes)
+	}
+}
+
+func TestHashesForArtifact_verifiesChecksumWhileDownloading(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "site.zip")
+	if err := os.WriteFile(artifactPath, newTestArtifact(t, map[string]string{"index.html": "hello"}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deployment{
+		Sources:        SourceFetchers{"file": fileSourceFetcher{}},
+		SourceChecksum: helloSHA256,
+	}
+	_, err := d.HashesForArtifact(context.Background(), "file://"+artifactPath, nil)
+
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestHTTPSourceFetcher_returnsContentAndETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "\"abc123\"")
+		_, _ = w.Write([]byte("artifact"))
+	}))
+	defer server.Close()
+
+	location, _ := url.Parse(server.URL + "/site.zip")
+	artifact, err := (&httpSourceFetcher{client: server.Client()}).Fetch(context.Background(), location, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer artifact.Body.Close()
+
+	content, err := io.ReadAll(artifact.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "artifact" || artifact.ETag != "abc123" {
+		t.Errorf("unexpected artifact: %q with ETag %q", content, artifact.ETag)
+	}
+}
+
+func TestHTTPSourceFetcher_failsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	location, _ := url.Parse(server.URL + "/site.zip")
+	_, err := (&httpSourceFetcher{client: server.Client()}).Fetch(context.Background(), location, nil)
+	if err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
+
+func TestSourceFetchers_unknownScheme(t *testing.T) {
+	location, _ := url.Parse("ftp://example.com/site.zip")
+
+	_, err := SourceFetchers{}.Fetch(context.Background(), location, nil)
+	if err == nil {
+		t.Error("expected an error for a scheme without a fetcher")
+	}
+}
+
+func TestSourceLocation(t *testing.T) {
+	location, err := (&Deployment{SourceBucket: "artifacts"}).sourceLocation("site/app 1.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Scheme != "s3" || location.Host != "artifacts" || location.Path != "/site/app 1.zip" {
+		t.Errorf("unexpected location: %#v", location)
+	}
+
+	location, err = (&Deployment{}).sourceLocation("https://example.com/site.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Scheme != "https" || location.Host != "example.com" {
+		t.Errorf("unexpected location: %#v", location)
+	}
+}
+
+// fakeDownloader is a SourceDownloader that writes the given content in two parts, last part first.
+type fakeDownloader struct {
+	content     []byte
+	concurrency int
+}
+
+func (f *fakeDownloader) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	return nil, errors.New("expected Download to be used")
+}
+
+func (f *fakeDownloader) Download(ctx context.Context, location *url.URL, version *string, w io.WriterAt, concurrency int) (string, int64, error) {
+	f.concurrency = concurrency
+	half := len(f.content) / 2
+	if _, err := w.WriteAt(f.content[half:], int64(half)); err != nil {
+		return "", 0, err
+	}
+	if _, err := w.WriteAt(f.content[:half], 0); err != nil {
+		return "", 0, err
+	}
+	return "etag", int64(len(f.content)), nil
+}
+
+func TestHashesForArtifact_usesSourceDownloader(t *testing.T) {
+	downloader := &fakeDownloader{content: newTestArtifact(t, map[string]string{"index.html": "hello"})}
+	d := &Deployment{SourceBucket: "artifacts", Sources: SourceFetchers{"s3": downloader}}
+
+	hashes, err := d.HashesForArtifact(context.Background(), "site.zip", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hashes["index.html"] != "5d41402abc4b2a76b9719d911017c592" || d.SourceETag() != "etag" {
+		t.Errorf("unexpected hashes %v and ETag %q", hashes, d.SourceETag())
+	}
+	if downloader.concurrency != DefaultDownloadConcurrency {
+		t.Errorf("expected the default concurrency, got %d", downloader.concurrency)
+	}
+}
+
+func TestDeploy_returnsSourceETag(t *testing.T) {
+	client := s3fake.New()
+	for _, content := range []string{"v1", "v2"} {
+		artifact := newTestArtifact(t, map[string]string{"index.html": content})
+		_, err := client.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("artifacts"), Key: aws.String("site.zip"), Body: bytes.NewReader(artifact)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, _ := newTestDeployment(t, client, nil)
+		d.SourceBucket = "artifacts"
+		d.Sources = SourceFetchers{"s3": NewS3SourceFetcher(client)}
+
+		if _, err := d.Deploy(context.Background(), "site.zip", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Every deployment returns the ETag of the artifact it deployed, so that a rebuilt artifact changes it.
+		if etag := client.Object("artifacts", "site.zip").ETag; d.SourceETag() != etag {
+			t.Errorf("expected the ETag %q of the artifact, got %q", etag, d.SourceETag())
+		}
+	}
+}
+
+// checkingFetcher is a SourceChecker whose artifacts cannot be downloaded.
+type checkingFetcher struct{}
+
+func (checkingFetcher) Fetch(ctx context.Context, location *url.URL, version *string) (*SourceArtifact, error) {
+	return nil, errors.New("unexpected download")
+}
+
+func (checkingFetcher) Check(ctx context.Context, location *url.URL, version *string) (string, error) {
+	return "etag", nil
+}
+
+func TestRefreshSourceETag_doesNotDownload(t *testing.T) {
+	d := &Deployment{SourceBucket: "artifacts", Sources: SourceFetchers{"s3": checkingFetcher{}}}
+
+	err := d.RefreshSourceETag(context.Background(), "site.zip", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.SourceETag() != "etag" {
+		t.Errorf("unexpected ETag: %q", d.SourceETag())
+	}
+}
+
+func TestResolveSourceVersion(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	client.Versioning = true
+	putArtifact := func(content string) {
+		t.Helper()
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String("source"),
+			Key:    aws.String("site.zip"),
+			Body:   bytes.NewReader(newTestArtifact(t, map[string]string{"index.html": content})),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := (&Deployer{S3Client: func(region string) S3API { return client }}).NewDeployment("source", "target", "eu-north-1")
+
+	putArtifact("first")
+	version, err := d.ResolveSourceVersion(ctx, "site.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version == "" {
+		t.Fatal("expected the version of the artifact to be resolved")
+	}
+
+	// The resolved version is deployed even though the artifact has been overwritten since.
+	putArtifact("second")
+	if _, err := d.Deploy(ctx, "site.zip", &version); err != nil {
+		t.Fatal(err)
+	}
+	if index := client.Object("target", "index.html"); string(index.Body) != "first" {
+		t.Errorf("expected the resolved version to be deployed, got %q", index.Body)
+	}
+
+	unversioned := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}}
+	if version, err := unversioned.ResolveSourceVersion(ctx, "file:///site.zip"); err != nil || version != "" {
+		t.Errorf("expected no version for a file source, got %q, %v", version, err)
+	}
+}
+
+func TestResolveTaggedSourceVersion(t *testing.T) {
+	ctx := context.Background()
+	client := s3fake.New()
+	client.Versioning = true
+	putArtifact := func(key string, content string, tagging string) string {
+		t.Helper()
+		output, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:  aws.String("source"),
+			Key:     aws.String(key),
+			Body:    bytes.NewReader(newTestArtifact(t, map[string]string{"index.html": content})),
+			Tagging: aws.String(tagging),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return aws.ToString(output.VersionId)
+	}
+	d := (&Deployer{S3Client: func(region string) S3API { return client }}).NewDeployment("source", "target", "eu-north-1")
+
+	staging := putArtifact("site.zip", "first", "environment=staging")
+	putArtifact("site.zip", "second", "environment=dev")
+	putArtifact("site.zip.sig", "signature", "environment=staging")
+	version, err := d.ResolveTaggedSourceVersion(ctx, "site.zip", "environment", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != staging {
+		t.Errorf("expected the tagged version %s, got %s", staging, version)
+	}
+
+	// Promoting another version moves the deployment to it.
+	promoted := putArtifact("site.zip", "third", "environment=staging")
+	if version, err := d.ResolveTaggedSourceVersion(ctx, "site.zip", "environment", "staging"); err != nil || version != promoted {
+		t.Errorf("expected the newest tagged version %s, got %q, %v", promoted, version, err)
+	}
+
+	if _, err := d.ResolveTaggedSourceVersion(ctx, "site.zip", "environment", "production"); err == nil {
+		t.Error("expected an error when no version has the tag")
+	}
+	unversioned := &Deployment{Sources: SourceFetchers{"file": fileSourceFetcher{}}}
+	if _, err := unversioned.ResolveTaggedSourceVersion(ctx, "file:///site.zip", "environment", "staging"); err == nil {
+		t.Error("expected an error for a file source")
+	}
+}
@@ -0,0 +1,50 @@
+package deployer
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// DefaultSPAEntryDocument is the entry document of single-page applications unless another one is configured.
+const DefaultSPAEntryDocument = "index.html"
+
+// DefaultSPAErrorDocuments are the error documents the entry document is deployed as unless others are configured.
+var DefaultSPAErrorDocuments = []string{"404.html", "error.html"}
+
+// SPAMode configures deployments of single-page applications, whose entry document is also deployed as the error
+// documents of the target, so that S3 website hosting and CloudFront custom error responses serve the application
+// for paths that only exist in its client-side router.
+type SPAMode struct {
+	// EntryDocument is the name of the artifact file to deploy as the error documents, e.g. "index.html".
+	EntryDocument string
+	// ErrorDocuments are the names the entry document is also deployed as, e.g. "404.html". Files of the artifact
+	// with one of these names are deployed as they are.
+	ErrorDocuments []string
+}
+
+// addSPAErrorDocuments adds copies of the entry document to the artifact entries, named after the error documents
+// the artifact does not have itself. They are then deployed, compared and kept like any other file.
+func (d *Deployment) addSPAErrorDocuments(artifactZip *zip.Reader) error {
+	names := make(map[string]bool, len(artifactZip.File))
+	var entry *zip.File
+	for _, file := range artifactZip.File {
+		names[file.Name] = true
+		if file.Name == d.SPA.EntryDocument {
+			entry = file
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("the artifact has no SPA entry document %s", d.SPA.EntryDocument)
+	}
+
+	for _, name := range d.SPA.ErrorDocuments {
+		if names[name] {
+			continue
+		}
+		errorDocument := *entry
+		errorDocument.Name = name
+		artifactZip.File = append(artifactZip.File, &errorDocument)
+		names[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+func TestDeploy_spaMode(t *testing.T) {
+	client := s3fake.New()
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "app", "error.html": "custom error"})
+	d.SPA = &SPAMode{EntryDocument: DefaultSPAEntryDocument, ErrorDocuments: DefaultSPAErrorDocuments}
+
+	files, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(client.Object("target", "404.html").Body); got != "app" {
+		t.Errorf("expected the entry document to be deployed as 404.html, got %q", got)
+	}
+	if got := string(client.Object("target", "error.html").Body); got != "custom error" {
+		t.Errorf("expected the error document of the artifact to be kept, got %q", got)
+	}
+	if got := client.Object("target", "404.html").ContentType; !strings.HasPrefix(got, "text/html") {
+		t.Errorf("expected 404.html to be deployed as HTML, got %q", got)
+	}
+	if _, found := files["404.html"]; !found {
+		t.Errorf("expected 404.html to be a deployed file, got %v", files)
+	}
+}
+
+func TestDeploy_spaModeWithoutEntryDocument(t *testing.T) {
+	d, source := newTestDeployment(t, s3fake.New(), map[string]string{"app.html": "app"})
+	d.SPA = &SPAMode{EntryDocument: "index.html", ErrorDocuments: []string{"404.html"}}
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err == nil || !strings.Contains(err.Error(), "no SPA entry document index.html") {
+		t.Errorf("expected a missing entry document error, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// PutVersionParameter writes the deployed source version to the given SSM parameter, creating it if needed.
+func (d *Deployer) PutVersionParameter(ctx context.Context, name string, version string) error {
+	client := ssm.NewFromConfig(d.DefaultAWSConfig)
+
+	_, err := client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(version),
+		Type:      types.ParameterTypeString,
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write version to SSM parameter (%s): %w", name, err)
+	}
+
+	return nil
+}
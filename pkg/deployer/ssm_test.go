@@ -0,0 +1,58 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// putParameterInput is the body of an SSM PutParameter request.
+type putParameterInput struct {
+	Name      string
+	Value     string
+	Type      string
+	Overwrite bool
+}
+
+func TestPutVersionParameter_overwritesStringParameter(t *testing.T) {
+	var inputs []putParameterInput
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "AmazonSSM.PutParameter" {
+			t.Errorf("unexpected operation %s", target)
+		}
+		var input putParameterInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+		inputs = append(inputs, input)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Version":2,"Tier":"Standard"}`))
+	})
+
+	err := (&Deployer{DefaultAWSConfig: cfg}).PutVersionParameter(context.Background(), "/my-site/version", "1.2.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The parameter is overwritten, as it is written on every deployment.
+	expected := putParameterInput{Name: "/my-site/version", Value: "1.2.3", Type: "String", Overwrite: true}
+	if len(inputs) != 1 || inputs[0] != expected {
+		t.Errorf("expected the parameter %+v to be written, got %+v", expected, inputs)
+	}
+}
+
+func TestPutVersionParameter_failsOnError(t *testing.T) {
+	cfg := newAWSTestConfig(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"AccessDeniedException","message":"not authorized to perform ssm:PutParameter"}`))
+	})
+
+	err := (&Deployer{DefaultAWSConfig: cfg}).PutVersionParameter(context.Background(), "/my-site/version", "1.2.3")
+	if err == nil || !strings.Contains(err.Error(), "SSM parameter (/my-site/version)") || !strings.Contains(err.Error(), "AccessDeniedException") {
+		t.Errorf("expected an error for the denied parameter, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package deployer
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// StagingPrefix is the prefix the files of a deployment with StagedPromotion are uploaded under before they are
+// promoted, followed by the deployment ID.
+const StagingPrefix = ".staticfiledeploy-staging/"
+
+// stagingKey returns the key the object with the given key is staged at before it is promoted. Files staged for
+// BatchOperations keep their keys in the staging bucket, as the job copies them to the same keys.
+func (d *Deployment) stagingKey(key string) string {
+	if d.BatchOperations != nil {
+		return key
+	}
+	return StagingPrefix + d.ID + "/" + key
+}
+
+// stagesFiles returns whether new and changed files are staged before they are promoted to their keys.
+func (d *Deployment) stagesFiles() bool {
+	return d.StagedPromotion || d.BatchOperations != nil
+}
+
+// stagedFile is an artifact file uploaded to the staging area, with the key it is promoted to.
+type stagedFile struct {
+	key  string
+	file *zip.File
+}
+
+// checkStagedPromotion returns an error if StagedPromotion is combined with settings it does not work with.
+func (d *Deployment) checkStagedPromotion() error {
+	if d.BlueGreen != nil {
+		return errors.New("staged promotion cannot be combined with blue/green deployments, which already go live in a single step")
+	}
+	if d.ObjectLock != nil {
+		return errors.New("staged promotion cannot be combined with Object Lock, as promoted objects are copied without the retention settings")
+	}
+	return nil
+}
+
+// verifyStagedFiles checks that every staged file exists with the expected size and content type, so that nothing
+// is promoted unless all of it can be.
+func (d *Deployment) verifyStagedFiles(ctx context.Context) error {
+	var mismatches []ObjectMismatch
+	for _, staged := range d.staged {
+		mismatch, err := d.verifyObject(ctx, d.stagingStore(), d.stagingKey(staged.key), staged.key, staged.file)
+		if err != nil {
+			return err
+		}
+		if mismatch != nil {
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+
+	tflog.Info(ctx, "Verified staged files", map[string]interface{}{
+		"files":      len(d.staged),
+		"mismatches": len(mismatches),
+	})
+
+	if len(mismatches) > 0 {
+		return &VerificationError{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// promoteStagedFiles copies the staged files server-side to their keys in the target, in the order they were
+// uploaded, and then deletes the staging area. The live files are only changed by fast copies within the target,
+// so they are inconsistent for a much shorter time than while the artifact is uploaded.
+func (d *Deployment) promoteStagedFiles(ctx context.Context) error {
+	for _, staged := range d.staged {
+		err := d.recordWrite(ctx, staged.key)
+		if err != nil {
+			return err
+		}
+		err = d.retryThrottled(ctx, staged.key, func() error {
+			return d.target.Copy(ctx, d.stagingKey(staged.key), staged.key)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	tflog.Info(ctx, "Promoted staged files", map[string]interface{}{
+		"files": len(d.staged),
+	})
+
+	return d.deleteStagedFiles(ctx)
+}
+
+// deleteStagedFiles deletes the staging area of the deployment. It is also called after the deployment failed,
+// possibly because its context was cancelled, so it does not use the context for cancellation.
+func (d *Deployment) deleteStagedFiles(ctx context.Context) error {
+	keys := make([]string, len(d.staged))
+	for i, staged := range d.staged {
+		keys[i] = d.stagingKey(staged.key)
+	}
+	d.staged = nil
+	return deleteKeysFrom(context.WithoutCancel(ctx), d.stagingStore(), keys)
+}
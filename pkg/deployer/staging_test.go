@@ -0,0 +1,49 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeploy_stagedPromotion(t *testing.T) {
+	client := &recordingS3API{Client: s3fake.New()}
+	putTestObjects(t, client.Client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.StagedPromotion = true
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range client.putKeys() {
+		if !strings.HasPrefix(key, StagingPrefix) {
+			t.Errorf("expected files to only be uploaded to the staging area, got %s", key)
+		}
+	}
+	if objects := testObjects(client.Client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "new", "about.html": "about"}) {
+		t.Errorf("expected the promoted files without the staging area, got %q", objects)
+	}
+}
+
+func TestDeploy_stagedPromotionFailure(t *testing.T) {
+	client := &recordingS3API{Client: s3fake.New()}
+	putTestObjects(t, client.Client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.StagedPromotion = true
+	// Files matching the upload order are uploaded last, so about.html has been staged when index.html fails.
+	d.UploadOrder = []string{"index.html"}
+	client.failKey = d.stagingKey("index.html")
+
+	_, err := d.Deploy(context.Background(), source, nil)
+	if err == nil {
+		t.Fatal("expected the deployment to fail")
+	}
+
+	if objects := testObjects(client.Client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "old"}) {
+		t.Errorf("expected the target to be unchanged, got %q", objects)
+	}
+}
@@ -0,0 +1,75 @@
+package deployer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// StatusSucceeded is the status of a deployment that completed without errors.
+	StatusSucceeded = "SUCCEEDED"
+	// StatusFailed is the status of a deployment that returned an error.
+	StatusFailed = "FAILED"
+)
+
+// DeploymentSummary describes the outcome of a deployment, and is what gets sent to notification targets.
+// FilesChanged includes files that only had their metadata updated. FilesResumed are the skipped files that were
+// already checked by a failed deployment, and were not checked again. Unchanged deployments found the target up to
+// date, and skipped all files.
+type DeploymentSummary struct {
+	DeploymentID  string    `json:"deployment_id"`
+	Source        string    `json:"source"`
+	SourceVersion string    `json:"source_version"`
+	Target        string    `json:"target"`
+	FilesDeployed int       `json:"files_deployed"`
+	FilesAdded    int       `json:"files_added"`
+	FilesChanged  int       `json:"files_changed"`
+	FilesDeleted  int       `json:"files_deleted"`
+	FilesSkipped  int       `json:"files_skipped"`
+	FilesResumed  int       `json:"files_resumed"`
+	Unchanged     bool      `json:"unchanged"`
+	BytesUploaded int64     `json:"bytes_uploaded"`
+	DurationMs    int64     `json:"duration_ms"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// newDeploymentID returns a random identifier for a single deployment run.
+func newDeploymentID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Summary returns a summary of a deployment, given the files it deployed and the error it returned, if any.
+func (d *Deployment) Summary(sourceKey string, sourceVersion string, files DeployedFiles, err error) DeploymentSummary {
+	summary := DeploymentSummary{
+		DeploymentID:  d.ID,
+		Source:        d.sourceName(sourceKey),
+		SourceVersion: sourceVersion,
+		Target:        d.TargetBucket,
+		FilesDeployed: len(files),
+		FilesAdded:    d.filesAdded,
+		FilesChanged:  d.filesChanged + d.filesCopied,
+		FilesDeleted:  d.filesDeleted,
+		FilesSkipped:  d.filesSkipped,
+		FilesResumed:  d.filesResumed,
+		Unchanged:     d.unchanged,
+		BytesUploaded: d.bytesUploaded,
+		Status:        StatusSucceeded,
+		Timestamp:     time.Now().UTC(),
+	}
+
+	if !d.startedAt.IsZero() {
+		summary.DurationMs = time.Since(d.startedAt).Milliseconds()
+	}
+
+	if err != nil {
+		summary.Status = StatusFailed
+		summary.Error = err.Error()
+	}
+
+	return summary
+}
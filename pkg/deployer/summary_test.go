@@ -0,0 +1,50 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestDeploymentSummary_status(t *testing.T) {
+	deployment := &Deployment{ID: "abc", SourceBucket: "source", TargetBucket: "target"}
+	files := DeployedFiles{"index.html": "hash", "app.js": "hash"}
+
+	succeeded := deployment.Summary("build.zip", "v1", files, nil)
+	if succeeded.Status != StatusSucceeded || succeeded.Error != "" {
+		t.Errorf("expected a succeeded summary without error, got %+v", succeeded)
+	}
+	if succeeded.Source != "source/build.zip" || succeeded.FilesDeployed != 2 || succeeded.DeploymentID != "abc" {
+		t.Errorf("unexpected summary contents: %+v", succeeded)
+	}
+
+	failed := deployment.Summary("build.zip", "v1", nil, errors.New("boom"))
+	if failed.Status != StatusFailed || failed.Error != "boom" {
+		t.Errorf("expected a failed summary with error, got %+v", failed)
+	}
+}
+
+func TestDeploymentSummary_counts(t *testing.T) {
+	client := s3fake.New()
+	d, key := newTestDeployment(t, client, map[string]string{"index.html": "v1", "app.js": "app", "old.html": "old"})
+	files, err := d.Deploy(context.Background(), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := d.Summary("site.zip", "v1", files, nil)
+	if first.FilesAdded != 3 || first.FilesChanged != 0 || first.FilesSkipped != 0 || first.FilesDeleted != 0 || first.BytesUploaded != 8 {
+		t.Errorf("expected every file to be added, got %+v", first)
+	}
+
+	d, key = newTestDeployment(t, client, map[string]string{"index.html": "v2", "app.js": "app", "new.html": "new"})
+	d.DeleteRemovedFiles = true
+	files, err = d.Deploy(context.Background(), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := d.Summary("site.zip", "v2", files, nil)
+	if second.FilesAdded != 1 || second.FilesChanged != 1 || second.FilesSkipped != 1 || second.FilesDeleted != 1 || second.BytesUploaded != 5 {
+		t.Errorf("expected one file of each kind, with only the added and changed files uploaded, got %+v", second)
+	}
+}
@@ -0,0 +1,160 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strconv"
+	"time"
+)
+
+const (
+	// DeploymentIDTag is the tag holding the ID of the last deployment that included an object, when a deployment
+	// has TagObjects set.
+	DeploymentIDTag = "sfd-deployment"
+	// DeploymentSequenceTag is the tag holding the sequence number of the last deployment that included an object.
+	DeploymentSequenceTag = "sfd-deployment-seq"
+	// DeploymentSequenceKey is the key the sequence number of the last tagged deployment is saved to, under the
+	// TargetPrefix of the deployment.
+	DeploymentSequenceKey = ".staticfiledeploy-sequence.json"
+)
+
+// errTaggingNotSupported is returned when a deployment tags objects in a target that does not support tags.
+var errTaggingNotSupported = errors.New("tagging objects is only supported by S3 targets")
+
+// objectTagger is implemented by target stores that can read and replace the tags of objects.
+type objectTagger interface {
+	// Tags returns the tags of the object with the given key.
+	Tags(ctx context.Context, key string) (map[string]string, error)
+	// SetTags replaces the tags of the object with the given key.
+	SetTags(ctx context.Context, key string, tags map[string]string) error
+}
+
+// deploymentSequenceContent is the sequence number of the last tagged deployment to a target.
+type deploymentSequenceContent struct {
+	Sequence     int       `json:"sequence"`
+	DeploymentID string    `json:"deployment_id"`
+	DeployedAt   time.Time `json:"deployed_at"`
+}
+
+// tagsObjects returns whether the deployment tags the objects it deploys.
+func (d *Deployment) tagsObjects() bool {
+	return d.TagObjects || d.KeepDeployments > 0
+}
+
+// deploymentSequenceKey returns the key the sequence number of the last tagged deployment is saved to.
+func (d *Deployment) deploymentSequenceKey() string {
+	return d.TargetPrefix + DeploymentSequenceKey
+}
+
+// deploymentTags returns the tags of the objects deployed by this deployment, which are its Tags and, if it tags
+// objects, the tags identifying the deployment, or nil if there are none.
+func (d *Deployment) deploymentTags() map[string]string {
+	if !d.tagsObjects() && len(d.Tags) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(d.Tags)+2)
+	for key, value := range d.Tags {
+		tags[key] = value
+	}
+	if d.tagsObjects() {
+		tags[DeploymentIDTag] = d.ID
+		tags[DeploymentSequenceTag] = strconv.Itoa(d.sequence)
+	}
+	return tags
+}
+
+// startTagging numbers the deployment one higher than the last tagged deployment to the target.
+func (d *Deployment) startTagging(ctx context.Context) error {
+	body, err := d.target.Get(ctx, d.deploymentSequenceKey())
+	if err != nil {
+		return err
+	}
+
+	var last deploymentSequenceContent
+	if body != nil {
+		defer body.Close()
+		err = json.NewDecoder(body).Decode(&last)
+		if err != nil {
+			return fmt.Errorf("failed to decode the deployment sequence in %s: %w", d.deploymentSequenceKey(), err)
+		}
+	}
+	d.sequence = last.Sequence + 1
+
+	return nil
+}
+
+// saveDeploymentSequence saves the sequence number of the deployment once all of its objects have been tagged.
+func (d *Deployment) saveDeploymentSequence(ctx context.Context) error {
+	content, err := json.Marshal(deploymentSequenceContent{
+		Sequence:     d.sequence,
+		DeploymentID: d.ID,
+		DeployedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode the deployment sequence: %w", err)
+	}
+
+	err = d.recordWrite(ctx, d.deploymentSequenceKey())
+	if err != nil {
+		return err
+	}
+	return d.target.Put(ctx, PutInput{
+		Key:  d.deploymentSequenceKey(),
+		Body: bytes.NewReader(content),
+		Size: int64(len(content)),
+		Metadata: ObjectMetadata{
+			ContentType:  "application/json",
+			CacheControl: "no-store",
+		},
+	})
+}
+
+// tagObject tags an object that was part of an earlier deployment as part of this one, without uploading it again.
+func (d *Deployment) tagObject(ctx context.Context, key string) error {
+	tagger, ok := d.target.(objectTagger)
+	if !ok {
+		return errTaggingNotSupported
+	}
+	return tagger.SetTags(ctx, key, d.deploymentTags())
+}
+
+// pruneOldDeployments deletes the objects that are not part of this deployment, and were last part of a deployment
+// more than KeepDeployments deployments ago. Objects without a sequence tag, such as those uploaded by other tools,
+// are kept.
+func (d *Deployment) pruneOldDeployments(ctx context.Context, existingFiles DeployedFiles, deployedKeys map[string]bool) error {
+	tagger, ok := d.target.(objectTagger)
+	if !ok {
+		return errTaggingNotSupported
+	}
+
+	var pruned []string
+	for _, key := range removedKeys(existingFiles, deployedKeys, d.KeepFiles) {
+		tags, err := tagger.Tags(ctx, key)
+		if err != nil {
+			return err
+		}
+		sequence, err := strconv.Atoi(tags[DeploymentSequenceTag])
+		if err != nil || sequence > d.sequence-d.KeepDeployments {
+			continue
+		}
+		pruned = append(pruned, key)
+	}
+
+	err := d.deleteKeys(ctx, pruned)
+	if err != nil {
+		return err
+	}
+	d.filesDeleted += len(pruned)
+	d.deletedKeys = append(d.deletedKeys, pruned...)
+
+	tflog.Info(ctx, "Pruned files of old deployments", map[string]interface{}{
+		"files":            len(pruned),
+		"keep_deployments": d.KeepDeployments,
+	})
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestDeploy_keepDeployments(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{"upload.txt": "user upload"})
+
+	deploy := func(files map[string]string) {
+		t.Helper()
+		d, source := newTestDeployment(t, client, files)
+		d.KeepDeployments = 2
+		if _, err := d.Deploy(context.Background(), source, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deploy(map[string]string{"index.html": "index", "app-1.js": "app"})
+	deploy(map[string]string{"index.html": "index", "app-2.js": "app"})
+	if client.Object("target", "app-1.js") == nil {
+		t.Fatal("expected the file removed by the last deployment to be kept")
+	}
+	if tags := client.Object("target", "index.html").Tags; tags[DeploymentSequenceTag] != "2" {
+		t.Errorf("expected the unchanged file to be tagged with the last deployment, got %v", tags)
+	}
+
+	deploy(map[string]string{"index.html": "index", "app-3.js": "app"})
+	if client.Object("target", "app-1.js") != nil {
+		t.Error("expected the file removed two deployments ago to be pruned")
+	}
+	for _, key := range []string{"index.html", "app-2.js", "app-3.js", "upload.txt"} {
+		if client.Object("target", key) == nil {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestDeploy_tagObjectsNotSupported(t *testing.T) {
+	d := &Deployment{TagObjects: true, target: &gcsStore{name: "site"}}
+	_, err := d.Deploy(context.Background(), "file:///site.zip", nil)
+	if err != errTaggingNotSupported {
+		t.Errorf("expected tagging to be rejected, got %v", err)
+	}
+}
+
+func TestDeploymentTags(t *testing.T) {
+	d := &Deployment{}
+	if tags := d.deploymentTags(); tags != nil {
+		t.Errorf("expected no tags, got %v", tags)
+	}
+
+	d = &Deployment{ID: "deployment", Tags: map[string]string{"team": "web"}}
+	if tags := d.deploymentTags(); len(tags) != 1 || tags["team"] != "web" {
+		t.Errorf("expected only the tags of the deployment, got %v", tags)
+	}
+
+	d.TagObjects = true
+	d.sequence = 3
+	if tags := d.deploymentTags(); len(tags) != 3 || tags["team"] != "web" || tags[DeploymentIDTag] != "deployment" || tags[DeploymentSequenceTag] != "3" {
+		t.Errorf("expected the tags of the deployment and the deployment tags, got %v", tags)
+	}
+}
@@ -0,0 +1,64 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// errObjectLockNotSupported is returned when Object Lock is requested for a target that is not an S3 bucket.
+var errObjectLockNotSupported = errors.New("Object Lock is only supported for S3 targets")
+
+// TargetStore is the storage a deployment publishes the files of an artifact to, e.g. an S3 bucket or an Azure container.
+// Errors are returned as ObjectError, or ConflictError when a conditional write fails.
+type TargetStore interface {
+	// Name is the name of the bucket, used in logs, errors and deployment summaries.
+	Name() string
+	// List returns the hex encoded MD5 hashes of all objects whose keys start with the given prefix.
+	// Objects without an MD5 hash, such as multipart uploads, are listed with a hash that never matches a file.
+	List(ctx context.Context, prefix string) (DeployedFiles, error)
+	// Head returns the size and metadata of an object, or nil if it does not exist.
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	// Get returns the content of an object, which must be closed by the caller, or nil if it does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Put uploads an object.
+	Put(ctx context.Context, input PutInput) error
+	// Copy copies an object with its metadata to another key in the same store.
+	Copy(ctx context.Context, sourceKey string, destinationKey string) error
+	// UpdateMetadata replaces the metadata of an existing object without uploading its content again.
+	UpdateMetadata(ctx context.Context, key string, metadata ObjectMetadata, lock *ObjectLock) error
+	// Delete deletes the objects with the given keys. At most maxDeleteObjects keys are given at a time.
+	Delete(ctx context.Context, keys []string) error
+}
+
+// ObjectInfo is the state of an object in a target store.
+type ObjectInfo struct {
+	Size     int64
+	Metadata ObjectMetadata
+	// VersionID is the ID of the current version of the object, if the store keeps versions of objects.
+	VersionID string
+}
+
+// PutInput describes an object to upload to a target store.
+type PutInput struct {
+	Key  string
+	Body io.Reader
+	Size int64
+	// MD5 is the hex encoded MD5 hash of the body. If set, stores check the upload against it, S3 by sending it as
+	// the Content-MD5 header of uploads that are not split into parts.
+	MD5 string
+	// SHA256 is the hex encoded SHA-256 hash of the body. If MD5 is not set, S3 targets that check uploads with
+	// SHA-256 send it as the checksum of uploads that are not split into parts.
+	SHA256   string
+	Metadata ObjectMetadata
+	// IfMatch, if set, is the hash the object must have for the upload to succeed.
+	IfMatch string
+	// IfNoneMatch makes the upload fail if the object already exists.
+	IfNoneMatch bool
+	// ObjectLock, if set, is applied to the object. It is only supported by S3.
+	ObjectLock *ObjectLock
+	// Multipart, if set, configures when and how the object is uploaded to S3 in parts.
+	Multipart *MultipartUpload
+	// Tags, if set, are the tags of the object. They are only supported by S3.
+	Tags map[string]string
+}
@@ -0,0 +1,117 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Template renders artifact files whose name matches Pattern, replacing "${NAME}" placeholders with the value of
+// NAME in Vars, so that one artifact can be deployed to several environments with their own configuration.
+// Placeholders of variables that are not in Vars are left as they are, e.g. template literals of JavaScript files.
+type Template struct {
+	// Pattern is a glob matched against the names of the artifact files, after SourceRoot and PathRewrites are
+	// applied, where "*" matches any sequence of characters including "/".
+	Pattern string
+	Vars    map[string]string
+}
+
+// templatePlaceholder matches "${NAME}" placeholders, capturing the name of the variable.
+var templatePlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// render returns the content with the placeholders of the variables of the template replaced.
+func (t Template) render(content []byte) []byte {
+	return templatePlaceholder.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		value, ok := t.Vars[string(placeholder[2:len(placeholder)-1])]
+		if !ok {
+			return placeholder
+		}
+		return []byte(value)
+	})
+}
+
+// renderTemplates replaces the artifact files matching a template with their rendered content. The first matching
+// template applies. The rendered files are then deployed, compared and kept like any other file.
+func (d *Deployment) renderTemplates(artifactZip *zip.Reader) error {
+	patterns := make([]*regexp.Regexp, len(d.Templates))
+	for i, template := range d.Templates {
+		patterns[i] = globRegexp(template.Pattern)
+	}
+
+	var names []string
+	rendered := make(map[string][]byte)
+	for _, file := range artifactZip.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		for i, pattern := range patterns {
+			if !pattern.MatchString(file.Name) {
+				continue
+			}
+			content, err := readEntry(file)
+			if err != nil {
+				return fmt.Errorf("failed to render template %s: %w", file.Name, err)
+			}
+			names = append(names, file.Name)
+			rendered[file.Name] = d.Templates[i].render(content)
+			break
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	entries, err := memoryEntries(names, rendered)
+	if err != nil {
+		return fmt.Errorf("failed to render templates: %w", err)
+	}
+	byName := make(map[string]*zip.File, len(entries))
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+	for i, file := range artifactZip.File {
+		if entry, ok := byName[file.Name]; ok {
+			artifactZip.File[i] = entry
+		}
+	}
+	return nil
+}
+
+// readEntry returns the uncompressed content of an artifact entry.
+func readEntry(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// memoryEntries returns ZIP entries with the given names and contents, backed by an in-memory archive, so that they
+// can take the place of artifact entries.
+func memoryEntries(names []string, contents map[string][]byte) ([]*zip.File, error) {
+	buf := new(bytes.Buffer)
+	writer := zip.NewWriter(buf)
+	for _, name := range names {
+		entry, err := writer.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		_, err = entry.Write(contents[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	return reader.File, nil
+}
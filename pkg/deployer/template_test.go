@@ -0,0 +1,60 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+func TestTemplate_render(t *testing.T) {
+	template := Template{Vars: map[string]string{"API_URL": "https://api.example.com", "ENV": "prod"}}
+	content := `{"api": "${API_URL}", "env": "${ENV}", "other": "${OTHER}", "literal": "$ENV"}`
+	want := `{"api": "https://api.example.com", "env": "prod", "other": "${OTHER}", "literal": "$ENV"}`
+	if got := string(template.render([]byte(content))); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestDeploy_templates(t *testing.T) {
+	client := s3fake.New()
+	deploy := func(apiURL string) (*Deployment, error) {
+		d, source := newTestDeployment(t, client, map[string]string{
+			"index.html":        "<p>${API_URL}</p>",
+			"config/app.json":   `{"api":"${API_URL}"}`,
+			"config/flags.json": `{"env":"${ENV}"}`,
+			"config/plain.txt":  "${API_URL}",
+		})
+		d.Templates = []Template{
+			{Pattern: "config/app.json", Vars: map[string]string{"API_URL": apiURL}},
+			{Pattern: "config/*.json", Vars: map[string]string{"API_URL": "unused", "ENV": "test"}},
+		}
+		_, err := d.Deploy(context.Background(), source, nil)
+		return d, err
+	}
+
+	if _, err := deploy("https://test.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"index.html":        "<p>${API_URL}</p>",
+		"config/app.json":   `{"api":"https://test.example.com"}`,
+		"config/flags.json": `{"env":"test"}`,
+		"config/plain.txt":  "${API_URL}",
+	}
+	if objects := testObjects(client, "target"); !reflect.DeepEqual(objects, want) {
+		t.Errorf("expected %q to be deployed, got %q", want, objects)
+	}
+
+	// The same artifact deployed with other variables only changes the rendered files.
+	again, err := deploy("https://prod.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(client.Object("target", "config/app.json").Body); got != `{"api":"https://prod.example.com"}` {
+		t.Errorf("expected the template to be rendered with the new variables, got %q", got)
+	}
+	if again.filesChanged != 1 || again.filesSkipped != 3 {
+		t.Errorf("expected only the rendered file to change, got %d changed and %d skipped", again.filesChanged, again.filesSkipped)
+	}
+}
@@ -0,0 +1,30 @@
+package deployer
+
+import (
+	"regexp"
+)
+
+// unmanagedPatterns returns the compiled UnmanagedPaths.
+func (d *Deployment) unmanagedPatterns() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(d.UnmanagedPaths))
+	for i, pattern := range d.UnmanagedPaths {
+		patterns[i] = globRegexp(pattern)
+	}
+	return patterns
+}
+
+// withoutUnmanaged returns the files whose keys do not match one of the UnmanagedPaths.
+func (d *Deployment) withoutUnmanaged(files DeployedFiles) DeployedFiles {
+	if len(d.UnmanagedPaths) == 0 {
+		return files
+	}
+
+	unmanaged := d.unmanagedPatterns()
+	managed := make(DeployedFiles, len(files))
+	for key, hash := range files {
+		if !matchesAny(unmanaged, key) {
+			managed[key] = hash
+		}
+	}
+	return managed
+}
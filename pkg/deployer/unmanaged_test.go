@@ -0,0 +1,40 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"reflect"
+	"testing"
+)
+
+func TestHashesForDeployedFiles_ignoresUnmanagedPaths(t *testing.T) {
+	client := s3fake.New()
+	putTestObjects(t, client, "target", map[string]string{
+		"index.html":          "hello",
+		"uploads/avatar.png":  "avatar",
+		"logs/2024-01-01.log": "log",
+	})
+	d, _ := newTestDeployment(t, client, nil)
+	d.UnmanagedPaths = []string{"uploads/*", "logs/*"}
+
+	files, err := d.HashesForDeployedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := files["index.html"]; !found || len(files) != 1 {
+		t.Errorf("expected only the managed file, got %v", files)
+	}
+}
+
+func TestRewriteEntryNames_skipsUnmanagedPaths(t *testing.T) {
+	artifactZip := newTestZip(t, "index.html", "uploads/placeholder.png")
+	d := &Deployment{UnmanagedPaths: []string{"uploads/*"}}
+
+	d.rewriteEntryNames(artifactZip)
+
+	expected := []string{"index.html"}
+	if got := entryNames(artifactZip); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
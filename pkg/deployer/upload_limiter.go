@@ -0,0 +1,39 @@
+package deployer
+
+import (
+	"context"
+)
+
+// UploadLimiter limits how many files are uploaded at the same time by all deployments sharing it, e.g. every
+// deployment of a Terraform workspace applied in parallel, so that they do not overwhelm S3 or the local network.
+// A nil UploadLimiter does not limit uploads.
+type UploadLimiter struct {
+	slots chan struct{}
+}
+
+// NewUploadLimiter returns a limiter allowing at most maxConcurrentUploads uploads at the same time.
+func NewUploadLimiter(maxConcurrentUploads int) *UploadLimiter {
+	return &UploadLimiter{slots: make(chan struct{}, maxConcurrentUploads)}
+}
+
+// acquire waits until an upload may start, or returns the error of the context if it is done first. Every
+// successful acquire must be followed by a release.
+func (l *UploadLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release lets another upload start.
+func (l *UploadLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
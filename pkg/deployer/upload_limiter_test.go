@@ -0,0 +1,93 @@
+package deployer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrentUploads records the most uploads in progress at the same time across the stores sharing it.
+type concurrentUploads struct {
+	active atomic.Int32
+	max    atomic.Int32
+}
+
+// slowS3API is an s3fake.Client whose uploads take a while, recording them in uploads.
+type slowS3API struct {
+	*s3fake.Client
+	uploads *concurrentUploads
+}
+
+func (c *slowS3API) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	active := c.uploads.active.Add(1)
+	defer c.uploads.active.Add(-1)
+	for {
+		max := c.uploads.max.Load()
+		if active <= max || c.uploads.max.CompareAndSwap(max, active) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return c.Client.PutObject(ctx, params, optFns...)
+}
+
+func TestUploadLimiter_sharedByDeployments(t *testing.T) {
+	content := newTestArtifact(t, map[string]string{"index.html": "hello", "app.js": "app", "style.css": "style"})
+	limiter := NewUploadLimiter(2)
+	uploads := &concurrentUploads{}
+
+	deployments := make([]*Deployment, 4)
+	for i := range deployments {
+		deployments[i], _ = newTestDeployment(t, &slowS3API{Client: s3fake.New(), uploads: uploads}, nil)
+		deployments[i].uploadLimiter = limiter
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(deployments))
+	for i, d := range deployments {
+		wg.Add(1)
+		go func(i int, d *Deployment) {
+			defer wg.Done()
+			reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = d.uploadDeploymentArtifactFiles(context.Background(), reader, DeployedFiles{}, DeployedFiles{})
+		}(i, d)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if max := uploads.max.Load(); max != 2 {
+		t.Errorf("expected the deployments to upload up to 2 files at the same time, got %d", max)
+	}
+}
+
+func TestUploadLimiter_stopsWhenCancelled(t *testing.T) {
+	limiter := NewUploadLimiter(1)
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.acquire(ctx); err == nil {
+		t.Error("expected a cancelled upload to fail while waiting")
+	}
+
+	limiter.release()
+	if err := limiter.acquire(context.Background()); err != nil {
+		t.Errorf("expected an upload to start once another one is done, got %v", err)
+	}
+}
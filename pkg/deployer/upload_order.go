@@ -0,0 +1,38 @@
+package deployer
+
+import (
+	"archive/zip"
+	"regexp"
+	"sort"
+)
+
+// DefaultUploadOrder uploads HTML entry points after all other files, so that users are never served a new page
+// referring to assets that have not been uploaded yet.
+var DefaultUploadOrder = []string{"*.html", "*.htm"}
+
+// orderedFiles returns the files of the artifact in the order they are uploaded in. Files matching one of the
+// UploadOrder glob patterns are uploaded after all other files, grouped by the first pattern they match in the
+// order of the patterns. Files keep their order in the artifact within each group.
+func (d *Deployment) orderedFiles(artifactZip *zip.Reader) []*zip.File {
+	patterns := make([]*regexp.Regexp, len(d.UploadOrder))
+	for i, pattern := range d.UploadOrder {
+		patterns[i] = globRegexp(pattern)
+	}
+
+	groups := make(map[*zip.File]int, len(artifactZip.File))
+	for _, file := range artifactZip.File {
+		key := d.objectKey(file.Name)
+		for i, pattern := range patterns {
+			if pattern.MatchString(key) {
+				groups[file] = i + 1
+				break
+			}
+		}
+	}
+
+	files := append([]*zip.File(nil), artifactZip.File...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return groups[files[i]] < groups[files[j]]
+	})
+	return files
+}
@@ -0,0 +1,40 @@
+package deployer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedFiles(t *testing.T) {
+	artifactZip := newTestZip(t, "index.html", "assets/app.js", "about/index.html", "sw.js", "assets/app.css")
+
+	tests := []struct {
+		uploadOrder []string
+		expected    []string
+	}{
+		{
+			uploadOrder: DefaultUploadOrder,
+			expected:    []string{"assets/app.js", "sw.js", "assets/app.css", "index.html", "about/index.html"},
+		},
+		{
+			uploadOrder: []string{"*.html", "sw.js"},
+			expected:    []string{"assets/app.js", "assets/app.css", "index.html", "about/index.html", "sw.js"},
+		},
+		{
+			uploadOrder: nil,
+			expected:    []string{"index.html", "assets/app.js", "about/index.html", "sw.js", "assets/app.css"},
+		},
+	}
+	for _, test := range tests {
+		d := &Deployment{UploadOrder: test.uploadOrder}
+
+		var names []string
+		for _, file := range d.orderedFiles(artifactZip) {
+			names = append(names, file.Name)
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%v: got %v, want %v", test.uploadOrder, names, test.expected)
+		}
+	}
+}
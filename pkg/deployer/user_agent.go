@@ -0,0 +1,40 @@
+package deployer
+
+import (
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/middleware"
+	"strings"
+)
+
+// UserAgent identifies who sends requests to AWS, so that S3 request costs and CloudTrail activity can be attributed
+// to them.
+type UserAgent struct {
+	// AppID is the application ID the AWS SDK adds to the User-Agent header as `app/<id>`. Empty means the ID
+	// configured in the environment or shared config file, if any.
+	AppID string
+	// Products are appended to the User-Agent header, each either a name or a `name/version` pair.
+	Products []string
+}
+
+// ConfigOptions returns the options that make an AWS configuration loaded with config.LoadDefaultConfig send the user
+// agent.
+func (u UserAgent) ConfigOptions() []func(*config.LoadOptions) error {
+	var options []func(*config.LoadOptions) error
+	if u.AppID != "" {
+		options = append(options, config.WithAppID(u.AppID))
+	}
+
+	var apiOptions []func(*middleware.Stack) error
+	for _, product := range u.Products {
+		if name, version, found := strings.Cut(product, "/"); found {
+			apiOptions = append(apiOptions, awsmiddleware.AddUserAgentKeyValue(name, version))
+		} else {
+			apiOptions = append(apiOptions, awsmiddleware.AddUserAgentKey(product))
+		}
+	}
+	if len(apiOptions) > 0 {
+		options = append(options, config.WithAPIOptions(apiOptions))
+	}
+	return options
+}
@@ -0,0 +1,43 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserAgent_addedToRequests(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	options := UserAgent{AppID: "platform-team", Products: []string{"terraform-provider-staticfiledeploy/1.2.3", "ci"}}.ConfigOptions()
+	options = append(options,
+		config.WithRegion("eu-west-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	)
+	cfg, err := config.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deployer{DefaultAWSConfig: cfg, S3Endpoint: server.URL}
+	_, err = d.s3Client("").HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("www"), Key: aws.String("index.html")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"app/platform-team", "terraform-provider-staticfiledeploy/1.2.3", " ci"} {
+		if !strings.Contains(userAgent, want) {
+			t.Errorf("expected the User-Agent header to contain %q, got %q", want, userAgent)
+		}
+	}
+}
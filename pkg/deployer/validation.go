@@ -0,0 +1,113 @@
+package deployer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"strings"
+)
+
+// ValidationType is how the content of files matching a ValidationRule is validated.
+type ValidationType string
+
+const (
+	// ValidationTypeJSON requires files to be a valid JSON document.
+	ValidationTypeJSON ValidationType = "json"
+	// ValidationTypeYAML requires files to be valid YAML.
+	ValidationTypeYAML ValidationType = "yaml"
+	// ValidationTypeRegex requires the content of files to match the Expression of the rule.
+	ValidationTypeRegex ValidationType = "regex"
+)
+
+// ValidationTypes are the supported validation types.
+var ValidationTypes = []ValidationType{ValidationTypeJSON, ValidationTypeYAML, ValidationTypeRegex}
+
+// ValidationRule validates the content of artifact files whose name matches Pattern before any file is deployed,
+// so that malformed configuration fails the deployment rather than breaking the site.
+type ValidationRule struct {
+	// Pattern is a glob matched against the names of the artifact files, after SourceRoot, PathRewrites and
+	// Templates are applied, where "*" matches any sequence of characters including "/".
+	Pattern string
+	Type    ValidationType
+	// Expression is the regular expression the content must match, for ValidationTypeRegex.
+	Expression *regexp.Regexp
+}
+
+// validate returns an error if the content is not valid according to the rule.
+func (r ValidationRule) validate(content []byte) error {
+	switch r.Type {
+	case ValidationTypeJSON:
+		if !json.Valid(content) {
+			var value interface{}
+			return fmt.Errorf("invalid JSON: %w", json.Unmarshal(content, &value))
+		}
+	case ValidationTypeYAML:
+		var value interface{}
+		if err := yaml.Unmarshal(content, &value); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+	case ValidationTypeRegex:
+		if !r.Expression.Match(content) {
+			return fmt.Errorf("content does not match %s", r.Expression)
+		}
+	default:
+		return fmt.Errorf("unsupported validation type %q", r.Type)
+	}
+	return nil
+}
+
+// validateFiles validates the artifact files against every matching validation rule, returning the errors of all
+// invalid files.
+func (d *Deployment) validateFiles(artifactZip *zip.Reader) error {
+	patterns := make([]*regexp.Regexp, len(d.ValidationRules))
+	for i, rule := range d.ValidationRules {
+		patterns[i] = globRegexp(rule.Pattern)
+	}
+
+	var errs []error
+	for _, file := range artifactZip.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		var content []byte
+		for i, pattern := range patterns {
+			if !pattern.MatchString(file.Name) {
+				continue
+			}
+			if content == nil {
+				var err error
+				content, err = readEntry(file)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", file.Name, err)
+				}
+			}
+			if err := d.ValidationRules[i].validate(content); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", file.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkRequiredFiles returns an error listing the required files the artifact does not have.
+func (d *Deployment) checkRequiredFiles(artifactZip *zip.Reader) error {
+	names := make(map[string]bool, len(artifactZip.File))
+	for _, file := range artifactZip.File {
+		if !file.FileInfo().IsDir() {
+			names[file.Name] = true
+		}
+	}
+	var missing []string
+	for _, name := range d.RequiredFiles {
+		if !names[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required files: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
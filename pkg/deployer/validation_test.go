@@ -0,0 +1,71 @@
+package deployer
+
+import (
+	"context"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidationRule_validate(t *testing.T) {
+	tests := []struct {
+		rule    ValidationRule
+		content string
+		valid   bool
+	}{
+		{ValidationRule{Type: ValidationTypeJSON}, `{"api": "https://api.example.com"}`, true},
+		{ValidationRule{Type: ValidationTypeJSON}, `{"api": }`, false},
+		{ValidationRule{Type: ValidationTypeYAML}, "api: https://api.example.com\nflags: [a, b]\n", true},
+		{ValidationRule{Type: ValidationTypeYAML}, "api: [unclosed\n", false},
+		{ValidationRule{Type: ValidationTypeRegex, Expression: regexp.MustCompile(`<title>.+</title>`)}, "<title>App</title>", true},
+		{ValidationRule{Type: ValidationTypeRegex, Expression: regexp.MustCompile(`<title>.+</title>`)}, "<title></title>", false},
+	}
+	for _, test := range tests {
+		err := test.rule.validate([]byte(test.content))
+		if test.valid && err != nil {
+			t.Errorf("expected %q to be valid %s, got %v", test.content, test.rule.Type, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected %q to be invalid %s", test.content, test.rule.Type)
+		}
+	}
+}
+
+func TestDeploy_validationRules(t *testing.T) {
+	client := s3fake.New()
+	deployment, source := newTestDeployment(t, client, map[string]string{
+		"index.html":        "<p>app</p>",
+		"config/app.json":   `{"api": "https://api.example.com"}`,
+		"config/flags.json": `{"beta": tru}`,
+	})
+	deployment.ValidationRules = []ValidationRule{{Pattern: "config/*.json", Type: ValidationTypeJSON}}
+
+	_, err := deployment.Deploy(context.Background(), source, nil)
+	if err == nil || !strings.Contains(err.Error(), "config/flags.json") || strings.Contains(err.Error(), "config/app.json") {
+		t.Errorf("expected only config/flags.json to be invalid, got %v", err)
+	}
+	if keys := client.Keys("target"); len(keys) != 0 {
+		t.Errorf("expected no files to be deployed, got %v", keys)
+	}
+}
+
+func TestDeploy_requiredFiles(t *testing.T) {
+	client := s3fake.New()
+	deployment, source := newTestDeployment(t, client, map[string]string{"build/index.html": "<p>app</p>"})
+	deployment.SourceRoot = "build"
+	deployment.RequiredFiles = []string{"index.html", "assets/manifest.json"}
+
+	_, err := deployment.Deploy(context.Background(), source, nil)
+	if err == nil || !strings.HasSuffix(err.Error(), "missing required files: assets/manifest.json") {
+		t.Errorf("expected assets/manifest.json to be reported as missing, got %v", err)
+	}
+	if keys := client.Keys("target"); len(keys) != 0 {
+		t.Errorf("expected no files to be deployed, got %v", keys)
+	}
+
+	deployment.RequiredFiles = []string{"index.html"}
+	if _, err := deployment.Deploy(context.Background(), source, nil); err != nil {
+		t.Errorf("expected an artifact with the required files to be deployed, got %v", err)
+	}
+}
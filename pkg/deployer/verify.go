@@ -0,0 +1,108 @@
+package deployer
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"strings"
+)
+
+// maxReportedMismatches limits how many mismatches are listed in a VerificationError message.
+const maxReportedMismatches = 20
+
+// ObjectMismatch describes a deployed object that does not match the artifact.
+type ObjectMismatch struct {
+	Key    string
+	Reason string
+}
+
+// VerificationError is returned when one or more deployed objects are missing or differ from the artifact.
+type VerificationError struct {
+	Mismatches []ObjectMismatch
+}
+
+func (e *VerificationError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d deployed object(s) failed verification:", len(e.Mismatches))
+
+	for i, mismatch := range e.Mismatches {
+		if i == maxReportedMismatches {
+			fmt.Fprintf(&sb, "\n  ... and %d more", len(e.Mismatches)-maxReportedMismatches)
+			break
+		}
+		fmt.Fprintf(&sb, "\n  - %s: %s", mismatch.Key, mismatch.Reason)
+	}
+
+	return sb.String()
+}
+
+// Verify checks that every file in the artifact with the given key exists in the target with the expected size and
+// content type, as VerifyAfterDeploy does after a deployment, and returns a VerificationError if one does not. If
+// version is nil, the latest version of the artifact is used.
+func (d *Deployment) Verify(ctx context.Context, key string, version *string) error {
+	downloaded, err := d.getDeploymentArtifact(ctx, key, version)
+	if err != nil {
+		return err
+	}
+	defer downloaded.Close()
+
+	return d.verifyDeployedFiles(ctx, downloaded.Reader)
+}
+
+// verifyDeployedFiles checks that every file in the artifact exists in the target
+// with the expected size and content type.
+func (d *Deployment) verifyDeployedFiles(ctx context.Context, artifactZip *zip.Reader) error {
+	var mismatches []ObjectMismatch
+
+	for _, file := range artifactZip.File {
+		key := d.objectKey(file.Name)
+		mismatch, err := d.verifyObject(ctx, d.target, key, key, file)
+		if err != nil {
+			return err
+		}
+		if mismatch != nil {
+			mismatches = append(mismatches, *mismatch)
+		}
+	}
+
+	tflog.Info(ctx, "Verified deployed files", map[string]interface{}{
+		"files":      len(artifactZip.File),
+		"mismatches": len(mismatches),
+	})
+
+	if len(mismatches) > 0 {
+		return &VerificationError{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// verifyObject checks that the object with the given key in the store has the size of the artifact file, and the
+// content type of the file deployed to deployedKey. It returns the mismatch if it does not.
+func (d *Deployment) verifyObject(ctx context.Context, store TargetStore, key string, deployedKey string, file *zip.File) (*ObjectMismatch, error) {
+	result, err := store.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return &ObjectMismatch{Key: key, Reason: "object is missing"}, nil
+	}
+
+	if result.Size != int64(file.UncompressedSize64) {
+		return &ObjectMismatch{
+			Key:    key,
+			Reason: fmt.Sprintf("expected size %d, got %d", file.UncompressedSize64, result.Size),
+		}, nil
+	}
+
+	expectedContentType := d.metadataForKey(deployedKey).ContentType
+	if expectedContentType != "" && result.Metadata.ContentType != expectedContentType {
+		return &ObjectMismatch{
+			Key:    key,
+			Reason: fmt.Sprintf("expected content type %q, got %q", expectedContentType, result.Metadata.ContentType),
+		}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,65 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"strings"
+	"testing"
+)
+
+func TestVerificationError_listsMismatches(t *testing.T) {
+	err := &VerificationError{Mismatches: []ObjectMismatch{
+		{Key: "index.html", Reason: "object is missing"},
+		{Key: "app.js", Reason: "expected size 10, got 12"},
+	}}
+
+	message := err.Error()
+	if !strings.Contains(message, "2 deployed object(s) failed verification") {
+		t.Errorf("expected summary line, got %q", message)
+	}
+	if !strings.Contains(message, "index.html: object is missing") || !strings.Contains(message, "app.js: expected size 10, got 12") {
+		t.Errorf("expected all mismatches to be listed, got %q", message)
+	}
+}
+
+func TestVerificationError_truncatesLongLists(t *testing.T) {
+	var mismatches []ObjectMismatch
+	for i := 0; i < maxReportedMismatches+5; i++ {
+		mismatches = append(mismatches, ObjectMismatch{Key: fmt.Sprintf("file%d.txt", i), Reason: "object is missing"})
+	}
+
+	message := (&VerificationError{Mismatches: mismatches}).Error()
+	if !strings.Contains(message, "... and 5 more") {
+		t.Errorf("expected truncation notice, got %q", message)
+	}
+	if strings.Contains(message, fmt.Sprintf("file%d.txt", maxReportedMismatches)) {
+		t.Errorf("expected mismatches beyond the limit to be omitted, got %q", message)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	client := s3fake.New()
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String("target"),
+		Key:         aws.String("index.html"),
+		Body:        strings.NewReader("index"),
+		ContentType: aws.String("text/html; charset=utf-8"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "index", "app.js": "app"})
+
+	err = d.Verify(context.Background(), source, nil)
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("expected a VerificationError, got %v", err)
+	}
+	if len(verificationErr.Mismatches) != 1 || verificationErr.Mismatches[0].Key != "app.js" {
+		t.Errorf("expected only app.js to be missing, got %v", verificationErr.Mismatches)
+	}
+}
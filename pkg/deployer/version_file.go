@@ -0,0 +1,76 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultVersionFileKey is the key the version file is written to unless another one is configured.
+const DefaultVersionFileKey = "version.json"
+
+// VersionFile configures a JSON file describing the deployed version, written to the target after the artifact.
+type VersionFile struct {
+	Key           string
+	SourceVersion string
+	// Metadata holds additional values to include in the file, e.g. git commit and branch.
+	Metadata map[string]string
+}
+
+type versionFileContent struct {
+	SourceVersion string            `json:"source_version"`
+	DeploymentID  string            `json:"deployment_id"`
+	DeployedAt    time.Time         `json:"deployed_at"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// uploadVersionFile writes the version file to the target bucket.
+func (d *Deployment) uploadVersionFile(ctx context.Context) error {
+	key := d.objectKey(d.VersionFile.Key)
+
+	content, err := json.MarshalIndent(versionFileContent{
+		SourceVersion: d.VersionFile.SourceVersion,
+		DeploymentID:  d.ID,
+		DeployedAt:    time.Now().UTC(),
+		Metadata:      d.VersionFile.Metadata,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode version file: %w", err)
+	}
+
+	err = d.recordWrite(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return d.target.Put(ctx, PutInput{
+		Key:  key,
+		Body: bytes.NewReader(content),
+		Size: int64(len(content)),
+		Metadata: ObjectMetadata{
+			ContentType: "application/json",
+			// Clients poll this file to detect new versions, so it must never be cached.
+			CacheControl: "no-cache",
+		},
+	})
+}
+
+// DeployedSourceVersion returns the source version in the version file with the given key in the target, or an
+// empty string if there is no version file.
+func (d *Deployment) DeployedSourceVersion(ctx context.Context, key string) (string, error) {
+	body, err := d.target.Get(ctx, d.objectKey(key))
+	if err != nil || body == nil {
+		return "", err
+	}
+	defer body.Close()
+
+	var content versionFileContent
+	err = json.NewDecoder(body).Decode(&content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode version file %s: %w", d.objectKey(key), err)
+	}
+
+	return content.SourceVersion, nil
+}
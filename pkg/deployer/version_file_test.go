@@ -0,0 +1,60 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"testing"
+)
+
+func TestDeploy_writesVersionFile(t *testing.T) {
+	client := s3fake.New()
+	for _, version := range []string{"v42", "v43"} {
+		d, key := newTestDeployment(t, client, map[string]string{"index.html": version})
+		d.TargetPrefix = "site/"
+		d.VersionFile = &VersionFile{Key: DefaultVersionFileKey, SourceVersion: version, Metadata: map[string]string{"commit": "abc123"}}
+		if _, err := d.Deploy(context.Background(), key, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// The version file is not part of the artifact, and is written again by every deployment.
+		object := client.Object("target", "site/version.json")
+		if object == nil {
+			t.Fatalf("expected the version file to be written under the prefix, got %v", client.Keys("target"))
+		}
+		if object.ContentType != "application/json" || object.CacheControl != "no-cache" {
+			t.Errorf("expected an uncached JSON file, got %s with cache control %q", object.ContentType, object.CacheControl)
+		}
+		var content versionFileContent
+		if err := json.Unmarshal(object.Body, &content); err != nil {
+			t.Fatal(err)
+		}
+		if content.SourceVersion != version || content.DeploymentID != d.ID || content.DeployedAt.IsZero() || content.Metadata["commit"] != "abc123" {
+			t.Errorf("expected the version file to describe deployment %s of %s, got %+v", d.ID, version, content)
+		}
+	}
+}
+
+func TestDeployedSourceVersion(t *testing.T) {
+	d, _ := newTestDeployment(t, s3fake.New(), nil)
+	d.TargetPrefix = "site/"
+	d.VersionFile = &VersionFile{Key: DefaultVersionFileKey, SourceVersion: "v42"}
+
+	version, err := d.DeployedSourceVersion(context.Background(), DefaultVersionFileKey)
+	if err != nil || version != "" {
+		t.Fatalf("expected no version before the version file is written, got %q (%v)", version, err)
+	}
+
+	err = d.uploadVersionFile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err = d.DeployedSourceVersion(context.Background(), DefaultVersionFileKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "v42" {
+		t.Errorf("expected the version of the version file, got %q", version)
+	}
+}
@@ -0,0 +1,64 @@
+package deployer
+
+import (
+	"context"
+)
+
+// objectVersioner is implemented by target stores that keep versions of objects, such as versioned S3 buckets.
+type objectVersioner interface {
+	// PutVersion uploads an object like Put, and returns the ID of the version it created, or an empty string if
+	// the store does not keep versions of the object.
+	PutVersion(ctx context.Context, input PutInput) (string, error)
+	// RestoreVersion copies the given version of the object with the given key over its current version.
+	RestoreVersion(ctx context.Context, key string, versionID string) error
+}
+
+// objectVersion identifies a version of an object.
+type objectVersion struct {
+	key       string
+	versionID string
+}
+
+// putObject uploads an object, and records the ID of the version it created if the target keeps versions.
+func (d *Deployment) putObject(ctx context.Context, input PutInput) error {
+	versioner, ok := d.target.(objectVersioner)
+	if !ok {
+		return d.target.Put(ctx, input)
+	}
+
+	versionID, err := versioner.PutVersion(ctx, input)
+	if err != nil {
+		return err
+	}
+	if versionID != "" {
+		if d.objectVersions == nil {
+			d.objectVersions = make(map[string]string)
+		}
+		d.objectVersions[input.Key] = versionID
+	}
+	return nil
+}
+
+// ObjectVersions returns the IDs of the versions the deployment created of the objects it uploaded, keyed by object
+// key. It is empty if the target does not keep versions. Files that were unchanged, or promoted from the staging
+// area, are not included.
+func (d *Deployment) ObjectVersions() map[string]string {
+	versions := make(map[string]string, len(d.objectVersions))
+	for key, versionID := range d.objectVersions {
+		versions[key] = versionID
+	}
+	return versions
+}
+
+// currentVersion returns the ID of the current version of the object with the given key, or an empty string if the
+// target does not keep versions of it.
+func (d *Deployment) currentVersion(ctx context.Context, key string) (string, error) {
+	if _, ok := d.target.(objectVersioner); !ok {
+		return "", nil
+	}
+	head, err := d.target.Head(ctx, key)
+	if err != nil || head == nil {
+		return "", err
+	}
+	return head.VersionID, nil
+}
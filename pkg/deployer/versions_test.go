@@ -0,0 +1,59 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/nsbno/terraform-provider-static-file-deploy/pkg/deployer/s3fake"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// backupCountingS3API is a recordingS3API that counts the objects copied other than from a version of themselves,
+// i.e. the backups made of them.
+type backupCountingS3API struct {
+	*recordingS3API
+	backups int
+}
+
+func (c *backupCountingS3API) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if !strings.Contains(aws.ToString(params.CopySource), "?versionId=") {
+		c.backups++
+	}
+	return c.Client.CopyObject(ctx, params, optFns...)
+}
+
+func TestDeploy_rollsBackToObjectVersions(t *testing.T) {
+	ctx := context.Background()
+	client := &backupCountingS3API{recordingS3API: &recordingS3API{Client: s3fake.New(), failKey: "version.json"}}
+	client.Versioning = true
+	putTestObjects(t, client.Client, "target", map[string]string{"index.html": "old"})
+	d, source := newTestDeployment(t, client, map[string]string{"index.html": "new", "about.html": "about"})
+	d.RollbackOnFailure = true
+	d.VersionFile = &VersionFile{Key: "version.json"}
+
+	_, err := d.Deploy(ctx, source, nil)
+	if err == nil {
+		t.Fatal("expected the deployment to fail")
+	}
+
+	if objects := testObjects(client.Client, "target"); !reflect.DeepEqual(objects, map[string]string{"index.html": "old"}) {
+		t.Errorf("expected only the original object to be left, got %q", objects)
+	}
+	if client.backups != 0 {
+		t.Errorf("expected the original version to be restored without a backup, got %d backups", client.backups)
+	}
+	versions := d.ObjectVersions()
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("target"), Key: aws.String("index.html"), VersionId: aws.String(versions["index.html"])})
+	if err != nil {
+		t.Fatalf("expected the deployed version of index.html, got %v", err)
+	}
+	if body, _ := io.ReadAll(object.Body); string(body) != "new" {
+		t.Errorf("expected the deployed version of index.html, got one with %q", body)
+	}
+	if versions["about.html"] == "" {
+		t.Errorf("expected the version of about.html, got %v", versions)
+	}
+}
@@ -0,0 +1,45 @@
+package deployer
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"time"
+)
+
+// WebIdentityRole is an IAM role assumed with a web identity token, e.g. the OIDC token of a GitHub Actions or GitLab
+// CI job.
+type WebIdentityRole struct {
+	RoleARN string
+	// SessionName is the name of the role session. Empty means a name generated by the AWS SDK.
+	SessionName string
+	// Token is the web identity token. If it is empty, the token is read from TokenFile whenever the credentials are
+	// refreshed, so that a CI runner can replace the file before the token expires.
+	Token     string
+	TokenFile string
+	// Duration is how long the credentials of the role are valid. Zero means the maximum session duration of the role.
+	Duration time.Duration
+}
+
+// webIdentityToken is a web identity token given as a value rather than a file.
+type webIdentityToken string
+
+func (t webIdentityToken) GetIdentityToken() ([]byte, error) {
+	return []byte(t), nil
+}
+
+// Credentials returns cached credentials of the role, which is assumed with an STS client created from cfg.
+func (r WebIdentityRole) Credentials(cfg aws.Config) aws.CredentialsProvider {
+	return r.credentials(sts.NewFromConfig(cfg))
+}
+
+func (r WebIdentityRole) credentials(client stscreds.AssumeRoleWithWebIdentityAPIClient) aws.CredentialsProvider {
+	var token stscreds.IdentityTokenRetriever = stscreds.IdentityTokenFile(r.TokenFile)
+	if r.Token != "" {
+		token = webIdentityToken(r.Token)
+	}
+	return aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(client, r.RoleARN, token, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = r.SessionName
+		o.Duration = r.Duration
+	}))
+}
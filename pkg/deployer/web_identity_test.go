@@ -0,0 +1,62 @@
+package deployer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeWebIdentitySTS is an STS client that records the token roles are assumed with.
+type fakeWebIdentitySTS struct {
+	input *sts.AssumeRoleWithWebIdentityInput
+}
+
+func (c *fakeWebIdentitySTS) AssumeRoleWithWebIdentity(ctx context.Context, input *sts.AssumeRoleWithWebIdentityInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	c.input = input
+	return &sts.AssumeRoleWithWebIdentityOutput{Credentials: &types.Credentials{
+		AccessKeyId:     aws.String("AKID"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("session"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestWebIdentityRole_credentials(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		role  WebIdentityRole
+		token string
+	}{
+		{name: "token value", role: WebIdentityRole{RoleARN: "arn:aws:iam::123456789012:role/deploy", Token: "value-token", TokenFile: tokenFile}, token: "value-token"},
+		{name: "token file", role: WebIdentityRole{RoleARN: "arn:aws:iam::123456789012:role/deploy", TokenFile: tokenFile, SessionName: "ci", Duration: time.Hour}, token: "file-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &fakeWebIdentitySTS{}
+			creds, err := test.role.credentials(client).Retrieve(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if creds.AccessKeyID != "AKID" {
+				t.Errorf("unexpected credentials: %+v", creds)
+			}
+			if aws.ToString(client.input.WebIdentityToken) != test.token || aws.ToString(client.input.RoleArn) != test.role.RoleARN {
+				t.Errorf("unexpected request: %+v", client.input)
+			}
+			if test.role.SessionName != "" && aws.ToString(client.input.RoleSessionName) != test.role.SessionName {
+				t.Errorf("expected the session name %q, got %q", test.role.SessionName, aws.ToString(client.input.RoleSessionName))
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// webhookTimeout bounds how long a webhook endpoint may take to respond.
+const webhookTimeout = 30 * time.Second
+
+// renderWebhookPayload renders the webhook payload for the given summary.
+// If payloadTemplate is empty the summary is sent as JSON, otherwise it is rendered as a Go template with the summary as data.
+func renderWebhookPayload(payloadTemplate string, summary DeploymentSummary) ([]byte, error) {
+	if payloadTemplate == "" {
+		return json.Marshal(summary)
+	}
+
+	tmpl, err := template.New("payload").Option("missingkey=error").Parse(payloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render payload template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// PostWebhook sends the deployment summary to the given URL as an HTTP POST request.
+func (d *Deployer) PostWebhook(ctx context.Context, url string, headers map[string]string, payloadTemplate string, summary DeploymentSummary) error {
+	payload, err := renderWebhookPayload(payloadTemplate, summary)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook responded with %s: %s", resp.Status, body)
+	}
+
+	return nil
+}
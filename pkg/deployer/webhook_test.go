@@ -0,0 +1,64 @@
+package deployer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostWebhook_sendsSummaryAsJSON(t *testing.T) {
+	var received DeploymentSummary
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	summary := DeploymentSummary{DeploymentID: "abc", Target: "target", Status: StatusSucceeded}
+	err := (&Deployer{}).PostWebhook(context.Background(), server.URL, map[string]string{"Authorization": "Bearer token"}, "", summary)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received.DeploymentID != "abc" || received.Target != "target" {
+		t.Errorf("unexpected payload: %+v", received)
+	}
+	if authorization != "Bearer token" {
+		t.Errorf("expected custom header to be sent, got %q", authorization)
+	}
+}
+
+func TestPostWebhook_rendersPayloadTemplate(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	defer server.Close()
+
+	summary := DeploymentSummary{Target: "my-site", Status: StatusFailed}
+	err := (&Deployer{}).PostWebhook(context.Background(), server.URL, nil, `{"text": "Deploy to {{.Target}}: {{.Status}}"}`, summary)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received != `{"text": "Deploy to my-site: FAILED"}` {
+		t.Errorf("unexpected payload: %s", received)
+	}
+}
+
+func TestPostWebhook_failsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := (&Deployer{}).PostWebhook(context.Background(), server.URL, nil, "", DeploymentSummary{})
+	if err == nil {
+		t.Fatal("expected an error for a non-2xx response")
+	}
+}